@@ -0,0 +1,203 @@
+// Package amenities finds the amenities of a listing in its Persian description
+package amenities
+
+import (
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+// phrases mention an amenity, they are matched as whole words after persian.Normalize
+var phrases = map[types.Amenity][]string{
+	types.Balcony:  {"بالکن", "تراس", "ایوان"},
+	types.Rooftop:  {"روف گاردن", "روفگاردن", "روف", "باغ بام", "بام سبز", "roof garden", "roof"},
+	types.Pool:     {"استخر", "pool"},
+	types.Sauna:    {"سونا", "sauna"},
+	types.Jacuzzi:  {"جکوزی", "jacuzzi"},
+	types.Gym:      {"سالن ورزش", "سالن بدنسازی", "باشگاه بدنسازی", "gym"},
+	types.Lobby:    {"لابی", "lobby"},
+	types.Guard:    {"نگهبان", "نگهبانی", "سرایدار", "سرایداری", "لابی من", "لابیمن", "حراست"},
+	types.Storage:  {"انباری", "انبار"},
+	types.Parking:  {"پارکینگ", "پارکینک", "parking"},
+	types.Elevator: {"آسانسور", "اسانسور", "elevator"},
+}
+
+// renovations are checked in order, "نیاز به بازسازی" must win over "بازسازی"
+var renovations = []struct {
+	renovation types.Renovation
+	phrases    []string
+}{
+	{types.NeedsRenovation, []string{"نیاز به بازسازی", "نیازمند بازسازی", "مناسب بازسازی", "کلنگی"}},
+	{types.NewlyBuilt, []string{"نوساز", "کلید نخورده", "کلیدنخورده", "تازه ساز"}},
+	{types.Renovated, []string{"بازسازی شده", "بازسازی کامل", "تازه بازسازی", "فول بازسازی", "بازسازی"}},
+}
+
+var documents = []struct {
+	document types.DocumentType
+	phrases  []string
+}{
+	{types.SingleDeed, []string{"سند تک برگ", "تک برگ", "تکبرگ", "سند تکبرگ"}},
+	{types.MultiDeed, []string{"منگوله دار", "منگوله ای", "سند دفترچه ای"}},
+	{types.Endowment, []string{"وقفی", "اوقافی"}},
+	{types.Promissory, []string{"قولنامه ای", "قولنامه"}},
+}
+
+var orientations = map[string]types.Orientation{
+	"شمالی": types.North,
+	"جنوبی": types.South,
+	"شرقی":  types.East,
+	"غربی":  types.West,
+}
+
+// orientationCues are the words after which شمالی and the like describe the unit, not a street or a
+// neighborhood like "جنت آباد جنوبی"
+var orientationCues = map[string]bool{
+	"واحد": true, "ملک": true, "ساختمان": true, "اپارتمان": true, "خانه": true, "ویلا": true, "منزل": true,
+	"متر": true, "متری": true, "جهت": true, "نما": true, "بر": true, "کلنگی": true, "زمین": true,
+}
+
+// negations before a phrase, as in "بدون استخر", and after it, as in "استخر ندارد"
+var (
+	negationsBefore = map[string]bool{"بدون": true, "فاقد": true}
+	negationsAfter  = map[string]bool{"ندارد": true, "نداره": true, "ندارند": true, "نمیباشد": true}
+)
+
+// Extract returns the amenities the description or the structured features of a listing mention
+func Extract(description string, features []string) models.Amenities {
+	words := wordsOf(description, features)
+	result := models.Amenities{
+		Balcony: has(words, types.Balcony),
+		Rooftop: has(words, types.Rooftop),
+		Pool:    has(words, types.Pool),
+		Sauna:   has(words, types.Sauna),
+		Jacuzzi: has(words, types.Jacuzzi),
+		Gym:     has(words, types.Gym),
+		Lobby:   has(words, types.Lobby),
+		Guard:   has(words, types.Guard),
+	}
+	for _, renovation := range renovations {
+		if mentions(words, renovation.phrases) {
+			result.Renovation = renovation.renovation
+			break
+		}
+	}
+	for _, document := range documents {
+		if mentions(words, document.phrases) {
+			result.Document = document.document
+			break
+		}
+	}
+	result.Orientation = orientation(words)
+	return result
+}
+
+// Has tells whether the description or the structured features of a listing mention an amenity
+func Has(description string, features []string, amenity types.Amenity) bool {
+	return has(wordsOf(description, features), amenity)
+}
+
+// Parse reads an amenity typed by a user, by its name or one of its Persian phrases
+func Parse(value string) (types.Amenity, bool) {
+	value = persian.Normalize(value)
+	for amenity, amenityPhrases := range phrases {
+		if value == string(amenity) {
+			return amenity, true
+		}
+		for _, phrase := range amenityPhrases {
+			if value == persian.Normalize(phrase) {
+				return amenity, true
+			}
+		}
+	}
+	return "", false
+}
+
+// All returns the amenities in a stable order
+func All() []types.Amenity {
+	return []types.Amenity{types.Balcony, types.Rooftop, types.Pool, types.Sauna, types.Jacuzzi, types.Gym,
+		types.Lobby, types.Guard, types.Storage, types.Parking, types.Elevator}
+}
+
+// Of returns the amenities a listing has, in the order of All
+func Of(post models.PostHistory) []types.Amenity {
+	found := map[types.Amenity]bool{
+		types.Balcony: post.Amenities.Balcony, types.Rooftop: post.Amenities.Rooftop, types.Pool: post.Amenities.Pool,
+		types.Sauna: post.Amenities.Sauna, types.Jacuzzi: post.Amenities.Jacuzzi, types.Gym: post.Amenities.Gym,
+		types.Lobby: post.Amenities.Lobby, types.Guard: post.Amenities.Guard, types.Storage: post.HasStorage,
+		types.Parking: post.HasParking, types.Elevator: post.HasElevator,
+	}
+	has := make([]types.Amenity, 0)
+	for _, amenity := range All() {
+		if found[amenity] {
+			has = append(has, amenity)
+		}
+	}
+	return has
+}
+
+func wordsOf(description string, features []string) []string {
+	// features are separate sentences, a newline keeps the words of two of them from forming a phrase
+	return strings.Fields(persian.Normalize(description + "\n" + strings.Join(features, "\n")))
+}
+
+func has(words []string, amenity types.Amenity) bool {
+	return mentions(words, phrases[amenity])
+}
+
+// mentions tells whether one of the phrases appears in words without being negated
+func mentions(words []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		phrase := strings.Fields(persian.Normalize(candidate))
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			if !equalAt(words, i, phrase) {
+				continue
+			}
+			if !negated(words, i, i+len(phrase)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// negated tells whether the phrase between start and end is negated, also as part of a list like
+// "فاقد استخر و سونا" or "استخر و سونا ندارد"
+func negated(words []string, start int, end int) bool {
+	before := start - 1
+	for before >= 2 && words[before] == "و" {
+		before -= 2
+	}
+	after := end
+	for after+2 < len(words) && words[after] == "و" {
+		after += 2
+	}
+	return (before >= 0 && negationsBefore[words[before]]) || (after < len(words) && negationsAfter[words[after]])
+}
+
+func orientation(words []string) types.Orientation {
+	for i, word := range words {
+		if i > 0 && orientationCues[words[i-1]] {
+			if value, exists := orientations[word]; exists {
+				return value
+			}
+		}
+		// "رو به شمال" and "رو به جنوب"
+		if i >= 2 && words[i-2] == "رو" && words[i-1] == "به" {
+			if value, exists := orientations[word+"ی"]; exists {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+func equalAt(words []string, i int, phrase []string) bool {
+	for j, word := range phrase {
+		if words[i+j] != word {
+			return false
+		}
+	}
+	return true
+}
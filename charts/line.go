@@ -0,0 +1,164 @@
+package charts
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+const (
+	defaultWidth  = 800
+	defaultHeight = 400
+	padding       = 40
+	gridLines     = 4
+)
+
+var (
+	Blue      = color.RGBA{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff}
+	LightBlue = color.RGBA{R: 0xae, G: 0xc7, B: 0xe8, A: 0xff}
+	Orange    = color.RGBA{R: 0xff, G: 0x7f, B: 0x0e, A: 0xff}
+	grid      = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+	axis      = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xff}
+)
+
+// ErrNoData is returned when a chart has no point to draw
+var ErrNoData = errors.New("chart has no data")
+
+// Series is one line of a chart, points with a NaN value are skipped
+type Series struct {
+	Color  color.RGBA
+	Points []float64
+}
+
+// LineChart draws series sharing the same x positions. It has no text, the caller describes it in the caption.
+type LineChart struct {
+	Width  int
+	Height int
+	Series []Series
+}
+
+// Bounds returns the smallest and largest point of all series
+func (chart LineChart) Bounds() (float64, float64, error) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, series := range chart.Series {
+		for _, point := range series.Points {
+			if math.IsNaN(point) {
+				continue
+			}
+			low = math.Min(low, point)
+			high = math.Max(high, point)
+		}
+	}
+	if math.IsInf(low, 1) {
+		return 0, 0, ErrNoData
+	}
+	return low, high, nil
+}
+
+// PNG renders the chart as a PNG image
+func (chart LineChart) PNG() ([]byte, error) {
+	low, high, err := chart.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	if high == low {
+		low, high = low*0.9, high*1.1
+		if high == low {
+			high = low + 1
+		}
+	}
+
+	width, height := chart.Width, chart.Height
+	if width == 0 || height == 0 {
+		width, height = defaultWidth, defaultHeight
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	plotWidth, plotHeight := width-2*padding, height-2*padding
+	for i := 0; i <= gridLines; i++ {
+		y := padding + plotHeight*i/gridLines
+		line(img, padding, y, width-padding, y, grid, 1)
+	}
+	line(img, padding, padding, padding, height-padding, axis, 1)
+	line(img, padding, height-padding, width-padding, height-padding, axis, 1)
+
+	for _, series := range chart.Series {
+		points := len(series.Points)
+		position := func(i int) (int, int) {
+			x := padding
+			if points > 1 {
+				x += plotWidth * i / (points - 1)
+			}
+			y := height - padding - int(float64(plotHeight)*(series.Points[i]-low)/(high-low))
+			return x, y
+		}
+
+		previous := -1
+		for i, point := range series.Points {
+			if math.IsNaN(point) {
+				continue
+			}
+			x, y := position(i)
+			dot(img, x, y, series.Color, 3)
+			if previous >= 0 {
+				px, py := position(previous)
+				line(img, px, py, x, y, series.Color, 2)
+			}
+			previous = i
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// line draws a line with Bresenham's algorithm
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA, thickness int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		dot(img, x0, y0, c, thickness)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func dot(img *image.RGBA, x, y int, c color.RGBA, size int) {
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			img.SetRGBA(x+i-size/2, y+j-size/2, c)
+		}
+	}
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/amenities"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+var (
+	renovationChoices = map[string]string{
+		"new": string(types.NewlyBuilt), "نوساز": string(types.NewlyBuilt),
+		"renovated": string(types.Renovated), "بازسازی شده": string(types.Renovated),
+		"needs renovation": string(types.NeedsRenovation), "نیاز به بازسازی": string(types.NeedsRenovation),
+		"کلنگی": string(types.NeedsRenovation),
+	}
+	documentChoices = map[string]string{
+		"single deed": string(types.SingleDeed), "تک برگ": string(types.SingleDeed),
+		"multi deed": string(types.MultiDeed), "منگوله دار": string(types.MultiDeed),
+		"endowment": string(types.Endowment), "وقفی": string(types.Endowment),
+		"promissory": string(types.Promissory), "قولنامه ای": string(types.Promissory),
+	}
+	orientationChoices = map[string]string{
+		"north": string(types.North), "شمالی": string(types.North),
+		"south": string(types.South), "جنوبی": string(types.South),
+		"east": string(types.East), "شرقی": string(types.East),
+		"west": string(types.West), "غربی": string(types.West),
+	}
+	// anyChoices clear a choice filter option
+	anyChoices = []string{"any", "فرقی نمی‌کند"}
+)
+
+// parseAmenities reads a comma separated list of amenities, by name or in Persian
+func parseAmenities(value string) string {
+	names := make([]string, 0)
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '،' }) {
+		if amenity, ok := amenities.Parse(part); ok {
+			names = append(names, string(amenity))
+		} else if strings.TrimSpace(part) != "" {
+			log.Printf("Error parsing Amenity: %s", part)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// parseChoice reads one of the choices of a filter option, an empty choice for "any". It returns false when the
+// value is none of them, so the user is asked again instead of the option being cleared.
+func parseChoice(value string, choices map[string]string) (string, bool) {
+	value = persian.Normalize(value)
+	for _, text := range anyChoices {
+		if value == persian.Normalize(text) {
+			return "", true
+		}
+	}
+	for text, choice := range choices {
+		if value == persian.Normalize(text) || value == choice {
+			return choice, true
+		}
+	}
+	return "", false
+}
+
+// promptChoiceAgain tells the user the answer was none of the choices and lists them
+func promptChoiceAgain(chatID int, user models.User, choices map[string]string) {
+	texts := append([]string(nil), anyChoices...)
+	for text := range choices {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+	sendMessage(chatID, tr(user, "filter.invalid_choice", strings.Join(texts, "، ")))
+}
+
+// formatAmenities lists the amenities of a listing for its card
+func formatAmenities(user models.User, post models.PostHistory) string {
+	names := make([]string, 0)
+	for _, amenity := range amenities.Of(post) {
+		names = append(names, string(amenity))
+	}
+
+	text := ""
+	if len(names) > 0 {
+		text += tr(user, "listing.amenities", strings.Join(names, ", "))
+	}
+	details := nonEmpty(string(post.Amenities.Renovation), string(post.Amenities.Document), string(post.Amenities.Orientation))
+	if len(details) > 0 {
+		text += tr(user, "listing.condition", strings.ReplaceAll(strings.Join(details, ", "), "_", " "))
+	}
+	return text
+}
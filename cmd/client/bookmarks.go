@@ -0,0 +1,410 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const bookmarkPageSize = 10
+
+// folderInvitePrefix starts the /start payload of folder invite links
+const folderInvitePrefix = "share_"
+
+// sendBookmarks sends the bookmarks of a user as cards, the ones of a folder when folderID is not 0,
+// followed by the folders to browse
+func sendBookmarks(chatID int, user models.User, folderID uint) {
+	title, role := tr(user, "bookmark.title"), models.FOLDER_OWNER
+	if folderID != 0 {
+		folder, folderRole, err := bookmarkService.FolderAccess(user, folderID)
+		if err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		title, role = tr(user, "bookmark.folder_title", folder.Name), folderRole
+	}
+	listings, err := bookmarkService.List(user, folderID)
+	if err != nil {
+		log.Printf("Error finding bookmarks: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "bookmark.fetch_error"))
+		return
+	}
+	folders, err := bookmarkService.Folders(user)
+	if err != nil {
+		log.Printf("Error finding bookmark folders: %v", err)
+	}
+	shared, err := bookmarkService.SharedFolders(user)
+	if err != nil {
+		log.Printf("Error finding shared bookmark folders: %v", err)
+	}
+
+	if len(listings) == 0 {
+		sendMessage(chatID, tr(user, "bookmark.empty", title, tr(user, "bookmark.help")))
+	} else if len(listings) > bookmarkPageSize {
+		sendMessage(chatID, tr(user, "bookmark.count_newest", title, len(listings), bookmarkPageSize))
+		listings = listings[:bookmarkPageSize]
+	} else {
+		sendMessage(chatID, tr(user, "bookmark.count", title, len(listings)))
+	}
+
+	now := time.Now()
+	for _, listing := range listings {
+		buttons := bookmarkButtons(listing, user, folderID != 0)
+		sendMessageWithInlineKeyboard(chatID, formatBookmark(user, listing, now), InlineKeyboardMarkup{InlineKeyboard: buttons})
+	}
+
+	rows := [][]InlineKeyboardButton{{{Text: tr(user, "bookmark.all_button"), Data: "bm_folder_0"}}}
+	if len(listings) >= services.MinCompared {
+		rows[0] = append(rows[0], InlineKeyboardButton{Text: tr(user, "bookmark.compare_button"), Data: fmt.Sprintf("bm_compare_%d", folderID)})
+	}
+	for _, folder := range folders {
+		rows = append(rows, []InlineKeyboardButton{{Text: "📁 " + folder.Name, Data: fmt.Sprintf("bm_folder_%d", folder.ID)}})
+	}
+	for _, folder := range shared {
+		rows = append(rows, []InlineKeyboardButton{{Text: "👥 " + folder.Folder.Name, Data: fmt.Sprintf("bm_folder_%d", folder.Folder.ID)}})
+	}
+	switch {
+	case folderID == 0:
+	case role == models.FOLDER_OWNER:
+		rows = append(rows,
+			[]InlineKeyboardButton{
+				{Text: tr(user, "bookmark.invite_viewer_button"), Data: fmt.Sprintf("bm_inviteviewer_%d", folderID)},
+				{Text: tr(user, "bookmark.invite_editor_button"), Data: fmt.Sprintf("bm_inviteeditor_%d", folderID)},
+			},
+			[]InlineKeyboardButton{
+				{Text: tr(user, "bookmark.unshare_button"), Data: fmt.Sprintf("bm_unshare_%d", folderID)},
+				{Text: tr(user, "bookmark.delete_folder_button"), Data: fmt.Sprintf("bm_delfolder_%d", folderID)},
+			})
+	default:
+		rows = append(rows, []InlineKeyboardButton{{Text: tr(user, "bookmark.leave_button"), Data: fmt.Sprintf("bm_leave_%d", folderID)}})
+	}
+	if len(listings) > 0 || folderID != 0 {
+		sendMessageWithInlineKeyboard(chatID, tr(user, "bookmark.help"), InlineKeyboardMarkup{InlineKeyboard: rows})
+	} else if len(rows) > 1 {
+		sendMessageWithInlineKeyboard(chatID, tr(user, "bookmark.folders"), InlineKeyboardMarkup{InlineKeyboard: rows})
+	}
+}
+
+// bookmarkButtons are the buttons of a bookmark card, in a folder members vote and comment on listings
+// and only the user who added a listing may move or remove it
+func bookmarkButtons(listing services.BookmarkedListing, user models.User, inFolder bool) [][]InlineKeyboardButton {
+	bookmark := listing.Bookmark
+	rows := make([][]InlineKeyboardButton, 0)
+	if listing.Latest.ID != 0 {
+		row := []InlineKeyboardButton{{Text: tr(user, "bookmark.details_button"), Data: fmt.Sprintf("post_%d", listing.Latest.ID)}}
+		if listing.Latest.PostURL != "" {
+			row = append(row, InlineKeyboardButton{Text: tr(user, "listing.view_button"), URL: listing.Latest.PostURL})
+		}
+		rows = append(rows, row)
+	}
+	if inFolder {
+		rows = append(rows, []InlineKeyboardButton{
+			{Text: fmt.Sprintf("👍 %d", listing.Up), Data: fmt.Sprintf("bm_up_%d", bookmark.ID)},
+			{Text: fmt.Sprintf("👎 %d", listing.Down), Data: fmt.Sprintf("bm_down_%d", bookmark.ID)},
+			{Text: fmt.Sprintf("💬 %d", listing.Comments), Data: fmt.Sprintf("bm_comments_%d", bookmark.ID)},
+		})
+	}
+	if bookmark.UserID != user.ID {
+		return rows
+	}
+	return append(rows, []InlineKeyboardButton{
+		{Text: tr(user, "bookmark.move_button"), Data: fmt.Sprintf("bm_move_%d", bookmark.ID)},
+		{Text: tr(user, "bookmark.remove_button"), Data: fmt.Sprintf("bm_remove_%d", bookmark.ID)},
+	})
+}
+
+// formatBookmark describes a bookmark with the latest prices of its listing
+func formatBookmark(user models.User, listing services.BookmarkedListing, now time.Time) string {
+	bookmark, post := listing.Bookmark, listing.Latest
+	if post.ID == 0 {
+		return tr(user, "bookmark.unavailable", bookmark.ID, bookmark.PostID)
+	}
+
+	text := fmt.Sprintf("🔖 #%d %s\n", bookmark.ID, post.Title)
+	if post.BuyMode == types.Rent {
+		text += tr(user, "bookmark.rent", formatPrice(float64(post.Deposit)), formatPrice(float64(post.Rent)))
+	} else {
+		text += tr(user, "bookmark.price", formatPrice(float64(post.Price)))
+	}
+	text += tr(user, "listing.place", post.City, post.Neighborhood, post.Area)
+	text += formatLifecycle(user, bookmark.Post, now)
+	if bookmark.Folder != nil {
+		text += tr(user, "bookmark.folder", bookmark.Folder.Name)
+	}
+	if bookmark.Note != "" {
+		text += tr(user, "bookmark.note", bookmark.Note)
+	}
+	return text
+}
+
+// addBookmark bookmarks a post for a user
+func addBookmark(chatID int, user models.User, postID uint) {
+	bookmark, err := bookmarkService.Add(user, postID)
+	if errors.Is(err, services.ErrBookmarkNotFound) {
+		sendMessage(chatID, tr(user, "bookmark.not_found"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving bookmark: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "bookmark.add_error"))
+		return
+	}
+	archiveBookmarkPhotos(postID)
+	sendMessage(chatID, tr(user, "bookmark.added", bookmark.ID, bookmark.ID))
+}
+
+// archiveBookmarkPhotos archives the photos of a bookmarked post in the background,
+// bookmarked posts keep their photos and the website may delete them any time
+func archiveBookmarkPhotos(postID uint) {
+	go func() {
+		if err := imageService.ArchivePost(context.Background(), postID); err != nil {
+			log.Printf("Error archiving bookmarked post photos: %v", err)
+		}
+	}()
+}
+
+// handleBookmarkCallback handles the bookmark buttons, their data is "bm_<action>_<id>" or "bm_to_<id>_<folder id>"
+func handleBookmarkCallback(chatID int, user models.User, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "bm_"), "_")
+	ids := make([]uint, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			sendMessage(chatID, tr(user, "invalid_selection"))
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 || (parts[0] == "to" && len(ids) != 2) {
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+
+	switch parts[0] {
+	case "add":
+		addBookmark(chatID, user, ids[0])
+	case "folder":
+		sendBookmarks(chatID, user, ids[0])
+	case "compare":
+		compareBookmarks(chatID, user, ids[0])
+	case "remove":
+		if err := bookmarkService.Remove(user, ids[0]); err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		sendMessage(chatID, tr(user, "bookmark.removed", ids[0]))
+	case "move":
+		sendFolderChoices(chatID, user, ids[0])
+	case "to":
+		bookmark, err := bookmarkService.Move(user, ids[0], ids[1])
+		if err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		if bookmark.Folder == nil {
+			sendMessage(chatID, tr(user, "bookmark.unfiled", bookmark.ID))
+		} else {
+			sendMessage(chatID, tr(user, "bookmark.moved", bookmark.ID, bookmark.Folder.Name))
+		}
+	case "up", "down":
+		value := int8(1)
+		if parts[0] == "down" {
+			value = -1
+		}
+		if err := bookmarkService.Vote(user, ids[0], value); err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		sendMessage(chatID, tr(user, "bookmark.voted", ids[0]))
+	case "comments":
+		sendBookmarkComments(chatID, user, ids[0])
+	case "inviteviewer", "inviteeditor":
+		role := models.FOLDER_VIEWER
+		if parts[0] == "inviteeditor" {
+			role = models.FOLDER_EDITOR
+		}
+		sendFolderInvite(chatID, user, ids[0], role)
+	case "unshare":
+		if err := bookmarkService.StopSharing(user, ids[0]); err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		sendMessage(chatID, tr(user, "bookmark.unshared"))
+	case "leave":
+		if err := bookmarkService.Leave(user, ids[0]); err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		sendMessage(chatID, tr(user, "bookmark.left"))
+	case "delfolder":
+		if err := bookmarkService.DeleteFolder(user, ids[0]); err != nil {
+			sendBookmarkError(chatID, user, err)
+			return
+		}
+		sendMessage(chatID, tr(user, "bookmark.folder_deleted"))
+	default:
+		sendMessage(chatID, tr(user, "invalid_selection"))
+	}
+}
+
+// sendFolderChoices lists the folders a user owns or edits to move a bookmark to
+func sendFolderChoices(chatID int, user models.User, bookmarkID uint) {
+	folders, err := bookmarkService.Folders(user)
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	shared, err := bookmarkService.SharedFolders(user)
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	for _, folder := range shared {
+		if folder.Role == models.FOLDER_EDITOR {
+			folders = append(folders, folder.Folder)
+		}
+	}
+	if len(folders) == 0 {
+		sendMessage(chatID, tr(user, "bookmark.no_folders"))
+		return
+	}
+	rows := [][]InlineKeyboardButton{{{Text: tr(user, "bookmark.no_folder_button"), Data: fmt.Sprintf("bm_to_%d_0", bookmarkID)}}}
+	for _, folder := range folders {
+		rows = append(rows, []InlineKeyboardButton{{Text: "📁 " + folder.Name, Data: fmt.Sprintf("bm_to_%d_%d", bookmarkID, folder.ID)}})
+	}
+	sendMessageWithInlineKeyboard(chatID, tr(user, "bookmark.move_to", bookmarkID), InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// setBookmarkNote reads "note=<bookmark id> <text>", an empty text removes the note
+func setBookmarkNote(chatID int, user models.User, value string) {
+	idText, note, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(value, "note=")), " ")
+	id, err := strconv.ParseUint(persian.LatinDigits(idText), 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "bookmark.note_format"))
+		return
+	}
+	bookmark, err := bookmarkService.SetNote(user, uint(id), note)
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	if bookmark.Note == "" {
+		sendMessage(chatID, tr(user, "bookmark.note_removed", bookmark.ID))
+	} else {
+		sendMessage(chatID, tr(user, "bookmark.note_saved", bookmark.ID))
+	}
+}
+
+// commentBookmark reads "comment=<bookmark id> <text>"
+func commentBookmark(chatID int, user models.User, value string) {
+	idText, text, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(value, "comment=")), " ")
+	id, err := strconv.ParseUint(persian.LatinDigits(idText), 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "bookmark.comment_format"))
+		return
+	}
+	if _, err := bookmarkService.Comment(user, uint(id), text); err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	sendMessage(chatID, tr(user, "bookmark.commented", id))
+}
+
+func sendBookmarkComments(chatID int, user models.User, bookmarkID uint) {
+	comments, err := bookmarkService.Comments(user, bookmarkID)
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	text := tr(user, "bookmark.comments", bookmarkID)
+	if len(comments) == 0 {
+		text += tr(user, "bookmark.no_comments")
+	}
+	for _, comment := range comments {
+		author := tr(user, "bookmark.member")
+		if comment.UserID == user.ID {
+			author = tr(user, "bookmark.you")
+		}
+		text += fmt.Sprintf("%s, %s: %s\n", author, formatDateTime(comment.CreatedAt), comment.Text)
+	}
+	sendMessage(chatID, text+tr(user, "bookmark.comment_help", bookmarkID))
+}
+
+// sendFolderInvite sends an invite link to a folder, the user forwards it to whoever they share the folder with
+func sendFolderInvite(chatID int, user models.User, folderID uint, role models.FolderRole) {
+	invite, err := bookmarkService.Invite(user, folderID, role, time.Now())
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	payload := folderInvitePrefix + invite.Token
+	link := tr(user, "bookmark.invite_start", payload)
+	if botName := utils.GetConfig("TELEGRAM_BOT_NAME"); botName != "" {
+		link = fmt.Sprintf("https://t.me/%s?start=%s", botName, payload)
+	}
+	sendMessage(chatID, tr(user, "bookmark.invite",
+		tr(user, "bookmark.role."+string(role)), formatDate(invite.ExpiresAt), link))
+}
+
+// joinSharedFolder makes a user a member of the folder of an invite token
+func joinSharedFolder(chatID int, user models.User, token string) {
+	folder, role, err := bookmarkService.Join(user, token, time.Now())
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	if role == models.FOLDER_OWNER {
+		sendMessage(chatID, tr(user, "bookmark.own_invite", folder.Name))
+		return
+	}
+	sendMessage(chatID, tr(user, "bookmark.joined", folder.Name, tr(user, "bookmark.role."+string(role))))
+	sendBookmarks(chatID, user, folder.ID)
+}
+
+// createBookmarkFolder reads "folder=<name>"
+func createBookmarkFolder(chatID int, user models.User, value string) {
+	folder, err := bookmarkService.CreateFolder(user, strings.TrimPrefix(value, "folder="))
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	sendMessage(chatID, tr(user, "bookmark.folder_created", folder.Name))
+}
+
+func sendBookmarkError(chatID int, user models.User, err error) {
+	switch {
+	case errors.Is(err, services.ErrBookmarkNotFound):
+		sendMessage(chatID, tr(user, "bookmark.error.not_found"))
+	case errors.Is(err, services.ErrInvalidFolderName):
+		sendMessage(chatID, tr(user, "bookmark.error.folder_name"))
+	case errors.Is(err, services.ErrFolderExists):
+		sendMessage(chatID, tr(user, "bookmark.error.folder_exists"))
+	case errors.Is(err, services.ErrTooManyFolders):
+		sendMessage(chatID, tr(user, "bookmark.error.too_many_folders"))
+	case errors.Is(err, services.ErrNoteTooLong):
+		sendMessage(chatID, tr(user, "bookmark.error.note_too_long"))
+	case errors.Is(err, services.ErrFolderReadOnly):
+		sendMessage(chatID, tr(user, "bookmark.error.read_only"))
+	case errors.Is(err, services.ErrInviteInvalid):
+		sendMessage(chatID, tr(user, "bookmark.error.invite_invalid"))
+	case errors.Is(err, services.ErrInvalidComment):
+		sendMessage(chatID, tr(user, "bookmark.error.comment"))
+	default:
+		log.Printf("Error updating bookmark: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "bookmark.error.update"))
+	}
+}
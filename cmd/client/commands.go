@@ -3,58 +3,104 @@ package client
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
 	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
 )
 
+// defaultMonitorTrendDays is how far back the Monitor command reports crawl trends when MONITOR_TREND_DAYS is not set
+const defaultMonitorTrendDays = 7
+
+// monitorTrendDays returns how many days of crawl trends the Monitor command reports
+func monitorTrendDays() int {
+	days, err := strconv.Atoi(utils.GetConfig("MONITOR_TREND_DAYS"))
+	if err != nil || days <= 0 {
+		return defaultMonitorTrendDays
+	}
+	return days
+}
+
+// initializeCommands registers the commands by stable IDs, the buttons that run them are translated
 func initializeCommands() {
 	CommandRegistry = map[string]Command{
-		"/start": &StartCommand{},
+		"start": &StartCommand{},
 		//user commands
-		"Help":          &HelpCommand{},
-		"Send Location": &SendLocationCommand{},
-		"Search":        &SearchCommand{},
-		"Populars":      &PopularsCommand{},
-		"Get Redius":    &GetRediusCommand{},
-
-		"Setting":             &SettingCommand{},
-		"Filter":              &FilterCommand{},
-		"Create New Filter":   &CreateFilterCommand{},
-		"Location Attachment": &GetLocationAttachmentCommand{},
-		"SaveFilter":      &SaveFilterCommand{},
-		"CancelFilter":         &CancelFilterCommand{},
-
-		"Select Resource Website": &GetResourceWebsite{},
-		"Bookmark":                &BookmarkCommand{},
-		"Get Website":             &GetWebsiteCommand{},
-		"Get Bookmark Id":         &GetBookmarkIDCommand{},
-		"Export CSV":              &ExportCSVCommand{},
+		"help":          &HelpCommand{},
+		"send_location": &SendLocationCommand{},
+		"search":        &SearchCommand{},
+		"populars":      &PopularsCommand{},
+		"get_radius":    &GetRediusCommand{},
+
+		"setting":             &SettingCommand{},
+		"filter":              &FilterCommand{},
+		"create_filter":       &CreateFilterCommand{},
+		"location_attachment": &GetLocationAttachmentCommand{},
+		"save_filter":         &SaveFilterCommand{},
+		"cancel_filter":       &CancelFilterCommand{},
+
+		"select_website":         &GetResourceWebsite{},
+		"bookmark":               &BookmarkCommand{},
+		"watchlist":              &WatchlistCommand{},
+		"subscribe":              &SubscribeCommand{},
+		"market":                 &MarketCommand{},
+		"market_index":           &MarketIndexCommand{},
+		"create_watchlist":       &CreateWatchlistCommand{},
+		"get_website":            &GetWebsiteCommand{},
+		"get_bookmark_id":        &GetBookmarkIDCommand{},
+		"bookmark_note":          &BookmarkNoteCommand{},
+		"create_bookmark_folder": &CreateBookmarkFolderCommand{},
+		"comment_bookmark":       &CommentBookmarkCommand{},
+		"compare":                &CompareCommand{},
+		"financing":              &FinancingCommand{},
+		"set_affordability":      &SetAffordabilityCommand{},
+		"digests":                &DigestsCommand{},
+		"set_digest":             &SetDigestCommand{},
+		"export_csv":             &ExportCSVCommand{},
+		"language":               &LanguageCommand{},
 
 		//admin commands
-		"Premium":           &PremiumCommand{},
-		"Errors":            &ErrorsCommand{},
-		"Clients":           &ClientCommand{},  //admin and super-admin
-		"Filters":           &FiltersCommand{}, //
-		"Change To Premium": &GetPremiumIdCommand{},
+		"premium":           &PremiumCommand{},
+		"errors":            &ErrorsCommand{},
+		"clients":           &ClientCommand{},  //admin and super-admin
+		"filters":           &FiltersCommand{}, //
+		"user_filters":      &UserFiltersCommand{},
+		"rent_rate":         &RentRateCommand{},
+		"set_rent_rate":     &SetRentRateCommand{},
+		"loan_products":     &LoanProductsCommand{},
+		"set_loan_product":  &SetLoanProductCommand{},
+		"review_queue":      &ReviewQueueCommand{},
+		"change_to_premium": &GetPremiumIdCommand{},
 		//super-admin commads
-		"Admins":          &AdminCommand{},
-		"Get Admin Id":    &GetAdminIdCommand{},
-		"Create Admin":    &CreateAdminCommand{},
-		"Monitor":         &MonitorCommand{},
-		"Advertisements":  &AdvertisementsCommand{},
-		"Crawler Setting": &CrawlerSettingCommand{},
+		"admins":          &AdminCommand{},
+		"get_admin_id":    &GetAdminIdCommand{},
+		"create_admin":    &CreateAdminCommand{},
+		"monitor":         &MonitorCommand{},
+		"advertisements":  &AdvertisementsCommand{},
+		"crawler_setting": &CrawlerSettingCommand{},
+		"permissions":     &PermissionsCommand{},
+		"set_permission":  &SetPermissionCommand{},
+		"demote":          &DemoteCommand{},
+		"revoke":          &RevokeRoleCommand{},
+		"audit_log":       &AuditLogCommand{},
 	}
+	initializeCommandLabels()
 }
 
 // //////////////////////////////////
 type CreateFilterCommand struct{}
 
 func (cmd *CreateFilterCommand) Execute(message *Message, user *models.User) {
-	showFilterOptions(message.Chat.ID)
+	showFilterOptions(message.Chat.ID, *user)
 }
-func (cmd *CreateFilterCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER, models.ADMIN, models.SUPER_ADMIN}
+func (cmd *CreateFilterCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // /////////////////////////////////// User Commands
@@ -62,27 +108,61 @@ type GetResourceWebsite struct{}
 
 func (cmd *GetResourceWebsite) Execute(message *Message, user *models.User) {
 
-	msg := "Select resource type for search"
-	
+	msg := tr(*user, "website.select")
+
 	sendMessageWithInlineKeyboard(message.Chat.ID, msg, generateResourceTypeButtons())
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
 
-func (cmd *GetResourceWebsite) AllowedRoles() []models.Role {
-	return []models.Role{models.USER, models.ADMIN, models.SUPER_ADMIN}
+func (cmd *GetResourceWebsite) Permission() models.Permission {
+	return ""
 }
 
 // /////////////////////////////////// User Commands
 type ExportCSVCommand struct{}
 
 func (cmd *ExportCSVCommand) Execute(message *Message, user *models.User) {
+	lastFilterItem, err := userRepository.GetLastFilterItem(user.ID)
+	if err != nil || lastFilterItem == nil {
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "filter.select_first"), getKeyboard(*user))
+		return
+	}
 
-	msg := "you enter csv"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	posts, err := filterRepository.SearchPostHistory(*lastFilterItem)
+	if err != nil {
+		log.Printf("Error fetching posts: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "posts.fetch_error"), getKeyboard(*user))
+		return
+	}
+	if len(posts) == 0 {
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "posts.none_match"), getKeyboard(*user))
+		return
+	}
+
+	msg := tr(*user, "export.done", len(posts))
+	if limit := quotaService.ExportLimit(*user); limit > 0 && len(posts) > limit {
+		msg = tr(*user, "export.limited", limit, len(posts), planName(*user, user.Type), limit)
+		posts = posts[:limit]
+	}
+
+	content, err := utils.ExportCSV(posts)
+	if err != nil {
+		log.Printf("Error exporting posts: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "export.error"), getKeyboard(*user))
+		return
+	}
+	if _, err := sendFile(int64(message.Chat.ID), content, ".csv"); err != nil {
+		log.Printf("Error sending csv file: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		msg = tr(*user, "export.send_error")
+	}
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
 
-func (cmd *ExportCSVCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER, models.ADMIN, models.SUPER_ADMIN}
+func (cmd *ExportCSVCommand) Permission() models.Permission {
+	return models.PERM_EXPORT
 }
 
 // ///////////////////////////////////
@@ -90,23 +170,26 @@ type GetWebsiteCommand struct{}
 
 func (cmd *GetWebsiteCommand) Execute(message *Message, user *models.User) {
 
-	msg := fmt.Sprintf("Now all your searches will be from the ' %s 'website.", message.Value)
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "website.selected", message.Value)
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
 
-func (cmd *GetWebsiteCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER, models.ADMIN, models.SUPER_ADMIN}
+func (cmd *GetWebsiteCommand) Permission() models.Permission {
+	return ""
 }
 
 // ////////////////////////////////////
 type StartCommand struct{}
 
 func (cmd *StartCommand) Execute(message *Message, user *models.User) {
-	sendMessageWithKeyboard(message.Chat.ID, getWelcomeMessage(message.From.FirstName, user.Role), getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, getWelcomeMessage(*user, message.From.FirstName), getKeyboard(*user))
+	if strings.HasPrefix(message.Value, folderInvitePrefix) {
+		joinSharedFolder(message.Chat.ID, *user, strings.TrimPrefix(message.Value, folderInvitePrefix))
+	}
 }
 
-func (cmd *StartCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER, models.ADMIN, models.SUPER_ADMIN}
+func (cmd *StartCommand) Permission() models.Permission {
+	return ""
 }
 
 ////// confirm filter
@@ -114,15 +197,19 @@ func (cmd *StartCommand) AllowedRoles() []models.Role {
 type SaveFilterCommand struct{}
 
 func (cmd *SaveFilterCommand) Execute(message *Message, user *models.User) {
-	
+	if err := quotaService.CheckSaveFilter(*user); err != nil {
+		sendMessageWithKeyboard(message.Chat.ID, quotaMessage(*user, err), getKeyboard(*user))
+		return
+	}
+
 	createFilter(user.ID)
-	msg := fmt.Sprintf("filter saved")
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "filter.saved")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
 
-func (cmd *SaveFilterCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *SaveFilterCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // ////// cancel filter
@@ -132,12 +219,12 @@ func (cmd *CancelFilterCommand) Execute(message *Message, user *models.User) {
 	// Here, we would typically remove the user's filter or mark it as canceled
 	cancelFilter(user.ID)
 
-	msg := fmt.Sprintf("Your filter has been canceled :(")
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "filter.canceled")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
 
-func (cmd *CancelFilterCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *CancelFilterCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 //////////////////////////////////////
@@ -146,35 +233,162 @@ type GetRediusCommand struct{}
 
 func (cmd *GetRediusCommand) Execute(message *Message, user *models.User) {
 
-	msg := fmt.Sprintf("You entered radius: %s", message.Value[7:])
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "radius.entered", message.Value[7:])
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *GetRediusCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *GetRediusCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // //////////////////////////////////
 type GetLocationAttachmentCommand struct{}
 
 func (cmd *GetLocationAttachmentCommand) Execute(message *Message, user *models.User) {
-	msg := fmt.Sprintf("Your selected location is with latitude: %f, and longitude: %f👌\n\nNow send me your desired radius with pattern👉 \"redius=<number>\"", message.Location.Latitude, message.Location.Longitude)
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "location.selected", message.Location.Latitude, message.Location.Longitude)
+	// the location is also the point compared listings are measured from
+	if _, err := userRepository.UpdateUser(user.ID, map[string]interface{}{
+		"latitude": message.Location.Latitude, "longitude": message.Location.Longitude,
+	}); err != nil {
+		log.Printf("Error saving user location: %v", err)
+	} else {
+		msg += tr(*user, "location.saved")
+	}
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *GetLocationAttachmentCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *GetLocationAttachmentCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // ////////////////////////////////
 type SettingCommand struct{}
 
 func (cmd *SettingCommand) Execute(message *Message, user *models.User) {
-	msg := "You entered setting"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	usage, err := quotaService.Usage(*user)
+	if err != nil {
+		log.Printf("Error fetching usage: %v", err)
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "plan.error"), getKeyboard(*user))
+		return
+	}
+
+	msg := tr(*user, "plan.usage",
+		planName(*user, usage.UserType),
+		usageOf(*user, usage.Filters, usage.Quota.MaxFilters),
+		usageOf(*user, usage.WatchLists, usage.Quota.MaxWatchLists),
+		usageOf(*user, int64(usage.SearchesToday), usage.Quota.MaxDailySearches),
+		limitOf(*user, usage.Quota.MaxExportRows),
+		usage.Quota.MinRefreshInterval)
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
+}
+func (cmd *SettingCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// //////////////////////////////////
+type MarketCommand struct{}
+
+func (cmd *MarketCommand) Execute(message *Message, user *models.User) {
+	sendMarketOverview(message.Chat.ID, *user)
+}
+func (cmd *MarketCommand) Permission() models.Permission {
+	return ""
+}
+
+// //////////////////////////////////
+type MarketIndexCommand struct{}
+
+func (cmd *MarketIndexCommand) Execute(message *Message, user *models.User) {
+	sendMarketIndices(message.Chat.ID, *user, message.Value)
+}
+func (cmd *MarketIndexCommand) Permission() models.Permission {
+	return ""
+}
+
+// //////////////////////////////////
+type SubscribeCommand struct{}
+
+func (cmd *SubscribeCommand) Execute(message *Message, user *models.User) {
+	msg := tr(*user, "subscribe.plan", planName(*user, user.Type))
+	subscription, err := subscriptionService.Current(*user)
+	if err != nil {
+		log.Printf("Error finding subscription: %v", err)
+	} else if subscription.ID != 0 {
+		msg += tr(*user, "subscribe.until", i18n.Date(languageOf(*user), subscription.EndsAt))
+	}
+	msg += tr(*user, "subscribe.choose")
+
+	buttons := make([][]InlineKeyboardButton, 0)
+	for _, plan := range subscriptionService.Plans() {
+		buttons = append(buttons, []InlineKeyboardButton{{
+			Text: tr(*user, "subscribe.plan_button", plan.Title, plan.Price),
+			Data: "subscribe_" + plan.ID,
+		}})
+	}
+	sendMessageWithInlineKeyboard(message.Chat.ID, msg, InlineKeyboardMarkup{InlineKeyboard: buttons})
 }
-func (cmd *SettingCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *SubscribeCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// //////////////////////////////////
+type WatchlistCommand struct{}
+
+func (cmd *WatchlistCommand) Execute(message *Message, user *models.User) {
+	watchLists, err := watchListRepository.FindByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error finding watchlists: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "watchlist.error"), getKeyboard(*user))
+		return
+	}
+
+	quota := quotaService.QuotaFor(*user)
+	msg := tr(*user, "watchlist.list", usageOf(*user, int64(len(watchLists)), quota.MaxWatchLists))
+	for _, watchList := range watchLists {
+		msg += tr(*user, "watchlist.item", watchList.ID, watchList.FilterItemID, watchList.RefreshInterval)
+		msg += fmt.Sprint("-------------------------\n")
+	}
+	msg += tr(*user, "watchlist.help")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
+}
+func (cmd *WatchlistCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// //////////////////////////////////
+type CreateWatchlistCommand struct{}
+
+func (cmd *CreateWatchlistCommand) Execute(message *Message, user *models.User) {
+	var filterID uint
+	var refreshInterval int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(message.Value, "watch="), "%d,%d", &filterID, &refreshInterval); err != nil || refreshInterval <= 0 {
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "watchlist.invalid"), getKeyboard(*user))
+		return
+	}
+
+	filterItem, err := filterRepository.FindByID(filterID)
+	if err != nil || filterItem.UserID != user.ID {
+		sendMessageWithKeyboard(message.Chat.ID, tr(*user, "filter.not_found"), getKeyboard(*user))
+		return
+	}
+
+	if err := quotaService.CheckWatchList(*user, refreshInterval); err != nil {
+		sendMessageWithKeyboard(message.Chat.ID, quotaMessage(*user, err), getKeyboard(*user))
+		return
+	}
+
+	msg := tr(*user, "done")
+	_, err = watchListRepository.Create(models.WatchList{UserID: user.ID, FilterItemID: filterItem.ID, RefreshInterval: refreshInterval})
+	if err != nil {
+		log.Printf("Error saving watchlist: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		msg = tr(*user, "watchlist.save_error")
+	}
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
+}
+func (cmd *CreateWatchlistCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // //////////////////////////////////
@@ -182,95 +396,150 @@ func (cmd *SettingCommand) AllowedRoles() []models.Role {
 type HelpCommand struct{}
 
 func (cmd *HelpCommand) Execute(message *Message, user *models.User) {
-	msg := `Real Estate Finder Bot!
-                    /search to find properties based on filters like price, location, and type.
-                    /help for more information.`
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "help.text")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
 
-func (cmd *HelpCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER, models.ADMIN, models.SUPER_ADMIN}
+func (cmd *HelpCommand) Permission() models.Permission {
+	return ""
 }
 
 // /////////////////////////////////
 type SendLocationCommand struct{}
 
 func (cmd *SendLocationCommand) Execute(message *Message, user *models.User) {
-	msg := "You can send me location📍 with your telegram attachment 👇"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "location.prompt")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
-func (cmd *SendLocationCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *SendLocationCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // /////////////////////////////////
 type BookmarkCommand struct{}
 
 func (cmd *BookmarkCommand) Execute(message *Message, user *models.User) {
-	bookmarks, err := bookmarkRepository.FindAll(uint(message.From.ID))
-	var msg string
-	if err != nil {
-		log.Printf("Error finding bookmarks: %v", err)
-		msg = "There was an error fetching your bookmarks. Please try again later."
-	} else {
-		// msg = "Your bookmarks:\n"
-		for _, b := range bookmarks {
-			msg += fmt.Sprintf("   ID: %d, Post ID: %+v\n", b.ID, b.Post)
-			msg += fmt.Sprint("-------------------------\n")
-		}
-	}
-
-	msg += "\nTo create new bookmark:\n\tsend me Post ID with pattern 'B=<number>'"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendBookmarks(message.Chat.ID, *user, 0)
 }
-func (cmd *BookmarkCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *BookmarkCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // /////////////////////////////////
 type GetBookmarkIDCommand struct{}
 
 func (cmd *GetBookmarkIDCommand) Execute(message *Message, user *models.User) {
-	var msg string
-	id, err := strconv.ParseInt(message.Value[2:], 10, 64)
+	id, err := strconv.ParseUint(message.Value[2:], 10, 64)
 	if err != nil {
-		msg = "Invalid ID format. Please use 'B=<number>'."
+		sendMessage(message.Chat.ID, tr(*user, "bookmark.invalid_id"))
+		return
 	}
-	post, err := postRepository.FindByID(uint(id))
+	addBookmark(message.Chat.ID, *user, uint(id))
+}
+func (cmd *GetBookmarkIDCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type BookmarkNoteCommand struct{}
+
+func (cmd *BookmarkNoteCommand) Execute(message *Message, user *models.User) {
+	setBookmarkNote(message.Chat.ID, *user, message.Value)
+}
+func (cmd *BookmarkNoteCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type CreateBookmarkFolderCommand struct{}
+
+func (cmd *CreateBookmarkFolderCommand) Execute(message *Message, user *models.User) {
+	createBookmarkFolder(message.Chat.ID, *user, message.Value)
+}
+func (cmd *CreateBookmarkFolderCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type CommentBookmarkCommand struct{}
+
+func (cmd *CommentBookmarkCommand) Execute(message *Message, user *models.User) {
+	commentBookmark(message.Chat.ID, *user, message.Value)
+}
+func (cmd *CommentBookmarkCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type CompareCommand struct{}
+
+func (cmd *CompareCommand) Execute(message *Message, user *models.User) {
+	ids, err := parseCompareIDs(message.Value)
 	if err != nil {
-		log.Printf("Error finding post: %v", err)
-		msg = "There was an error fetching post. Please try again later."
-	} else {
-		user, err := userRepository.Find(uint(message.From.ID))
-		if err != nil {
-			log.Printf("Error finding user: %v", err)
-			msg = "There was an error fetching user. Please try again later."
-		} else {
-			err = bookmarkRepository.Save(post, user)
-			if err != nil {
-				log.Printf("Error saving bookmark: %v", err)
-				msg = "There was an error bookmarking this post. Please try again later."
-			} else {
-				msg = "Done!"
-			}
-		}
+		sendMessage(message.Chat.ID, tr(*user, "invalid_format")+" "+tr(*user, "compare.help"))
+		return
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendComparison(message.Chat.ID, *user, ids)
+}
+func (cmd *CompareCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type FinancingCommand struct{}
+
+func (cmd *FinancingCommand) Execute(message *Message, user *models.User) {
+	sendAffordability(message.Chat.ID, *user)
 }
-func (cmd *GetBookmarkIDCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *FinancingCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type SetAffordabilityCommand struct{}
+
+func (cmd *SetAffordabilityCommand) Execute(message *Message, user *models.User) {
+	setAffordability(message.Chat.ID, *user, message.Value)
+}
+func (cmd *SetAffordabilityCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type DigestsCommand struct{}
+
+func (cmd *DigestsCommand) Execute(message *Message, user *models.User) {
+	sendDigests(message.Chat.ID, *user)
+}
+func (cmd *DigestsCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
+}
+
+// /////////////////////////////////
+type SetDigestCommand struct{}
+
+func (cmd *SetDigestCommand) Execute(message *Message, user *models.User) {
+	setDigest(message.Chat.ID, *user, message.Value)
+}
+func (cmd *SetDigestCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // ////////////////////////////////////
 type SearchCommand struct{}
 
 func (cmd *SearchCommand) Execute(message *Message, user *models.User) {
-	msg := fmt.Sprintf("wait please :)")
-	searchLastFilter(message.Chat.ID, int(user.ID))
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	if err := quotaService.UseSearch(*user); err != nil {
+		sendMessageWithKeyboard(message.Chat.ID, quotaMessage(*user, err), getKeyboard(*user))
+		return
+	}
+
+	msg := tr(*user, "search.wait")
+	searchLastFilter(message.Chat.ID, *user)
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
-func (cmd *SearchCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *SearchCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // ////////////////////////////////////
@@ -293,26 +562,26 @@ func (cmd *FilterCommand) Execute(message *Message, user *models.User) {
 	// }
 
 	// msg := "Select a filter to apply:"
-	showFilterMenu(message.Chat.ID, user.ID)
+	showFilterMenu(message.Chat.ID, *user)
 	// sendMessageWithInlineKeyboard(message.Chat.ID, msg, createInlineKeyboardFromOptions(filterOptions))
 }
 
-func (cmd *FilterCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *FilterCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 // ////////////////////////////////
 type PopularsCommand struct{}
 
 func (cmd *PopularsCommand) Execute(message *Message, user *models.User) {
-	msg := "    All Popular Advertisements:\n\n"
+	msg := tr(*user, "populars.title")
 	ads, err := postRepository.GetMostVisitedPost()
 	if err != nil {
-		msg = "Error fetching posts: "
-		log.Fatal(msg + err.Error())
+		msg = tr(*user, "posts.fetch_error_later")
+		tracking.Capture(models.BOT_ERROR, fmt.Errorf("fetching posts: %w", err), tracking.Origin{})
 	} else {
 		if len(ads) == 0 {
-			msg = "Nothing found"
+			msg = tr(*user, "nothing_found")
 		} else {
 			for _, a := range ads {
 				msg += fmt.Sprintf("%s  \n", a.Title) //, a.Post.WatchedNum)
@@ -320,11 +589,11 @@ func (cmd *PopularsCommand) Execute(message *Message, user *models.User) {
 			}
 		}
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *PopularsCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.USER}
+func (cmd *PopularsCommand) Permission() models.Permission {
+	return models.PERM_SEARCH
 }
 
 ///////////////////////////////////////////////// Admin Commands
@@ -332,81 +601,150 @@ func (cmd *PopularsCommand) AllowedRoles() []models.Role {
 type ErrorsCommand struct{}
 
 func (cmd *ErrorsCommand) Execute(message *Message, user *models.User) {
-	msg := "You entered errors"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendErrorsPage(message.Chat.ID, *user, errorsView{status: models.ERROR_OPEN})
 }
-func (cmd *ErrorsCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.ADMIN, models.SUPER_ADMIN}
+func (cmd *ErrorsCommand) Permission() models.Permission {
+	return models.PERM_VIEW_ERRORS
 }
 
 // ///////////////////////////////
 type ClientCommand struct{}
 
 func (cmd *ClientCommand) Execute(message *Message, user *models.User) {
-	msg := "All Clients:\n"
+	msg := tr(*user, "clients.title")
 	users, err := userRepository.FindAllUsersByRole(models.USER)
 	if err != nil {
-		msg = "Error fetching clients: "
-		log.Fatal(msg + err.Error())
+		msg = tr(*user, "clients.error")
+		tracking.Capture(models.BOT_ERROR, fmt.Errorf("fetching clients: %w", err), tracking.Origin{})
 	} else {
 		if len(users) == 0 {
-			msg = "No clients found"
+			msg = tr(*user, "clients.none")
 		} else {
 			for _, u := range users {
-				msg += fmt.Sprintf("   ID: %d, TelegramID: %d\n", u.ID, u.TelegramID)
+				msg += tr(*user, "clients.item", u.ID, u.TelegramID)
 				msg += fmt.Sprint("-------------------------\n")
 			}
 		}
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *ClientCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.ADMIN, models.SUPER_ADMIN}
+func (cmd *ClientCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_USERS
 }
 
 // ////////////////////////////////
 type FiltersCommand struct{}
 
 func (cmd *FiltersCommand) Execute(message *Message, user *models.User) {
-	msg := "You entered filters"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendFiltersAnalytics(message.Chat.ID, *user)
+}
+func (cmd *FiltersCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_USERS
+}
+
+// ///////////////////////////////
+type UserFiltersCommand struct{}
+
+func (cmd *UserFiltersCommand) Execute(message *Message, user *models.User) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(message.Value, "filters="), 10, 64)
+	if err != nil {
+		sendMessage(message.Chat.ID, tr(*user, "filters.invalid_user"))
+		return
+	}
+	sendUserFilters(message.Chat.ID, *user, uint(id))
 }
-func (cmd *FiltersCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.ADMIN, models.SUPER_ADMIN}
+func (cmd *UserFiltersCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_USERS
+}
+
+// ///////////////////////////////
+type RentRateCommand struct{}
+
+func (cmd *RentRateCommand) Execute(message *Message, user *models.User) {
+	sendRentRate(message.Chat.ID, *user)
+}
+func (cmd *RentRateCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_SETTINGS
+}
+
+// ///////////////////////////////
+type SetRentRateCommand struct{}
+
+func (cmd *SetRentRateCommand) Execute(message *Message, user *models.User) {
+	setRentRate(message.Chat.ID, user, message.Value)
+}
+func (cmd *SetRentRateCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_SETTINGS
+}
+
+// ///////////////////////////////
+type LoanProductsCommand struct{}
+
+func (cmd *LoanProductsCommand) Execute(message *Message, user *models.User) {
+	sendLoanProducts(message.Chat.ID, *user)
+}
+func (cmd *LoanProductsCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_SETTINGS
+}
+
+// ///////////////////////////////
+type SetLoanProductCommand struct{}
+
+func (cmd *SetLoanProductCommand) Execute(message *Message, user *models.User) {
+	setLoanProduct(message.Chat.ID, *user, message.Value)
+}
+func (cmd *SetLoanProductCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_SETTINGS
+}
+
+// ///////////////////////////////
+type ReviewQueueCommand struct{}
+
+func (cmd *ReviewQueueCommand) Execute(message *Message, user *models.User) {
+	sendRiskQueue(message.Chat.ID, *user)
+}
+func (cmd *ReviewQueueCommand) Permission() models.Permission {
+	return models.PERM_REVIEW_LISTINGS
 }
 
 // ///////////////////////////////
 type PremiumCommand struct{}
 
 func (cmd *PremiumCommand) Execute(message *Message, user *models.User) {
-	msg := "Send me user id with pattern👉 \"Id=<number>\""
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "premium.prompt", subscriptionService.Plans()[0].Days)
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *PremiumCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.ADMIN, models.SUPER_ADMIN}
+func (cmd *PremiumCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_USERS
 }
 
 // ////////////////////////////////
 type GetPremiumIdCommand struct{}
 
 func (cmd *GetPremiumIdCommand) Execute(message *Message, user *models.User) {
-	msg := fmt.Sprintf("User with id :%s changed to Premium client.", message.Value[3:])
+	var msg string
 	id, err := strconv.ParseInt(message.Value[3:], 10, 64)
 	if err != nil {
-		msg = "Invalid ID format. Please use 'Id=<number>'."
+		msg = tr(*user, "premium.invalid")
+	} else if client, err := userRepository.Find(uint(id)); err != nil || client.ID == 0 || client.Role != models.USER {
+		msg = tr(*user, "user.not_found", id)
 	} else {
-		_, err := userRepository.UpdateUserType(uint(id), models.PREMIUM)
+		// admins grant the shortest plan instead of Premium without an end, it expires like a paid one
+		plan := subscriptionService.Plans()[0]
+		subscription, err := subscriptionService.Grant(client, plan.ID)
 		if err != nil {
-			msg = fmt.Sprintf("Error updating user type: %v", err)
+			msg = tr(*user, "premium.error", err)
+		} else {
+			msg = tr(*user, "premium.granted", id, plan.Days, i18n.Date(languageOf(*user), subscription.EndsAt))
 		}
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *GetPremiumIdCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.ADMIN, models.SUPER_ADMIN}
+func (cmd *GetPremiumIdCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_USERS
 }
 
 //////////////////////////////////////////////////// Super-admin commands
@@ -414,27 +752,27 @@ func (cmd *GetPremiumIdCommand) AllowedRoles() []models.Role {
 type AdminCommand struct{}
 
 func (cmd *AdminCommand) Execute(message *Message, user *models.User) {
-	msg := "All Admins:\n"
+	msg := tr(*user, "admins.title")
 	users, err := userRepository.FindAllUsersByRole(models.ADMIN)
 	if err != nil {
-		msg = "Error fetching clients: "
-		log.Fatal(msg + err.Error())
+		msg = tr(*user, "clients.error")
+		tracking.Capture(models.BOT_ERROR, fmt.Errorf("fetching clients: %w", err), tracking.Origin{})
 	} else {
 		if len(users) == 0 {
-			msg = "No admins found"
+			msg = tr(*user, "admins.none")
 		} else {
 			for _, u := range users {
-				msg += fmt.Sprintf("   ID: %d, TelegramID: %d\n", u.ID, u.TelegramID)
+				msg += tr(*user, "clients.item", u.ID, u.TelegramID)
 				msg += fmt.Sprint("-------------------------\n")
 			}
 		}
-		msg += "\nEnter 'c' to Create Admin"
+		msg += tr(*user, "admins.create_hint")
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *AdminCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.SUPER_ADMIN}
+func (cmd *AdminCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
 }
 
 // //////////////////////////////////
@@ -442,34 +780,79 @@ type MonitorCommand struct{}
 
 func (cmd *MonitorCommand) Execute(message *Message, user *models.User) {
 
-	msg := "You entered Monitor\nCrawls\n\n"
+	trendDays := monitorTrendDays()
+	msg := tr(*user, "monitor.title", trendDays)
 	/////////////
-	crawlHistories := postRepository.GetAllCrawlHistory()
-	for _, crawl := range crawlHistories {
-		msg += fmt.Sprintf("\nID: %v, CPU: %v, Memory: %v\n", crawl.ID, crawl.CpuUsage, crawl.MemoryUsage)
-
+	trends, err := crawlRunRepository.DailyTrends(utils.TehranDayStart(time.Now()).AddDate(0, 0, 1-trendDays), utils.TehranLocation())
+	if err != nil {
+		msg += tr(*user, "monitor.load_error")
+	} else if len(trends) == 0 {
+		msg += tr(*user, "monitor.no_crawls")
+	}
+	for _, trend := range trends {
+		msg += tr(*user, "monitor.trend",
+			i18n.Date(languageOf(*user), trend.Day), trend.Source, trend.Runs, (time.Duration(trend.AvgDurationMs) * time.Millisecond).String(),
+			trend.RequestsNum, trend.FailureNum, float64(trend.BytesFetched)/(1<<20),
+			trend.PostNum, trend.NewPostNum, trend.UpdatedPostNum, trend.PeakRSS>>20)
+		if len(trend.ErrorCounts) > 0 {
+			classes := make([]string, 0, len(trend.ErrorCounts))
+			for class := range trend.ErrorCounts {
+				classes = append(classes, class)
+			}
+			sort.Strings(classes)
+			for i, class := range classes {
+				classes[i] = fmt.Sprintf("%s %d", class, trend.ErrorCounts[class])
+			}
+			msg += tr(*user, "monitor.errors", strings.Join(classes, ", "))
+		}
 	}
 	/////////////
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg += tr(*user, "monitor.limiters")
+	limiterStates := crawlers.Limiters().Snapshot()
+	if len(limiterStates) == 0 {
+		msg += tr(*user, "monitor.no_hosts")
+	}
+	for _, state := range limiterStates {
+		status := tr(*user, "monitor.active")
+		if state.Paused() {
+			status = tr(*user, "monitor.paused",
+				i18n.Digits(languageOf(*user), state.PausedUntil.In(utils.TehranLocation()).Format("15:04:05")))
+		}
+		msg += tr(*user, "monitor.limiter",
+			state.Host, status, state.Tokens, state.InFlight, state.Requests, state.Throttled, state.ConsecutiveBlocks)
+	}
+	proxyStates := crawlers.Identities().Proxies()
+	if len(proxyStates) > 0 {
+		msg += tr(*user, "monitor.proxies")
+		for _, proxy := range proxyStates {
+			status := tr(*user, "monitor.in_rotation")
+			if proxy.Retired {
+				status = tr(*user, "monitor.retired",
+					i18n.Digits(languageOf(*user), proxy.RetiredUntil.In(utils.TehranLocation()).Format("15:04")))
+			}
+			msg += tr(*user, "monitor.proxy", proxy.URL, status, proxy.Failures)
+		}
+	}
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
 
-func (cmd *MonitorCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.SUPER_ADMIN}
+func (cmd *MonitorCommand) Permission() models.Permission {
+	return models.PERM_CONFIGURE_CRAWLER
 }
 
 // //////////////////////////////////
 type AdvertisementsCommand struct{}
 
 func (cmd *AdvertisementsCommand) Execute(message *Message, user *models.User) {
-	msg := "    Advertisements:\n\n"
+	msg := tr(*user, "advertisements.title")
 	ads, err := postRepository.GetAllPosts()
 	if err != nil {
-		msg = "Error fetching posts: "
-		log.Fatal(msg + err.Error())
+		msg = tr(*user, "posts.fetch_error_later")
+		tracking.Capture(models.BOT_ERROR, fmt.Errorf("fetching posts: %w", err), tracking.Origin{})
 	} else {
 		if len(ads) == 0 {
-			msg = "Nothing found"
+			msg = tr(*user, "nothing_found")
 		} else {
 			for _, a := range ads {
 				msg += fmt.Sprintf("%s  \n", a.Title) //, a.Post.WatchedNum)
@@ -477,54 +860,55 @@ func (cmd *AdvertisementsCommand) Execute(message *Message, user *models.User) {
 			}
 		}
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *AdvertisementsCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.SUPER_ADMIN}
+func (cmd *AdvertisementsCommand) Permission() models.Permission {
+	return models.PERM_CONFIGURE_CRAWLER
 }
 
 // //////////////////////////////////
 type CrawlerSettingCommand struct{}
 
 func (cmd *CrawlerSettingCommand) Execute(message *Message, user *models.User) {
-	msg := "You entered Crawler Setting"
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "crawler_setting.entered")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *CrawlerSettingCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.SUPER_ADMIN}
+func (cmd *CrawlerSettingCommand) Permission() models.Permission {
+	return models.PERM_CONFIGURE_CRAWLER
 }
 
 // //////////////////////////////////
 type CreateAdminCommand struct{}
 
 func (cmd *CreateAdminCommand) Execute(message *Message, user *models.User) {
-	msg := fmt.Sprintf("User with id :%s changed to Admin.", message.Value[6:])
+	var msg string
 	id, err := strconv.ParseInt(message.Value[6:], 10, 64)
 	if err != nil {
-		msg = "Invalid ID format. Please use 'admin=<number>'."
+		msg = tr(*user, "admin.invalid")
 	} else {
+		msg = tr(*user, "admin.changed", id)
 		_, err := userRepository.UpdateUserRole(uint(id), models.ADMIN)
 		if err != nil {
-			msg = fmt.Sprintf("Error updating user role: %v", err)
+			msg = tr(*user, "admin.role_error", err)
 		}
 		// msg += "\nEnter 'c' to Create Admin"
 	}
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 }
-func (cmd *CreateAdminCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.SUPER_ADMIN}
+func (cmd *CreateAdminCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
 }
 
 // ///////////////////////////////
 type GetAdminIdCommand struct{}
 
 func (cmd *GetAdminIdCommand) Execute(message *Message, user *models.User) {
-	msg := "Send me user's ID with pattern \"admin=<number>\""
-	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(user.Role))
+	msg := tr(*user, "admin.prompt")
+	sendMessageWithKeyboard(message.Chat.ID, msg, getKeyboard(*user))
 	return
 }
-func (cmd *GetAdminIdCommand) AllowedRoles() []models.Role {
-	return []models.Role{models.SUPER_ADMIN}
+func (cmd *GetAdminIdCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
 }
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/report"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+)
+
+// parseCompareIDs reads "compare=12,15 18"
+func parseCompareIDs(value string) ([]uint, error) {
+	fields := strings.FieldsFunc(strings.TrimPrefix(value, "compare="), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '،'
+	})
+	ids := make([]uint, 0, len(fields))
+	for _, field := range fields {
+		id, err := strconv.ParseUint(strings.TrimPrefix(field, "#"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listing id %q", field)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// sendComparison sends the comparison of listings as a message and as a PDF document
+func sendComparison(chatID int, user models.User, postHistoryIDs []uint) {
+	comparison, err := comparisonService.Compare(postHistoryIDs, user)
+	if errors.Is(err, services.ErrCompareCount) {
+		sendMessage(chatID, tr(user, "compare.count", tr(user, "compare.help")))
+		return
+	}
+	if errors.Is(err, services.ErrComparedNotFound) {
+		sendMessage(chatID, tr(user, "compare.not_found",
+			strings.TrimPrefix(err.Error(), services.ErrComparedNotFound.Error()+": ")))
+		return
+	}
+	if err != nil {
+		log.Printf("Error comparing listings: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "compare.error"))
+		return
+	}
+	sendMessage(chatID, formatComparison(user, comparison))
+
+	html, err := report.ComparisonHTML(languageOf(user), comparison, time.Now())
+	if err != nil {
+		log.Printf("Error rendering comparison: %v", err)
+		return
+	}
+	// without a browser the table is sent as a web page, which opens anywhere, and the user is told why
+	fileName, content, caption := "comparison.pdf", html, tr(user, "compare.caption")
+	if pdf, err := report.PDF(html); err != nil {
+		log.Printf("Error printing comparison: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		fileName, caption = "comparison.html", tr(user, "compare.pdf_failed")
+	} else {
+		content = pdf
+	}
+	if _, err := uploadFile(int64(chatID), "sendDocument", "document", fileName, content, caption); err != nil {
+		log.Printf("Error sending comparison: %v", err)
+	}
+}
+
+// formatComparison lists the listings by number, then every figure with the value of each listing
+func formatComparison(user models.User, comparison services.Comparison) string {
+	rows := report.ComparisonRows(languageOf(user), comparison)
+	text := tr(user, "compare.title")
+	for i, value := range rows[0].Values {
+		text += i18n.Digits(languageOf(user), strconv.Itoa(i+1)) + ". " + value + "\n"
+	}
+	for _, row := range rows[1:] {
+		text += "\n" + row.Name + ":\n"
+		for i, value := range row.Values {
+			if value == "" {
+				value = tr(user, "compare.none")
+			}
+			text += "  " + i18n.Digits(languageOf(user), strconv.Itoa(i+1)) + ". " + value + "\n"
+		}
+	}
+	return text + "\n" + strings.Join(report.ComparisonNotes(languageOf(user), comparison), "\n")
+}
+
+// compareBookmarks compares the newest listings of a bookmark view, all bookmarks when folderID is 0
+func compareBookmarks(chatID int, user models.User, folderID uint) {
+	listings, err := bookmarkService.List(user, folderID)
+	if err != nil {
+		sendBookmarkError(chatID, user, err)
+		return
+	}
+	ids := make([]uint, 0, services.MaxCompared)
+	for _, listing := range listings {
+		if listing.Latest.ID != 0 && len(ids) < services.MaxCompared {
+			ids = append(ids, listing.Latest.ID)
+		}
+	}
+	sendComparison(chatID, user, ids)
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+// digestSections are the catalog names of the sections of a digest, in the order they are sent
+var digestSections = []struct {
+	section services.DigestSection
+	name    string
+}{
+	{services.SectionNew, "new"},
+	{services.SectionPriceDrops, "drops"},
+	{services.SectionRemoved, "removed"},
+}
+
+// sendDigests lists the saved filters of a user with their digest schedules
+func sendDigests(chatID int, user models.User) {
+	filters, err := filterRepository.FindByUserID(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error finding filters: %v", err)
+		sendMessage(chatID, tr(user, "digest.filters_error"))
+		return
+	}
+	if len(filters) == 0 {
+		sendMessage(chatID, tr(user, "digest.no_filters"))
+		return
+	}
+	subscriptions, err := digestService.Subscriptions(user)
+	if err != nil {
+		log.Printf("Error finding digest subscriptions: %v", err)
+		sendMessage(chatID, tr(user, "digest.fetch_error"))
+		return
+	}
+	schedules := make(map[uint]string)
+	for _, subscription := range subscriptions {
+		schedules[subscription.FilterItemID] = services.DescribeSchedule(user, subscription)
+	}
+
+	msg := tr(user, "digest.filters")
+	for _, filter := range filters {
+		schedule, exists := schedules[filter.ID]
+		if !exists {
+			schedule = tr(user, "digest.none")
+		}
+		msg += "\n" + formatFilter(user, filter) + tr(user, "digest.schedule", schedule)
+	}
+	sendMessage(chatID, msg+"\n"+tr(user, "digest.help"))
+}
+
+// setDigest reads "digest=<filter id>,<schedule>" or "digest=<filter id>,off"
+func setDigest(chatID int, user models.User, value string) {
+	filterText, scheduleText, _ := strings.Cut(strings.TrimPrefix(value, "digest="), ",")
+	filterID, err := strconv.ParseUint(strings.TrimSpace(filterText), 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "digest.invalid", tr(user, "digest.help")))
+		return
+	}
+
+	if strings.EqualFold(strings.TrimSpace(scheduleText), "off") {
+		err = digestService.Unsubscribe(user, uint(filterID))
+		if err == nil {
+			sendMessage(chatID, tr(user, "digest.off", filterID))
+			return
+		}
+	} else {
+		var schedule, subscription models.DigestSubscription
+		schedule, err = services.ParseDigestSchedule(scheduleText)
+		if err == nil {
+			subscription, err = digestService.Subscribe(user, uint(filterID), schedule)
+		}
+		if err == nil {
+			sendMessage(chatID, tr(user, "digest.subscribed", filterID, services.DescribeSchedule(user, subscription)))
+			return
+		}
+	}
+
+	switch {
+	case errors.Is(err, services.ErrInvalidDigestSchedule):
+		sendMessage(chatID, tr(user, "digest.invalid_schedule", tr(user, "digest.help")))
+	case errors.Is(err, services.ErrDigestFilterNotFound):
+		sendMessage(chatID, tr(user, "digest.filter_not_found"))
+	default:
+		log.Printf("Error saving digest: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "digest.save_error"))
+	}
+}
+
+// sendDigest sends the top listings of each section of a digest, with buttons to page through all of them
+func sendDigest(user models.User, digest services.Digest, top int) {
+	msg := tr(user, "digest.title", digest.Filter.ID,
+		formatDateTime(digest.Run.PeriodStart), formatDateTime(digest.Run.PeriodEnd))
+	buttons := make([][]InlineKeyboardButton, 0)
+	for _, section := range digestSections {
+		listings := digest.Section(section.section)
+		if len(listings) == 0 {
+			continue
+		}
+		msg += tr(user, "digest.section", tr(user, "digest.section."+section.name), len(listings))
+		for _, listing := range listings[:min(top, len(listings))] {
+			msg += formatDigestListing(user, listing)
+		}
+		if len(listings) > top {
+			buttons = append(buttons, []InlineKeyboardButton{{
+				Text: tr(user, "digest.see_all", len(listings), tr(user, "digest.label."+section.name)),
+				Data: fmt.Sprintf("dg_%s_%d_0", section.section, digest.Run.ID),
+			}})
+		}
+	}
+	sendMessageWithInlineKeyboard(int(user.TelegramID), msg, InlineKeyboardMarkup{InlineKeyboard: buttons})
+}
+
+// handleDigestCallback sends a page of a digest section, the data is dg_<section>_<run id>_<page>
+func handleDigestCallback(chatID int, user models.User, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "dg_"), "_")
+	if len(parts) != 3 {
+		sendMessage(chatID, tr(user, "digest.invalid_page"))
+		return
+	}
+	section := services.DigestSection(parts[0])
+	runID, err := strconv.ParseUint(parts[1], 10, 64)
+	page, pageErr := strconv.Atoi(parts[2])
+	if err != nil || pageErr != nil || page < 0 {
+		sendMessage(chatID, tr(user, "digest.invalid_page"))
+		return
+	}
+
+	listings, total, err := digestService.Page(user, uint(runID), section, page)
+	if errors.Is(err, services.ErrDigestNotFound) {
+		sendMessage(chatID, tr(user, "digest.not_found"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching digest page: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "digest.page_error"))
+		return
+	}
+	if total == 0 {
+		sendMessage(chatID, tr(user, "digest.empty"))
+		return
+	}
+
+	pageSize := digestService.PageSize()
+	pages := (total + pageSize - 1) / pageSize
+	msg := tr(user, "digest.page", digestSectionTitle(user, section), min(page+1, pages), pages)
+	for _, listing := range listings {
+		msg += formatDigestListing(user, listing)
+	}
+	navigationRow := make([]InlineKeyboardButton, 0)
+	if page > 0 {
+		navigationRow = append(navigationRow, InlineKeyboardButton{Text: tr(user, "page.previous"), Data: fmt.Sprintf("dg_%s_%d_%d", section, runID, page-1)})
+	}
+	if page+1 < pages {
+		navigationRow = append(navigationRow, InlineKeyboardButton{Text: tr(user, "page.next"), Data: fmt.Sprintf("dg_%s_%d_%d", section, runID, page+1)})
+	}
+	sendMessageWithInlineKeyboard(chatID, msg, InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{navigationRow}})
+}
+
+func digestSectionTitle(user models.User, section services.DigestSection) string {
+	for _, entry := range digestSections {
+		if entry.section == section {
+			return tr(user, "digest.section."+entry.name)
+		}
+	}
+	return string(section)
+}
+
+// formatDigestListing describes a listing of a digest in a few lines
+func formatDigestListing(user models.User, listing services.DigestListing) string {
+	post := listing.History
+	text := "• " + post.Title + "\n  "
+	if post.BuyMode == types.Rent {
+		text += tr(user, "digest.rent", formatPrice(float64(post.Deposit)), formatPrice(float64(post.Rent)))
+	} else {
+		text += tr(user, "digest.price", formatPrice(float64(post.Price)))
+	}
+	if listing.Drop > 0 {
+		text += tr(user, "digest.drop", listing.Drop)
+	}
+	if post.Post.RemovedAt != nil {
+		text += tr(user, "digest.gone", tr(user, "digest.status."+string(post.Post.Status)), formatDate(*post.Post.RemovedAt))
+	}
+	text += "\n  " + tr(user, "listing.place", post.City, post.Neighborhood, post.Area)
+	if post.PostURL != "" {
+		text += "  " + post.PostURL + "\n"
+	}
+	return text
+}
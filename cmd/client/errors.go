@@ -0,0 +1,196 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+)
+
+const (
+	errorsPageSize       = 5
+	errorsMessagePreview = 200
+	errorsStackPreview   = 3000
+	errorsAll            = "all"
+)
+
+// errorsView is the page and filters of the admin Errors list, it travels in the callback data
+type errorsView struct {
+	category models.ErrorCategory
+	status   models.ErrorStatus
+	page     int
+}
+
+func (view errorsView) encode() string {
+	return fmt.Sprintf("%s_%s_%d", orAll(string(view.category)), orAll(string(view.status)), view.page)
+}
+
+func parseErrorsView(parts []string) (errorsView, error) {
+	if len(parts) != 3 {
+		return errorsView{}, fmt.Errorf("invalid errors view %q", strings.Join(parts, "_"))
+	}
+	page, err := strconv.Atoi(parts[2])
+	if err != nil || page < 0 {
+		return errorsView{}, fmt.Errorf("invalid errors page %q", parts[2])
+	}
+	view := errorsView{page: page}
+	if parts[0] != errorsAll {
+		view.category = models.ErrorCategory(parts[0])
+	}
+	if parts[1] != errorsAll {
+		view.status = models.ErrorStatus(parts[1])
+	}
+	return view, nil
+}
+
+func orAll(value string) string {
+	if value == "" {
+		return errorsAll
+	}
+	return value
+}
+
+// sendErrorsPage lists one page of the grouped error events with their actions
+func sendErrorsPage(chatID int, user models.User, view errorsView) {
+	filter := models.ErrorEventFilter{Category: view.category, Status: view.status}
+	events, total, err := errorEventRepository.FindPage(filter, view.page*errorsPageSize, errorsPageSize)
+	if err != nil {
+		log.Printf("Error fetching error events: %v", err)
+		sendMessage(chatID, tr(user, "errors.fetch_error"))
+		return
+	}
+
+	pages := int((total + errorsPageSize - 1) / errorsPageSize)
+	msg := tr(user, "errors.title", orAll(string(view.category)), orAll(string(view.status)), total, view.page+1, max(pages, 1))
+	if len(events) == 0 {
+		msg += tr(user, "errors.none")
+	}
+
+	buttons := make([][]InlineKeyboardButton, 0)
+	for _, event := range events {
+		msg += tr(user, "errors.item", event.ID, event.Category, event.Status, event.Occurrences)
+		if event.Source != "" || event.City != "" {
+			msg += fmt.Sprintf("%s %s\n", event.Source, event.City)
+		}
+		msg += tr(user, "errors.last_seen", truncate(event.Message, errorsMessagePreview), formatDateTime(event.LastSeenAt))
+
+		row := []InlineKeyboardButton{{Text: tr(user, "errors.details_button", event.ID), Data: fmt.Sprintf("errors_show_%d", event.ID)}}
+		if event.Status == models.ERROR_OPEN {
+			row = append(row, InlineKeyboardButton{Text: tr(user, "errors.ack_button", event.ID), Data: fmt.Sprintf("errors_ack_%d_%s", event.ID, view.encode())})
+		}
+		if event.Status != models.ERROR_RESOLVED {
+			row = append(row, InlineKeyboardButton{Text: tr(user, "errors.resolve_button", event.ID), Data: fmt.Sprintf("errors_resolve_%d_%s", event.ID, view.encode())})
+		}
+		buttons = append(buttons, row)
+	}
+
+	categoryRow := make([]InlineKeyboardButton, 0)
+	for _, category := range []models.ErrorCategory{"", models.CRAWLER_ERROR, models.SERVICE_ERROR, models.BOT_ERROR} {
+		target := errorsView{category: category, status: view.status}
+		categoryRow = append(categoryRow, InlineKeyboardButton{Text: filterLabel(string(category), category == view.category), Data: "errors_list_" + target.encode()})
+	}
+	statusRow := make([]InlineKeyboardButton, 0)
+	for _, status := range []models.ErrorStatus{models.ERROR_OPEN, models.ERROR_ACKNOWLEDGED, models.ERROR_RESOLVED, ""} {
+		target := errorsView{category: view.category, status: status}
+		statusRow = append(statusRow, InlineKeyboardButton{Text: filterLabel(string(status), status == view.status), Data: "errors_list_" + target.encode()})
+	}
+	buttons = append(buttons, categoryRow, statusRow)
+
+	navigationRow := make([]InlineKeyboardButton, 0)
+	if view.page > 0 {
+		previous := view
+		previous.page--
+		navigationRow = append(navigationRow, InlineKeyboardButton{Text: tr(user, "page.previous"), Data: "errors_list_" + previous.encode()})
+	}
+	if view.page+1 < pages {
+		next := view
+		next.page++
+		navigationRow = append(navigationRow, InlineKeyboardButton{Text: tr(user, "page.next"), Data: "errors_list_" + next.encode()})
+	}
+	if len(navigationRow) > 0 {
+		buttons = append(buttons, navigationRow)
+	}
+
+	sendMessageWithInlineKeyboard(chatID, msg, InlineKeyboardMarkup{InlineKeyboard: buttons})
+}
+
+func filterLabel(value string, selected bool) string {
+	label := orAll(value)
+	if selected {
+		return "• " + label
+	}
+	return label
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// handleErrorsCallback handles the buttons of the Errors list: errors_list_<view>, errors_show_<id>,
+// errors_ack_<id>_<view> and errors_resolve_<id>_<view>
+func handleErrorsCallback(chatID int, user models.User, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "errors_"), "_")
+	if len(parts) < 2 {
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+
+	if parts[0] == "list" {
+		view, err := parseErrorsView(parts[1:])
+		if err != nil {
+			sendMessage(chatID, tr(user, "invalid_selection"))
+			return
+		}
+		sendErrorsPage(chatID, user, view)
+		return
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+
+	switch parts[0] {
+	case "show":
+		event, err := errorEventRepository.Find(uint(id))
+		if err != nil {
+			sendMessage(chatID, tr(user, "errors.not_found"))
+			return
+		}
+		msg := tr(user, "errors.details", event.ID, event.Category, event.Status, event.Occurrences,
+			formatDateTime(event.FirstSeenAt), formatDateTime(event.LastSeenAt))
+		if event.Source != "" || event.City != "" {
+			msg += tr(user, "errors.source", event.Source, event.City)
+		}
+		if event.URL != "" {
+			msg += tr(user, "errors.url", event.URL)
+		}
+		msg += fmt.Sprintf("\n%s\n\n%s", event.Message, truncate(event.Stack, errorsStackPreview))
+		sendMessage(chatID, msg)
+	case "ack", "resolve":
+		view, err := parseErrorsView(parts[2:])
+		if err != nil {
+			sendMessage(chatID, tr(user, "invalid_selection"))
+			return
+		}
+		status := models.ERROR_ACKNOWLEDGED
+		if parts[0] == "resolve" {
+			status = models.ERROR_RESOLVED
+		}
+		if _, err := errorEventRepository.UpdateStatus(uint(id), status); err != nil {
+			log.Printf("Error updating error event %d: %v", id, err)
+			sendMessage(chatID, tr(user, "errors.update_error"))
+			return
+		}
+		sendErrorsPage(chatID, user, view)
+	default:
+		sendMessage(chatID, tr(user, "invalid_selection"))
+	}
+}
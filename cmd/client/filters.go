@@ -0,0 +1,262 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/jalali"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	filtersTopLimit      = 10
+	filtersBarWidth      = 20
+	keywordSnippetPosts  = 5
+	keywordSnippetRadius = 40 // runes of context on each side of a keyword
+)
+
+// sendFiltersAnalytics sends the demand statistics over all saved filters with buttons to open the top users
+func sendFiltersAnalytics(chatID int, user models.User) {
+	analytics, err := filterAnalyticsService.Analyze(filtersTopLimit)
+	if err != nil {
+		log.Printf("Error analyzing filters: %v", err)
+		sendMessage(chatID, tr(user, "filters.fetch_error"))
+		return
+	}
+	if analytics.Filters == 0 {
+		sendMessage(chatID, tr(user, "filters.none"))
+		return
+	}
+
+	msg := tr(user, "filters.summary", analytics.Filters, analytics.Users)
+	msg += tr(user, "filters.zero_results", analytics.ZeroResults, analytics.ZeroResultShare())
+	if analytics.Unchecked > 0 {
+		msg += tr(user, "filters.unchecked", analytics.Unchecked)
+	}
+	msg += "\n"
+
+	msg += tr(user, "filters.categories")
+	for _, category := range analytics.Categories {
+		msg += tr(user, "filters.category", category.Value, category.Count, float64(category.Count)*100/float64(analytics.Filters))
+	}
+	msg += tr(user, "filters.cities") + formatValueCounts(analytics.Cities)
+	if len(analytics.Neighborhoods) > 0 {
+		msg += tr(user, "filters.neighborhoods") + formatValueCounts(analytics.Neighborhoods)
+	}
+
+	categories := make([]string, 0, len(analytics.PriceBands))
+	for category := range analytics.PriceBands {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		msg += tr(user, "filters.max_price", category)
+		msg += formatPriceBands(analytics.PriceBands[category])
+	}
+	msg += tr(user, "filters.no_price", analytics.NoPrice)
+	msg += tr(user, "filters.user_help")
+
+	buttons := make([][]InlineKeyboardButton, 0)
+	for _, top := range analytics.TopUsers {
+		buttons = append(buttons, []InlineKeyboardButton{{
+			Text: tr(user, "filters.user_button", top.UserID, top.Count),
+			Data: fmt.Sprintf("filters_user_%d", top.UserID),
+		}})
+	}
+	sendMessageWithInlineKeyboard(chatID, msg, InlineKeyboardMarkup{InlineKeyboard: buttons})
+}
+
+// sendUserFilters sends the filters of one user with the number of posts each matches
+func sendUserFilters(chatID int, user models.User, userID uint) {
+	reports, err := filterAnalyticsService.UserFilters(userID)
+	if err != nil || len(reports) == 0 {
+		sendMessage(chatID, tr(user, "filters.user_none", userID))
+		return
+	}
+
+	msg := tr(user, "filters.user_title", userID)
+	for _, report := range reports {
+		msg += "\n" + formatFilter(user, report.Filter)
+		if report.Err != nil {
+			msg += tr(user, "filters.results_unknown")
+		} else {
+			msg += tr(user, "filters.results", report.Results)
+		}
+	}
+	sendMessage(chatID, msg)
+}
+
+func handleFiltersCallback(chatID int, user models.User, data string) {
+	userID, err := strconv.ParseUint(strings.TrimPrefix(data, "filters_user_"), 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "filters.invalid_selection"))
+		return
+	}
+	sendUserFilters(chatID, user, uint(userID))
+}
+
+// formatFilter describes the conditions a filter sets, one per line
+func formatFilter(user models.User, filter models.FilterItem) string {
+	text := tr(user, "filter.id", filter.ID)
+	conditions := []struct {
+		name  string
+		value string
+	}{
+		{"city", filter.City},
+		{"neighborhood", filter.Neighborhood},
+		{"category", filter.Category},
+		{"property_type", filter.PropertyType},
+		{"seller", filter.SellerType},
+		{"keywords", filter.IncludeKeywords},
+		{"without_keywords", filter.ExcludeKeywords},
+		{"amenities", filter.Amenities},
+		{"renovation", filter.Renovation},
+		{"document", filter.Document},
+		{"orientation", filter.Orientation},
+		{"price", formatRange(user, filter.PriceMin, filter.PriceMax, formatPrice)},
+		{"area", formatRange(user, float64(filter.AreaMin), float64(filter.AreaMax), formatNumber)},
+		{"bedrooms", formatRange(user, float64(filter.BedroomsMin), float64(filter.BedroomsMax), formatNumber)},
+		{"age", formatRange(user, float64(filter.AgeMin), float64(filter.AgeMax), formatNumber)},
+		{"floor", formatRange(user, float64(filter.FloorMin), float64(filter.FloorMax), formatNumber)},
+		{"monthly_cost", formatRange(user, filter.MonthlyCostMin, filter.MonthlyCostMax, formatPrice)},
+		{"deposit", formatRange(user, 0, filter.DepositMax, formatPrice)},
+		{"rent", formatRange(user, 0, filter.RentMax, formatPrice)},
+	}
+	for _, condition := range conditions {
+		if condition.value != "" {
+			text += tr(user, "filter.condition", tr(user, "filter.condition."+condition.name), condition.value)
+		}
+	}
+	if !filter.CreatedDateStart.IsZero() || !filter.CreatedDateEnd.IsZero() {
+		text += tr(user, "filter.posted", formatDate(filter.CreatedDateStart), formatDate(filter.CreatedDateEnd))
+	}
+	if filter.HasStorage {
+		text += tr(user, "filter.has_storage")
+	}
+	if filter.HasElevator {
+		text += tr(user, "filter.has_elevator")
+	}
+	if filter.SortBy == models.SORT_BY_MONTHLY_COST {
+		text += tr(user, "filter.sorted_monthly_cost")
+	}
+	if filter.HideRisky {
+		text += tr(user, "filter.risky_hidden")
+	}
+	return text
+}
+
+func formatRange(user models.User, min float64, max float64, format func(float64) string) string {
+	switch {
+	case min > 0 && max > 0:
+		return format(min) + " - " + format(max)
+	case min > 0:
+		return tr(user, "filter.from", format(min))
+	case max > 0:
+		return tr(user, "filter.up_to", format(max))
+	}
+	return ""
+}
+
+// formatDate writes the Jalali day of a date in Tehran
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return "..."
+	}
+	return jalali.FromTime(date.In(utils.TehranLocation())).String()
+}
+
+// formatDateTime writes the Jalali day and the minute of a date in Tehran
+func formatDateTime(date time.Time) string {
+	return formatDate(date) + " " + date.In(utils.TehranLocation()).Format("15:04")
+}
+
+// parseDateRange reads "<day> to <day>" with Jalali or Gregorian days, the range covers both days in Tehran
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	start, end, found := strings.Cut(value, " to ")
+	if !found {
+		start, end, found = strings.Cut(value, " تا ")
+	}
+	if !found {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date range %q", value)
+	}
+	startDate, err := jalali.ParseDay(start, utils.TehranLocation())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endDate, err := jalali.ParseDay(end, utils.TehranLocation())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return startDate, endDate.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatPrice shortens a price to millions or billions with at most two decimals
+func formatPrice(price float64) string {
+	switch {
+	case price >= 1_000_000_000:
+		return formatNumber(math.Round(price/10_000_000)/100) + "B"
+	case price >= 1_000_000:
+		return formatNumber(math.Round(price/10_000)/100) + "M"
+	}
+	return formatNumber(math.Round(price))
+}
+
+func formatValueCounts(values []services.ValueCount) string {
+	text := ""
+	for i, value := range values {
+		text += fmt.Sprintf("%d. %s: %d\n", i+1, value.Value, value.Count)
+	}
+	return text
+}
+
+// formatPriceBands draws the bands as a text histogram
+func formatPriceBands(bands []services.PriceBand) string {
+	largest := 0
+	for _, band := range bands {
+		largest = max(largest, band.Count)
+	}
+
+	text := ""
+	for _, band := range bands {
+		label := formatPrice(band.Min) + "-" + formatPrice(band.Max)
+		if band.Max == 0 {
+			label = formatPrice(band.Min) + "+"
+		}
+		bar := ""
+		if largest > 0 {
+			bar = strings.Repeat("█", band.Count*filtersBarWidth/largest)
+		}
+		text += fmt.Sprintf("%-10s %s %d\n", label, bar, band.Count)
+	}
+	return text
+}
+
+// keywordSnippets quotes where the first posts mention the keywords of a filter, the keywords between « and »
+func keywordSnippets(posts []models.PostHistory, terms []string) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	text := ""
+	for i, post := range posts {
+		if i == keywordSnippetPosts {
+			break
+		}
+		snippet := persian.Highlight(post.Title+"\n"+post.Description, terms, keywordSnippetRadius)
+		if snippet != "" {
+			text += fmt.Sprintf("%d. %s\n%s\n\n", i+1, post.Title, snippet)
+		}
+	}
+	return text
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+)
+
+// sendAffordability shows what a user can afford, with the loan products to choose from
+func sendAffordability(chatID int, user models.User) {
+	profile, err := financingService.Profile(user)
+	if errors.Is(err, services.ErrNoProfile) {
+		sendMessage(chatID, tr(user, "financing.intro", tr(user, "financing.usage")))
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching affordability profile: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "financing.fetch_error"))
+		return
+	}
+
+	ranges := services.Ranges(profile)
+	text := tr(user, "financing.budget", formatPrice(float64(profile.Cash)), formatPrice(float64(profile.MonthlyBudget)))
+	if profile.LoanProduct != nil && profile.LoanProduct.Active {
+		text += tr(user, "financing.loan", formatLoanProduct(user, *profile.LoanProduct))
+	} else {
+		text += tr(user, "financing.loan", tr(user, "financing.no_loan"))
+	}
+	text += tr(user, "financing.buy", formatPrice(float64(ranges.PriceMax)))
+	if ranges.Loan > 0 {
+		text += tr(user, "financing.with_loan", formatPrice(float64(ranges.Loan)))
+	}
+	text += tr(user, "financing.rent",
+		formatPrice(float64(ranges.DepositMax)), formatPrice(float64(ranges.RentMax)), tr(user, "financing.usage"))
+
+	products, err := financingService.Products(true)
+	if err != nil {
+		log.Printf("Error fetching loan products: %v", err)
+	}
+	rows := [][]InlineKeyboardButton{{
+		{Text: tr(user, "financing.apply_button"), Data: "fin_apply"},
+		{Text: tr(user, "financing.remove_button"), Data: "fin_remove"},
+	}}
+	for _, product := range products {
+		rows = append(rows, []InlineKeyboardButton{{Text: tr(user, "financing.loan_button", product.Name), Data: fmt.Sprintf("fin_product_%d", product.ID)}})
+	}
+	if len(products) > 0 {
+		rows = append(rows, []InlineKeyboardButton{{Text: tr(user, "financing.no_loan_button"), Data: "fin_product_0"}})
+	}
+	sendMessageWithInlineKeyboard(chatID, text, InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// setAffordability reads "afford=<cash>,<monthly budget>"
+func setAffordability(chatID int, user models.User, value string) {
+	fields := strings.Split(strings.TrimPrefix(value, "afford="), ",")
+	if len(fields) != 2 {
+		sendMessage(chatID, tr(user, "financing.invalid", tr(user, "financing.usage")))
+		return
+	}
+	cash, cashErr := services.ParseAmount(fields[0])
+	budget, budgetErr := services.ParseAmount(fields[1])
+	if cashErr != nil || budgetErr != nil {
+		sendMessage(chatID, tr(user, "financing.invalid_amounts", tr(user, "financing.usage")))
+		return
+	}
+	if _, err := financingService.SaveProfile(user, cash, budget); errors.Is(err, services.ErrInvalidProfile) {
+		sendMessage(chatID, tr(user, "financing.invalid_profile"))
+		return
+	} else if err != nil {
+		log.Printf("Error saving affordability profile: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "financing.save_error"))
+		return
+	}
+	sendAffordability(chatID, user)
+}
+
+// handleFinancingCallback handles "fin_apply", "fin_apply_replace", "fin_remove", "fin_product_<id>" and, for admins,
+// "fin_enable_<id>" and "fin_disable_<id>"
+func handleFinancingCallback(chatID int, user models.User, data string) {
+	switch data {
+	case "fin_apply", "fin_apply_replace":
+		applyAffordability(chatID, user, data == "fin_apply_replace")
+		return
+	case "fin_remove":
+		removeAffordability(chatID, user)
+		return
+	}
+	action, idText, _ := strings.Cut(strings.TrimPrefix(data, "fin_"), "_")
+	id, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+
+	switch action {
+	case "product":
+		if _, err := financingService.ChooseProduct(user, uint(id)); errors.Is(err, services.ErrInvalidLoanProduct) {
+			sendMessage(chatID, tr(user, "financing.product_withdrawn"))
+			return
+		} else if errors.Is(err, services.ErrNoProfile) {
+			sendMessage(chatID, tr(user, "financing.usage"))
+			return
+		} else if err != nil {
+			log.Printf("Error choosing loan product: %v", err)
+			sendMessage(chatID, tr(user, "financing.product_error"))
+			return
+		}
+		sendAffordability(chatID, user)
+	case "enable", "disable":
+		if _, err := financingService.SetProductActive(uint(id), action == "enable", user.ID); err != nil {
+			log.Printf("Error changing loan product: %v", err)
+			sendMessage(chatID, tr(user, "financing.change_error"))
+			return
+		}
+		sendLoanProducts(chatID, user)
+	default:
+		sendMessage(chatID, tr(user, "invalid_selection"))
+	}
+}
+
+// applyAffordability sets the affordable ranges on the filter the user is building. The limits the user set
+// themselves are only replaced once they confirm.
+func applyAffordability(chatID int, user models.User, replace bool) {
+	profile, err := financingService.Profile(user)
+	if err != nil {
+		sendMessage(chatID, tr(user, "financing.usage"))
+		return
+	}
+	filterItem, exists := userFilterItems[user.ID]
+	if !exists || filterItem == nil {
+		filterItem = &models.FilterItem{}
+		userFilterItems[user.ID] = filterItem
+	}
+	ranges := services.Ranges(profile)
+	if !replace && services.ReplacesLimits(*filterItem, ranges) {
+		sendMessageWithInlineKeyboard(chatID, tr(user, "financing.replace", formatFilter(user, *filterItem)), InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{
+			{{Text: tr(user, "financing.replace_button"), Data: "fin_apply_replace"}},
+		}})
+		return
+	}
+	services.ApplyRanges(filterItem, ranges)
+	sendMessage(chatID, tr(user, "financing.applied", formatFilter(user, *filterItem)))
+	sendFilterConfirmationMenu(int64(chatID), user)
+}
+
+// removeAffordability deletes the budget of a user and the ranges it set on their filters
+func removeAffordability(chatID int, user models.User) {
+	if err := financingService.RemoveProfile(user); errors.Is(err, services.ErrNoProfile) {
+		sendMessage(chatID, tr(user, "financing.usage"))
+		return
+	} else if err != nil {
+		log.Printf("Error removing affordability profile: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "financing.remove_error"))
+		return
+	}
+	if filterItem := userFilterItems[user.ID]; filterItem != nil {
+		services.ClearRanges(filterItem)
+	}
+	sendMessage(chatID, tr(user, "financing.removed"))
+}
+
+// sendLoanProducts lists all loan products for admins, with buttons to offer or withdraw them
+func sendLoanProducts(chatID int, user models.User) {
+	products, err := financingService.Products(false)
+	if err != nil {
+		log.Printf("Error fetching loan products: %v", err)
+		sendMessage(chatID, tr(user, "financing.products_error"))
+		return
+	}
+	text := tr(user, "financing.products")
+	if len(products) == 0 {
+		text += tr(user, "financing.no_products")
+	}
+	rows := make([][]InlineKeyboardButton, 0, len(products))
+	for _, product := range products {
+		status, button := tr(user, "financing.offered"), InlineKeyboardButton{Text: tr(user, "financing.withdraw_button", product.Name), Data: fmt.Sprintf("fin_disable_%d", product.ID)}
+		if !product.Active {
+			status, button = tr(user, "financing.withdrawn"), InlineKeyboardButton{Text: tr(user, "financing.offer_button", product.Name), Data: fmt.Sprintf("fin_enable_%d", product.ID)}
+		}
+		text += tr(user, "financing.product_item", formatLoanProduct(user, product), status)
+		rows = append(rows, []InlineKeyboardButton{button})
+	}
+	sendMessageWithInlineKeyboard(chatID, text+"\n"+tr(user, "financing.loan_usage"), InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// setLoanProduct reads "loan=<name>,<annual rate>,<years>,<max ltv>[,<max amount>]"
+func setLoanProduct(chatID int, user models.User, value string) {
+	product, err := services.ParseLoanProduct(strings.TrimPrefix(value, "loan="))
+	if err != nil {
+		sendMessage(chatID, tr(user, "financing.invalid_product", tr(user, "financing.loan_usage")))
+		return
+	}
+	if _, err := financingService.SaveProduct(product, user.ID); err != nil {
+		log.Printf("Error saving loan product: %v", err)
+		sendMessage(chatID, tr(user, "financing.product_save_error"))
+		return
+	}
+	sendLoanProducts(chatID, user)
+}
+
+// formatPayment describes the monthly loan payment of buying a listing
+func formatPayment(user models.User, post models.PostHistory) string {
+	payment, ok, err := financingService.EstimatePayment(user, post)
+	if err != nil {
+		log.Printf("Error estimating loan payment: %v", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return tr(user, "financing.payment",
+		formatPrice(float64(payment.Monthly)), formatPrice(float64(payment.Loan)), payment.Product.Name)
+}
+
+// formatLoanProduct describes the terms of a loan product
+func formatLoanProduct(user models.User, product models.LoanProduct) string {
+	text := tr(user, "financing.product", product.Name, product.AnnualRate, float64(product.TermMonths)/12, product.MaxLTV)
+	if product.MaxAmount > 0 {
+		text += tr(user, "financing.product_max", formatPrice(float64(product.MaxAmount)))
+	}
+	return text
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"log"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+)
+
+// commandLabels maps the label of a keyboard button in every language to the ID of its command
+var commandLabels = make(map[string]string)
+
+// keyboardLayouts are the rows of command IDs of the keyboard of each role, the labels come from the catalogs
+var keyboardLayouts = map[models.Role][][]string{
+	models.ADMIN: {
+		{"filters", "premium", "clients"},
+		{"errors", "rent_rate", "review_queue"},
+		{"loan_products", "filter", "search"},
+		{"language"},
+	},
+	models.SUPER_ADMIN: {
+		{"admins", "monitor", "clients"},
+		{"advertisements", "crawler_setting", "rent_rate", "loan_products"},
+		{"permissions", "audit_log", "filter", "search"},
+		{"language"},
+	},
+	models.USER: {
+		{"filter", "search", "bookmark"},
+		{"send_location", "export_csv"},
+		{"setting", "populars", "watchlist"},
+		{"subscribe", "market", "financing"},
+		{"digests", "select_website", "language"},
+	},
+}
+
+// initializeCommandLabels lets every registered command with a label be run by its button, whatever the language
+func initializeCommandLabels() {
+	commandLabels = make(map[string]string)
+	for id := range CommandRegistry {
+		for _, label := range i18n.Labels("command." + id) {
+			commandLabels[label] = id
+		}
+	}
+}
+
+// languageOf returns the language a user reads the bot in
+func languageOf(user models.User) i18n.Language {
+	return i18n.Parse(user.Language)
+}
+
+// tr returns the message of a key in the language of a user
+func tr(user models.User, key string, args ...interface{}) string {
+	return i18n.T(languageOf(user), key, args...)
+}
+
+// planName returns the name of the plan of a user type in the language of a user
+func planName(user models.User, userType models.UserType) string {
+	if userType == models.PREMIUM {
+		return tr(user, "plan.premium")
+	}
+	return tr(user, "plan.free")
+}
+
+func getKeyboard(user models.User) ReplyKeyboardMarkupWithLocation {
+	layout, exists := keyboardLayouts[user.Role]
+	if !exists {
+		layout = [][]string{{"help"}}
+	}
+	keyboard := make([][]KeyboardButton, 0, len(layout))
+	for _, ids := range layout {
+		row := make([]KeyboardButton, 0, len(ids))
+		for _, id := range ids {
+			// buttons of commands whose permission was revoked from the role are left out
+			if cmd, exists := CommandRegistry[id]; exists && !permissionService.Allowed(user, cmd.Permission()) {
+				continue
+			}
+			row = append(row, KeyboardButton{Text: tr(user, "command."+id)})
+		}
+		if len(row) > 0 {
+			keyboard = append(keyboard, row)
+		}
+	}
+	return ReplyKeyboardMarkupWithLocation{
+		Keyboard:        keyboard,
+		ResizeKeyboard:  true,
+		OneTimeKeyboard: true,
+	}
+}
+
+// /////////////////////////////////
+type LanguageCommand struct{}
+
+func (cmd *LanguageCommand) Execute(message *Message, user *models.User) {
+	buttons := make([][]InlineKeyboardButton, 0, len(i18n.Languages))
+	for _, language := range i18n.Languages {
+		buttons = append(buttons, []InlineKeyboardButton{{Text: i18n.Name(language), Data: "lang_" + string(language)}})
+	}
+	sendMessageWithInlineKeyboard(message.Chat.ID, tr(*user, "language.choose"), InlineKeyboardMarkup{InlineKeyboard: buttons})
+}
+func (cmd *LanguageCommand) Permission() models.Permission {
+	return ""
+}
+
+// handleLanguageCallback saves the language chosen with lang_<code> and sends the keyboard in it
+func handleLanguageCallback(chatID int, user models.User, data string) {
+	language := i18n.Parse(strings.TrimPrefix(data, "lang_"))
+	if _, err := userRepository.UpdateUser(user.ID, map[string]interface{}{"language": string(language)}); err != nil {
+		log.Printf("Error saving user language: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "language.error"))
+		return
+	}
+	user.Language = string(language)
+	sendMessageWithKeyboard(chatID, tr(user, "language.changed"), getKeyboard(user))
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+const valuationComparablesShown = 5
+
+// sendListingCard sends the details of a post history with its actions
+func sendListingCard(chatID int, user models.User, postHistoryID uint) {
+	post, err := postRepository.FindPostHistory(postHistoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		sendMessage(chatID, tr(user, "listing.not_found"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching post history: %v", err)
+		sendMessage(chatID, tr(user, "listing.fetch_error"))
+		return
+	}
+
+	buttons := [][]InlineKeyboardButton{{
+		{Text: tr(user, "listing.fair_button"), Data: fmt.Sprintf("fair_%d", post.ID)},
+		{Text: tr(user, "listing.bookmark_button"), Data: fmt.Sprintf("bm_add_%d", post.PostID)},
+	}}
+	if post.PostURL != "" {
+		buttons = append(buttons, []InlineKeyboardButton{{Text: tr(user, "listing.view_button"), URL: post.PostURL}})
+	}
+	text := formatListing(user, post) + formatPayment(user, post)
+	if listed, err := postRepository.FindByID(post.PostID); err == nil {
+		text += formatLifecycle(user, listed, time.Now())
+	}
+	if risk, err := fraudService.Risk(post.ID); err != nil {
+		log.Printf("Error fetching listing risk: %v", err)
+	} else if risk.Score >= models.HIGH_RISK_SCORE && risk.Review != models.RISK_DISMISSED {
+		text += tr(user, "listing.risky", formatReasons(user, risk.Reasons))
+	}
+	sendListingPhoto(chatID, post.ID)
+	sendMessageWithInlineKeyboard(chatID, text, InlineKeyboardMarkup{InlineKeyboard: buttons})
+}
+
+// formatLifecycle tells whether a post is still listed and for how long it has been
+func formatLifecycle(user models.User, post models.Post, now time.Time) string {
+	days := post.DaysOnMarket(now)
+	if post.Active() {
+		return tr(user, "lifecycle.listed", days)
+	}
+	status := tr(user, "lifecycle.removed")
+	switch post.Status {
+	case models.POST_EXPIRED:
+		status = tr(user, "lifecycle.expired")
+	case models.POST_SOLD:
+		status = tr(user, "lifecycle.sold")
+	}
+	if post.RemovedAt == nil {
+		return tr(user, "lifecycle.gone", status, days)
+	}
+	return tr(user, "lifecycle.gone_on", status, formatDate(*post.RemovedAt), days)
+}
+
+// sendListingPhoto sends the archived cover photo of a post history, it still works after the website deleted the post
+func sendListingPhoto(chatID int, postHistoryID uint) {
+	content, err := imageService.Cover(postHistoryID, false)
+	if errors.Is(err, services.ErrNoImage) {
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching listing photo: %v", err)
+		return
+	}
+	if _, err := uploadFile(int64(chatID), "sendPhoto", "photo", "listing.jpg", content, ""); err != nil {
+		log.Printf("Error sending listing photo: %v", err)
+	}
+}
+
+// sendValuation tells whether the price of a post history is fair compared to similar listings
+func sendValuation(chatID int, user models.User, postHistoryID uint) {
+	valuation, err := valuationService.Value(postHistoryID, time.Now())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sendMessage(chatID, tr(user, "listing.not_found"))
+		return
+	case errors.Is(err, services.ErrNotValuable):
+		sendMessage(chatID, tr(user, "valuation.not_valuable"))
+		return
+	case errors.Is(err, services.ErrNotEnoughComparables):
+		sendMessage(chatID, tr(user, "valuation.not_enough"))
+		return
+	case err != nil:
+		log.Printf("Error valuing post history: %v", err)
+		tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "valuation.error"))
+		return
+	}
+
+	kind := tr(user, "valuation.price")
+	if valuation.Post.BuyMode == types.Rent {
+		kind = tr(user, "valuation.monthly_cost")
+	}
+	msg := tr(user, "valuation.result", valuation.Post.Title, kind, formatPrice(float64(valuation.Asked)),
+		formatPrice(float64(valuation.Estimate)), formatPrice(float64(valuation.Low)), formatPrice(float64(valuation.High)),
+		verdictText(user, valuation))
+
+	msg += tr(user, "valuation.comparables", len(valuation.Comparables))
+	for i, comparable := range valuation.Comparables {
+		if i == valuationComparablesShown {
+			break
+		}
+		post := comparable.Post
+		msg += tr(user, "valuation.comparable", i+1, post.Neighborhood, post.Area, post.Age, post.FloorsNum,
+			formatPrice(float64(services.ListingValue(post))))
+	}
+	sendMessage(chatID, msg)
+}
+
+func verdictText(user models.User, valuation services.Valuation) string {
+	switch valuation.Verdict {
+	case services.OVERPRICED:
+		return tr(user, "valuation.overpriced", percentDifference(valuation.Asked, valuation.Estimate))
+	case services.UNDERPRICED:
+		return tr(user, "valuation.underpriced", -percentDifference(valuation.Asked, valuation.Estimate))
+	}
+	return tr(user, "valuation.fair")
+}
+
+func percentDifference(asked int64, estimate int64) float64 {
+	return (float64(asked) - float64(estimate)) * 100 / float64(estimate)
+}
+
+// formatListing describes a post history for a listing card
+func formatListing(user models.User, post models.PostHistory) string {
+	text := fmt.Sprintf("🏡 %s\n\n", post.Title)
+	if post.BuyMode == types.Rent {
+		text += tr(user, "listing.rent", formatPrice(float64(post.Deposit)), formatPrice(float64(post.Rent)))
+		text += formatRentCost(user, post)
+	} else {
+		text += tr(user, "listing.price", formatPrice(float64(post.Price)))
+	}
+	text += tr(user, "listing.details", post.City, post.Neighborhood, post.Area, post.BedroomNum, post.Age, post.FloorsNum)
+
+	switch post.SellerType {
+	case types.Owner:
+		text += tr(user, "listing.seller_owner")
+	case types.Agency:
+		text += tr(user, "listing.seller_agency")
+	}
+	return text + formatAmenities(user, post)
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/charts"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+// sendMarketOverview lists the latest week of every city
+func sendMarketOverview(chatID int, user models.User) {
+	indices, err := marketService.LatestCities()
+	if err != nil {
+		log.Printf("Error fetching market indices: %v", err)
+		sendMessage(chatID, tr(user, "market.fetch_error"))
+		return
+	}
+	if len(indices) == 0 {
+		sendMessage(chatID, tr(user, "market.none", tr(user, "market.usage")))
+		return
+	}
+
+	msg := tr(user, "market.overview", formatWeek(indices[0].Week))
+	for _, index := range indices {
+		msg += "\n" + index.City + "\n" + formatMarketIndex(user, index)
+	}
+	sendMessage(chatID, msg+"\n"+tr(user, "market.usage"))
+}
+
+// sendMarketIndices sends the weekly indices of a city as a text table and a chart of the price per m²
+func sendMarketIndices(chatID int, user models.User, value string) {
+	parts := strings.Split(strings.TrimPrefix(value, "market="), ",")
+	city := strings.TrimSpace(parts[0])
+	if city == "" {
+		sendMessage(chatID, tr(user, "market.usage"))
+		return
+	}
+	var neighborhood string
+	var building types.Building
+	if len(parts) > 1 {
+		neighborhood = strings.TrimSpace(parts[1])
+	}
+	if len(parts) > 2 {
+		building = types.Building(strings.ToLower(strings.TrimSpace(parts[2])))
+	}
+
+	indices, err := marketService.Indices(city, neighborhood, building, time.Now())
+	if err != nil {
+		log.Printf("Error fetching market indices: %v", err)
+		sendMessage(chatID, tr(user, "market.fetch_error"))
+		return
+	}
+	title := strings.Join(nonEmpty(city, neighborhood, string(building)), ", ")
+	if len(indices) == 0 {
+		sendMessage(chatID, tr(user, "market.not_found", title, marketService.Weeks()))
+		return
+	}
+
+	msg := tr(user, "market.title", title, marketService.Weeks())
+	for _, index := range indices {
+		msg += tr(user, "market.week", formatWeek(index.Week)) + formatMarketIndex(user, index)
+	}
+	sendMessage(chatID, msg)
+
+	chart, caption := marketChart(user, title, indices)
+	content, err := chart.PNG()
+	if err != nil {
+		log.Printf("Error drawing market chart: %v", err)
+		return
+	}
+	if _, err := sendPhoto(int64(chatID), content, caption); err != nil {
+		log.Printf("Error sending market chart: %v", err)
+	}
+}
+
+// marketChart draws the sale price per m² of the weeks, or the rent when there are no sales
+func marketChart(user models.User, title string, indices []models.MarketIndex) (charts.LineChart, string) {
+	median := make([]float64, len(indices))
+	p25 := make([]float64, len(indices))
+	p75 := make([]float64, len(indices))
+	sales := false
+	for _, index := range indices {
+		sales = sales || index.SaleListings > 0
+	}
+
+	for i, index := range indices {
+		median[i], p25[i], p75[i] = math.NaN(), math.NaN(), math.NaN()
+		if sales && index.SaleListings > 0 {
+			median[i], p25[i], p75[i] = index.PricePerMeterMedian, index.PricePerMeterP25, index.PricePerMeterP75
+		} else if !sales && index.RentListings > 0 {
+			median[i], p25[i], p75[i] = index.RentPerMeterMedian, index.RentPerMeterP25, index.RentPerMeterP75
+		}
+	}
+
+	chart := charts.LineChart{Series: []charts.Series{
+		{Color: charts.LightBlue, Points: p25},
+		{Color: charts.LightBlue, Points: p75},
+		{Color: charts.Blue, Points: median},
+	}}
+	kind := tr(user, "market.sale_price")
+	if !sales {
+		kind = tr(user, "market.monthly_rent")
+	}
+	caption := tr(user, "market.caption", kind, title, formatWeek(indices[0].Week), formatWeek(indices[len(indices)-1].Week))
+	if low, high, err := chart.Bounds(); err == nil {
+		caption += tr(user, "market.scale", formatPrice(low), formatPrice(high))
+	}
+	return chart, caption
+}
+
+func formatMarketIndex(user models.User, index models.MarketIndex) string {
+	text := ""
+	if index.SaleListings > 0 {
+		text += tr(user, "market.sale", formatPrice(index.PricePerMeterMedian),
+			formatPrice(index.PricePerMeterP25), formatPrice(index.PricePerMeterP75), index.SaleListings)
+	}
+	if index.RentListings > 0 {
+		text += tr(user, "market.rent", formatPrice(index.RentPerMeterMedian),
+			formatPrice(index.RentPerMeterP25), formatPrice(index.RentPerMeterP75), index.RentListings, index.DepositToRentMedian)
+	}
+	return text
+}
+
+func formatWeek(week time.Time) string {
+	return formatDate(week)
+}
+
+func nonEmpty(values ...string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
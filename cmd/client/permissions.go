@@ -0,0 +1,179 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+)
+
+// auditLogLimit is how many of the latest privileged actions the AuditLog command shows
+const auditLogLimit = 20
+
+// authorize tells whether a user may use a permission, privileged actions are recorded in the audit log
+// whether they are allowed or not
+func authorize(user models.User, permission models.Permission, action string, target string) bool {
+	allowed := permissionService.Allowed(user, permission)
+	if permission.Privileged() {
+		permissionService.Audit(user, action, target, allowed)
+	}
+	return allowed
+}
+
+// sendPermissions lists the permissions of every role
+func sendPermissions(chatID int, user models.User) {
+	msg := tr(user, "permissions.title")
+	for _, role := range models.Roles {
+		names := make([]string, 0)
+		for _, permission := range permissionService.Permissions(role) {
+			names = append(names, string(permission))
+		}
+		list := strings.Join(names, ", ")
+		if len(names) == 0 {
+			list = tr(user, "permissions.none")
+		}
+		msg += tr(user, "permissions.role", role.String(), list)
+	}
+	sendMessage(chatID, msg+tr(user, "permissions.help"))
+}
+
+// setPermission reads "perm=<role>,<permission>,on|off"
+func setPermission(chatID int, user models.User, value string) {
+	parts := strings.Split(strings.TrimPrefix(value, "perm="), ",")
+	if len(parts) != 3 {
+		sendMessage(chatID, tr(user, "permissions.invalid"))
+		return
+	}
+	role, ok := models.ParseRole(strings.ToLower(strings.TrimSpace(parts[0])))
+	permission := models.Permission(strings.ToLower(strings.TrimSpace(parts[1])))
+	state := strings.ToLower(strings.TrimSpace(parts[2]))
+	if !ok || (state != "on" && state != "off") {
+		sendMessage(chatID, tr(user, "permissions.invalid"))
+		return
+	}
+
+	err := permissionService.SetPermission(user, role, permission, state == "on")
+	switch {
+	case errors.Is(err, services.ErrUnknownPermission):
+		sendMessage(chatID, tr(user, "permissions.unknown"))
+	case errors.Is(err, services.ErrPermissionLockout):
+		sendMessage(chatID, tr(user, "permissions.lockout"))
+	case err != nil:
+		log.Printf("Error changing role permission: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "permissions.error"))
+	case state == "on":
+		sendMessage(chatID, tr(user, "permissions.granted", permission, role.String()))
+	default:
+		sendMessage(chatID, tr(user, "permissions.revoked", permission, role.String()))
+	}
+}
+
+// changeRole reads "<prefix><user id>" and demotes the user or revokes their role
+func changeRole(chatID int, user models.User, value string, prefix string,
+	change func(models.User, uint) (models.User, error)) {
+	id, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(value, prefix)), 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "role.invalid", prefix))
+		return
+	}
+
+	changed, err := change(user, uint(id))
+	switch {
+	case errors.Is(err, services.ErrRoleUserNotFound):
+		sendMessage(chatID, tr(user, "user.not_found", id))
+	case errors.Is(err, services.ErrOwnRole):
+		sendMessage(chatID, tr(user, "role.own"))
+	case errors.Is(err, services.ErrRoleNotAllowed):
+		sendMessage(chatID, tr(user, "role.not_allowed"))
+	case errors.Is(err, services.ErrLowestRole):
+		sendMessage(chatID, tr(user, "role.lowest"))
+	case err != nil:
+		log.Printf("Error changing user role: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "role.error"))
+	default:
+		sendMessage(chatID, tr(user, "role.changed", changed.ID, changed.Role.String()))
+	}
+}
+
+// sendAuditLog lists the latest privileged actions
+func sendAuditLog(chatID int, user models.User) {
+	entries, err := permissionService.AuditLogs(auditLogLimit)
+	if err != nil {
+		log.Printf("Error finding audit log: %v", err)
+		sendMessage(chatID, tr(user, "audit.error"))
+		return
+	}
+	if len(entries) == 0 {
+		sendMessage(chatID, tr(user, "audit.none"))
+		return
+	}
+	msg := tr(user, "audit.title")
+	for _, entry := range entries {
+		msg += tr(user, "audit.item", formatDateTime(entry.CreatedAt), entry.UserID, entry.Role.String(), entry.Action)
+		if entry.Target != "" {
+			msg += fmt.Sprintf(" (%s)", entry.Target)
+		}
+		if !entry.Allowed {
+			msg += tr(user, "audit.denied")
+		}
+		msg += "\n"
+	}
+	sendMessage(chatID, msg)
+}
+
+// ///////////////////////////////
+type PermissionsCommand struct{}
+
+func (cmd *PermissionsCommand) Execute(message *Message, user *models.User) {
+	sendPermissions(message.Chat.ID, *user)
+}
+func (cmd *PermissionsCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
+}
+
+// ///////////////////////////////
+type SetPermissionCommand struct{}
+
+func (cmd *SetPermissionCommand) Execute(message *Message, user *models.User) {
+	setPermission(message.Chat.ID, *user, message.Value)
+}
+func (cmd *SetPermissionCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
+}
+
+// ///////////////////////////////
+type DemoteCommand struct{}
+
+func (cmd *DemoteCommand) Execute(message *Message, user *models.User) {
+	changeRole(message.Chat.ID, *user, message.Value, "demote=", permissionService.Demote)
+}
+func (cmd *DemoteCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
+}
+
+// ///////////////////////////////
+type RevokeRoleCommand struct{}
+
+func (cmd *RevokeRoleCommand) Execute(message *Message, user *models.User) {
+	changeRole(message.Chat.ID, *user, message.Value, "revoke=", permissionService.Revoke)
+}
+func (cmd *RevokeRoleCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
+}
+
+// ///////////////////////////////
+type AuditLogCommand struct{}
+
+func (cmd *AuditLogCommand) Execute(message *Message, user *models.User) {
+	sendAuditLog(message.Chat.ID, *user)
+}
+func (cmd *AuditLogCommand) Permission() models.Permission {
+	return models.PERM_MANAGE_ADMINS
+}
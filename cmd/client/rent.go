@@ -0,0 +1,56 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+)
+
+// sendRentRate shows the rate used to convert deposits to rent
+func sendRentRate(chatID int, user models.User) {
+	rate := rentService.Rate()
+	deposit := int64(100_000_000)
+	fullDeposit, monthlyCost := services.ConvertRent(deposit, 0, rate)
+	msg := tr(user, "rent.rate", formatRate(rate), formatPrice(float64(deposit)), formatPrice(float64(monthlyCost)),
+		formatPrice(float64(monthlyCost)), formatPrice(float64(fullDeposit)), tr(user, "rent.usage"))
+	sendMessage(chatID, msg)
+}
+
+// setRentRate changes the rate and recomputes all rentals with it
+func setRentRate(chatID int, user *models.User, value string) {
+	rate, err := services.ParseRate(strings.TrimPrefix(value, "rate="))
+	if err != nil {
+		sendMessage(chatID, tr(*user, "rent.invalid", tr(*user, "rent.usage")))
+		return
+	}
+	updated, err := rentService.SetRate(rate, user.ID)
+	if errors.Is(err, services.ErrInvalidRate) {
+		sendMessage(chatID, tr(*user, "rent.invalid", tr(*user, "rent.usage")))
+		return
+	} else if err != nil {
+		log.Printf("Error setting rent conversion rate: %v", err)
+		sendMessage(chatID, tr(*user, "rent.error"))
+		return
+	}
+	sendMessage(chatID, tr(*user, "rent.set", formatRate(rate), updated))
+}
+
+// formatRentCost describes the normalized cost of a rental
+func formatRentCost(user models.User, post models.PostHistory) string {
+	if post.MonthlyCost == 0 && post.FullDeposit == 0 {
+		return ""
+	}
+	text := tr(user, "rent.cost", formatPrice(float64(post.MonthlyCost)), formatPrice(float64(post.FullDeposit)))
+	if post.Convertible {
+		text += tr(user, "rent.convertible")
+	}
+	return text
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+)
+
+const riskQueuePageSize = 5
+
+// sendRiskQueue sends the riskiest unreviewed listings, each with its review buttons
+func sendRiskQueue(chatID int, user models.User) {
+	risks, total, err := fraudService.Pending(riskQueuePageSize)
+	if err != nil {
+		log.Printf("Error fetching risky listings: %v", err)
+		sendMessage(chatID, tr(user, "risk.fetch_error"))
+		return
+	}
+	if total == 0 {
+		sendMessage(chatID, tr(user, "risk.none"))
+		return
+	}
+
+	sendMessage(chatID, tr(user, "risk.queue", total, len(risks)))
+	for _, risk := range risks {
+		msg := tr(user, "risk.item", risk.ID, risk.Score, formatReasons(user, risk.Reasons), formatListing(user, risk.PostHistory))
+		buttons := [][]InlineKeyboardButton{{
+			{Text: tr(user, "risk.confirm_button"), Data: fmt.Sprintf("risk_confirm_%d", risk.ID)},
+			{Text: tr(user, "risk.dismiss_button"), Data: fmt.Sprintf("risk_dismiss_%d", risk.ID)},
+		}}
+		if risk.PostHistory.PostURL != "" {
+			buttons = append(buttons, []InlineKeyboardButton{{Text: tr(user, "listing.view_button"), URL: risk.PostHistory.PostURL}})
+		}
+		sendMessageWithInlineKeyboard(chatID, truncate(msg, 3500), InlineKeyboardMarkup{InlineKeyboard: buttons})
+	}
+}
+
+// handleRiskCallback records the review of an admin, "risk_confirm_<id>" or "risk_dismiss_<id>"
+func handleRiskCallback(chatID int, user models.User, data string) {
+	parts := strings.Split(strings.TrimPrefix(data, "risk_"), "_")
+	if len(parts) != 2 {
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+
+	var review models.RiskReview
+	switch parts[0] {
+	case "confirm":
+		review = models.RISK_CONFIRMED
+	case "dismiss":
+		review = models.RISK_DISMISSED
+	default:
+		sendMessage(chatID, tr(user, "invalid_selection"))
+		return
+	}
+
+	risk, err := fraudService.Review(uint(id), review, user)
+	if err != nil {
+		log.Printf("Error reviewing listing risk: %v", err)
+		sendMessage(chatID, tr(user, "risk.review_error"))
+		return
+	}
+	if review == models.RISK_CONFIRMED {
+		sendMessage(chatID, tr(user, "risk.confirmed", risk.ID))
+	} else {
+		sendMessage(chatID, tr(user, "risk.dismissed", risk.ID))
+	}
+}
+
+// formatReasons describes the reasons of a risk, the reasons without a message are written as they are stored
+func formatReasons(user models.User, reasons string) string {
+	texts := make([]string, 0)
+	for _, reason := range services.SplitReasons(reasons) {
+		if key := "risk.reason." + string(reason); i18n.Has(languageOf(user), key) {
+			texts = append(texts, tr(user, key))
+		} else {
+			texts = append(texts, string(reason))
+		}
+	}
+	return strings.Join(texts, ", ")
+}
@@ -7,29 +7,101 @@ import (
 	"time"
 
 	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
 	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/services"
 	"github.com/MagicalCrawler/RealEstateApp/utils"
 )
 
 type Command interface {
 	Execute(message *Message, user *models.User)
-	AllowedRoles() []models.Role // returns roles that can execute this command
+	Permission() models.Permission // returns the permission needed to execute this command, empty for everyone
 }
 
 var (
-	CommandRegistry    map[string]Command
-	userRepository     db.UserRepository
-	postRepository     db.PostRepo
-	bookmarkRepository db.BookmarkRepo
-	filterRepository   db.FilterItemRepository
-	apiURL             string
+	CommandRegistry        map[string]Command
+	userRepository         db.UserRepository
+	postRepository         db.PostRepo
+	bookmarkRepository     db.BookmarkRepo
+	filterRepository       db.FilterItemRepository
+	watchListRepository    db.WatchListRepository
+	crawlRunRepository     db.CrawlRunRepository
+	errorEventRepository   db.ErrorEventRepository
+	quotaService           *services.QuotaService
+	subscriptionService    *services.SubscriptionService
+	filterAnalyticsService *services.FilterAnalyticsService
+	marketService          *services.MarketService
+	valuationService       *services.ValuationService
+	rentService            *services.RentService
+	fraudService           *services.FraudService
+	imageService           *services.ImageService
+	lifecycleService       *services.LifecycleService
+	bookmarkService        *services.BookmarkService
+	comparisonService      *services.ComparisonService
+	financingService       *services.FinancingService
+	digestService          *services.DigestService
+	permissionService      *services.PermissionService
+	apiURL                 string
 )
 
-func Run(userRepo db.UserRepository, postRepo db.PostRepo, bookmarkRepo db.BookmarkRepo, filterRepo db.FilterItemRepository) {
-	postRepository = postRepo
-	userRepository = userRepo
-	bookmarkRepository = bookmarkRepo
-	filterRepository = filterRepo
+// Dependencies are the repositories and services the bot commands use
+type Dependencies struct {
+	UserRepository         db.UserRepository
+	PostRepository         db.PostRepo
+	BookmarkRepository     db.BookmarkRepo
+	FilterRepository       db.FilterItemRepository
+	WatchListRepository    db.WatchListRepository
+	CrawlRunRepository     db.CrawlRunRepository
+	ErrorEventRepository   db.ErrorEventRepository
+	QuotaService           *services.QuotaService
+	SubscriptionService    *services.SubscriptionService
+	FilterAnalyticsService *services.FilterAnalyticsService
+	MarketService          *services.MarketService
+	ValuationService       *services.ValuationService
+	RentService            *services.RentService
+	FraudService           *services.FraudService
+	ImageService           *services.ImageService
+	LifecycleService       *services.LifecycleService
+	BookmarkService        *services.BookmarkService
+	ComparisonService      *services.ComparisonService
+	FinancingService       *services.FinancingService
+	DigestService          *services.DigestService
+	PermissionService      *services.PermissionService
+}
+
+func Run(dependencies Dependencies) {
+	postRepository = dependencies.PostRepository
+	userRepository = dependencies.UserRepository
+	bookmarkRepository = dependencies.BookmarkRepository
+	filterRepository = dependencies.FilterRepository
+	watchListRepository = dependencies.WatchListRepository
+	crawlRunRepository = dependencies.CrawlRunRepository
+	errorEventRepository = dependencies.ErrorEventRepository
+	quotaService = dependencies.QuotaService
+	subscriptionService = dependencies.SubscriptionService
+	filterAnalyticsService = dependencies.FilterAnalyticsService
+	marketService = dependencies.MarketService
+	valuationService = dependencies.ValuationService
+	rentService = dependencies.RentService
+	fraudService = dependencies.FraudService
+	imageService = dependencies.ImageService
+	lifecycleService = dependencies.LifecycleService
+	bookmarkService = dependencies.BookmarkService
+	comparisonService = dependencies.ComparisonService
+	financingService = dependencies.FinancingService
+	digestService = dependencies.DigestService
+	permissionService = dependencies.PermissionService
+	subscriptionService.SetNotifier(func(user models.User, text string) {
+		sendMessage(int(user.TelegramID), text)
+	})
+	lifecycleService.SetNotifier(func(user models.User, text string) {
+		sendMessage(int(user.TelegramID), text)
+	})
+	bookmarkService.SetNotifier(func(user models.User, text string) {
+		sendMessage(int(user.TelegramID), text)
+	})
+	digestService.SetNotifier(sendDigest)
 
 	apiURL = "https://api.telegram.org/bot" + utils.GetConfig("TELEGRAM_TOKEN")
 	initializeCommands()
@@ -42,14 +114,6 @@ func Run(userRepo db.UserRepository, postRepo db.PostRepo, bookmarkRepo db.Bookm
 	log.Println("Bot is running...")
 	select {}
 }
-func isRoleAllowed(userRole models.Role, allowedRoles []models.Role) bool {
-	for _, role := range allowedRoles {
-		if userRole == role {
-			return true
-		}
-	}
-	return false
-}
 func timedGoroutine() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -74,39 +138,98 @@ func handleMessage(message *Message) {
 	deleteMessage(message.Chat.ID, message.MessageID-2)
 
 	user := getOrCreateUserRunCommand(message)
+	if user.ID == 0 {
+		// the profile could not be read or created, the user was told so
+		return
+	}
+	// Persian keyboards type Persian digits, command labels and numbers are read with Latin ones while free text,
+	// like notes, comments and folder names, is kept as typed
+	title := persian.LatinDigits(message.Title)
+	// keyboard buttons are labelled in the language of the user, the label tells the ID of the command
+	id := commandLabels[title]
 	if message.Location.Latitude != 0 {
-		message.Title = "Location Attachment"
-	} else if strings.Contains(message.Title, "Id=") {
-		message.Value = message.Title
-		message.Title = "Change To Premium"
-	} else if strings.Contains(message.Title, "admin=") {
+		id = "location_attachment"
+	} else if title == "/start" || strings.HasPrefix(title, "/start ") {
+		// deep links open the bot with a payload, like the invite links of shared bookmark folders
+		message.Value = strings.TrimSpace(strings.TrimPrefix(message.Title, "/start"))
+		id = "start"
+	} else if strings.HasPrefix(title, "watch=") {
+		message.Value = title
+		id = "create_watchlist"
+	} else if strings.HasPrefix(title, "market=") {
+		message.Value = title
+		id = "market_index"
+	} else if strings.HasPrefix(title, "rate=") {
+		message.Value = title
+		id = "set_rent_rate"
+	} else if strings.HasPrefix(title, "note=") {
 		message.Value = message.Title
-		message.Title = "Create Admin"
-	} else if strings.Contains(message.Title, "redius=") {
+		id = "bookmark_note"
+	} else if strings.HasPrefix(title, "comment=") {
 		message.Value = message.Title
-		message.Title = "Get Redius"
-	} else if strings.Contains(message.Title, "B=") {
+		id = "comment_bookmark"
+	} else if strings.HasPrefix(title, "afford=") {
+		message.Value = title
+		id = "set_affordability"
+	} else if strings.HasPrefix(title, "loan=") {
+		message.Value = title
+		id = "set_loan_product"
+	} else if strings.HasPrefix(title, "digest=") {
+		message.Value = title
+		id = "set_digest"
+	} else if strings.HasPrefix(title, "compare=") {
+		message.Value = title
+		id = "compare"
+	} else if strings.HasPrefix(title, "folder=") {
 		message.Value = message.Title
-		message.Title = "Get Bookmark Id"
-	} else if message.Title == "s" || message.Title == "d" {
-		if message.Title == "s" {
+		id = "create_bookmark_folder"
+	} else if strings.HasPrefix(title, "filters=") {
+		message.Value = title
+		id = "user_filters"
+	} else if strings.HasPrefix(title, "perm=") {
+		message.Value = title
+		id = "set_permission"
+	} else if strings.HasPrefix(title, "demote=") {
+		message.Value = title
+		id = "demote"
+	} else if strings.HasPrefix(title, "revoke=") {
+		message.Value = title
+		id = "revoke"
+	} else if strings.Contains(title, "Id=") {
+		message.Value = title
+		id = "change_to_premium"
+	} else if strings.Contains(title, "admin=") {
+		message.Value = title
+		id = "create_admin"
+	} else if strings.Contains(title, "redius=") {
+		message.Value = title
+		id = "get_radius"
+	} else if strings.Contains(title, "B=") {
+		message.Value = title
+		id = "get_bookmark_id"
+	} else if title == "s" || title == "d" {
+		if title == "s" {
 			message.Value = "sheypoor"
 		} else {
 			message.Value = "divar"
 		}
-		message.Title = "Get Website"
-	} else if message.Title == "c" {
-		message.Title = "Get Admin Id"
+		id = "get_website"
+	} else if title == "c" {
+		id = "get_admin_id"
 	}
-	if cmd, exists := CommandRegistry[message.Title]; exists {
-		if isRoleAllowed(user.Role, cmd.AllowedRoles()) {
+	if cmd, exists := CommandRegistry[id]; exists {
+		if authorize(user, cmd.Permission(), id, message.Value) {
+			start := time.Now()
 			cmd.Execute(message, &user)
+			metrics.ObserveSince(metrics.BotCommandDuration.WithLabelValues(id), start)
+			metrics.BotCommands.WithLabelValues(id, "ok").Inc()
 			return
 		} else {
-			sendMessageWithKeyboard(message.Chat.ID, "You do not have permission to use this command.", getKeyboard(user.Role))
+			metrics.BotCommands.WithLabelValues(id, "denied").Inc()
+			sendMessageWithKeyboard(message.Chat.ID, tr(user, "permission.denied"), getKeyboard(user))
 		}
 	} else {
-		saveUserFilterInput(message.Chat.ID, user.ID, message.Title)
-		// sendMessageWithKeyboard(message.Chat.ID, "I didn't understand that command.", getKeyboard(user.Role))
+		saveUserFilterInput(message.Chat.ID, user, message.Title)
+		// sendMessageWithKeyboard(message.Chat.ID, "I didn't understand that command.", getKeyboard(user))
 	}
 }
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
+)
+
+// telegramClient is used for every Bot API call so requests and failures are counted per method
+var telegramClient = &http.Client{Transport: telegramTransport{base: http.DefaultTransport}}
+
+type telegramTransport struct {
+	base http.RoundTripper
+}
+
+func (t telegramTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	metrics.TelegramRequests.WithLabelValues(method).Inc()
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		metrics.TelegramErrors.WithLabelValues(method).Inc()
+	}
+	return resp, err
+}
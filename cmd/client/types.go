@@ -16,9 +16,10 @@ type Message struct {
 }
 
 type User struct {
-	ID        int    `json:"id"`
-	FirstName string `json:"first_name"`
-	Username  string `json:"username"`
+	ID           int    `json:"id"`
+	FirstName    string `json:"first_name"`
+	Username     string `json:"username"`
+	LanguageCode string `json:"language_code"`
 }
 
 type Chat struct {
@@ -54,5 +55,6 @@ type InlineKeyboardMarkup struct {
 
 type InlineKeyboardButton struct {
 	Text string `json:"text"`
-	Data string `json:"callback_data"`
+	Data string `json:"callback_data,omitempty"`
+	URL  string `json:"url,omitempty"`
 }
@@ -3,8 +3,8 @@ package client
 import (
 	"bytes"
 	"context"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,7 +15,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
 	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
 )
 
 const (
@@ -34,17 +41,19 @@ func getOrCreateUserRunCommand(message *Message) models.User {
 	user, err := userRepository.FindByTelegramID(uint64(message.From.ID))
 	if err != nil {
 		log.Printf("Error checking if user exists: %v", err)
-		sendMessage(message.Chat.ID, "There was an error checking your profile. Please try again later.")
+		sendMessage(message.Chat.ID, i18n.T(i18n.Parse(message.From.LanguageCode), "profile.check_error"))
 		return empty_user
 	}
 	if user.ID == 0 {
 		// If the user does not exist, create a new user
-		user = models.User{TelegramID: uint64(message.From.ID), Role: models.Role(models.USER)}
+		// new users get the bot in the language of their Telegram app
+		language := i18n.Parse(message.From.LanguageCode)
+		user = models.User{TelegramID: uint64(message.From.ID), Role: models.Role(models.USER), Language: string(language)}
 		log.Printf("User with id : %d created with role regular", message.From.ID)
-		_, err = userRepository.Save(user)
+		user, err = userRepository.Save(user)
 		if err != nil {
 			log.Printf("Error saving new user: %v", err)
-			sendMessage(message.Chat.ID, "There was an error creating your profile. Please try again later.")
+			sendMessage(message.Chat.ID, i18n.T(language, "profile.create_error"))
 			return empty_user
 		}
 	}
@@ -64,6 +73,7 @@ func pollUpdates(ctx context.Context) {
 			updates, err := getUpdates(offset)
 			if err != nil {
 				log.Printf("Error getting updates: %v", err)
+				tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
 				// Short delay before retrying to prevent tight error loop
 				time.Sleep(1 * time.Second)
 				continue
@@ -71,13 +81,7 @@ func pollUpdates(ctx context.Context) {
 
 			for _, update := range updates {
 				offset = update.UpdateID + 1
-				if update.Message != nil {
-					handleMessage(update.Message)
-				}
-
-				if update.Callback != nil {
-					handleCallbackQuery(update.Callback)
-				}
+				handleUpdate(update)
 			}
 
 			// Avoid excessive API polling; sleep for 1 second between calls
@@ -86,12 +90,32 @@ func pollUpdates(ctx context.Context) {
 	}
 }
 
+// handleUpdate dispatches one update; a panicking handler is recorded instead of stopping the bot
+func handleUpdate(update Update) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Recovered from panic while handling update %d: %v", update.UpdateID, recovered)
+			tracking.CapturePanic(models.BOT_ERROR, recovered, tracking.Origin{})
+		}
+	}()
+
+	if update.Message != nil {
+		metrics.BotUpdates.WithLabelValues("message").Inc()
+		handleMessage(update.Message)
+	}
+
+	if update.Callback != nil {
+		metrics.BotUpdates.WithLabelValues("callback_query").Inc()
+		handleCallbackQuery(update.Callback)
+	}
+}
+
 func deleteMessage(chatID int, messageID int) error {
 	data := url.Values{}
 	data.Set("chat_id", strconv.Itoa(chatID))
 	data.Set("message_id", strconv.Itoa(messageID))
 
-	resp, err := http.PostForm(fmt.Sprintf("%s/deleteMessage", apiURL), data)
+	resp, err := telegramClient.PostForm(fmt.Sprintf("%s/deleteMessage", apiURL), data)
 	if err != nil {
 		log.Printf("Error deleting message: %v", err)
 		return err
@@ -101,7 +125,7 @@ func deleteMessage(chatID int, messageID int) error {
 	return nil
 }
 func getUpdates(offset int) ([]Update, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/getUpdates?offset=%d&timeout=%d", apiURL, offset, timeout))
+	resp, err := telegramClient.Get(fmt.Sprintf("%s/getUpdates?offset=%d&timeout=%d", apiURL, offset, timeout))
 
 	if err != nil {
 		return nil, err
@@ -117,92 +141,22 @@ func getUpdates(offset int) ([]Update, error) {
 	}
 	return result.Result, nil
 }
-func getKeyboard(role models.Role) ReplyKeyboardMarkupWithLocation {
+func getWelcomeMessage(user models.User, name string) string {
 	switch {
-	case role == models.ADMIN:
-		return ReplyKeyboardMarkupWithLocation{
-			Keyboard: [][]KeyboardButton{
-				{
-					{Text: "Filters"},
-					{Text: "Premium"},
-					{Text: "Clients"},
-				}, {
-					{Text: "Errors"},
-				},
-			},
-			ResizeKeyboard:  true,
-			OneTimeKeyboard: true,
-		}
-	case role == models.SUPER_ADMIN:
-		return ReplyKeyboardMarkupWithLocation{
-			Keyboard: [][]KeyboardButton{
-				{
-					{Text: "Admins"},
-					{Text: "Monitor"},
-					{Text: "Clients"},
-				},
-				{
-
-					{Text: "Advertisements"},
-					{Text: "Crawler Setting"},
-				},
-			},
-			ResizeKeyboard:  true,
-			OneTimeKeyboard: true,
-		}
-	case role == models.USER:
-		return ReplyKeyboardMarkupWithLocation{
-			Keyboard: [][]KeyboardButton{
-				{
-					{Text: "Filter"},
-					{Text: "Search"},
-					{Text: "Bookmark"},
-				},
-				{
-					// {Text: "Help"},
-					{Text: "Send Location"},
-					{Text: "Export CSV"},
-				},
-				{
-					{Text: "Setting"},
-					{Text: "Populars"},
-				},
-				{
-					{Text: "Select Resource Website"},
-				},
-			},
-			ResizeKeyboard:  true,
-			OneTimeKeyboard: true,
-		}
+	case user.Role == models.USER:
+		return tr(user, "welcome.user", name)
+	case user.Role == models.ADMIN:
+		return tr(user, "welcome.admin", name)
+	case user.Role == models.SUPER_ADMIN:
+		return tr(user, "welcome.super_admin", name)
 	default:
-		return ReplyKeyboardMarkupWithLocation{
-			Keyboard: [][]KeyboardButton{
-				{
-					{Text: "Help"},
-				},
-			},
-			ResizeKeyboard:  true,
-			OneTimeKeyboard: true,
-		}
-	}
-
-}
-func getWelcomeMessage(name string, role models.Role) string {
-	switch {
-	case role == models.USER:
-		return fmt.Sprintf("Welcome %s!", name)
-	case role == models.ADMIN:
-		return fmt.Sprintf("Hi %s\nWelcome to admin panel !", name)
-	case role == models.SUPER_ADMIN:
-		return fmt.Sprintf("Hi %s\nWelcome to superadmin panel !", name)
-	default:
-		return "Welcome!"
+		return tr(user, "welcome.default")
 	}
 }
 
-func sendHelpMessage(chatID int, text string) {
+func sendHelpMessage(chatID int, user models.User, text string) {
 
-	sendMessageWithKeyboard(chatID, text, getKeyboard(models.USER))
+	sendMessageWithKeyboard(chatID, text, getKeyboard(user))
 }
 
 func answerCallbackQuery(callbackID, text string) {
@@ -218,7 +172,7 @@ func answerCallbackQuery(callbackID, text string) {
 		return
 	}
 
-	_, err = http.Post(fmt.Sprintf("%s/answerCallbackQuery", apiURL), "application/json", bytes.NewBuffer(payloadBytes))
+	_, err = telegramClient.Post(fmt.Sprintf("%s/answerCallbackQuery", apiURL), "application/json", bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		log.Printf("Error answering callback query: %v", err)
 	}
@@ -237,7 +191,7 @@ func sendMessageWithInlineKeyboard(chatID int, text string, keyboard InlineKeybo
 		return
 	}
 
-	resp, err := http.Post(fmt.Sprintf("%s/sendMessage", apiURL), "application/json", bytes.NewBuffer(payloadBytes))
+	resp, err := telegramClient.Post(fmt.Sprintf("%s/sendMessage", apiURL), "application/json", bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		log.Printf("Error sending message with inline keyboard: %v", err)
 		return
@@ -258,7 +212,7 @@ func sendMessageWithKeyboard(chatID int, text string, keyboard ReplyKeyboardMark
 		return
 	}
 
-	resp, err := http.Post(fmt.Sprintf("%s/sendMessage", apiURL), "application/json", bytes.NewBuffer(payloadBytes))
+	resp, err := telegramClient.Post(fmt.Sprintf("%s/sendMessage", apiURL), "application/json", bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		log.Printf("Error sending message with keyboard: %v", err)
 		return
@@ -271,20 +225,20 @@ func sendMessage(chatID int, text string) {
 	data.Set("chat_id", strconv.Itoa(chatID))
 	data.Set("text", text)
 
-	resp, err := http.PostForm(fmt.Sprintf("%s/sendMessage", apiURL), data)
+	resp, err := telegramClient.PostForm(fmt.Sprintf("%s/sendMessage", apiURL), data)
 	if err != nil {
 		log.Printf("Error sending message: %v", err)
 		return
 	}
 	defer resp.Body.Close()
 }
-func sendLocationRequest(chatID int) {
+func sendLocationRequest(chatID int, user models.User) {
 	// Set up a keyboard with a location request button
 	keyboard := ReplyKeyboardMarkupWithLocation{
 		Keyboard: [][]KeyboardButton{
 			{
 				KeyboardButton{
-					Text:            "Send your location 📍",
+					Text:            tr(user, "location.share_button"),
 					RequestLocation: true,
 				},
 			},
@@ -293,16 +247,16 @@ func sendLocationRequest(chatID int) {
 		OneTimeKeyboard: true,
 	}
 
-	sendMessageWithKeyboard(chatID, "Please share your location:", keyboard)
+	sendMessageWithKeyboard(chatID, tr(user, "location.share"), keyboard)
 }
 
-func createInlineKeyboardFromOptions(options []string) InlineKeyboardMarkup {
+func createInlineKeyboardFromOptions(user models.User, options []filterOption) InlineKeyboardMarkup {
 	buttons := make([][]InlineKeyboardButton, 0)
 	for _, option := range options {
 		row := []InlineKeyboardButton{
 			{
-				Text: option,
-				Data: option, // Use the filter name as callback data
+				Text: tr(user, option.key),
+				Data: option.id, // Use the filter name as callback data
 			},
 		}
 		buttons = append(buttons, row)
@@ -331,7 +285,95 @@ func handleCallbackQuery(callbackQuery *CallbackQuery) {
 		userFilters[uint64(user.ID)] = make(map[string]string)
 	}
 
+	if strings.HasPrefix(callbackQuery.Data, "errors_") {
+		if !authorize(user, (&ErrorsCommand{}).Permission(), "errors", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleErrorsCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "filters_user_") {
+		if !authorize(user, (&FiltersCommand{}).Permission(), "filters", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleFiltersCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "fin_") {
+		command, name := Command(&FinancingCommand{}), "financing"
+		if strings.HasPrefix(callbackQuery.Data, "fin_enable_") || strings.HasPrefix(callbackQuery.Data, "fin_disable_") {
+			command, name = &LoanProductsCommand{}, "loan_products"
+		}
+		if !authorize(user, command.Permission(), name, callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleFinancingCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "bm_") {
+		if !authorize(user, (&BookmarkCommand{}).Permission(), "bookmark", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleBookmarkCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "dg_") {
+		if !authorize(user, (&DigestsCommand{}).Permission(), "digests", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleDigestCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "risk_") {
+		if !authorize(user, (&ReviewQueueCommand{}).Permission(), "review_queue", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleRiskCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "lang_") {
+		if !authorize(user, (&LanguageCommand{}).Permission(), "language", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		handleLanguageCallback(int(chatID), user, callbackQuery.Data)
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "subscribe_") {
+		if !authorize(user, (&SubscribeCommand{}).Permission(), "subscribe", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		sendPaymentLink(int(chatID), user, strings.TrimPrefix(callbackQuery.Data, "subscribe_"))
+		return
+	}
+
 	if strings.HasPrefix(callbackQuery.Data, "resource_") {
+		if !authorize(user, (&GetResourceWebsite{}).Permission(), "website", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
 		// Extract resource type from the callback data
 		resource := strings.TrimPrefix(callbackQuery.Data, "resource_")
 
@@ -341,63 +383,74 @@ func handleCallbackQuery(callbackQuery *CallbackQuery) {
 	}
 
 	if strings.HasPrefix(callbackQuery.Data, "post_") {
+		if !authorize(user, models.PERM_SEARCH, "listing", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
 		// Extract the post ID
 		postIDStr := strings.TrimPrefix(callbackQuery.Data, "post_")
 		postID, err := strconv.Atoi(postIDStr)
 		if err != nil {
-			sendMessage(int(chatID), "Invalid post selection.")
+			sendMessage(int(chatID), tr(user, "post.invalid"))
 			return
 		}
 
-		sendMessage(postID, "post id")
+		answerCallbackQuery(callbackQuery.ID, "")
+		sendListingCard(int(chatID), user, uint(postID))
+		return
+	}
+
+	if strings.HasPrefix(callbackQuery.Data, "fair_") {
+		if !authorize(user, models.PERM_SEARCH, "valuation", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
+		postID, err := strconv.Atoi(strings.TrimPrefix(callbackQuery.Data, "fair_"))
+		if err != nil {
+			sendMessage(int(chatID), tr(user, "post.invalid"))
+			return
+		}
+		answerCallbackQuery(callbackQuery.ID, "")
+		sendValuation(int(chatID), user, uint(postID))
 		return
 	}
 
 	if strings.HasPrefix(callbackQuery.Data, "filter_") {
+		if !authorize(user, (&FilterCommand{}).Permission(), "filter", callbackQuery.Data) {
+			sendMessage(int(chatID), tr(user, "permission.denied"))
+			return
+		}
 		// Extract the filter ID
 		filterIDStr := strings.TrimPrefix(callbackQuery.Data, "filter_")
 		filterID, err := strconv.Atoi(filterIDStr)
 		if err != nil {
-			sendMessage(int(chatID), "Invalid filter selection.")
+			sendMessage(int(chatID), tr(user, "filter.invalid"))
 			return
 		}
 
-		// Use the filterID as needed
-		sendMessageWithKeyboard(int(chatID), fmt.Sprintf("Selected filter ID: %d", filterID), getKeyboard(user.Role))
-
-		handleFilterSelection(user.ID, uint(filterID))
+		// only the owner of a filter may select it
+		if err := handleFilterSelection(user.ID, uint(filterID)); err != nil {
+			sendMessage(int(chatID), tr(user, "filter.not_found"))
+			return
+		}
+		sendMessageWithKeyboard(int(chatID), tr(user, "filter.selected", filterID), getKeyboard(user))
+		return
+	}
+	if !authorize(user, (&CreateFilterCommand{}).Permission(), "filter", callbackQuery.Data) {
+		sendMessage(int(chatID), tr(user, "permission.denied"))
 		return
 	}
 	// Prompt user for input based on the selected filter
-	switch selectedFilter {
-	case "Price Range":
-		promptUserForInput(chatID, "Enter price range (e.g., 100000-200000):")
-	case "City":
-		promptUserForInput(chatID, "Enter city name:")
-	case "Neighborhood":
-		promptUserForInput(chatID, "Enter neighborhood name:")
-	case "Area Range":
-		promptUserForInput(chatID, "Enter area range (e.g., 50-200 square meters):")
-	case "Bedroom Count Range":
-		promptUserForInput(chatID, "Enter bedroom count range (e.g., 1-3):")
-	case "Category (Rent/Buy/Mortgage)":
-		promptUserForInput(chatID, "Enter category (Rent/Buy/Mortgage):")
-	case "Building Age Range":
-		promptUserForInput(chatID, "Enter building age range (e.g., 0-20 years):")
-	case "Property Type (Apartment/Villa)":
-		promptUserForInput(chatID, "Enter property type (Apartment/Villa):")
-	case "Floor Range":
-		promptUserForInput(chatID, "Enter floor range (e.g., 1-10):")
-	case "Storage Availability":
-		promptUserForInput(chatID, "Enter storage availability (Yes/No):")
-	case "Elevator Availability":
-		promptUserForInput(chatID, "Enter elevator availability (Yes/No):")
-	case "Advertisement Creation Date Range":
-		promptUserForInput(chatID, "Enter advertisement creation date range (e.g., YYYY-MM-DD to YYYY-MM-DD):")
-	default:
-		sendMessage(int(chatID), "Invalid filter selection.")
+	option, exists := findFilterOption(selectedFilter)
+	if !exists {
+		sendMessage(int(chatID), tr(user, "filter.invalid"))
 		return
 	}
+	prompt := tr(user, option.key+".prompt")
+	if option.id == "Monthly Cost Range" {
+		prompt = tr(user, option.key+".prompt", formatRate(rentService.Rate()))
+	}
+	promptUserForInput(chatID, prompt)
 
 	// Temporarily save the selected filter type
 	userFilters[uint64(user.ID)]["lastFilter"] = selectedFilter
@@ -405,7 +458,8 @@ func handleCallbackQuery(callbackQuery *CallbackQuery) {
 }
 
 // Function to save user filter input in memory
-func saveUserFilterInput(chatId int, userID uint, value string) {
+func saveUserFilterInput(chatId int, user models.User, value string) {
+	userID := user.ID
 	// Get or initialize the user's FilterItem
 	filterItem, exists := userFilterItems[userID]
 	if !exists {
@@ -416,12 +470,14 @@ func saveUserFilterInput(chatId int, userID uint, value string) {
 	// Debug: Log the filter type and value
 	filterType := userFilters[uint64(userID)]["lastFilter"]
 	log.Printf("Filter Type: %s, Value: %s", filterType, value)
+	// ranges and dates may be typed with Persian digits, text like keywords is kept as typed
+	number := persian.LatinDigits(value)
 
 	// Update the relevant field based on filterType
 	switch filterType {
 	case "Price Range":
 		var priceMin, priceMax float64
-		if _, err := fmt.Sscanf(value, "%f-%f", &priceMin, &priceMax); err == nil {
+		if _, err := fmt.Sscanf(number, "%f-%f", &priceMin, &priceMax); err == nil {
 			filterItem.PriceMin = priceMin
 			filterItem.PriceMax = priceMax
 		} else {
@@ -434,7 +490,7 @@ func saveUserFilterInput(chatId int, userID uint, value string) {
 		filterItem.Neighborhood = value
 	case "Area Range":
 		var areaMin, areaMax int
-		if _, err := fmt.Sscanf(value, "%d-%d", &areaMin, &areaMax); err == nil {
+		if _, err := fmt.Sscanf(number, "%d-%d", &areaMin, &areaMax); err == nil {
 			filterItem.AreaMin = areaMin
 			filterItem.AreaMax = areaMax
 		} else {
@@ -442,7 +498,7 @@ func saveUserFilterInput(chatId int, userID uint, value string) {
 		}
 	case "Bedroom Count Range":
 		var bedroomsMin, bedroomsMax int
-		if _, err := fmt.Sscanf(value, "%d-%d", &bedroomsMin, &bedroomsMax); err == nil {
+		if _, err := fmt.Sscanf(number, "%d-%d", &bedroomsMin, &bedroomsMax); err == nil {
 			filterItem.BedroomsMin = bedroomsMin
 			filterItem.BedroomsMax = bedroomsMax
 		} else {
@@ -452,7 +508,7 @@ func saveUserFilterInput(chatId int, userID uint, value string) {
 		filterItem.Category = value
 	case "Building Age Range":
 		var ageMin, ageMax int
-		if _, err := fmt.Sscanf(value, "%d-%d", &ageMin, &ageMax); err == nil {
+		if _, err := fmt.Sscanf(number, "%d-%d", &ageMin, &ageMax); err == nil {
 			filterItem.AgeMin = ageMin
 			filterItem.AgeMax = ageMax
 		} else {
@@ -462,7 +518,7 @@ func saveUserFilterInput(chatId int, userID uint, value string) {
 		filterItem.PropertyType = value
 	case "Floor Range":
 		var floorMin, floorMax int
-		if _, err := fmt.Sscanf(value, "%d-%d", &floorMin, &floorMax); err == nil {
+		if _, err := fmt.Sscanf(number, "%d-%d", &floorMin, &floorMax); err == nil {
 			filterItem.FloorMin = floorMin
 			filterItem.FloorMax = floorMax
 		} else {
@@ -473,18 +529,67 @@ func saveUserFilterInput(chatId int, userID uint, value string) {
 	case "Elevator Availability":
 		filterItem.HasElevator = (value == "yes")
 	case "Advertisement Creation Date Range":
-		var startDate, endDate time.Time
-		dates := strings.Split(value, " to ")
-		if len(dates) == 2 {
-			startDate, _ = time.Parse("2006-01-02", dates[0])
-			endDate, _ = time.Parse("2006-01-02", dates[1])
+		if startDate, endDate, err := parseDateRange(number); err == nil {
 			filterItem.CreatedDateStart = startDate
 			filterItem.CreatedDateEnd = endDate
+		} else {
+			log.Printf("Error parsing Advertisement Creation Date Range: %v", err)
+		}
+	case "Monthly Cost Range":
+		var costMin, costMax float64
+		if _, err := fmt.Sscanf(number, "%f-%f", &costMin, &costMax); err == nil {
+			filterItem.MonthlyCostMin = costMin
+			filterItem.MonthlyCostMax = costMax
+		} else {
+			log.Printf("Error parsing Monthly Cost Range: %v", err)
 		}
+	case "Sort by Monthly Cost":
+		filterItem.SortBy = ""
+		if strings.EqualFold(value, "yes") {
+			filterItem.SortBy = models.SORT_BY_MONTHLY_COST
+		}
+	case "Hide Risky Listings":
+		filterItem.HideRisky = strings.EqualFold(value, "yes")
+	case "Seller (Owner/Agency)":
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case string(types.Owner), "شخصی", "مالک":
+			filterItem.SellerType = string(types.Owner)
+		case string(types.Agency), "مشاور املاک", "املاک":
+			filterItem.SellerType = string(types.Agency)
+		default:
+			log.Printf("Error parsing Seller: %s", value)
+		}
+	case "Amenities":
+		filterItem.Amenities = parseAmenities(value)
+	case "Renovation (New/Renovated/Needs Renovation)":
+		choice, ok := parseChoice(value, renovationChoices)
+		if !ok {
+			promptChoiceAgain(chatId, user, renovationChoices)
+			return
+		}
+		filterItem.Renovation = choice
+	case "Document Type (Single Deed/Multi Deed/Endowment/Promissory)":
+		choice, ok := parseChoice(value, documentChoices)
+		if !ok {
+			promptChoiceAgain(chatId, user, documentChoices)
+			return
+		}
+		filterItem.Document = choice
+	case "Orientation (North/South/East/West)":
+		choice, ok := parseChoice(value, orientationChoices)
+		if !ok {
+			promptChoiceAgain(chatId, user, orientationChoices)
+			return
+		}
+		filterItem.Orientation = choice
+	case "Include Keywords":
+		filterItem.IncludeKeywords = strings.Join(persian.Terms(value), ", ")
+	case "Exclude Keywords":
+		filterItem.ExcludeKeywords = strings.Join(persian.Terms(value), ", ")
 	}
 
 	// Send confirmation menu
-	sendFilterConfirmationMenu(int64(chatId))
+	sendFilterConfirmationMenu(int64(chatId), user)
 
 	// Log the updated filter item
 	log.Printf("Updated filter item for user %d: %+v", userID, filterItem)
@@ -494,15 +599,15 @@ func promptUserForInput(chatID int64, prompt string) {
 	sendMessage(int(chatID), prompt)
 }
 
-func sendFilterConfirmationMenu(chatID int64) {
+func sendFilterConfirmationMenu(chatID int64, user models.User) {
 	keyboard := ReplyKeyboardMarkupWithLocation{
 		Keyboard: [][]KeyboardButton{
 			{
 				KeyboardButton{
-					Text: "SaveFilter",
+					Text: tr(user, "command.save_filter"),
 				},
 				KeyboardButton{
-					Text: "CancelFilter",
+					Text: tr(user, "command.cancel_filter"),
 				},
 			},
 		},
@@ -510,23 +615,23 @@ func sendFilterConfirmationMenu(chatID int64) {
 		OneTimeKeyboard: true,
 	}
 
-	sendMessageWithKeyboard(int(chatID), "continue add filter", keyboard)
+	sendMessageWithKeyboard(int(chatID), tr(user, "filter.continue"), keyboard)
 }
 
-func showFilterMenu(chatID int, userId uint) {
+func showFilterMenu(chatID int, user models.User) {
 	// Fetch filters from the database
-	filters, _ := filterRepository.FindByUserID(userId)
+	filters, _ := filterRepository.FindByUserID(user.ID)
 	// Create keyboard buttons for each filter
 	var filterButtons [][]KeyboardButton
 
 	// Add the "Create New Filter" button
 	filterButtons = append(filterButtons, []KeyboardButton{
-		{Text: "Create New Filter"},
+		{Text: tr(user, "command.create_filter")},
 	})
 
 	// Proceed safely with filterItems
 	if len(filters) == 0 {
-		log.Printf("No filters returned for user %d", userId)
+		log.Printf("No filters returned for user %d", user.ID)
 	} else {
 		for _, filter := range filters {
 			filterButtons = append(filterButtons, []KeyboardButton{
@@ -543,41 +648,75 @@ func showFilterMenu(chatID int, userId uint) {
 	}
 
 	// Send the menu
-	sendMessageWithKeyboard(int(chatID), "Select a filter or create a new one:", keyboard)
-}
-
-func showFilterOptions(chatID int) {
-	filterOptions := []string{
-		"Price Range",
-		"City",
-		"Neighborhood",
-		"Area Range",
-		"Bedroom Count Range",
-		"Category (Rent/Buy/Mortgage)",
-		"Building Age Range",
-		"Property Type (Apartment/Villa)",
-		"Floor Range",
-		"Storage Availability",
-		"Elevator Availability",
-		"Advertisement Creation Date Range",
-	}
-
-	msg := "Select a filter to apply:"
+	sendMessageWithKeyboard(int(chatID), tr(user, "filter.menu"), keyboard)
+}
+
+// filterOption is a filter a user can set, the id is its callback data and the key its label in the catalogs
+type filterOption struct {
+	id  string
+	key string
+}
+
+var filterOptions = []filterOption{
+	{"Price Range", "filter.price_range"},
+	{"City", "filter.city"},
+	{"Neighborhood", "filter.neighborhood"},
+	{"Area Range", "filter.area_range"},
+	{"Bedroom Count Range", "filter.bedroom_range"},
+	{"Category (Rent/Buy/Mortgage)", "filter.category"},
+	{"Building Age Range", "filter.age_range"},
+	{"Property Type (Apartment/Villa)", "filter.property_type"},
+	{"Floor Range", "filter.floor_range"},
+	{"Storage Availability", "filter.storage"},
+	{"Elevator Availability", "filter.elevator"},
+	{"Advertisement Creation Date Range", "filter.created_range"},
+	{"Monthly Cost Range", "filter.monthly_cost_range"},
+	{"Sort by Monthly Cost", "filter.sort_monthly_cost"},
+	{"Hide Risky Listings", "filter.hide_risky"},
+	{"Seller (Owner/Agency)", "filter.seller"},
+	{"Include Keywords", "filter.include_keywords"},
+	{"Exclude Keywords", "filter.exclude_keywords"},
+	{"Amenities", "filter.amenities"},
+	{"Renovation (New/Renovated/Needs Renovation)", "filter.renovation"},
+	{"Document Type (Single Deed/Multi Deed/Endowment/Promissory)", "filter.document"},
+	{"Orientation (North/South/East/West)", "filter.orientation"},
+}
+
+func findFilterOption(id string) (filterOption, bool) {
+	for _, option := range filterOptions {
+		if option.id == id {
+			return option, true
+		}
+	}
+	return filterOption{}, false
+}
+
+func showFilterOptions(chatID int, user models.User) {
+	msg := tr(user, "filter.options")
 	sendMessageWithInlineKeyboard(
 		int(chatID),
 		msg,
-		createInlineKeyboardFromOptions(filterOptions),
+		createInlineKeyboardFromOptions(user, filterOptions),
 	)
-	sendFilterConfirmationMenu(int64(chatID))
+	sendFilterConfirmationMenu(int64(chatID), user)
 }
 
-func handleFilterSelection(userID uint, filterID uint) {
+// handleFilterSelection makes a filter of the user their last filter, a filter of another user is not found
+func handleFilterSelection(userID uint, filterID uint) error {
+	filter, err := filterRepository.FindByID(filterID)
+	if err != nil {
+		return err
+	}
+	if filter.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
 
 	updatedFields := map[string]interface{}{
 		"LastFilterItemID": filterID,
 	}
 
-	userRepository.UpdateUser(userID, updatedFields)
+	_, err = userRepository.UpdateUser(userID, updatedFields)
+	return err
 }
 
 func createFilter(userId uint) {
@@ -591,7 +730,9 @@ func createFilter(userId uint) {
 		return
 	}
 
-	handleFilterSelection(userId, createdFilterItem.ID)
+	if err := handleFilterSelection(userId, createdFilterItem.ID); err != nil {
+		log.Printf("Error selecting the new filter: %v", err)
+	}
 	// userLastFilterMap = make(map[uint]uint) 
 	// userFilterItems = make(map[uint]*models.FilterItem)
 }
@@ -602,23 +743,31 @@ func cancelFilter(userId uint) {
 }
 
 func sendFile(chatID int64, content []byte, fileType string) ([]byte, error) {
+	return uploadFile(chatID, "sendDocument", "document", "result"+fileType, content, "")
+}
+
+// sendPhoto sends a PNG image with a caption
+func sendPhoto(chatID int64, content []byte, caption string) ([]byte, error) {
+	return uploadFile(chatID, "sendPhoto", "photo", "chart.png", content, caption)
+}
+
+func uploadFile(chatID int64, method string, field string, fileName string, content []byte, caption string) ([]byte, error) {
 	var (
 		buf    = new(bytes.Buffer)
 		writer = multipart.NewWriter(buf)
 	)
 
-	chatIdField, err := writer.CreateFormField("chat_id")
+	err := writer.WriteField("chat_id", strconv.FormatInt(chatID, 10))
 	if err != nil {
 		return []byte{}, err
 	}
-	chatIdByteArray := make([]byte, 8)
-	binary.LittleEndian.PutUint64(chatIdByteArray, uint64(chatID))
-	_, err = chatIdField.Write(chatIdByteArray)
-	if err != nil {
-		return []byte{}, err
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return []byte{}, err
+		}
 	}
 
-	part, err := writer.CreateFormFile("document", "result"+fileType)
+	part, err := writer.CreateFormFile(field, fileName)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -633,14 +782,13 @@ func sendFile(chatID int64, content []byte, fileType string) ([]byte, error) {
 		return []byte{}, err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/sendDocument", apiURL), buf)
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s", apiURL, method), buf)
 	if err != nil {
 		return []byte{}, err
 	}
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := telegramClient.Do(req)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -653,8 +801,13 @@ func sendFile(chatID int64, content []byte, fileType string) ([]byte, error) {
 	return cnt, nil
 }
 
-func searchLastFilter(chatID int, userID int) {
-	lastFilterItem, err := userRepository.GetLastFilterItem(uint(userID))
+// searchLastFilter sends the posts matching the last filter of a user as buttons that open their listing cards
+func searchLastFilter(chatID int, user models.User) {
+	if !authorize(user, models.PERM_SEARCH, "search", "") {
+		sendMessage(chatID, tr(user, "permission.denied"))
+		return
+	}
+	lastFilterItem, err := userRepository.GetLastFilterItem(user.ID)
 	if err != nil {
 		log.Printf("Error retrieving last filter item: %v", err)
 		return
@@ -667,33 +820,35 @@ func searchLastFilter(chatID int, userID int) {
 	posts, err := filterRepository.SearchPostHistory(*lastFilterItem)
 	if err != nil {
 		log.Printf("Error fetching posts: %v", err)
-		sendMessage(chatID, "An error occurred while fetching posts.")
+		sendMessage(chatID, tr(user, "posts.fetch_error"))
 		return
 	}
 
 	if len(posts) == 0 {
 		// Inform the user if no posts match the filter
-		sendMessage(chatID, "No posts found matching your filter.")
+		sendMessage(chatID, tr(user, "posts.none_match"))
 		return
 	}
 
 	// Generate the inline keyboard
-	keyboard := createInlineKeyboardFromPosts(posts)
+	keyboard := createInlineKeyboardFromPosts(user, posts)
 
 	// Prepare the message text
-	text := "Here are the posts matching your filter:\nSelect one to view details."
+	text := tr(user, "search.results")
+	if snippets := keywordSnippets(posts, persian.Terms(lastFilterItem.IncludeKeywords)); snippets != "" {
+		text = tr(user, "search.results_snippets", snippets)
+	}
 
 	// Send the message with the inline keyboard
 	sendMessageWithInlineKeyboard(chatID, text, keyboard)
 }
 
 // Function to create an inline keyboard from a list of posts
-func createInlineKeyboardFromPosts(posts []models.PostHistory) InlineKeyboardMarkup {
+func createInlineKeyboardFromPosts(user models.User, posts []models.PostHistory) InlineKeyboardMarkup {
 	buttons := make([][]InlineKeyboardButton, 0)
 
 	for _, post := range posts {
-		text := fmt.Sprintf(
-			"🏡 *%s*\n\nPrice: %d\nCity: %s\nNeighborhood: %s\nArea: %d m²\nBedrooms: %d\n\n[View Post](%s)",
+		text := tr(user, "search.post_button",
 			post.Title, post.Price, post.City, post.Neighborhood, post.Area, post.BedroomNum, post.PostURL,
 		)
 		// Each row will have one button with the post's title or summary
@@ -730,4 +885,47 @@ func generateResourceTypeButtons() InlineKeyboardMarkup {
 
 	// Return the inline keyboard markup
 	return InlineKeyboardMarkup{InlineKeyboard: buttons}
-}
\ No newline at end of file
+}
+// quotaMessage returns the text shown to a user when a quota check fails
+func quotaMessage(user models.User, err error) string {
+	var quotaError *services.QuotaError
+	if errors.As(err, &quotaError) {
+		return quotaError.Message
+	}
+	log.Printf("Error checking quota: %v", err)
+	tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+	return tr(user, "quota.error")
+}
+
+// usageOf formats a count against its limit, e.g. "2 of 3"
+func usageOf(user models.User, count int64, limit int) string {
+	return tr(user, "usage.of", count, limitOf(user, limit))
+}
+
+func limitOf(user models.User, limit int) string {
+	if limit == 0 {
+		return tr(user, "usage.unlimited")
+	}
+	return i18n.Digits(languageOf(user), strconv.Itoa(limit))
+}
+
+// sendPaymentLink creates an invoice of the plan and sends its payment link to the user
+func sendPaymentLink(chatID int, user models.User, planID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	invoice, paymentURL, err := subscriptionService.CreateInvoice(ctx, user, planID)
+	if errors.Is(err, services.ErrUnknownPlan) {
+		sendMessage(chatID, tr(user, "payment.invalid_plan"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating invoice: %v", err)
+		tracking.Capture(models.BOT_ERROR, err, tracking.Origin{})
+		sendMessage(chatID, tr(user, "payment.error"))
+		return
+	}
+
+	keyboard := InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{{Text: tr(user, "payment.pay"), URL: paymentURL}}}}
+	sendMessageWithInlineKeyboard(chatID, tr(user, "payment.invoice", invoice.ID, invoice.Amount), keyboard)
+}
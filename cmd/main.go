@@ -4,8 +4,14 @@ import (
 	"log/slog"
 
 	"github.com/MagicalCrawler/RealEstateApp/cmd/client"
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
 	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/handlers"
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
+	"github.com/MagicalCrawler/RealEstateApp/payments"
 	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/storage"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
 	"github.com/MagicalCrawler/RealEstateApp/utils"
 )
 
@@ -19,14 +25,83 @@ func main() {
 	logger.Debug("Initialize DB connection")
 	dbConnection := db.NewConnection()
 
+	errorEventRepository := db.NewErrorEventRepository(dbConnection)
+	tracking.SetStore(errorEventRepository)
+
 	logger.Debug("Initialize crawler service jobs")
 	userRepository := db.CreateNewUserRepository(dbConnection)
 	postRepository := db.NewPostRepository(dbConnection)
 	bookmarkRepository := db.NewBookmarkRepository(dbConnection)
 	filterRepository := db.NewFilterItemRepository(dbConnection)
-	crawlerService := services.NewCrawlerService(&postRepository)
+	watchListRepository := db.NewWatchListRepository(dbConnection)
+	crawlRunRepository := db.NewCrawlRunRepository(dbConnection)
+	rentService := services.NewRentService(db.NewAppSettingRepository(dbConnection), postRepository)
+	if _, err := rentService.Recompute(); err != nil {
+		logger.Error("Recompute rent equivalents failed", slog.Any("error", err))
+	}
+	fraudService := services.NewFraudService(db.NewListingRiskRepository(dbConnection))
+	imageStore, err := storage.NewBlobStoreFromConfig()
+	if err != nil {
+		logger.Error("Create image store failed", slog.Any("error", err))
+		panic(err)
+	}
+	imageService := services.NewImageService(imageStore, db.NewListingImageRepository(dbConnection), crawlers.Limiters(),
+		crawlers.Identities())
+	bookmarkService := services.NewBookmarkService(bookmarkRepository, db.NewSharedFolderRepository(dbConnection), postRepository)
+	crawlerService := services.NewCrawlerService(&postRepository, crawlRunRepository, rentService, fraudService, imageService,
+		bookmarkService)
 	crawlerService.Start()
+	lifecycleService := services.NewLifecycleService(db.NewPostLifecycleRepository(dbConnection), crawlerService.Crawlers())
+	lifecycleService.Start()
+
+	paymentGateway, err := payments.NewGatewayFromConfig()
+	if err != nil {
+		logger.Error("Create payment gateway failed", slog.Any("error", err))
+		panic(err)
+	}
+	subscriptionService := services.NewSubscriptionService(paymentGateway, db.NewSubscriptionRepository(dbConnection),
+		db.NewInvoiceRepository(dbConnection), userRepository)
+	subscriptionService.Start()
+	marketService := services.NewMarketService(db.NewMarketIndexRepository(dbConnection))
+	marketService.Start()
+	digestService := services.NewDigestService(db.NewDigestRepository(dbConnection), filterRepository)
+	digestService.Start()
+
+	logger.Debug("Start the metrics and health server")
+	if sqlDB, err := dbConnection.DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB, "postgres")
+	}
+	handlers.NewServer(dbConnection, crawlerService.LastSuccessfulCrawl).Start()
+
+	logger.Debug("Start the payment server")
+	if err := handlers.StartPaymentServer(subscriptionService); err != nil {
+		logger.Error("Start payment server failed", slog.Any("error", err))
+		panic(err)
+	}
 
 	logger.Debug("Run the Telegram bot")
-	client.Run(userRepository, postRepository, bookmarkRepository, filterRepository)
+	client.Run(client.Dependencies{
+		UserRepository:         userRepository,
+		PostRepository:         postRepository,
+		BookmarkRepository:     bookmarkRepository,
+		FilterRepository:       filterRepository,
+		WatchListRepository:    watchListRepository,
+		CrawlRunRepository:     crawlRunRepository,
+		ErrorEventRepository:   errorEventRepository,
+		QuotaService:           services.NewQuotaService(filterRepository, watchListRepository, db.NewUsageRepository(dbConnection)),
+		SubscriptionService:    subscriptionService,
+		FilterAnalyticsService: services.NewFilterAnalyticsService(filterRepository),
+		MarketService:          marketService,
+		ValuationService:       services.NewValuationService(postRepository),
+		RentService:            rentService,
+		FraudService:           fraudService,
+		ImageService:           imageService,
+		LifecycleService:       lifecycleService,
+		BookmarkService:        bookmarkService,
+		ComparisonService:      services.NewComparisonService(postRepository, rentService),
+		FinancingService:       services.NewFinancingService(db.NewFinancingRepository(dbConnection)),
+		DigestService:          digestService,
+		PermissionService: services.NewPermissionService(db.NewPermissionRepository(dbConnection),
+			db.NewAuditLogRepository(dbConnection), userRepository),
+	})
 }
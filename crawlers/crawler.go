@@ -3,10 +3,12 @@ package crawlers
 import (
 	"context"
 	crawlerModels "github.com/MagicalCrawler/RealEstateApp/models/crawler"
+	"github.com/MagicalCrawler/RealEstateApp/types"
 )
 
 // Crawler is the interface that all crawler implementations must satisfy
 type Crawler interface {
+	Source() types.WebsiteSource
 	Crawl(ctx context.Context, city crawlerModels.City) ([]crawlerModels.Post, error)
 	CrawlPostDetails(ctx context.Context, postURL string) (crawlerModels.Post, error)
 }
@@ -2,13 +2,15 @@ package divar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/MagicalCrawler/RealEstateApp/models"
 	crawlerModels "github.com/MagicalCrawler/RealEstateApp/models/crawler"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
 	"github.com/MagicalCrawler/RealEstateApp/types"
 	"github.com/MagicalCrawler/RealEstateApp/utils"
-	"log"
 	"log/slog"
-	"math/rand"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,49 +22,28 @@ import (
 
 const (
 	defaultMaxRetries        = 3
-	defaultRetryDelay        = 5 * time.Second
 	defaultPageLimit         = 1
 	defaultGotoTimeout       = 30000
 	defaultMaxScrollAttempts = 5
 	scrollWaitDuration       = 2 * time.Second
-	randomSleepMin           = 3
-	randomSleepMax           = 10
 )
 
 // DivarCrawler implements the Crawler interface for the Divar website
 type DivarCrawler struct {
 	baseURL    string
-	userAgents []string
+	identities *crawlers.IdentityProvider
+	limiter    *crawlers.HostLimiter
 	logger     *slog.Logger
 }
 
 // NewDivarCrawler creates a new instance of DivarCrawler
 func NewDivarCrawler() *DivarCrawler {
+	baseURL := utils.GetConfig("DIVAR_BASE_URL")
 	return &DivarCrawler{
-		baseURL: utils.GetConfig("DIVAR_BASE_URL"),
-		userAgents: []string{
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/85.0.4183.121 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
-			"Mozilla/5.0 (Linux; Android 10; SM-G975F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Mobile Safari/537.36",
-			"Mozilla/5.0 (iPhone; CPU iPhone OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A372 Safari/604.1",
-			"Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Mobile Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:92.0) Gecko/20100101 Firefox/92.0",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Safari/605.1.15",
-			"Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/56.0.2924.87 Safari/537.36",
-			"Mozilla/5.0 (Linux; Android 9; SM-G960F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/76.0.3809.89 Mobile Safari/537.36",
-			"Mozilla/5.0 (iPad; CPU OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A5341f Safari/604.1",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.96 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; AS; rv:11.0) like Gecko",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.11; rv:42.0) Gecko/20100101 Firefox/42.0",
-			"Mozilla/5.0 (Linux; Android 10; SM-A505F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.96 Mobile Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; Trident/7.0; AS; rv:11.0) like Gecko",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36",
-			"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36",
-			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0",
-			"Mozilla/5.0 (Linux; U; Android 9; en-US; SM-G960U Build/PPR1.180610.011) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/76.0.3809.89 Mobile Safari/537.36",
-		},
-		logger: utils.NewLogger("Divar_Crawler"),
+		baseURL:    baseURL,
+		identities: crawlers.Identities(),
+		limiter:    crawlers.Limiters().For(baseURL),
+		logger:     utils.NewLogger("Divar_Crawler"),
 	}
 }
 
@@ -75,11 +56,12 @@ func (c *DivarCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]cr
 	}
 	pageURL := fmt.Sprintf("%s/s/%s/real-estate", c.baseURL, city.Slug)
 	var allPosts []crawlerModels.Post
+	stats := crawlers.StatsFromContext(ctx)
 
 	// Initialize Playwright
 	pw, err := playwright.Run()
 	if err != nil {
-		c.logger.Error("could not start Playwright: ", err)
+		c.logger.Error("could not start Playwright", slog.Any("error", err))
 		return nil, fmt.Errorf("could not start Playwright: %w", err)
 	}
 	defer pw.Stop()
@@ -88,7 +70,7 @@ func (c *DivarCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]cr
 		Headless: playwright.Bool(true),
 	})
 	if err != nil {
-		c.logger.Error("could not launch browser: ", err)
+		c.logger.Error("could not launch browser", slog.Any("error", err))
 		return nil, fmt.Errorf("could not launch browser: %w", err)
 	}
 	defer browser.Close()
@@ -104,30 +86,36 @@ func (c *DivarCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]cr
 		maxPageRetries = defaultMaxRetries
 	}
 
-	retryDelaySeconds, err := strconv.Atoi(utils.GetConfig("CRAWLER_RETRY_DELAY"))
-	if err != nil || retryDelaySeconds <= 0 {
-		retryDelaySeconds = int(defaultRetryDelay.Seconds())
-	}
-	retryDelay := time.Duration(retryDelaySeconds) * time.Second
-
 	for attempt := 1; attempt <= maxPageRetries; attempt++ {
-		c.logger.Info("Crawling page: ", pageURL, "Attempt ", attempt)
+		c.logger.Info("Crawling page", slog.String("url", pageURL), slog.Any("attempt", attempt))
 
-		page, err := browser.NewPage()
+		profile, err := c.identities.Next()
 		if err != nil {
-			c.logger.Error("could not create new page: ", err, " | Attempt: ", attempt)
-			time.Sleep(retryDelay)
+			c.logger.Warn("no proxy to crawl through", slog.Any("error", err), slog.Int("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorNoProxy)
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return allPosts, err
+			}
+			continue
+		}
+		browserContext, err := profile.NewContext(browser)
+		if err != nil {
+			c.logger.Error("could not create browser context", slog.Any("error", err), slog.Int("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorBrowser)
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return allPosts, err
+			}
 			continue
 		}
 
-		err = page.SetExtraHTTPHeaders(map[string]string{
-			"User-Agent": c.randomUserAgent(),
-			"Referer":    "https://google.com",
-		})
+		page, err := browserContext.NewPage()
 		if err != nil {
-			c.logger.Error("could not set extra headers: ", err, " | Attempt: ", attempt)
-			page.Close()
-			time.Sleep(retryDelay)
+			c.logger.Error("could not create new page", slog.Any("error", err), slog.Int("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorBrowser)
+			browserContext.Close()
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return allPosts, err
+			}
 			continue
 		}
 
@@ -135,22 +123,32 @@ func (c *DivarCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]cr
 		if err != nil || playwrightTimeout <= 0 {
 			playwrightTimeout = defaultGotoTimeout
 		}
-		_, err = page.Goto(pageURL, playwright.PageGotoOptions{
-			WaitUntil: playwright.WaitUntilStateNetworkidle,
-			Timeout:   playwright.Float(float64(playwrightTimeout)),
-		})
+		err = c.navigate(ctx, page, profile, pageURL, playwrightTimeout)
+		if errors.Is(err, crawlers.ErrCircuitOpen) {
+			c.logger.Warn("host is paused by circuit breaker", slog.String("url", pageURL))
+			browserContext.Close()
+			return allPosts, err
+		}
 		if err != nil {
-			c.logger.Error("Error navigating to: ", pageURL, " | Attempt: ", attempt, " error: ", err)
-			page.Close()
-			time.Sleep(retryDelay)
+			c.logger.Error("Error navigating", slog.String("url", pageURL), slog.Any("attempt", attempt), slog.Any("error", err))
+			tracking.Capture(models.CRAWLER_ERROR, err, tracking.Origin{Source: types.Divar, City: city.Name, URL: pageURL})
+			browserContext.Close()
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return allPosts, err
+			}
 			continue
 		}
 
 		// Scroll to load all content
-		postLinks, err := c.autoScroll(page)
+		postLinks, err := c.autoScroll(page, stats)
 		if err != nil {
-			c.logger.Error("Error during auto-scroll: ", err, " | Attempt: ", attempt)
+			c.logger.Error("Error during auto-scroll", slog.Any("error", err), slog.Any("attempt", attempt))
+			tracking.Capture(models.CRAWLER_ERROR, err, tracking.Origin{Source: types.Divar, City: city.Name, URL: pageURL})
+			stats.RecordFailure(crawlers.ErrorParse)
+		} else {
+			stats.RecordSuccess()
 		}
+		browserContext.Close()
 
 		var mu sync.Mutex
 		chunkSize := 5
@@ -174,7 +172,8 @@ func (c *DivarCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]cr
 
 					post, err := c.CrawlPostDetails(ctx, link)
 					if err != nil {
-						c.logger.Error("Error crawling post: ", link, " | error: ", err)
+						c.logger.Error("Error crawling post", slog.String("url", link), slog.Any("error", err))
+						tracking.Capture(models.CRAWLER_ERROR, err, tracking.Origin{Source: types.Divar, City: city.Name, URL: link})
 						return
 					}
 					post.City = city
@@ -188,29 +187,32 @@ func (c *DivarCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]cr
 
 			wg.Wait()
 		}
+
+		// The listing page was crawled, retrying would only send duplicate requests to the host
+		break
 	}
 	return allPosts, nil
 }
 
+// Source returns the website this crawler reads from
+func (c *DivarCrawler) Source() types.WebsiteSource {
+	return types.Divar
+}
+
 // CrawlPostDetails fetches details for a single post
 func (c *DivarCrawler) CrawlPostDetails(ctx context.Context, postURL string) (crawlerModels.Post, error) {
 	var post crawlerModels.Post
+	stats := crawlers.StatsFromContext(ctx)
 
 	maxRetries, err := strconv.Atoi(utils.GetConfig("CRAWLER_MAX_RETRIES"))
 	if err != nil || maxRetries <= 0 {
 		maxRetries = defaultMaxRetries
 	}
 
-	retryDelaySeconds, err := strconv.Atoi(utils.GetConfig("CRAWLER_RETRY_DELAY"))
-	if err != nil || retryDelaySeconds <= 0 {
-		retryDelaySeconds = int(defaultRetryDelay.Seconds())
-	}
-	retryDelay := time.Duration(retryDelaySeconds) * time.Second
-
 	// Initialize Playwright
 	pw, err := playwright.Run()
 	if err != nil {
-		c.logger.Error("could not start Playwright: ", err)
+		c.logger.Error("could not start Playwright", slog.Any("error", err))
 		return post, fmt.Errorf("could not start Playwright: %w", err)
 	}
 	defer pw.Stop()
@@ -219,7 +221,7 @@ func (c *DivarCrawler) CrawlPostDetails(ctx context.Context, postURL string) (cr
 		Headless: playwright.Bool(true),
 	})
 	if err != nil {
-		c.logger.Error("could not launch browser: ", err)
+		c.logger.Error("could not launch browser", slog.Any("error", err))
 		return post, fmt.Errorf("could not launch browser: %w", err)
 	}
 	defer browser.Close()
@@ -231,22 +233,33 @@ func (c *DivarCrawler) CrawlPostDetails(ctx context.Context, postURL string) (cr
 		default:
 		}
 
-		page, err := browser.NewPage()
+		profile, err := c.identities.Next()
 		if err != nil {
-			c.logger.Error("could not create new page: ", err, " | Attempt: ", attempt)
-			log.Printf("Attempt %d: could not create new page: %v", attempt, err)
-			time.Sleep(retryDelay)
+			c.logger.Warn("no proxy to crawl through", slog.Any("error", err), slog.Int("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorNoProxy)
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
+			continue
+		}
+		browserContext, err := profile.NewContext(browser)
+		if err != nil {
+			c.logger.Error("could not create browser context", slog.Any("error", err), slog.Int("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorBrowser)
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
 			continue
 		}
 
-		err = page.SetExtraHTTPHeaders(map[string]string{
-			"User-Agent": c.randomUserAgent(),
-			"Referer":    "https://google.com",
-		})
+		page, err := browserContext.NewPage()
 		if err != nil {
-			c.logger.Error("could not set extra headers:", err, " | Attempt: ", attempt)
-			page.Close()
-			time.Sleep(retryDelay)
+			c.logger.Error("could not create new page", slog.Any("error", err), slog.Int("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorBrowser)
+			browserContext.Close()
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
 			continue
 		}
 
@@ -254,30 +267,45 @@ func (c *DivarCrawler) CrawlPostDetails(ctx context.Context, postURL string) (cr
 		if err != nil || playwrightTimeout <= 0 {
 			playwrightTimeout = defaultGotoTimeout
 		}
-		_, err = page.Goto(postURL, playwright.PageGotoOptions{
-			WaitUntil: playwright.WaitUntilStateNetworkidle,
-			Timeout:   playwright.Float(float64(playwrightTimeout)),
-		})
+		err = c.navigate(ctx, page, profile, postURL, playwrightTimeout)
+		if errors.Is(err, crawlers.ErrCircuitOpen) {
+			c.logger.Warn("host is paused by circuit breaker", slog.String("url", postURL))
+			browserContext.Close()
+			return post, err
+		}
+		if errors.Is(err, crawlers.ErrPostRemoved) {
+			browserContext.Close()
+			return post, err
+		}
 		if err != nil {
-			c.logger.Error("Error navigating to: ", postURL, " | Attempt: ", attempt, " error: ", err)
-			page.Close()
-			time.Sleep(retryDelay)
+			c.logger.Error("Error navigating", slog.String("url", postURL), slog.Any("attempt", attempt), slog.Any("error", err))
+			browserContext.Close()
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
 			continue
 		}
 
 		content, err := page.Content()
 		if err != nil {
-			c.logger.Error("could not get page content: ", err, " | Attempt: ", attempt)
-			page.Close()
-			time.Sleep(retryDelay)
+			c.logger.Error("could not get page content", slog.Any("error", err), slog.Any("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorBrowser)
+			browserContext.Close()
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
 			continue
 		}
-		page.Close()
+		browserContext.Close()
+		stats.RecordBytes(len(content))
 
 		doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
 		if err != nil {
-			c.logger.Error("could not parse HTML from: ", postURL, " | Attempt: ", attempt, " error: ", err)
-			time.Sleep(retryDelay)
+			c.logger.Error("could not parse HTML", slog.String("url", postURL), slog.Any("attempt", attempt), slog.Any("error", err))
+			stats.RecordFailure(crawlers.ErrorParse)
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
 			continue
 		}
 
@@ -288,10 +316,18 @@ func (c *DivarCrawler) CrawlPostDetails(ctx context.Context, postURL string) (cr
 		post.Title = strings.TrimSpace(doc.Find("h1.kt-page-title__title").Text())
 		post.Description = strings.TrimSpace(doc.Find("div.post-page__section--padded").Text())
 		post.Website = types.Divar
+		if err := crawlers.DetectPageRemoval(doc, post.Title, post.Description); err != nil {
+			// the website shows a removal notice, retrying would show it again
+			stats.RecordSuccess()
+			return post, err
+		}
 		// Check if essential details are present
 		if post.Title == "" || post.Description == "" {
-			c.logger.Error("Missing essential post details for: ", postURL, " | Attempt: ", attempt)
-			time.Sleep(retryDelay)
+			c.logger.Error("Missing essential post details", slog.String("url", postURL), slog.Any("attempt", attempt))
+			stats.RecordFailure(crawlers.ErrorMissingFields)
+			if err := c.limiter.WaitBackoff(ctx, attempt); err != nil {
+				return post, err
+			}
 			continue
 		}
 
@@ -299,10 +335,11 @@ func (c *DivarCrawler) CrawlPostDetails(ctx context.Context, postURL string) (cr
 		c.extractPostDetails(doc, &post)
 
 		// If successful, return
+		stats.RecordSuccess()
 		return post, nil
 	}
 
-	c.logger.Error("failed to crawl post details from", postURL, " after ", maxRetries, " attempts")
+	c.logger.Error("failed to crawl post details", slog.String("url", postURL), slog.Any("attempts", maxRetries))
 	return post, fmt.Errorf("failed to crawl post details from %s after %d attempts", postURL, maxRetries)
 }
 
@@ -321,12 +358,47 @@ func splitIntoChunks(postLinks []string, chunkSize int) [][]string {
 	return chunks
 }
 
-func (c *DivarCrawler) randomUserAgent() string {
-	return c.userAgents[rand.Intn(len(c.userAgents))]
+// navigate opens the URL in page while respecting the host's politeness policy
+func (c *DivarCrawler) navigate(ctx context.Context, page playwright.Page, profile crawlers.Profile, pageURL string, timeout int) error {
+	stats := crawlers.StatsFromContext(ctx)
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		stats.RecordFailure(crawlers.ClassifyError(err))
+		return err
+	}
+	defer release()
+
+	stats.RecordRequest()
+	response, err := page.Goto(pageURL, playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateNetworkidle,
+		Timeout:   playwright.Float(float64(timeout)),
+	})
+	if err != nil {
+		c.identities.ReportFailure(profile)
+		stats.RecordFailure(crawlers.ClassifyError(err))
+		return err
+	}
+	if response != nil {
+		c.limiter.Report(response.Status())
+		if crawlers.IsBlockedStatus(response.Status()) {
+			c.identities.ReportFailure(profile)
+			stats.RecordFailure(crawlers.ErrorBlocked)
+			return fmt.Errorf("%w: status %d", crawlers.ErrBlocked, response.Status())
+		}
+		if err := crawlers.DetectRemoval(response.Status(), ""); err != nil {
+			c.identities.ReportSuccess(profile)
+			stats.RecordSuccess()
+			return err
+		}
+	}
+	c.identities.ReportSuccess(profile)
+	return nil
 }
 
-func (c *DivarCrawler) autoScroll(page playwright.Page) ([]string, error) {
+func (c *DivarCrawler) autoScroll(page playwright.Page, stats *crawlers.CrawlStats) ([]string, error) {
 	var allLinks []string
+	// the listing page keeps what it loaded before, only its growth was fetched by a scroll
+	loadedSize := 0
 
 	maxScrollAttempts, err := strconv.Atoi(utils.GetConfig("CRAWLER_MAX_SCROLL_ATTEMPTS"))
 	if err != nil || maxScrollAttempts <= 0 {
@@ -343,7 +415,7 @@ func (c *DivarCrawler) autoScroll(page playwright.Page) ([]string, error) {
             return document.querySelectorAll('div.kt-post-card__body').length;
         }`)
 		if err != nil {
-			c.logger.Error("error scrolling page: ", err)
+			c.logger.Error("error scrolling page", slog.Any("error", err))
 			return nil, fmt.Errorf("error scrolling page: %w", err)
 		}
 
@@ -353,14 +425,18 @@ func (c *DivarCrawler) autoScroll(page playwright.Page) ([]string, error) {
 		// Get page content
 		content, err := page.Content()
 		if err != nil {
-			c.logger.Error("error getting page content: ", err)
+			c.logger.Error("error getting page content", slog.Any("error", err))
 			return nil, fmt.Errorf("error getting page content: %w", err)
 		}
+		if len(content) > loadedSize {
+			stats.RecordBytes(len(content) - loadedSize)
+			loadedSize = len(content)
+		}
 
 		// Parse the page content with goquery
 		doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
 		if err != nil {
-			c.logger.Error("error parsing page content: ", err)
+			c.logger.Error("error parsing page content", slog.Any("error", err))
 			return nil, fmt.Errorf("error parsing page content: %w", err)
 		}
 
@@ -404,7 +480,7 @@ func (c *DivarCrawler) autoScroll(page playwright.Page) ([]string, error) {
             return false;
         }`)
 		if err != nil {
-			c.logger.Error("error clicking load more button: ", err)
+			c.logger.Error("error clicking load more button", slog.Any("error", err))
 			return nil, fmt.Errorf("error clicking load more button: %w", err)
 		}
 
@@ -431,6 +507,10 @@ func (c *DivarCrawler) extractPostLinksFromSelection(doc *goquery.Document) []st
 }
 
 func (c *DivarCrawler) extractPostDetails(doc *goquery.Document, post *crawlerModels.Post) {
+	if latitude, longitude, ok := crawlers.ParseCoordinates(doc); ok {
+		post.Latitude, post.Longitude = latitude, longitude
+	}
+
 	isRental := doc.Find("div.kt-base-row:contains('ودیعه')").Length() > 0 ||
 		doc.Find("div.kt-base-row:contains('اجاره')").Length() > 0
 
@@ -494,6 +574,17 @@ func (c *DivarCrawler) extractPostDetails(doc *goquery.Document, post *crawlerMo
 		})
 	}
 
+	// Extract the seller type, shown for agencies and some owners
+	doc.Find("div.kt-base-row").Each(func(i int, s *goquery.Selection) {
+		switch strings.TrimSpace(s.Find("p.kt-unexpandable-row__title").Text()) {
+		case "آگهی‌دهنده", "نوع آگهی‌دهنده":
+			post.SellerType = strings.TrimSpace(s.Find("p.kt-unexpandable-row__value").Text())
+		}
+	})
+	if post.SellerType == "" && doc.Find("a[href*='/real-estate-agency/']").Length() > 0 {
+		post.SellerType = "مشاور املاک"
+	}
+
 	// Extract area, year built, rooms
 	doc.Find("thead + tbody tr.kt-group-row__data-row").Each(func(i int, s *goquery.Selection) {
 		columns := s.Find("td.kt-group-row-item__value.kt-group-row-item--info-row")
@@ -528,6 +619,13 @@ func (c *DivarCrawler) extractPostDetails(doc *goquery.Document, post *crawlerMo
 		post.Neighborhood = strings.TrimSpace(subTitle[1])
 	}
 
+	// Extract the category breadcrumbs, e.g. "املاک › فروش مسکونی › آپارتمان"
+	var categories []string
+	doc.Find("nav.kt-breadcrumbs a").Each(func(i int, s *goquery.Selection) {
+		categories = append(categories, strings.TrimSpace(s.Text()))
+	})
+	post.Category = strings.Join(categories, " ")
+
 	// Extract images
 	var images []string
 	doc.Find("div.kt-base-carousel__slide img.kt-image-block__image").Each(func(i int, s *goquery.Selection) {
@@ -0,0 +1,420 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"github.com/playwright-community/playwright-go"
+)
+
+// ErrNoProxy is returned while every proxy of the pool is retired, requests then wait instead of going out
+// from the server's own address
+var ErrNoProxy = errors.New("every proxy is retired")
+
+const (
+	defaultLocale             = "fa-IR"
+	defaultTimezone           = "Asia/Tehran"
+	defaultAcceptLanguage     = "fa-IR,fa;q=0.9,en-US;q=0.6,en;q=0.4"
+	defaultProxyMaxFailures   = 3
+	defaultProxyCheckURL      = "https://divar.ir"
+	defaultProxyCheckInterval = 10 * time.Minute
+	defaultProxyCooldown      = 30 * time.Minute
+	defaultHTTPTimeout        = 30 * time.Second
+)
+
+// Viewport is the browser window size that goes with a user agent
+type Viewport struct {
+	Width    int
+	Height   int
+	IsMobile bool
+}
+
+type browserIdentity struct {
+	userAgent string
+	viewport  Viewport
+}
+
+var (
+	desktopViewport = Viewport{Width: 1366, Height: 768}
+	wideViewport    = Viewport{Width: 1920, Height: 1080}
+	phoneViewport   = Viewport{Width: 412, Height: 915, IsMobile: true}
+	iPhoneViewport  = Viewport{Width: 390, Height: 844, IsMobile: true}
+	tabletViewport  = Viewport{Width: 820, Height: 1180, IsMobile: true}
+)
+
+// browserIdentities pairs every user agent with a viewport a real device of that kind would report
+var browserIdentities = []browserIdentity{
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/85.0.4183.121 Safari/537.36", desktopViewport},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36", wideViewport},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0", desktopViewport},
+	{"Mozilla/5.0 (Linux; Android 10; SM-G975F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Mobile Safari/537.36", phoneViewport},
+	{"Mozilla/5.0 (iPhone; CPU iPhone OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A372 Safari/604.1", iPhoneViewport},
+	{"Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Mobile Safari/537.36", phoneViewport},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:92.0) Gecko/20100101 Firefox/92.0", wideViewport},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Safari/605.1.15", wideViewport},
+	{"Mozilla/5.0 (Windows NT 6.1; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/56.0.2924.87 Safari/537.36", desktopViewport},
+	{"Mozilla/5.0 (Linux; Android 9; SM-G960F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/76.0.3809.89 Mobile Safari/537.36", phoneViewport},
+	{"Mozilla/5.0 (iPad; CPU OS 14_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15A5341f Safari/604.1", tabletViewport},
+	{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.96 Safari/537.36", wideViewport},
+	{"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; AS; rv:11.0) like Gecko", desktopViewport},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.11; rv:42.0) Gecko/20100101 Firefox/42.0", desktopViewport},
+	{"Mozilla/5.0 (Linux; Android 10; SM-A505F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/88.0.4324.96 Mobile Safari/537.36", phoneViewport},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; Trident/7.0; AS; rv:11.0) like Gecko", desktopViewport},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36", wideViewport},
+	{"Mozilla/5.0 (Windows NT 10.0; WOW64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36", desktopViewport},
+	{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0", wideViewport},
+	{"Mozilla/5.0 (Linux; U; Android 9; en-US; SM-G960U Build/PPR1.180610.011) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/76.0.3809.89 Mobile Safari/537.36", phoneViewport},
+}
+
+// Profile is a consistent browser identity used for a whole page visit or HTTP request
+type Profile struct {
+	UserAgent  string
+	Viewport   Viewport
+	Locale     string
+	TimezoneID string
+	ProxyURL   string // empty when the request goes out directly
+}
+
+// Headers returns the extra headers a browser with this profile would send
+func (p Profile) Headers() map[string]string {
+	return map[string]string{
+		"Accept-Language": defaultAcceptLanguage,
+		"Referer":         "https://google.com",
+	}
+}
+
+// ContextOptions returns Playwright browser context options matching the profile
+func (p Profile) ContextOptions() playwright.BrowserNewContextOptions {
+	options := playwright.BrowserNewContextOptions{
+		UserAgent:        playwright.String(p.UserAgent),
+		Viewport:         &playwright.Size{Width: p.Viewport.Width, Height: p.Viewport.Height},
+		IsMobile:         playwright.Bool(p.Viewport.IsMobile),
+		HasTouch:         playwright.Bool(p.Viewport.IsMobile),
+		Locale:           playwright.String(p.Locale),
+		TimezoneId:       playwright.String(p.TimezoneID),
+		ExtraHttpHeaders: p.Headers(),
+	}
+	if proxy := p.playwrightProxy(); proxy != nil {
+		options.Proxy = proxy
+	}
+	return options
+}
+
+// NewContext opens a browser context configured with the profile
+func (p Profile) NewContext(browser playwright.Browser) (playwright.BrowserContext, error) {
+	return browser.NewContext(p.ContextOptions())
+}
+
+// HTTPClient returns a plain HTTP client that goes through the profile's proxy
+func (p Profile) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL, err := url.Parse(p.ProxyURL); err == nil && p.ProxyURL != "" {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport, Timeout: defaultHTTPTimeout}
+}
+
+// NewRequest builds an HTTP request carrying the profile's user agent and headers
+func (p Profile) NewRequest(ctx context.Context, method, rawURL string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", p.UserAgent)
+	for key, value := range p.Headers() {
+		request.Header.Set(key, value)
+	}
+	return request, nil
+}
+
+func (p Profile) playwrightProxy() *playwright.Proxy {
+	if p.ProxyURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(p.ProxyURL)
+	if err != nil {
+		return nil
+	}
+	proxy := &playwright.Proxy{Server: fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host)}
+	if parsed.User != nil {
+		proxy.Username = playwright.String(parsed.User.Username())
+		if password, ok := parsed.User.Password(); ok {
+			proxy.Password = playwright.String(password)
+		}
+	}
+	return proxy
+}
+
+// ProxyState is a point-in-time view of a proxy in the pool
+type ProxyState struct {
+	URL          string
+	Failures     int
+	Retired      bool
+	RetiredUntil time.Time // when a retired proxy is probed again
+	LastChecked  time.Time
+}
+
+type proxyEntry struct {
+	url          string
+	failures     int
+	retired      bool
+	retiredUntil time.Time
+	lastChecked  time.Time
+}
+
+// IdentityProvider hands out browser profiles and manages the proxy pool.
+// Retired proxies are probed again by the health checks after a cool-down and rejoin the rotation when they answer.
+type IdentityProvider struct {
+	maxFailures int
+	cooldown    time.Duration
+	logger      *slog.Logger
+
+	mu        sync.Mutex
+	proxies   []*proxyEntry
+	nextProxy int
+}
+
+// NewIdentityProvider creates a provider over the given proxy URLs. An empty pool means direct connections.
+func NewIdentityProvider(proxyURLs []string, maxFailures int, cooldown time.Duration) *IdentityProvider {
+	if maxFailures <= 0 {
+		maxFailures = defaultProxyMaxFailures
+	}
+	if cooldown <= 0 {
+		cooldown = defaultProxyCooldown
+	}
+	provider := &IdentityProvider{maxFailures: maxFailures, cooldown: cooldown, logger: utils.NewLogger("Identity_Provider")}
+	for _, proxyURL := range proxyURLs {
+		proxyURL = strings.TrimSpace(proxyURL)
+		if proxyURL != "" {
+			provider.proxies = append(provider.proxies, &proxyEntry{url: proxyURL})
+		}
+	}
+	return provider
+}
+
+// Next returns a new profile with a random device identity and the next healthy proxy, or ErrNoProxy when
+// the pool has proxies but all of them are retired
+func (ip *IdentityProvider) Next() (Profile, error) {
+	proxyURL, err := ip.pickProxy()
+	if err != nil {
+		return Profile{}, err
+	}
+	return newProfile(proxyURL), nil
+}
+
+func newProfile(proxyURL string) Profile {
+	identity := browserIdentities[rand.Intn(len(browserIdentities))]
+	return Profile{
+		UserAgent:  identity.userAgent,
+		Viewport:   identity.viewport,
+		Locale:     defaultLocale,
+		TimezoneID: defaultTimezone,
+		ProxyURL:   proxyURL,
+	}
+}
+
+// ReportSuccess resets the failure count of the profile's proxy and puts a retired proxy back into rotation
+func (ip *IdentityProvider) ReportSuccess(profile Profile) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	entry := ip.find(profile.ProxyURL)
+	if entry == nil {
+		return
+	}
+	entry.failures = 0
+	if entry.retired {
+		entry.retired = false
+		entry.retiredUntil = time.Time{}
+		ip.logger.Info("proxy back in rotation", slog.String("proxy", redactProxy(entry.url)))
+	}
+}
+
+// ReportFailure counts a failed request against the profile's proxy and retires it after too many in a row.
+// Retiring the last proxy in rotation is reported as an error, crawls wait until the health checks readmit one.
+func (ip *IdentityProvider) ReportFailure(profile Profile) {
+	if ip.retire(profile.ProxyURL) {
+		ip.logger.Error("every proxy is retired, requests wait for one to recover", slog.Int("proxies", len(ip.proxies)))
+		tracking.Capture(models.CRAWLER_ERROR, ErrNoProxy, tracking.Origin{})
+	}
+}
+
+// retire counts a failure of a proxy, it returns true when the failure retired the last proxy in rotation
+func (ip *IdentityProvider) retire(proxyURL string) bool {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	entry := ip.find(proxyURL)
+	if entry == nil {
+		return false
+	}
+	if entry.retired {
+		// a failed probe after the cool-down starts a new one
+		entry.retiredUntil = time.Now().Add(ip.cooldown)
+		return false
+	}
+	entry.failures++
+	if entry.failures < ip.maxFailures {
+		return false
+	}
+	entry.retired = true
+	entry.retiredUntil = time.Now().Add(ip.cooldown)
+	ip.logger.Warn("proxy retired after repeated failures", slog.String("proxy", redactProxy(entry.url)),
+		slog.Int("failures", entry.failures), slog.Time("until", entry.retiredUntil))
+	for _, other := range ip.proxies {
+		if !other.retired {
+			return false
+		}
+	}
+	return true
+}
+
+// HealthCheck requests checkURL through every proxy in rotation and every retired proxy whose cool-down is over
+func (ip *IdentityProvider) HealthCheck(ctx context.Context, checkURL string) {
+	now := time.Now()
+	ip.mu.Lock()
+	var candidates []string
+	for _, entry := range ip.proxies {
+		if !entry.retired || !now.Before(entry.retiredUntil) {
+			candidates = append(candidates, entry.url)
+		}
+	}
+	ip.mu.Unlock()
+
+	for _, proxyURL := range candidates {
+		profile := newProfile(proxyURL)
+
+		ok := checkProxy(ctx, profile, checkURL)
+
+		ip.mu.Lock()
+		if entry := ip.find(proxyURL); entry != nil {
+			entry.lastChecked = time.Now()
+		}
+		ip.mu.Unlock()
+
+		if ok {
+			ip.ReportSuccess(profile)
+		} else {
+			ip.ReportFailure(profile)
+		}
+	}
+}
+
+// StartHealthChecks runs HealthCheck periodically until ctx is canceled
+func (ip *IdentityProvider) StartHealthChecks(ctx context.Context, checkURL string, interval time.Duration) {
+	if len(ip.proxies) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultProxyCheckInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ip.HealthCheck(ctx, checkURL)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Proxies returns the state of the proxy pool with credentials removed
+func (ip *IdentityProvider) Proxies() []ProxyState {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	states := make([]ProxyState, 0, len(ip.proxies))
+	for _, entry := range ip.proxies {
+		states = append(states, ProxyState{
+			URL:          redactProxy(entry.url),
+			Failures:     entry.failures,
+			Retired:      entry.retired,
+			RetiredUntil: entry.retiredUntil,
+			LastChecked:  entry.lastChecked,
+		})
+	}
+	return states
+}
+
+// pickProxy returns the next proxy in rotation, "" for direct connections when there is no pool
+func (ip *IdentityProvider) pickProxy() (string, error) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	if len(ip.proxies) == 0 {
+		return "", nil
+	}
+	for i := 0; i < len(ip.proxies); i++ {
+		entry := ip.proxies[ip.nextProxy%len(ip.proxies)]
+		ip.nextProxy++
+		if !entry.retired {
+			return entry.url, nil
+		}
+	}
+	return "", ErrNoProxy
+}
+
+func (ip *IdentityProvider) find(proxyURL string) *proxyEntry {
+	if proxyURL == "" {
+		return nil
+	}
+	for _, entry := range ip.proxies {
+		if entry.url == proxyURL {
+			return entry
+		}
+	}
+	return nil
+}
+
+func checkProxy(ctx context.Context, profile Profile, checkURL string) bool {
+	request, err := profile.NewRequest(ctx, http.MethodHead, checkURL)
+	if err != nil {
+		return false
+	}
+	response, err := profile.HTTPClient().Do(request)
+	if err != nil {
+		return false
+	}
+	defer response.Body.Close()
+	return response.StatusCode < http.StatusBadRequest
+}
+
+func redactProxy(proxyURL string) string {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil || parsed.User == nil {
+		return proxyURL
+	}
+	return parsed.Redacted()
+}
+
+var identityProviderProducer func() *IdentityProvider = sync.OnceValue(func() *IdentityProvider {
+	var proxies []string
+	if value := utils.GetConfig("CRAWLER_PROXIES"); value != "" {
+		proxies = strings.Split(value, ",")
+	}
+	provider := NewIdentityProvider(proxies, intConfig("CRAWLER_PROXY_MAX_FAILURES", defaultProxyMaxFailures),
+		secondsConfig("CRAWLER_PROXY_COOLDOWN", defaultProxyCooldown))
+
+	checkURL := utils.GetConfig("CRAWLER_PROXY_CHECK_URL")
+	if checkURL == "" {
+		checkURL = defaultProxyCheckURL
+	}
+	provider.StartHealthChecks(context.Background(), checkURL, secondsConfig("CRAWLER_PROXY_CHECK_INTERVAL", defaultProxyCheckInterval))
+	return provider
+})
+
+// Identities returns the process-wide identity provider shared by all crawlers
+func Identities() *IdentityProvider {
+	return identityProviderProducer()
+}
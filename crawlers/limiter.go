@@ -0,0 +1,357 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	defaultRequestsPerSecond = 1.0
+	defaultBurst             = 3
+	defaultMaxConcurrency    = 3
+	defaultJitterMin         = 3 * time.Second
+	defaultJitterMax         = 10 * time.Second
+	defaultBackoffBase       = 5 * time.Second
+	defaultBackoffMax        = 2 * time.Minute
+	defaultBreakerThreshold  = 5
+	defaultBreakerCooldown   = 10 * time.Minute
+)
+
+// ErrCircuitOpen is returned when a host is paused after repeated blocks
+var ErrCircuitOpen = errors.New("circuit breaker is open for host")
+
+// PolitenessConfig holds the limits applied to every host we crawl
+type PolitenessConfig struct {
+	RequestsPerSecond float64
+	Burst             int
+	MaxConcurrency    int
+	JitterMin         time.Duration
+	JitterMax         time.Duration
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+	BreakerThreshold  int
+	BreakerCooldown   time.Duration
+}
+
+// LoadPolitenessConfig reads the politeness policy from environment variables
+func LoadPolitenessConfig() PolitenessConfig {
+	return PolitenessConfig{
+		RequestsPerSecond: floatConfig("CRAWLER_REQUESTS_PER_SECOND", defaultRequestsPerSecond),
+		Burst:             intConfig("CRAWLER_BURST", defaultBurst),
+		MaxConcurrency:    intConfig("CRAWLER_MAX_CONCURRENCY", defaultMaxConcurrency),
+		JitterMin:         secondsConfig("CRAWLER_JITTER_MIN", defaultJitterMin),
+		JitterMax:         secondsConfig("CRAWLER_JITTER_MAX", defaultJitterMax),
+		BackoffBase:       secondsConfig("CRAWLER_RETRY_DELAY", defaultBackoffBase),
+		BackoffMax:        secondsConfig("CRAWLER_BACKOFF_MAX", defaultBackoffMax),
+		BreakerThreshold:  intConfig("CRAWLER_BREAKER_THRESHOLD", defaultBreakerThreshold),
+		BreakerCooldown:   secondsConfig("CRAWLER_BREAKER_COOLDOWN", defaultBreakerCooldown),
+	}
+}
+
+// HostLimiterState is a point-in-time view of a HostLimiter, used by the admin Monitor view
+type HostLimiterState struct {
+	Host              string
+	Tokens            float64
+	InFlight          int
+	Requests          uint64
+	Throttled         uint64
+	ConsecutiveBlocks int
+	PausedUntil       time.Time
+}
+
+// Paused reports whether the circuit breaker was open when the snapshot was taken
+func (s HostLimiterState) Paused() bool {
+	return time.Now().Before(s.PausedUntil)
+}
+
+// HostLimiter is a token-bucket limiter with a concurrency cap and a circuit breaker for one host
+type HostLimiter struct {
+	host   string
+	config PolitenessConfig
+	slots  chan struct{}
+
+	mu                sync.Mutex
+	tokens            float64
+	lastRefill        time.Time
+	inFlight          int
+	requests          uint64
+	throttled         uint64
+	consecutiveBlocks int
+	pausedUntil       time.Time
+}
+
+// NewHostLimiter creates a limiter for a single host
+func NewHostLimiter(host string, config PolitenessConfig) *HostLimiter {
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = 1
+	}
+	return &HostLimiter{
+		host:       host,
+		config:     config,
+		slots:      make(chan struct{}, config.MaxConcurrency),
+		tokens:     float64(config.Burst),
+		lastRefill: time.Now(),
+	}
+}
+
+// Acquire blocks until a concurrency slot and a token are available for the host.
+// The returned function must be called to release the concurrency slot.
+func (l *HostLimiter) Acquire(ctx context.Context) (func(), error) {
+	if err := l.checkBreaker(); err != nil {
+		return nil, err
+	}
+
+	if err := sleepContext(ctx, l.jitter()); err != nil {
+		return nil, err
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		wait, err := l.takeToken()
+		if err != nil {
+			<-l.slots
+			return nil, err
+		}
+		if wait == 0 {
+			break
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			<-l.slots
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	l.inFlight++
+	l.requests++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight--
+			l.mu.Unlock()
+			<-l.slots
+		})
+	}, nil
+}
+
+// Report records the outcome of a request. 429 and 5xx responses count as blocks
+// and open the circuit breaker once BreakerThreshold is reached in a row.
+func (l *HostLimiter) Report(statusCode int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !IsBlockedStatus(statusCode) {
+		l.consecutiveBlocks = 0
+		return
+	}
+
+	l.throttled++
+	l.consecutiveBlocks++
+	if l.config.BreakerThreshold > 0 && l.consecutiveBlocks >= l.config.BreakerThreshold {
+		l.pausedUntil = time.Now().Add(l.config.BreakerCooldown)
+		l.consecutiveBlocks = 0
+	}
+}
+
+// Backoff returns the exponential delay to wait before the given retry attempt (starting at 1)
+func (l *HostLimiter) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(l.config.BackoffBase) * math.Pow(2, float64(attempt-1))
+	if l.config.BackoffMax > 0 && delay > float64(l.config.BackoffMax) {
+		delay = float64(l.config.BackoffMax)
+	}
+	// up to 20% jitter so retries from different goroutines do not line up
+	delay += rand.Float64() * delay * 0.2
+	return time.Duration(delay)
+}
+
+// WaitBackoff waits the backoff of a retry attempt, it returns early with the error of ctx when it is canceled
+func (l *HostLimiter) WaitBackoff(ctx context.Context, attempt int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(l.Backoff(attempt)):
+		return nil
+	}
+}
+
+// Snapshot returns the current state of the limiter
+func (l *HostLimiter) Snapshot() HostLimiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	return HostLimiterState{
+		Host:              l.host,
+		Tokens:            l.tokens,
+		InFlight:          l.inFlight,
+		Requests:          l.requests,
+		Throttled:         l.throttled,
+		ConsecutiveBlocks: l.consecutiveBlocks,
+		PausedUntil:       l.pausedUntil,
+	}
+}
+
+func (l *HostLimiter) checkBreaker() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().Before(l.pausedUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// takeToken consumes a token if one is available, otherwise returns how long to wait for the next one
+func (l *HostLimiter) takeToken() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Now().Before(l.pausedUntil) {
+		return 0, ErrCircuitOpen
+	}
+
+	l.refill()
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, nil
+	}
+	if l.config.RequestsPerSecond <= 0 {
+		return time.Second, nil
+	}
+	missing := 1 - l.tokens
+	return time.Duration(missing / l.config.RequestsPerSecond * float64(time.Second)), nil
+}
+
+func (l *HostLimiter) refill() {
+	now := time.Now()
+	elapsed := now.Sub(l.lastRefill).Seconds()
+	l.lastRefill = now
+	l.tokens = math.Min(float64(l.config.Burst), l.tokens+elapsed*l.config.RequestsPerSecond)
+}
+
+func (l *HostLimiter) jitter() time.Duration {
+	if l.config.JitterMax <= l.config.JitterMin {
+		return l.config.JitterMin
+	}
+	return l.config.JitterMin + time.Duration(rand.Int63n(int64(l.config.JitterMax-l.config.JitterMin)))
+}
+
+// LimiterRegistry hands out one HostLimiter per host so all crawlers share the same budget
+type LimiterRegistry struct {
+	config   PolitenessConfig
+	mu       sync.Mutex
+	limiters map[string]*HostLimiter
+}
+
+// NewLimiterRegistry creates an empty registry using the given policy for every host
+func NewLimiterRegistry(config PolitenessConfig) *LimiterRegistry {
+	return &LimiterRegistry{config: config, limiters: make(map[string]*HostLimiter)}
+}
+
+// For returns the limiter responsible for the host of rawURL
+func (r *LimiterRegistry) For(rawURL string) *HostLimiter {
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	limiter, exists := r.limiters[host]
+	if !exists {
+		limiter = NewHostLimiter(host, r.config)
+		r.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// Snapshot returns the state of every known host, sorted by host name
+func (r *LimiterRegistry) Snapshot() []HostLimiterState {
+	r.mu.Lock()
+	limiters := make([]*HostLimiter, 0, len(r.limiters))
+	for _, limiter := range r.limiters {
+		limiters = append(limiters, limiter)
+	}
+	r.mu.Unlock()
+
+	states := make([]HostLimiterState, 0, len(limiters))
+	for _, limiter := range limiters {
+		states = append(states, limiter.Snapshot())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
+
+var limiterRegistryProducer func() *LimiterRegistry = sync.OnceValue(func() *LimiterRegistry {
+	return NewLimiterRegistry(LoadPolitenessConfig())
+})
+
+// Limiters returns the process-wide limiter registry shared by all crawlers
+func Limiters() *LimiterRegistry {
+	return limiterRegistryProducer()
+}
+
+// IsBlockedStatus reports whether a response status means the host is throttling or failing
+func IsBlockedStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func intConfig(key string, defaultValue int) int {
+	value, err := strconv.Atoi(utils.GetConfig(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func floatConfig(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(utils.GetConfig(key), 64)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func secondsConfig(key string, defaultValue time.Duration) time.Duration {
+	value, err := strconv.ParseFloat(utils.GetConfig(key), 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return time.Duration(value * float64(time.Second))
+}
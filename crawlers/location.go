@@ -0,0 +1,95 @@
+package crawlers
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// coordinateParams are the query parameters map links and static map images put "lat,lng" in
+var coordinateParams = []string{"q", "ll", "center", "destination", "query", "markers"}
+
+var (
+	coordinatePair = regexp.MustCompile(`(-?\d{1,2}\.\d+)\s*[,;]\s*(-?\d{1,3}\.\d+)`)
+	latitudeJSON   = regexp.MustCompile(`"(?:latitude|lat)"\s*:\s*"?(-?\d{1,2}\.\d+)`)
+	longitudeJSON  = regexp.MustCompile(`"(?:longitude|lng|lon)"\s*:\s*"?(-?\d{1,3}\.\d+)`)
+)
+
+// ParseCoordinates finds the map location of a post page in its meta tags, map links and images, or
+// structured data. ok is false when the page has no map, owners may hide the location of their listing.
+func ParseCoordinates(doc *goquery.Document) (latitude float64, longitude float64, ok bool) {
+	metaLatitude, _ := doc.Find(`meta[property="place:location:latitude"]`).Attr("content")
+	metaLongitude, _ := doc.Find(`meta[property="place:location:longitude"]`).Attr("content")
+	if latitude, longitude, ok = parsePair(metaLatitude, metaLongitude); ok {
+		return
+	}
+	if position, exists := doc.Find(`meta[name="geo.position"], meta[name="ICBM"]`).Attr("content"); exists {
+		if latitude, longitude, ok = findPair(position); ok {
+			return
+		}
+	}
+
+	doc.Find("a[href], img[src]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		link, exists := s.Attr("href")
+		if !exists {
+			link, _ = s.Attr("src")
+		}
+		latitude, longitude, ok = linkCoordinates(link)
+		return !ok
+	})
+	if ok {
+		return
+	}
+
+	doc.Find(`script[type="application/ld+json"], script#__NEXT_DATA__`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		text := s.Text()
+		latitudes, longitudes := latitudeJSON.FindStringSubmatch(text), longitudeJSON.FindStringSubmatch(text)
+		if latitudes != nil && longitudes != nil {
+			latitude, longitude, ok = parsePair(latitudes[1], longitudes[1])
+		}
+		return !ok
+	})
+	return
+}
+
+func linkCoordinates(link string) (float64, float64, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil || !strings.Contains(strings.ToLower(parsed.Host+parsed.Path), "map") {
+		return 0, 0, false
+	}
+	query := parsed.Query()
+	for _, param := range coordinateParams {
+		if latitude, longitude, ok := findPair(query.Get(param)); ok {
+			return latitude, longitude, true
+		}
+	}
+	// google maps puts the location in the path, like /maps/@35.7,51.4,15z
+	return findPair(parsed.Path)
+}
+
+func findPair(text string) (float64, float64, bool) {
+	match := coordinatePair.FindStringSubmatch(text)
+	if match == nil {
+		return 0, 0, false
+	}
+	return parsePair(match[1], match[2])
+}
+
+// parsePair rejects invalid coordinates and 0,0, which maps use for a missing location
+func parsePair(latitudeText string, longitudeText string) (float64, float64, bool) {
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(latitudeText), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(longitudeText), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 || (latitude == 0 && longitude == 0) {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
@@ -0,0 +1,65 @@
+package crawlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// ErrPostRemoved is returned when the website says a post was deleted, by its owner or by moderators
+	ErrPostRemoved = errors.New("post removed")
+	// ErrPostExpired is returned when the website says a post reached the end of its publication period
+	ErrPostExpired = errors.New("post expired")
+)
+
+// expiredPhrases are checked before removedPhrases, the expiry notices of the websites also say the post is not shown
+var (
+	expiredPhrases = []string{"منقضی شده", "مهلت نمایش این آگهی", "زمان نمایش این آگهی به پایان رسیده"}
+	removedPhrases = []string{
+		"این آگهی حذف شده", "آگهی حذف شده است", "این آگهی دیگر در دسترس نیست", "آگهی مورد نظر یافت نشد",
+		"آگهی مورد نظر شما پیدا نشد", "این آگهی توسط کاربر حذف شده",
+	}
+)
+
+// DetectRemoval tells from the status and the text of a post page whether the post is gone:
+// ErrPostExpired or ErrPostRemoved, or nil for a live post
+func DetectRemoval(statusCode int, text string) error {
+	if statusCode == http.StatusNotFound || statusCode == http.StatusGone {
+		return ErrPostRemoved
+	}
+	text = persian.Normalize(text)
+	for _, phrase := range expiredPhrases {
+		if strings.Contains(text, persian.Normalize(phrase)) {
+			return ErrPostExpired
+		}
+	}
+	for _, phrase := range removedPhrases {
+		if strings.Contains(text, persian.Normalize(phrase)) {
+			return ErrPostRemoved
+		}
+	}
+	return nil
+}
+
+// PageText returns the visible text of a page, without scripts whose strings could contain the removal phrases
+func PageText(doc *goquery.Document) string {
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript").Remove()
+	return body.Text()
+}
+
+// DetectPageRemoval looks for a removal notice on a post page whose essential fields are missing. A removed
+// post shows the notice instead of its details, while a live post may quote the phrases in its description,
+// so a page with all the fields is never taken for removed.
+func DetectPageRemoval(doc *goquery.Document, fields ...string) error {
+	for _, field := range fields {
+		if field == "" {
+			return DetectRemoval(0, PageText(doc))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ErrorClass groups crawl failures so they can be counted per run
+type ErrorClass string
+
+const (
+	ErrorTimeout       ErrorClass = "timeout"
+	ErrorNavigation    ErrorClass = "navigation"
+	ErrorBlocked       ErrorClass = "blocked"
+	ErrorCircuitOpen   ErrorClass = "circuit_open"
+	ErrorBrowser       ErrorClass = "browser"
+	ErrorParse         ErrorClass = "parse"
+	ErrorMissingFields ErrorClass = "missing_fields"
+	ErrorCanceled      ErrorClass = "canceled"
+	ErrorNoProxy       ErrorClass = "no_proxy"
+)
+
+// CrawlStats collects request counters for a single crawl task (one source and one city)
+type CrawlStats struct {
+	mu           sync.Mutex
+	requests     uint
+	successes    uint
+	failures     uint
+	bytesFetched int64
+	errorCounts  map[ErrorClass]uint
+}
+
+// CrawlStatsSnapshot is a copy of the counters of a CrawlStats
+type CrawlStatsSnapshot struct {
+	Requests     uint
+	Successes    uint
+	Failures     uint
+	BytesFetched int64
+	ErrorCounts  map[ErrorClass]uint
+}
+
+// NewCrawlStats creates an empty stats collector
+func NewCrawlStats() *CrawlStats {
+	return &CrawlStats{errorCounts: make(map[ErrorClass]uint)}
+}
+
+// RecordRequest counts a page request sent to the host
+func (s *CrawlStats) RecordRequest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+}
+
+// RecordBytes adds the size of a fetched page
+func (s *CrawlStats) RecordBytes(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytesFetched += int64(size)
+}
+
+// RecordSuccess counts a page that was fetched and parsed
+func (s *CrawlStats) RecordSuccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.successes++
+}
+
+// RecordFailure counts a failed attempt under the given class
+func (s *CrawlStats) RecordFailure(class ErrorClass) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+	s.errorCounts[class]++
+}
+
+// Snapshot returns a copy of the counters
+func (s *CrawlStats) Snapshot() CrawlStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errorCounts := make(map[ErrorClass]uint, len(s.errorCounts))
+	for class, count := range s.errorCounts {
+		errorCounts[class] = count
+	}
+	return CrawlStatsSnapshot{
+		Requests:     s.requests,
+		Successes:    s.successes,
+		Failures:     s.failures,
+		BytesFetched: s.bytesFetched,
+		ErrorCounts:  errorCounts,
+	}
+}
+
+// ClassifyError maps a navigation error to its ErrorClass
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorCircuitOpen
+	case errors.Is(err, ErrNoProxy):
+		return ErrorNoProxy
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorCanceled
+	case errors.Is(err, playwright.ErrTimeout):
+		return ErrorTimeout
+	case errors.Is(err, ErrBlocked):
+		return ErrorBlocked
+	default:
+		return ErrorNavigation
+	}
+}
+
+// ErrBlocked is returned when the host answers with 429 or a 5xx status
+var ErrBlocked = errors.New("blocked by host")
+
+type statsContextKey struct{}
+
+// WithStats attaches a stats collector to ctx so crawlers can report into it
+func WithStats(ctx context.Context, stats *CrawlStats) context.Context {
+	return context.WithValue(ctx, statsContextKey{}, stats)
+}
+
+// StatsFromContext returns the collector attached to ctx, or a throwaway one when there is none
+func StatsFromContext(ctx context.Context) *CrawlStats {
+	if stats, ok := ctx.Value(statsContextKey{}).(*CrawlStats); ok && stats != nil {
+		return stats
+	}
+	return NewCrawlStats()
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AppSettingRepository interface {
+	Find(key models.SettingKey) (models.AppSetting, error)
+	Set(key models.SettingKey, value string, updatedBy uint) error
+}
+
+type AppSettingRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewAppSettingRepository(dbConnection *gorm.DB) AppSettingRepository {
+	return AppSettingRepositoryImpl{dbConnection: dbConnection}
+}
+
+// Find returns the setting of key, or a zero setting when it was never set
+func (repo AppSettingRepositoryImpl) Find(key models.SettingKey) (models.AppSetting, error) {
+	var setting models.AppSetting
+	err := repo.dbConnection.Where("key = ?", key).Limit(1).Find(&setting).Error
+	return setting, err
+}
+
+// Set creates or updates the setting of key
+func (repo AppSettingRepositoryImpl) Set(key models.SettingKey, value string, updatedBy uint) error {
+	setting := models.AppSetting{Key: key, Value: value, UpdatedBy: updatedBy}
+	return repo.dbConnection.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"value":      value,
+			"updated_by": updatedBy,
+			"updated_at": time.Now(),
+		}),
+	}).Create(&setting).Error
+}
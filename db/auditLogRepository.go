@@ -0,0 +1,31 @@
+package db
+
+import (
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository interface {
+	Create(entry models.AuditLog) (models.AuditLog, error)
+	FindLatest(limit int) ([]models.AuditLog, error)
+}
+
+type AuditLogRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewAuditLogRepository(dbConnection *gorm.DB) AuditLogRepository {
+	return AuditLogRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (r AuditLogRepositoryImpl) Create(entry models.AuditLog) (models.AuditLog, error) {
+	err := r.dbConnection.Omit("User").Create(&entry).Error
+	return entry, err
+}
+
+// FindLatest returns the latest entries with their users, latest first
+func (r AuditLogRepositoryImpl) FindLatest(limit int) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := r.dbConnection.Preload("User").Order("id DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
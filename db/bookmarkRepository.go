@@ -7,9 +7,18 @@ import (
 
 type BookmarkRepo interface {
 	Find(bookmark models.Bookmark) (models.Bookmark, error)
+	FindByID(ID uint) (models.Bookmark, error)
 	FindAll(UserID uint) ([]models.Bookmark, error)
+	FindByCrawl(crawlHistoryID uint) ([]models.Bookmark, error)
+	FindLatestHistories(postIDs []uint) ([]models.PostHistory, error)
 	Save(post models.Post, user models.User) error
+	Update(bookmark models.Bookmark) error
 	Delete(bookmark models.Bookmark) error
+
+	FindFolders(userID uint) ([]models.BookmarkFolder, error)
+	FindFolder(ID uint) (models.BookmarkFolder, error)
+	SaveFolder(folder models.BookmarkFolder) (models.BookmarkFolder, error)
+	DeleteFolder(folder models.BookmarkFolder) error
 }
 
 // Connection to database
@@ -24,22 +33,55 @@ func NewBookmarkRepository(dbConnection *gorm.DB) BookmarkRepo {
 
 // find a bookmark by an isntance of its model
 func (br BookmarkRepositoryImpl) Find(bookmark models.Bookmark) (models.Bookmark, error) {
-	if err := br.dbConnection.Find(&bookmark).Error; err != nil {
+	var found models.Bookmark
+	if err := br.dbConnection.Where(&bookmark).First(&found).Error; err != nil {
 		return models.Bookmark{}, err
 	}
-	return bookmark, nil
+	return found, nil
+}
+
+// find a bookmark by its id with its post and folder
+func (br BookmarkRepositoryImpl) FindByID(ID uint) (models.Bookmark, error) {
+	var bookmark models.Bookmark
+	err := br.dbConnection.Preload("Post").Preload("Folder").First(&bookmark, ID).Error
+	return bookmark, err
 }
 
-// find all bookmark of a user
+// find all bookmark of a user, the newest first
 func (br BookmarkRepositoryImpl) FindAll(userID uint) ([]models.Bookmark, error) {
 	bookmarks := []models.Bookmark{}
-	if err := br.dbConnection.Find(&bookmarks).Error; err != nil {
+	if err := br.dbConnection.Preload("Post").Preload("Folder").
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Find(&bookmarks).Error; err != nil {
 		return []models.Bookmark{}, err
 	}
 	return bookmarks, nil
 
 }
 
+// find the bookmarks, with their users, of the posts a crawl found again
+func (br BookmarkRepositoryImpl) FindByCrawl(crawlHistoryID uint) ([]models.Bookmark, error) {
+	bookmarks := []models.Bookmark{}
+	err := br.dbConnection.Preload("User").
+		Where("post_id IN (?)", br.dbConnection.Model(&models.PostHistory{}).Select("post_id").Where("crawl_history_id = ?", crawlHistoryID)).
+		Find(&bookmarks).Error
+	return bookmarks, err
+}
+
+// find the newest history of every post
+func (br BookmarkRepositoryImpl) FindLatestHistories(postIDs []uint) ([]models.PostHistory, error) {
+	histories := []models.PostHistory{}
+	if len(postIDs) == 0 {
+		return histories, nil
+	}
+	err := br.dbConnection.
+		Where("id IN (?)", br.dbConnection.Model(&models.PostHistory{}).Select("MAX(id)").Where("post_id IN ?", postIDs).Group("post_id")).
+		Find(&histories).Error
+	return histories, err
+}
+
+// save a bookmark of a post, bookmarking a post twice keeps the first bookmark
 func (br BookmarkRepositoryImpl) Save(post models.Post, user models.User) error {
 	bookmark := models.Bookmark{
 		Post:   post,
@@ -47,9 +89,56 @@ func (br BookmarkRepositoryImpl) Save(post models.Post, user models.User) error
 		User:   user,
 		UserID: user.ID,
 	}
-	return br.dbConnection.Create(&bookmark).Error
+	return br.dbConnection.Omit("Post", "User").
+		Where(models.Bookmark{PostID: post.ID, UserID: user.ID}).
+		FirstOrCreate(&bookmark).Error
+}
+
+// update the note, folder and notified prices of a bookmark
+func (br BookmarkRepositoryImpl) Update(bookmark models.Bookmark) error {
+	return br.dbConnection.Model(&models.Bookmark{}).Where("id = ?", bookmark.ID).
+		Select("note", "folder_id", "price", "deposit", "rent").
+		Updates(map[string]interface{}{
+			"note": bookmark.Note, "folder_id": bookmark.FolderID,
+			"price": bookmark.Price, "deposit": bookmark.Deposit, "rent": bookmark.Rent,
+		}).Error
 }
 
 func (br BookmarkRepositoryImpl) Delete(bookmark models.Bookmark) error {
 	return br.dbConnection.Where("post_id = ? AND user_id = ?", bookmark.PostID, bookmark.UserID).Delete(&models.Bookmark{}).Error
 }
+
+// find the folders of a user by name
+func (br BookmarkRepositoryImpl) FindFolders(userID uint) ([]models.BookmarkFolder, error) {
+	folders := []models.BookmarkFolder{}
+	err := br.dbConnection.Where("user_id = ?", userID).Order("name").Find(&folders).Error
+	return folders, err
+}
+
+// find a folder with its owner
+func (br BookmarkRepositoryImpl) FindFolder(ID uint) (models.BookmarkFolder, error) {
+	var folder models.BookmarkFolder
+	err := br.dbConnection.Preload("User").First(&folder, ID).Error
+	return folder, err
+}
+
+func (br BookmarkRepositoryImpl) SaveFolder(folder models.BookmarkFolder) (models.BookmarkFolder, error) {
+	err := br.dbConnection.Create(&folder).Error
+	return folder, err
+}
+
+// delete a folder for good, so its name can be used again, with its members and invites,
+// its bookmarks stay without a folder
+func (br BookmarkRepositoryImpl) DeleteFolder(folder models.BookmarkFolder) error {
+	return br.dbConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Bookmark{}).Where("folder_id = ?", folder.ID).Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.FolderMember{}, &models.FolderInvite{}} {
+			if err := tx.Unscoped().Where("folder_id = ?", folder.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&models.BookmarkFolder{}, folder.ID).Error
+	})
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+type CrawlRunRepository interface {
+	Save(crawlRun models.CrawlRun) (models.CrawlRun, error)
+	FindSince(since time.Time) ([]models.CrawlRun, error)
+	DailyTrends(since time.Time, location *time.Location) ([]models.CrawlTrend, error)
+}
+
+type CrawlRunRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewCrawlRunRepository(dbConnection *gorm.DB) CrawlRunRepository {
+	return CrawlRunRepositoryImpl{dbConnection: dbConnection}
+}
+
+// Save stores the telemetry of a single crawl run
+func (repo CrawlRunRepositoryImpl) Save(crawlRun models.CrawlRun) (models.CrawlRun, error) {
+	err := repo.dbConnection.Create(&crawlRun).Error
+	return crawlRun, err
+}
+
+// FindSince returns all runs started after since, oldest first
+func (repo CrawlRunRepositoryImpl) FindSince(since time.Time) ([]models.CrawlRun, error) {
+	var crawlRuns []models.CrawlRun
+	err := repo.dbConnection.Where("started_at >= ?", since).Order("started_at").Find(&crawlRuns).Error
+	return crawlRuns, err
+}
+
+// DailyTrends groups the runs started after since by day (in location) and source
+func (repo CrawlRunRepositoryImpl) DailyTrends(since time.Time, location *time.Location) ([]models.CrawlTrend, error) {
+	crawlRuns, err := repo.FindSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	type trendKey struct {
+		day    time.Time
+		source types.WebsiteSource
+	}
+	trends := make(map[trendKey]*models.CrawlTrend)
+	var totalDuration = make(map[trendKey]int64)
+
+	for _, run := range crawlRuns {
+		started := run.StartedAt.In(location)
+		key := trendKey{
+			day:    time.Date(started.Year(), started.Month(), started.Day(), 0, 0, 0, 0, location),
+			source: run.Source,
+		}
+		trend, exists := trends[key]
+		if !exists {
+			trend = &models.CrawlTrend{Day: key.day, Source: key.source, ErrorCounts: make(map[string]uint)}
+			trends[key] = trend
+		}
+
+		trend.Runs++
+		trend.RequestsNum += run.RequestsNum
+		trend.FailureNum += run.FailureNum
+		trend.PostNum += run.PostNum
+		trend.NewPostNum += run.NewPostNum
+		trend.UpdatedPostNum += run.UpdatedPostNum
+		trend.BytesFetched += run.BytesFetched
+		if run.ProcessRSS > trend.PeakRSS {
+			trend.PeakRSS = run.ProcessRSS
+		}
+		totalDuration[key] += run.DurationMs
+
+		var errorCounts map[string]uint
+		if run.ErrorCounts != "" && json.Unmarshal([]byte(run.ErrorCounts), &errorCounts) == nil {
+			for class, count := range errorCounts {
+				trend.ErrorCounts[class] += count
+			}
+		}
+	}
+
+	result := make([]models.CrawlTrend, 0, len(trends))
+	for key, trend := range trends {
+		trend.AvgDurationMs = totalDuration[key] / int64(trend.Runs)
+		result = append(result, *trend)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Day.Equal(result[j].Day) {
+			return result[i].Day.Before(result[j].Day)
+		}
+		return result[i].Source < result[j].Source
+	})
+	return result, nil
+}
@@ -34,18 +34,35 @@ func NewConnection() *gorm.DB {
 	}
 
 	datab.AutoMigrate(&models.User{}, &models.WatchList{}, &models.FilterItem{})
-	datab.AutoMigrate(&models.Post{}, &models.PostHistory{}, &models.Bookmark{})
+	datab.AutoMigrate(&models.Post{}, &models.PostHistory{}, &models.BookmarkFolder{}, &models.Bookmark{},
+		&models.FolderMember{}, &models.FolderInvite{}, &models.BookmarkVote{}, &models.BookmarkComment{})
+	datab.AutoMigrate(&models.ErrorEvent{}, &models.UsageCounter{})
+	datab.AutoMigrate(&models.Subscription{}, &models.Invoice{})
+	datab.AutoMigrate(&models.LoanProduct{}, &models.AffordabilityProfile{})
+	datab.AutoMigrate(&models.DigestSubscription{}, &models.DigestRun{})
+	if err := datab.AutoMigrate(&models.RolePermission{}, &models.AuditLog{}); err != nil {
+		log.Fatalf("Failed to migrate RolePermission model: %v", err)
+	}
 
-	err = datab.AutoMigrate(&models.CrawlHistory{})
+	err = datab.AutoMigrate(&models.CrawlHistory{}, &models.CrawlRun{})
 	if err != nil {
 		log.Fatalf("Failed to migrate CrawlHistory model: %v", err)
 	}
+	if err := datab.AutoMigrate(&models.MarketIndex{}); err != nil {
+		log.Fatalf("Failed to migrate MarketIndex model: %v", err)
+	}
+	datab.AutoMigrate(&models.AppSetting{}, &models.ListingRisk{}, &models.ListingImage{})
+	backfillPostHistoryCreatedAt(datab, logger)
+	backfillPostLifecycle(datab, logger)
+	createSearchIndex(datab, logger)
 	// Run auto-migrations for FilterItem and WatchList models
 	// if err := datab.AutoMigrate(&models.FilterItem{}, &models.WatchList{}); err != nil {
 	// 	panic("AutoMigrate Failed")
 	// }
 	seedSuperAdminUser(datab, logger)
+	seedRolePermissions(datab, logger)
 	postsSeeds(datab)
+	backfillSearchText(datab, logger)
 	return datab
 }
 
@@ -57,6 +74,59 @@ func logDatabaseEnv(host string, user string, dbname string, port string, logger
 	logger.Debug("database env loaded", slog.Group("database", hostLogAttr, userLogAttr, nameLogAttr, portLogAttr))
 }
 
+// backfillPostHistoryCreatedAt dates the post histories saved before they had created_at by their crawl
+func backfillPostHistoryCreatedAt(datab *gorm.DB, logger *slog.Logger) {
+	result := datab.Exec(`UPDATE post_histories SET created_at = crawl_histories.created_at
+		FROM crawl_histories WHERE post_histories.crawl_history_id = crawl_histories.id AND post_histories.created_at IS NULL`)
+	if result.Error != nil {
+		logger.Error("Could not backfill post history dates", slog.Any("error", result.Error))
+	} else if result.RowsAffected > 0 {
+		logger.Info("Backfilled post history dates", slog.Int64("rows", result.RowsAffected))
+	}
+}
+
+// backfillPostLifecycle dates the posts saved before their lifecycle was tracked by their creation and last update
+func backfillPostLifecycle(datab *gorm.DB, logger *slog.Logger) {
+	result := datab.Exec(`UPDATE posts SET first_seen_at = created_at, last_seen_at = updated_at
+		WHERE first_seen_at IS NULL OR last_seen_at IS NULL`)
+	if result.Error != nil {
+		logger.Error("Could not backfill post lifecycle dates", slog.Any("error", result.Error))
+	} else if result.RowsAffected > 0 {
+		logger.Info("Backfilled post lifecycle dates", slog.Int64("rows", result.RowsAffected))
+	}
+}
+
+// createSearchIndex indexes the search text for the full-text queries of keyword filters
+func createSearchIndex(datab *gorm.DB, logger *slog.Logger) {
+	err := datab.Exec("CREATE INDEX IF NOT EXISTS idx_post_histories_search_text ON post_histories USING GIN (" + textSearchVector + ")").Error
+	if err != nil {
+		logger.Error("Could not create the search text index", slog.Any("error", err))
+	}
+}
+
+// backfillSearchText normalizes the text of the post histories saved without it
+func backfillSearchText(datab *gorm.DB, logger *slog.Logger) {
+	var posts []models.PostHistory
+	var updated int64
+	result := datab.Select("id", "title", "description").Where("search_text IS NULL OR search_text = ''").
+		FindInBatches(&posts, 500, func(tx *gorm.DB, batch int) error {
+			for _, post := range posts {
+				if text := searchText(post); text != "" {
+					if err := datab.Model(&post).Update("search_text", text).Error; err != nil {
+						return err
+					}
+					updated++
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		logger.Error("Could not backfill post history search text", slog.Any("error", result.Error))
+	} else if updated > 0 {
+		logger.Info("Backfilled post history search text", slog.Int64("rows", updated))
+	}
+}
+
 func seedSuperAdminUser(datab *gorm.DB, logger *slog.Logger) {
 	superAdminTelegramId, _ := strconv.ParseUint(utils.GetConfig("SUPER_ADMIN"), 10, 64)
 	superAdminUser := models.User{
@@ -70,9 +140,16 @@ func seedSuperAdminUser(datab *gorm.DB, logger *slog.Logger) {
 	}
 }
 
+// seedRolePermissions grants the roles their default permissions on the first start
+func seedRolePermissions(datab *gorm.DB, logger *slog.Logger) {
+	if err := NewPermissionRepository(datab).Seed(models.DefaultPermissions); err != nil {
+		logger.Error("Could not seed role permissions", slog.Any("error", err))
+		panic("Could not seed role permissions")
+	}
+}
+
+// postsSeeds adds the sample posts once, crawled posts and their archived images are kept across restarts
 func postsSeeds(datab *gorm.DB) {
-	datab.Unscoped().Where("1 = 1").Delete(&models.PostHistory{})
-	datab.Unscoped().Where("1 = 1").Delete(&models.Post{})
 	postRepository := NewPostRepository(datab)
 	crawlInfo := models.CrawlHistory{}
 	if err := datab.Create(&crawlInfo).Error; err != nil {
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DigestRepository interface {
+	FindSubscriptions() ([]models.DigestSubscription, error)
+	FindUserSubscriptions(userID uint) ([]models.DigestSubscription, error)
+	SaveSubscription(subscription models.DigestSubscription) (models.DigestSubscription, error)
+	DeleteSubscription(filterItemID uint) error
+
+	StartRun(subscriptionID uint, scheduledFor time.Time, periodStart time.Time) (models.DigestRun, error)
+	SaveRun(run models.DigestRun) error
+	FindRun(ID uint) (models.DigestRun, error)
+	FindLastSentRun(subscriptionID uint) (models.DigestRun, error)
+
+	FindNewListings(filter models.FilterItem, start time.Time, end time.Time) ([]models.PostHistory, error)
+	FindUpdatedListings(filter models.FilterItem, start time.Time, end time.Time) ([]models.PostHistory, error)
+	FindRemovedListings(filter models.FilterItem, start time.Time, end time.Time) ([]models.PostHistory, error)
+	FindHistoriesAt(postIDs []uint, at time.Time) ([]models.PostHistory, error)
+}
+
+type DigestRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewDigestRepository(dbConnection *gorm.DB) DigestRepository {
+	return DigestRepositoryImpl{dbConnection: dbConnection}
+}
+
+// FindSubscriptions returns all digest subscriptions with their filters and users
+func (r DigestRepositoryImpl) FindSubscriptions() ([]models.DigestSubscription, error) {
+	var subscriptions []models.DigestSubscription
+	err := r.dbConnection.Preload("FilterItem").Preload("User").Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// FindUserSubscriptions returns the digest subscriptions of a user
+func (r DigestRepositoryImpl) FindUserSubscriptions(userID uint) ([]models.DigestSubscription, error) {
+	var subscriptions []models.DigestSubscription
+	err := r.dbConnection.Where("user_id = ?", userID).Order("filter_item_id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// SaveSubscription creates the digest subscription of a filter or changes its schedule
+func (r DigestRepositoryImpl) SaveSubscription(subscription models.DigestSubscription) (models.DigestSubscription, error) {
+	err := r.dbConnection.Omit("User", "FilterItem").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "filter_item_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"frequency", "hour", "weekday", "updated_at", "deleted_at"}),
+	}).Create(&subscription).Error
+	if err != nil {
+		return subscription, err
+	}
+	var saved models.DigestSubscription
+	err = r.dbConnection.Where("filter_item_id = ?", subscription.FilterItemID).First(&saved).Error
+	return saved, err
+}
+
+// DeleteSubscription stops the digest of a filter
+func (r DigestRepositoryImpl) DeleteSubscription(filterItemID uint) error {
+	return r.dbConnection.Where("filter_item_id = ?", filterItemID).Delete(&models.DigestSubscription{}).Error
+}
+
+// StartRun records a scheduled digest, or returns the record when the digest was already started
+func (r DigestRepositoryImpl) StartRun(subscriptionID uint, scheduledFor time.Time, periodStart time.Time) (models.DigestRun, error) {
+	run := models.DigestRun{SubscriptionID: subscriptionID, ScheduledFor: scheduledFor}
+	err := r.dbConnection.
+		Where("subscription_id = ? AND scheduled_for = ?", subscriptionID, scheduledFor).
+		Attrs(models.DigestRun{PeriodStart: periodStart, PeriodEnd: scheduledFor}).
+		FirstOrCreate(&run).Error
+	return run, err
+}
+
+func (r DigestRepositoryImpl) SaveRun(run models.DigestRun) error {
+	return r.dbConnection.Omit("Subscription").Save(&run).Error
+}
+
+// FindRun returns a digest run with its subscription, filter and user
+func (r DigestRepositoryImpl) FindRun(ID uint) (models.DigestRun, error) {
+	var run models.DigestRun
+	err := r.dbConnection.Preload("Subscription.FilterItem").Preload("Subscription.User").First(&run, ID).Error
+	return run, err
+}
+
+// FindLastSentRun returns the latest digest of a subscription that was sent
+func (r DigestRepositoryImpl) FindLastSentRun(subscriptionID uint) (models.DigestRun, error) {
+	var run models.DigestRun
+	err := r.dbConnection.Where("subscription_id = ? AND sent_at IS NOT NULL", subscriptionID).
+		Order("scheduled_for DESC").First(&run).Error
+	return run, err
+}
+
+// FindNewListings returns how the posts first crawled in (start, end] that match the filter looked at end
+func (r DigestRepositoryImpl) FindNewListings(filter models.FilterItem, start time.Time, end time.Time) ([]models.PostHistory, error) {
+	var histories []models.PostHistory
+	err := r.latestAt(filter, end).
+		Where("post_id IN (?)", r.dbConnection.Model(&models.Post{}).Select("id").
+			Where("status = ? AND first_seen_at > ? AND first_seen_at <= ?", models.POST_ACTIVE, start, end)).
+		Find(&histories).Error
+	return histories, err
+}
+
+// FindUpdatedListings returns the latest histories crawled in (start, end] of active posts matching the filter
+// that were first crawled before start, their prices may have changed
+func (r DigestRepositoryImpl) FindUpdatedListings(filter models.FilterItem, start time.Time, end time.Time) ([]models.PostHistory, error) {
+	var histories []models.PostHistory
+	err := r.latestAt(filter, end).
+		Where("created_at > ?", start).
+		Where("post_id IN (?)", r.dbConnection.Model(&models.Post{}).Select("id").
+			Where("status = ? AND first_seen_at <= ?", models.POST_ACTIVE, start)).
+		Find(&histories).Error
+	return histories, err
+}
+
+// FindRemovedListings returns the last histories of posts matching the filter that went away in (start, end]
+func (r DigestRepositoryImpl) FindRemovedListings(filter models.FilterItem, start time.Time, end time.Time) ([]models.PostHistory, error) {
+	var histories []models.PostHistory
+	err := r.latestAt(filter, end).
+		Where("post_id IN (?)", r.dbConnection.Model(&models.Post{}).Select("id").
+			Where("status <> ? AND removed_at > ? AND removed_at <= ?", models.POST_ACTIVE, start, end)).
+		Preload("Post").
+		Find(&histories).Error
+	return histories, err
+}
+
+// FindHistoriesAt returns the latest history of each post crawled at or before a time
+func (r DigestRepositoryImpl) FindHistoriesAt(postIDs []uint, at time.Time) ([]models.PostHistory, error) {
+	histories := []models.PostHistory{}
+	if len(postIDs) == 0 {
+		return histories, nil
+	}
+	err := r.dbConnection.
+		Where("id IN (?)", r.dbConnection.Model(&models.PostHistory{}).Select("MAX(id)").
+			Where("post_id IN ? AND created_at <= ?", postIDs, at).Group("post_id")).
+		Find(&histories).Error
+	return histories, err
+}
+
+// latestAt matches the filter against the latest history of each post crawled at or before a time
+func (r DigestRepositoryImpl) latestAt(filter models.FilterItem, at time.Time) *gorm.DB {
+	return FilterItemRepositoryImpl{dbConnection: r.dbConnection}.filterQuery(filter).
+		Where("id IN (?)", r.dbConnection.Model(&models.PostHistory{}).Select("MAX(id)").
+			Where("created_at <= ?", at).Group("post_id"))
+}
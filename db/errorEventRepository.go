@@ -0,0 +1,90 @@
+package db
+
+import (
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ErrorEventRepository interface {
+	Record(event models.ErrorEvent) (models.ErrorEvent, error)
+	Find(ID uint) (models.ErrorEvent, error)
+	FindPage(filter models.ErrorEventFilter, offset int, limit int) ([]models.ErrorEvent, int64, error)
+	UpdateStatus(ID uint, status models.ErrorStatus) (models.ErrorEvent, error)
+}
+
+type ErrorEventRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewErrorEventRepository(dbConnection *gorm.DB) ErrorEventRepository {
+	return ErrorEventRepositoryImpl{dbConnection: dbConnection}
+}
+
+// Record inserts the event, or counts one more occurrence when its fingerprint is already stored.
+// A resolved event that happens again is reopened.
+func (repo ErrorEventRepositoryImpl) Record(event models.ErrorEvent) (models.ErrorEvent, error) {
+	event.Occurrences = 1
+	event.Status = models.ERROR_OPEN
+	if event.FirstSeenAt.IsZero() {
+		event.FirstSeenAt = event.LastSeenAt
+	}
+
+	err := repo.dbConnection.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "fingerprint"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"occurrences":  gorm.Expr("error_events.occurrences + 1"),
+			"last_seen_at": event.LastSeenAt,
+			"updated_at":   event.LastSeenAt,
+			"message":      event.Message,
+			"stack":        event.Stack,
+			"url":          event.URL,
+			"city":         event.City,
+			"status": gorm.Expr("CASE WHEN error_events.status = ? THEN ? ELSE error_events.status END",
+				models.ERROR_RESOLVED, models.ERROR_OPEN),
+		}),
+	}).Create(&event).Error
+	if err != nil {
+		return event, err
+	}
+
+	var stored models.ErrorEvent
+	err = repo.dbConnection.Where("fingerprint = ?", event.Fingerprint).First(&stored).Error
+	return stored, err
+}
+
+func (repo ErrorEventRepositoryImpl) Find(ID uint) (models.ErrorEvent, error) {
+	var event models.ErrorEvent
+	err := repo.dbConnection.First(&event, ID).Error
+	return event, err
+}
+
+// FindPage returns a page of the matching events, most recent first, and the number of all matches
+func (repo ErrorEventRepositoryImpl) FindPage(filter models.ErrorEventFilter, offset int, limit int) ([]models.ErrorEvent, int64, error) {
+	query := repo.dbConnection.Model(&models.ErrorEvent{})
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.ErrorEvent
+	err := query.Order("last_seen_at desc").Offset(offset).Limit(limit).Find(&events).Error
+	return events, total, err
+}
+
+func (repo ErrorEventRepositoryImpl) UpdateStatus(ID uint, status models.ErrorStatus) (models.ErrorEvent, error) {
+	event, err := repo.Find(ID)
+	if err != nil {
+		return event, err
+	}
+	event.Status = status
+	err = repo.dbConnection.Model(&event).Update("status", status).Error
+	return event, err
+}
@@ -2,11 +2,37 @@ package db
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/types"
 	"gorm.io/gorm"
 )
 
+// amenityColumns are the PostHistory columns of the amenities a filter can require
+var amenityColumns = map[types.Amenity]string{
+	types.Balcony:  "amenity_balcony",
+	types.Rooftop:  "amenity_rooftop",
+	types.Pool:     "amenity_pool",
+	types.Sauna:    "amenity_sauna",
+	types.Jacuzzi:  "amenity_jacuzzi",
+	types.Gym:      "amenity_gym",
+	types.Lobby:    "amenity_lobby",
+	types.Guard:    "amenity_guard",
+	types.Storage:  "has_storage",
+	types.Parking:  "has_parking",
+	types.Elevator: "has_elevator",
+}
+
+// countBatchSize is how many filters CountPostHistories counts in one query, it keeps the query below the
+// parameter limits of the databases
+const countBatchSize = 50
+
+// textSearchVector is the indexed tsvector of the search text, the "simple" configuration keeps Persian words
+// as persian.Normalize wrote them
+const textSearchVector = "to_tsvector('simple', coalesce(search_text, ''))"
+
 type FilterItemRepository interface {
 	Create(filterItem models.FilterItem) (models.FilterItem, error)
 	FindByID(id uint) (models.FilterItem, error)
@@ -15,6 +41,9 @@ type FilterItemRepository interface {
 	Delete(id uint) error
 	SearchPostHistory(filter models.FilterItem) ([]models.PostHistory, error)
 	FindByUserID(userID uint) ([]models.FilterItem, error)
+	CountByUserID(userID uint) (int64, error)
+	CountPostHistory(filter models.FilterItem) (int64, error)
+	CountPostHistories(filters []models.FilterItem) ([]int64, error)
 }
 
 type FilterItemRepositoryImpl struct {
@@ -62,6 +91,43 @@ func (repo FilterItemRepositoryImpl) Delete(id uint) error {
 
 func (repo FilterItemRepositoryImpl) SearchPostHistory(filter models.FilterItem) ([]models.PostHistory, error) {
 	var posts []models.PostHistory
+	query := repo.filterQuery(filter)
+	if filter.SortBy == models.SORT_BY_MONTHLY_COST {
+		query = query.Where("monthly_cost > 0").Order("monthly_cost")
+	}
+	err := query.Find(&posts).Error
+	return posts, err
+}
+
+// CountPostHistory returns the number of posts matching the filter
+func (repo FilterItemRepositoryImpl) CountPostHistory(filter models.FilterItem) (int64, error) {
+	var count int64
+	err := repo.filterQuery(filter).Count(&count).Error
+	return count, err
+}
+
+// CountPostHistories returns the number of posts matching each filter, the filters are counted together in one
+// query per batch instead of one query each
+func (repo FilterItemRepositoryImpl) CountPostHistories(filters []models.FilterItem) ([]int64, error) {
+	counts := make([]int64, len(filters))
+	for start := 0; start < len(filters); start += countBatchSize {
+		end := min(start+countBatchSize, len(filters))
+		columns := make([]string, 0, end-start)
+		subqueries := make([]interface{}, 0, end-start)
+		destinations := make([]interface{}, 0, end-start)
+		for i := start; i < end; i++ {
+			columns = append(columns, "(?)")
+			subqueries = append(subqueries, repo.filterQuery(filters[i]).Select("count(*)"))
+			destinations = append(destinations, &counts[i])
+		}
+		if err := repo.dbConnection.Raw("SELECT "+strings.Join(columns, ", "), subqueries...).Row().Scan(destinations...); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+func (repo FilterItemRepositoryImpl) filterQuery(filter models.FilterItem) *gorm.DB {
 	query := repo.dbConnection.Model(&models.PostHistory{})
 
 	// Apply filter conditions
@@ -75,7 +141,7 @@ func (repo FilterItemRepositoryImpl) SearchPostHistory(filter models.FilterItem)
 		query = query.Where("city = ?", filter.City)
 	}
 	if filter.Neighborhood != "" {
-		query = query.Where("neighbor = ?", filter.Neighborhood)
+		query = query.Where("neighborhood = ?", filter.Neighborhood)
 	}
 	if filter.AreaMin > 0 {
 		query = query.Where("area >= ?", filter.AreaMin)
@@ -108,7 +174,7 @@ func (repo FilterItemRepositoryImpl) SearchPostHistory(filter models.FilterItem)
 		query = query.Where("floors_num <= ?", filter.FloorMax)
 	}
 	if filter.HasStorage {
-		query = query.Where("has_ware_house = ?", filter.HasStorage)
+		query = query.Where("has_storage = ?", filter.HasStorage)
 	}
 	if filter.HasElevator {
 		query = query.Where("has_elevator = ?", filter.HasElevator)
@@ -119,9 +185,47 @@ func (repo FilterItemRepositoryImpl) SearchPostHistory(filter models.FilterItem)
 	if !filter.CreatedDateEnd.IsZero() {
 		query = query.Where("created_at <= ?", filter.CreatedDateEnd)
 	}
-
-	err := query.Find(&posts).Error
-	return posts, err
+	if filter.MonthlyCostMin > 0 {
+		query = query.Where("monthly_cost >= ?", filter.MonthlyCostMin)
+	}
+	if filter.MonthlyCostMax > 0 {
+		query = query.Where("monthly_cost <= ?", filter.MonthlyCostMax)
+	}
+	if filter.DepositMax > 0 {
+		query = query.Where("deposit <= ?", filter.DepositMax)
+	}
+	if filter.RentMax > 0 {
+		query = query.Where("rent <= ?", filter.RentMax)
+	}
+	for _, amenity := range strings.Split(filter.Amenities, ",") {
+		if column, exists := amenityColumns[types.Amenity(strings.TrimSpace(amenity))]; exists {
+			query = query.Where(column+" = ?", true)
+		}
+	}
+	if filter.Renovation != "" {
+		query = query.Where("amenity_renovation = ?", filter.Renovation)
+	}
+	if filter.Document != "" {
+		query = query.Where("amenity_document = ?", filter.Document)
+	}
+	if filter.Orientation != "" {
+		query = query.Where("amenity_orientation = ?", filter.Orientation)
+	}
+	if filter.SellerType != "" {
+		query = query.Where("seller_type = ?", filter.SellerType)
+	}
+	for _, term := range persian.Terms(filter.IncludeKeywords) {
+		query = query.Where(repo.textMatch(term))
+	}
+	for _, term := range persian.Terms(filter.ExcludeKeywords) {
+		query = query.Not(repo.textMatch(term))
+	}
+	if filter.HideRisky {
+		query = query.Where(`NOT EXISTS (SELECT 1 FROM listing_risks WHERE listing_risks.post_history_id = post_histories.id
+			AND listing_risks.score >= ? AND listing_risks.review <> ? AND listing_risks.deleted_at IS NULL)`,
+			models.HIGH_RISK_SCORE, models.RISK_DISMISSED)
+	}
+	return query
 }
 
 // FindByUserID retrieves all filters associated with a specific user
@@ -142,3 +246,19 @@ func (repo FilterItemRepositoryImpl) FindByUserID(userID uint) ([]models.FilterI
 
 	return filterItems, nil
 }
+
+// CountByUserID returns the number of filters saved by a user
+func (repo FilterItemRepositoryImpl) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := repo.dbConnection.Model(&models.FilterItem{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// textMatch matches the post histories containing the words of a normalized term next to each other, with the
+// full-text index on PostgreSQL and by padded LIKE elsewhere
+func (repo FilterItemRepositoryImpl) textMatch(term string) *gorm.DB {
+	if repo.dbConnection.Dialector.Name() == "postgres" {
+		return repo.dbConnection.Where(textSearchVector+" @@ phraseto_tsquery('simple', ?)", term)
+	}
+	return repo.dbConnection.Where("' ' || coalesce(search_text, '') || ' ' LIKE ?", "% "+term+" %")
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FinancingRepository interface {
+	FindLoanProducts(activeOnly bool) ([]models.LoanProduct, error)
+	FindLoanProduct(ID uint) (models.LoanProduct, error)
+	SaveLoanProduct(product models.LoanProduct) (models.LoanProduct, error)
+	FindProfile(userID uint) (models.AffordabilityProfile, error)
+	SaveProfile(profile models.AffordabilityProfile) (models.AffordabilityProfile, error)
+	DeleteProfile(userID uint) (bool, error)
+}
+
+type FinancingRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewFinancingRepository(dbConnection *gorm.DB) FinancingRepository {
+	return FinancingRepositoryImpl{dbConnection: dbConnection}
+}
+
+// find the loan products by rate, the cheapest first
+func (r FinancingRepositoryImpl) FindLoanProducts(activeOnly bool) ([]models.LoanProduct, error) {
+	products := []models.LoanProduct{}
+	query := r.dbConnection.Order("annual_rate, id")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Find(&products).Error
+	return products, err
+}
+
+func (r FinancingRepositoryImpl) FindLoanProduct(ID uint) (models.LoanProduct, error) {
+	var product models.LoanProduct
+	err := r.dbConnection.First(&product, ID).Error
+	return product, err
+}
+
+// save a loan product, a product with the name of an existing one replaces its terms
+func (r FinancingRepositoryImpl) SaveLoanProduct(product models.LoanProduct) (models.LoanProduct, error) {
+	err := r.dbConnection.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"annual_rate", "term_months", "max_ltv", "max_amount", "active", "updated_at"}),
+	}).Create(&product).Error
+	if err != nil {
+		return product, err
+	}
+	err = r.dbConnection.Where("name = ?", product.Name).First(&product).Error
+	return product, err
+}
+
+// find the profile of a user with its loan product
+func (r FinancingRepositoryImpl) FindProfile(userID uint) (models.AffordabilityProfile, error) {
+	var profile models.AffordabilityProfile
+	err := r.dbConnection.Preload("LoanProduct").Where("user_id = ?", userID).First(&profile).Error
+	return profile, err
+}
+
+// save the profile of a user, replacing the previous one
+func (r FinancingRepositoryImpl) SaveProfile(profile models.AffordabilityProfile) (models.AffordabilityProfile, error) {
+	err := r.dbConnection.Omit("User", "LoanProduct").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cash", "monthly_budget", "loan_product_id", "updated_at"}),
+	}).Create(&profile).Error
+	if err != nil {
+		return profile, err
+	}
+	return r.FindProfile(profile.UserID)
+}
+
+// delete the profile of a user and clear the ranges it set on their saved filters, false when there was no profile
+func (r FinancingRepositoryImpl) DeleteProfile(userID uint) (bool, error) {
+	deleted := false
+	err := r.dbConnection.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.AffordabilityProfile{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		return tx.Model(&models.FilterItem{}).Where("user_id = ? AND affordable = ?", userID, true).
+			Updates(map[string]interface{}{"price_max": 0, "deposit_max": 0, "rent_max": 0, "affordable": false}).Error
+	})
+	return deleted, err
+}
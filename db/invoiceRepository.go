@@ -0,0 +1,49 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+type InvoiceRepository interface {
+	Create(invoice models.Invoice) (models.Invoice, error)
+	FindByAuthority(authority string) (models.Invoice, error)
+	MarkPaid(ID uint, refID string, paidAt time.Time) (bool, error)
+	MarkFailed(ID uint) error
+}
+
+type InvoiceRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewInvoiceRepository(dbConnection *gorm.DB) InvoiceRepository {
+	return InvoiceRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (repo InvoiceRepositoryImpl) Create(invoice models.Invoice) (models.Invoice, error) {
+	err := repo.dbConnection.Create(&invoice).Error
+	return invoice, err
+}
+
+func (repo InvoiceRepositoryImpl) FindByAuthority(authority string) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := repo.dbConnection.Where("authority = ?", authority).First(&invoice).Error
+	return invoice, err
+}
+
+// MarkPaid sets a pending invoice as paid; it reports false when the invoice was not pending anymore
+func (repo InvoiceRepositoryImpl) MarkPaid(ID uint, refID string, paidAt time.Time) (bool, error) {
+	result := repo.dbConnection.Model(&models.Invoice{}).
+		Where("id = ? AND status = ?", ID, models.INVOICE_PENDING).
+		Updates(map[string]interface{}{"status": models.INVOICE_PAID, "ref_id": refID, "paid_at": paidAt})
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkFailed sets a pending invoice as failed
+func (repo InvoiceRepositoryImpl) MarkFailed(ID uint) error {
+	return repo.dbConnection.Model(&models.Invoice{}).
+		Where("id = ? AND status = ?", ID, models.INVOICE_PENDING).
+		Update("status", models.INVOICE_FAILED).Error
+}
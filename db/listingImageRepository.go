@@ -0,0 +1,137 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+type ListingImageRepository interface {
+	FindCrawlPostHistories(crawlHistoryID uint) ([]models.PostHistory, error)
+	FindLatestPostHistory(postID uint) (models.PostHistory, error)
+	FindBySources(sourceURLs []string) ([]models.ListingImage, error)
+	FindByPostHistory(postHistoryID uint) ([]models.ListingImage, error)
+	Save(images []models.ListingImage) error
+	FindExpired(before time.Time, limit int) ([]models.ListingImage, error)
+	Delete(images []models.ListingImage) ([]string, error)
+}
+
+type ListingImageRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewListingImageRepository(dbConnection *gorm.DB) ListingImageRepository {
+	return ListingImageRepositoryImpl{dbConnection: dbConnection}
+}
+
+// FindCrawlPostHistories returns the post histories with photos saved by a crawl
+func (repo ListingImageRepositoryImpl) FindCrawlPostHistories(crawlHistoryID uint) ([]models.PostHistory, error) {
+	var posts []models.PostHistory
+	err := repo.dbConnection.
+		Select("id", "post_id", "image_url").
+		Where("crawl_history_id = ? AND image_url <> ''", crawlHistoryID).
+		Order("id").
+		Find(&posts).Error
+	return posts, err
+}
+
+// FindLatestPostHistory returns the newest history of a post
+func (repo ListingImageRepositoryImpl) FindLatestPostHistory(postID uint) (models.PostHistory, error) {
+	var post models.PostHistory
+	err := repo.dbConnection.
+		Select("id", "post_id", "image_url").
+		Where("post_id = ?", postID).
+		Order("id DESC").
+		First(&post).Error
+	return post, err
+}
+
+// FindBySources returns archived images of the given links, a link archived by several histories is returned once per history
+func (repo ListingImageRepositoryImpl) FindBySources(sourceURLs []string) ([]models.ListingImage, error) {
+	var images []models.ListingImage
+	if len(sourceURLs) == 0 {
+		return images, nil
+	}
+	err := repo.dbConnection.
+		Where("source_url IN ? AND status = ?", sourceURLs, models.IMAGE_ARCHIVED).
+		Order("id").
+		Find(&images).Error
+	return images, err
+}
+
+// FindByPostHistory returns the images of a post history in the order of the listing
+func (repo ListingImageRepositoryImpl) FindByPostHistory(postHistoryID uint) ([]models.ListingImage, error) {
+	var images []models.ListingImage
+	err := repo.dbConnection.
+		Where("post_history_id = ?", postHistoryID).
+		Order("position").
+		Find(&images).Error
+	return images, err
+}
+
+func (repo ListingImageRepositoryImpl) Save(images []models.ListingImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+	return repo.dbConnection.CreateInBatches(&images, 200).Error
+}
+
+// FindExpired returns images of post histories created before a time, except the images of bookmarked posts
+func (repo ListingImageRepositoryImpl) FindExpired(before time.Time, limit int) ([]models.ListingImage, error) {
+	var images []models.ListingImage
+	err := repo.dbConnection.
+		Where("created_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM bookmarks WHERE bookmarks.post_id = listing_images.post_id AND bookmarks.deleted_at IS NULL)").
+		Order("id").
+		Limit(limit).
+		Find(&images).Error
+	return images, err
+}
+
+// Delete removes the images and returns the blob keys no remaining image uses, their blobs can be deleted
+func (repo ListingImageRepositoryImpl) Delete(images []models.ListingImage) ([]string, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, 0, len(images))
+	keys := make([]string, 0)
+	for _, image := range images {
+		ids = append(ids, image.ID)
+		for _, key := range []string{image.BlobKey, image.ThumbnailKey} {
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	orphans := make([]string, 0)
+	err := repo.dbConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.ListingImage{}).Error; err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		var used []models.ListingImage
+		if err := tx.Unscoped().Select("blob_key", "thumbnail_key").
+			Where("blob_key IN ? OR thumbnail_key IN ?", keys, keys).
+			Find(&used).Error; err != nil {
+			return err
+		}
+		stillUsed := make(map[string]bool)
+		for _, image := range used {
+			stillUsed[image.BlobKey] = true
+			stillUsed[image.ThumbnailKey] = true
+		}
+		seen := make(map[string]bool)
+		for _, key := range keys {
+			if !stillUsed[key] && !seen[key] {
+				orphans = append(orphans, key)
+				seen[key] = true
+			}
+		}
+		return nil
+	})
+	return orphans, err
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ListingRiskRepository interface {
+	FindWindow(since time.Time) ([]models.PostHistory, error)
+	FindWindowImages(since time.Time) ([]models.ListingImage, error)
+	Save(risks []models.ListingRisk) error
+	FindByPostHistory(postHistoryID uint) (models.ListingRisk, error)
+	FindLatestReviews(postIDs []uint) (map[uint]models.ListingRisk, error)
+	FindPending(limit int) ([]models.ListingRisk, int64, error)
+	SetReview(ID uint, review models.RiskReview, reviewedBy uint) (models.ListingRisk, error)
+}
+
+type ListingRiskRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewListingRiskRepository(dbConnection *gorm.DB) ListingRiskRepository {
+	return ListingRiskRepositoryImpl{dbConnection: dbConnection}
+}
+
+// FindWindow returns the fields scoring needs of the post histories created after since
+func (repo ListingRiskRepositoryImpl) FindWindow(since time.Time) ([]models.PostHistory, error) {
+	var posts []models.PostHistory
+	err := repo.dbConnection.
+		Select("id", "post_id", "city", "neighborhood", "buy_mode", "price", "rent", "area", "image_url",
+			"description", "crawl_history_id", "created_at").
+		Where("created_at >= ?", since).
+		Order("id").
+		Find(&posts).Error
+	return posts, err
+}
+
+// FindWindowImages returns the post history, status and hash of the archived photos of post histories created after since
+func (repo ListingRiskRepositoryImpl) FindWindowImages(since time.Time) ([]models.ListingImage, error) {
+	var images []models.ListingImage
+	err := repo.dbConnection.
+		Select("listing_images.post_history_id", "listing_images.status", "listing_images.hash").
+		Joins("JOIN post_histories ON post_histories.id = listing_images.post_history_id").
+		Where("post_histories.created_at >= ? AND listing_images.status = ?", since, models.IMAGE_ARCHIVED).
+		Find(&images).Error
+	return images, err
+}
+
+// Save stores the scores, a rescored listing keeps its review
+func (repo ListingRiskRepositoryImpl) Save(risks []models.ListingRisk) error {
+	if len(risks) == 0 {
+		return nil
+	}
+	return repo.dbConnection.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_history_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"score", "reasons", "updated_at"}),
+	}).CreateInBatches(&risks, 200).Error
+}
+
+// FindByPostHistory returns the risk of a post history, or a zero risk when it was not scored
+func (repo ListingRiskRepositoryImpl) FindByPostHistory(postHistoryID uint) (models.ListingRisk, error) {
+	var risk models.ListingRisk
+	err := repo.dbConnection.Where("post_history_id = ?", postHistoryID).Limit(1).Find(&risk).Error
+	return risk, err
+}
+
+// FindLatestReviews returns the latest admin review of each of the posts, by post id. A recrawl saves a new
+// post history, so its risk starts from the review given to the earlier histories of the same post.
+func (repo ListingRiskRepositoryImpl) FindLatestReviews(postIDs []uint) (map[uint]models.ListingRisk, error) {
+	reviews := make(map[uint]models.ListingRisk)
+	if len(postIDs) == 0 {
+		return reviews, nil
+	}
+	var risks []models.ListingRisk
+	err := repo.dbConnection.Joins("PostHistory").
+		Where(`"PostHistory".post_id IN ? AND listing_risks.review <> ?`, postIDs, models.RISK_PENDING).
+		Order("listing_risks.reviewed_at, listing_risks.id").
+		Find(&risks).Error
+	for _, risk := range risks {
+		reviews[risk.PostHistory.PostID] = risk
+	}
+	return reviews, err
+}
+
+// FindPending returns the riskiest unreviewed high risk listings and how many there are in total
+func (repo ListingRiskRepositoryImpl) FindPending(limit int) ([]models.ListingRisk, int64, error) {
+	query := repo.dbConnection.Model(&models.ListingRisk{}).
+		Where("score >= ? AND review = ?", models.HIGH_RISK_SCORE, models.RISK_PENDING)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var risks []models.ListingRisk
+	err := query.Preload("PostHistory").Order("score DESC, id").Limit(limit).Find(&risks).Error
+	return risks, total, err
+}
+
+func (repo ListingRiskRepositoryImpl) SetReview(ID uint, review models.RiskReview, reviewedBy uint) (models.ListingRisk, error) {
+	var risk models.ListingRisk
+	if err := repo.dbConnection.First(&risk, ID).Error; err != nil {
+		return risk, err
+	}
+	now := time.Now()
+	risk.Review, risk.ReviewedBy, risk.ReviewedAt = review, reviewedBy, &now
+	err := repo.dbConnection.Model(&risk).Select("review", "reviewed_by", "reviewed_at").Updates(&risk).Error
+	return risk, err
+}
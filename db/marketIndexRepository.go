@@ -0,0 +1,86 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+type MarketIndexRepository interface {
+	FindSamples(since time.Time) ([]models.PostHistory, error)
+	ReplaceSince(since time.Time, indices []models.MarketIndex) error
+	Find(query models.MarketIndexQuery) ([]models.MarketIndex, error)
+	FindLatestCities() ([]models.MarketIndex, error)
+	FindWithoutBuilding(afterID uint, limit int) ([]models.PostHistory, error)
+	UpdateBuilding(postHistoryID uint, building types.Building) error
+}
+
+type MarketIndexRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewMarketIndexRepository(dbConnection *gorm.DB) MarketIndexRepository {
+	return MarketIndexRepositoryImpl{dbConnection: dbConnection}
+}
+
+// FindSamples returns the priced fields of the post histories created after since, oldest first
+func (repo MarketIndexRepositoryImpl) FindSamples(since time.Time) ([]models.PostHistory, error) {
+	var posts []models.PostHistory
+	err := repo.dbConnection.
+		Select("id", "post_id", "city", "neighborhood", "building", "buy_mode", "price", "deposit", "rent", "area", "created_at").
+		Where("created_at >= ? AND area > 0", since).
+		Order("created_at").
+		Find(&posts).Error
+	return posts, err
+}
+
+// ReplaceSince swaps the index rows of the weeks starting from since for the given ones
+func (repo MarketIndexRepositoryImpl) ReplaceSince(since time.Time, indices []models.MarketIndex) error {
+	return repo.dbConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("week >= ?", since).Delete(&models.MarketIndex{}).Error; err != nil {
+			return err
+		}
+		if len(indices) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&indices, 200).Error
+	})
+}
+
+// Find returns the weekly rows matching the query exactly, oldest first
+func (repo MarketIndexRepositoryImpl) Find(query models.MarketIndexQuery) ([]models.MarketIndex, error) {
+	var indices []models.MarketIndex
+	err := repo.dbConnection.
+		Where("city = ? AND neighborhood = ? AND building = ? AND week >= ?", query.City, query.Neighborhood, query.Building, query.Since).
+		Order("week").
+		Find(&indices).Error
+	return indices, err
+}
+
+// FindLatestCities returns the city wide rows of the latest computed week
+func (repo MarketIndexRepositoryImpl) FindLatestCities() ([]models.MarketIndex, error) {
+	var indices []models.MarketIndex
+	latestWeek := repo.dbConnection.Model(&models.MarketIndex{}).Select("MAX(week)")
+	err := repo.dbConnection.
+		Where("week = (?) AND neighborhood = '' AND building = ''", latestWeek).
+		Order("sale_listings + rent_listings DESC").
+		Find(&indices).Error
+	return indices, err
+}
+
+// FindWithoutBuilding returns the titles of the post histories saved without a building type, in pages ordered by ID
+func (repo MarketIndexRepositoryImpl) FindWithoutBuilding(afterID uint, limit int) ([]models.PostHistory, error) {
+	var posts []models.PostHistory
+	err := repo.dbConnection.Select("id", "title").
+		Where("(building IS NULL OR building = '') AND id > ?", afterID).
+		Order("id").Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// UpdateBuilding sets the building type of a post history
+func (repo MarketIndexRepositoryImpl) UpdateBuilding(postHistoryID uint, building types.Building) error {
+	return repo.dbConnection.Model(&models.PostHistory{}).Where("id = ?", postHistoryID).Update("building", building).Error
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+type PermissionRepository interface {
+	FindAll() ([]models.RolePermission, error)
+	Seed(defaults map[models.Role][]models.Permission) error
+	Grant(role models.Role, permission models.Permission) error
+	Revoke(role models.Role, permission models.Permission) error
+}
+
+type PermissionRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewPermissionRepository(dbConnection *gorm.DB) PermissionRepository {
+	return PermissionRepositoryImpl{dbConnection: dbConnection}
+}
+
+// FindAll returns the permissions of all roles
+func (r PermissionRepositoryImpl) FindAll() ([]models.RolePermission, error) {
+	var permissions []models.RolePermission
+	err := r.dbConnection.Order("role, permission").Find(&permissions).Error
+	return permissions, err
+}
+
+// Seed grants the default permissions when no role has any yet, later changes of admins are kept
+func (r PermissionRepositoryImpl) Seed(defaults map[models.Role][]models.Permission) error {
+	var count int64
+	if err := r.dbConnection.Model(&models.RolePermission{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	return r.dbConnection.Transaction(func(tx *gorm.DB) error {
+		for role, permissions := range defaults {
+			for _, permission := range permissions {
+				if err := tx.Create(&models.RolePermission{Role: role, Permission: permission}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Grant gives a permission to a role, granting it twice changes nothing
+func (r PermissionRepositoryImpl) Grant(role models.Role, permission models.Permission) error {
+	return r.dbConnection.FirstOrCreate(&models.RolePermission{}, models.RolePermission{Role: role, Permission: permission}).Error
+}
+
+// Revoke takes a permission from a role. The row is deleted for good so the permission can be granted again.
+func (r PermissionRepositoryImpl) Revoke(role models.Role, permission models.Permission) error {
+	return r.dbConnection.Unscoped().Where("role = ? AND permission = ?", role, permission).Delete(&models.RolePermission{}).Error
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+type PostLifecycleRepository interface {
+	FindStale(before time.Time, limit int) ([]models.Post, error)
+	FindLatestHistory(postID uint) (models.PostHistory, error)
+	MarkChecked(postID uint, checkedAt time.Time, live bool) error
+	MarkGone(postID uint, status models.PostStatus, at time.Time) (bool, error)
+	FindBookmarkUsers(postID uint) ([]models.User, error)
+	FindWatchListUsers(history models.PostHistory) ([]models.User, error)
+}
+
+type PostLifecycleRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewPostLifecycleRepository(dbConnection *gorm.DB) PostLifecycleRepository {
+	return PostLifecycleRepositoryImpl{dbConnection: dbConnection}
+}
+
+// FindStale returns active posts no crawl found since before and nobody checked since then, the longest unseen first
+func (repo PostLifecycleRepositoryImpl) FindStale(before time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := repo.dbConnection.
+		Where("status = ? AND last_seen_at < ?", models.POST_ACTIVE, before).
+		Where("checked_at IS NULL OR checked_at < ?", before).
+		Order("last_seen_at").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// FindLatestHistory returns the newest history of a post
+func (repo PostLifecycleRepositoryImpl) FindLatestHistory(postID uint) (models.PostHistory, error) {
+	var post models.PostHistory
+	err := repo.dbConnection.Where("post_id = ?", postID).Order("id DESC").First(&post).Error
+	return post, err
+}
+
+// MarkChecked records a check of a post, a live post is also seen at that time
+func (repo PostLifecycleRepositoryImpl) MarkChecked(postID uint, checkedAt time.Time, live bool) error {
+	updates := map[string]interface{}{"checked_at": checkedAt}
+	if live {
+		updates["last_seen_at"] = checkedAt
+	}
+	return repo.dbConnection.Model(&models.Post{}).Where("id = ?", postID).Updates(updates).Error
+}
+
+// MarkGone sets the status of an active post, it returns false when the post was not active anymore
+func (repo PostLifecycleRepositoryImpl) MarkGone(postID uint, status models.PostStatus, at time.Time) (bool, error) {
+	result := repo.dbConnection.Model(&models.Post{}).
+		Where("id = ? AND status = ?", postID, models.POST_ACTIVE).
+		Updates(map[string]interface{}{"status": status, "removed_at": at, "checked_at": at})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindBookmarkUsers returns the users who bookmarked a post
+func (repo PostLifecycleRepositoryImpl) FindBookmarkUsers(postID uint) ([]models.User, error) {
+	var users []models.User
+	err := repo.dbConnection.
+		Where("id IN (?)", repo.dbConnection.Model(&models.Bookmark{}).Select("user_id").Where("post_id = ?", postID)).
+		Find(&users).Error
+	return users, err
+}
+
+// FindWatchListUsers returns the users with a watchlist whose filter matches a post history, each user once.
+// The filters are read first, then one query matches the post history against all of them.
+func (repo PostLifecycleRepositoryImpl) FindWatchListUsers(history models.PostHistory) ([]models.User, error) {
+	var watchLists []models.WatchList
+	if err := repo.dbConnection.Preload("FilterItem").Find(&watchLists).Error; err != nil {
+		return nil, err
+	}
+	users := make([]models.User, 0)
+	if len(watchLists) == 0 {
+		return users, nil
+	}
+	filters := FilterItemRepositoryImpl{dbConnection: repo.dbConnection}
+	matches := repo.dbConnection.Session(&gorm.Session{NewDB: true})
+	for _, watchList := range watchLists {
+		match := filters.filterQuery(watchList.FilterItem).Select("1").Where("post_histories.id = ?", history.ID)
+		matches = matches.Or("watch_lists.id = ? AND EXISTS (?)", watchList.ID, match)
+	}
+	err := repo.dbConnection.
+		Where("id IN (?)", repo.dbConnection.Model(&models.WatchList{}).Select("user_id").Where(matches)).
+		Find(&users).Error
+	return users, err
+}
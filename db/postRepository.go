@@ -2,9 +2,11 @@ package db
 
 import (
 	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
 	"github.com/MagicalCrawler/RealEstateApp/types"
 	"gorm.io/gorm"
 	"log/slog"
+	"time"
 )
 
 type PostRepo interface {
@@ -21,6 +23,10 @@ type PostRepo interface {
 	CrawlHistoryIsExist(crawlHistory models.CrawlHistory) bool
 	GetMostVisitedPost() ([]models.PostHistory, error)
 	GetAllPosts() ([]models.PostHistory, error)
+	FindPostHistory(ID uint) (models.PostHistory, error)
+	FindComparables(query models.ComparableQuery) ([]models.PostHistory, error)
+	UpdateRentEquivalents(rate float64) (int64, error)
+	BackfillBuyModes() (int64, error)
 }
 
 // connection to database
@@ -88,11 +94,15 @@ func (pr PostRepository) PostIsExist(post models.Post) bool {
 	}
 }
 
-// save a post by unicode and its source
+// save a post by unicode and its source, a crawl finding the post marks it seen and active again
 func (pr PostRepository) PostSaving(uniCode string, src types.WebsiteSource) (models.Post, error) {
+	now := time.Now()
 	post := models.Post{
-		UniqueCode: uniCode,
-		Website:    src,
+		UniqueCode:  uniCode,
+		Website:     src,
+		Status:      models.POST_ACTIVE,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
 	}
 
 	if pr.PostIsExist(post) {
@@ -103,7 +113,10 @@ func (pr PostRepository) PostSaving(uniCode string, src types.WebsiteSource) (mo
 			return post, err
 		}
 
-		return post, nil
+		err = pr.dbConnection.Model(&post).Updates(map[string]interface{}{
+			"status": models.POST_ACTIVE, "last_seen_at": now, "removed_at": nil,
+		}).Error
+		return post, err
 	}
 
 	err := pr.dbConnection.Create(&post).Error
@@ -140,6 +153,66 @@ func (pr PostRepository) FindByID(ID uint) (models.Post, error) {
 
 }
 
+// find a post history by its id
+func (pr PostRepository) FindPostHistory(ID uint) (models.PostHistory, error) {
+	var postHistory models.PostHistory
+	err := pr.dbConnection.First(&postHistory, ID).Error
+	return postHistory, err
+}
+
+// FindComparables returns the newest histories like the query, at most one per post
+func (pr PostRepository) FindComparables(query models.ComparableQuery) ([]models.PostHistory, error) {
+	var candidates []models.PostHistory
+	db := pr.dbConnection.
+		Where("city = ? AND buy_mode = ? AND post_id <> ?", query.City, query.BuyMode, query.ExcludePostID).
+		Where("area BETWEEN ? AND ?", query.AreaMin, query.AreaMax).
+		Where("created_at >= ?", query.Since)
+	if query.Building != "" {
+		db = db.Where("building = ?", query.Building)
+	}
+	if query.BuyMode == types.Rent {
+		db = db.Where("rent > 0")
+	} else {
+		db = db.Where("price > 0")
+	}
+	if err := db.Order("created_at DESC").Limit(query.Limit).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool)
+	comparables := make([]models.PostHistory, 0, len(candidates))
+	for _, candidate := range candidates {
+		if seen[candidate.PostID] {
+			continue
+		}
+		seen[candidate.PostID] = true
+		comparables = append(comparables, candidate)
+	}
+	return comparables, nil
+}
+
+// UpdateRentEquivalents recomputes the full deposit and monthly cost of all rentals with a monthly
+// rate of rent per unit of deposit, it returns the number of rentals updated
+func (pr PostRepository) UpdateRentEquivalents(rate float64) (int64, error) {
+	result := pr.dbConnection.Model(&models.PostHistory{}).
+		Where("buy_mode = ?", types.Rent).
+		Updates(map[string]interface{}{
+			"monthly_cost": gorm.Expr("ROUND(rent + deposit * ?)", rate),
+			"full_deposit": gorm.Expr("ROUND(deposit + rent / ?)", rate),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// BackfillBuyModes sets the buy mode of the post histories saved without one from their prices, a deposit or rent
+// makes a rental and a price alone a sale. It returns the number of post histories updated.
+func (pr PostRepository) BackfillBuyModes() (int64, error) {
+	result := pr.dbConnection.Model(&models.PostHistory{}).
+		Where("buy_mode IS NULL OR buy_mode = ''").
+		Where("deposit > 0 OR rent > 0 OR price > 0").
+		Update("buy_mode", gorm.Expr("CASE WHEN deposit > 0 OR rent > 0 THEN ? ELSE ? END", types.Rent, types.Shopping))
+	return result.RowsAffected, result.Error
+}
+
 // save post history with all its dependencies
 func (daba PostRepository) PostHistorySaving(postHistory models.PostHistory, post models.Post, crawlHistory models.CrawlHistory) (models.PostHistory, error) {
 
@@ -157,13 +230,19 @@ func (daba PostRepository) PostHistorySaving(postHistory models.PostHistory, pos
 		BedroomNum:     postHistory.BedroomNum,
 		BuyMode:        postHistory.BuyMode,
 		Building:       postHistory.Building,
+		SellerType:     postHistory.SellerType,
 		Age:            postHistory.Age,
 		FloorsNum:      postHistory.FloorsNum,
 		HasStorage:     postHistory.HasStorage,
 		HasElevator:    postHistory.HasElevator,
 		HasParking:     postHistory.HasParking,
+		Amenities:      postHistory.Amenities,
+		Convertible:    postHistory.Convertible,
+		FullDeposit:    postHistory.FullDeposit,
+		MonthlyCost:    postHistory.MonthlyCost,
 		ImageURL:       postHistory.ImageURL,
 		Description:    postHistory.Description,
+		SearchText:     searchText(postHistory),
 		CrawlHistory:   crawlHistory,
 		CrawlHistoryID: crawlHistory.ID,
 	}
@@ -172,6 +251,11 @@ func (daba PostRepository) PostHistorySaving(postHistory models.PostHistory, pos
 	return myPostHistory, err
 }
 
+// searchText is the text keyword filters search in a post history
+func searchText(postHistory models.PostHistory) string {
+	return persian.Normalize(postHistory.Title + "\n" + postHistory.Description)
+}
+
 func (dba PostRepository) CrawlHistorySaving(crawlHistory models.CrawlHistory) (models.CrawlHistory, error) {
 	myCrawlHistory := models.CrawlHistory{
 		PostNum:     crawlHistory.PostNum,
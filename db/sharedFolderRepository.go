@@ -0,0 +1,131 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SharedFolderRepo interface {
+	FindMember(folderID uint, userID uint) (models.FolderMember, error)
+	FindMembers(folderID uint) ([]models.FolderMember, error)
+	FindMemberships(userID uint) ([]models.FolderMember, error)
+	SaveMember(member models.FolderMember) error
+	DeleteMember(folderID uint, userID uint) error
+	DeleteMembers(folderID uint) error
+
+	FindInvite(token string, now time.Time) (models.FolderInvite, error)
+	SaveInvite(invite models.FolderInvite) (models.FolderInvite, error)
+
+	FindFolderBookmarks(folderID uint) ([]models.Bookmark, error)
+	FindVotes(bookmarkIDs []uint) ([]models.BookmarkVote, error)
+	SaveVote(vote models.BookmarkVote) error
+	FindComments(bookmarkIDs []uint) ([]models.BookmarkComment, error)
+	SaveComment(comment models.BookmarkComment) (models.BookmarkComment, error)
+}
+
+type SharedFolderRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewSharedFolderRepository(dbConnection *gorm.DB) SharedFolderRepo {
+	return SharedFolderRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (r SharedFolderRepositoryImpl) FindMember(folderID uint, userID uint) (models.FolderMember, error) {
+	var member models.FolderMember
+	err := r.dbConnection.Where("folder_id = ? AND user_id = ?", folderID, userID).First(&member).Error
+	return member, err
+}
+
+// find the members of a folder with their users
+func (r SharedFolderRepositoryImpl) FindMembers(folderID uint) ([]models.FolderMember, error) {
+	members := []models.FolderMember{}
+	err := r.dbConnection.Preload("User").Where("folder_id = ?", folderID).Order("id").Find(&members).Error
+	return members, err
+}
+
+// find the folders shared with a user
+func (r SharedFolderRepositoryImpl) FindMemberships(userID uint) ([]models.FolderMember, error) {
+	members := []models.FolderMember{}
+	err := r.dbConnection.Preload("Folder").Where("user_id = ?", userID).Order("id").Find(&members).Error
+	return members, err
+}
+
+// save a member, a user joining a folder again gets the role of the new invite
+func (r SharedFolderRepositoryImpl) SaveMember(member models.FolderMember) error {
+	return r.dbConnection.Omit("Folder", "User").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "folder_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&member).Error
+}
+
+func (r SharedFolderRepositoryImpl) DeleteMember(folderID uint, userID uint) error {
+	return r.dbConnection.Unscoped().Where("folder_id = ? AND user_id = ?", folderID, userID).Delete(&models.FolderMember{}).Error
+}
+
+// stop sharing a folder, its members and invites are deleted
+func (r SharedFolderRepositoryImpl) DeleteMembers(folderID uint) error {
+	return r.dbConnection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("folder_id = ?", folderID).Delete(&models.FolderMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("folder_id = ?", folderID).Delete(&models.FolderInvite{}).Error
+	})
+}
+
+// find an invite that did not expire, with its folder
+func (r SharedFolderRepositoryImpl) FindInvite(token string, now time.Time) (models.FolderInvite, error) {
+	var invite models.FolderInvite
+	err := r.dbConnection.Preload("Folder").Where("token = ? AND expires_at > ?", token, now).First(&invite).Error
+	return invite, err
+}
+
+func (r SharedFolderRepositoryImpl) SaveInvite(invite models.FolderInvite) (models.FolderInvite, error) {
+	err := r.dbConnection.Omit("Folder").Create(&invite).Error
+	return invite, err
+}
+
+// find the bookmarks of all users in a folder, newest first
+func (r SharedFolderRepositoryImpl) FindFolderBookmarks(folderID uint) ([]models.Bookmark, error) {
+	bookmarks := []models.Bookmark{}
+	err := r.dbConnection.Preload("Post").Preload("Folder").
+		Where("folder_id = ?", folderID).
+		Order("id DESC").
+		Find(&bookmarks).Error
+	return bookmarks, err
+}
+
+func (r SharedFolderRepositoryImpl) FindVotes(bookmarkIDs []uint) ([]models.BookmarkVote, error) {
+	votes := []models.BookmarkVote{}
+	if len(bookmarkIDs) == 0 {
+		return votes, nil
+	}
+	err := r.dbConnection.Where("bookmark_id IN ?", bookmarkIDs).Find(&votes).Error
+	return votes, err
+}
+
+// save a vote, voting again replaces the previous vote of the user
+func (r SharedFolderRepositoryImpl) SaveVote(vote models.BookmarkVote) error {
+	return r.dbConnection.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bookmark_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&vote).Error
+}
+
+// find the comments on bookmarks, oldest first
+func (r SharedFolderRepositoryImpl) FindComments(bookmarkIDs []uint) ([]models.BookmarkComment, error) {
+	comments := []models.BookmarkComment{}
+	if len(bookmarkIDs) == 0 {
+		return comments, nil
+	}
+	err := r.dbConnection.Where("bookmark_id IN ?", bookmarkIDs).Order("id").Find(&comments).Error
+	return comments, err
+}
+
+func (r SharedFolderRepositoryImpl) SaveComment(comment models.BookmarkComment) (models.BookmarkComment, error) {
+	err := r.dbConnection.Create(&comment).Error
+	return comment, err
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+type SubscriptionRepository interface {
+	Create(subscription models.Subscription) (models.Subscription, error)
+	Update(subscription models.Subscription) error
+	FindByInvoiceID(invoiceID uint) (models.Subscription, error)
+	FindCurrent(userID uint, now time.Time) (models.Subscription, error)
+	FindLatest(userID uint) (models.Subscription, error)
+	FindEnding(before time.Time) ([]models.Subscription, error)
+	FindEnded(now time.Time) ([]models.Subscription, error)
+	FindGraceEnded(now time.Time) ([]models.Subscription, error)
+	FindPremiumWithoutSubscription() ([]models.User, error)
+}
+
+type SubscriptionRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewSubscriptionRepository(dbConnection *gorm.DB) SubscriptionRepository {
+	return SubscriptionRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (repo SubscriptionRepositoryImpl) Create(subscription models.Subscription) (models.Subscription, error) {
+	err := repo.dbConnection.Create(&subscription).Error
+	return subscription, err
+}
+
+func (repo SubscriptionRepositoryImpl) Update(subscription models.Subscription) error {
+	return repo.dbConnection.Save(&subscription).Error
+}
+
+// FindByInvoiceID returns the subscription activated by an invoice, or a zero subscription when there is none
+func (repo SubscriptionRepositoryImpl) FindByInvoiceID(invoiceID uint) (models.Subscription, error) {
+	var subscription models.Subscription
+	err := repo.dbConnection.Where("invoice_id = ?", invoiceID).Limit(1).Find(&subscription).Error
+	return subscription, err
+}
+
+// FindCurrent returns the active or grace subscription of the user covering now, or a zero subscription
+func (repo SubscriptionRepositoryImpl) FindCurrent(userID uint, now time.Time) (models.Subscription, error) {
+	var subscription models.Subscription
+	err := repo.dbConnection.
+		Where("user_id = ? AND status IN ? AND starts_at <= ?", userID, []models.SubscriptionStatus{models.SUBSCRIPTION_ACTIVE, models.SUBSCRIPTION_GRACE}, now).
+		Order("ends_at desc").Limit(1).Find(&subscription).Error
+	return subscription, err
+}
+
+// FindLatest returns the not expired subscription of the user that ends last, or a zero subscription
+func (repo SubscriptionRepositoryImpl) FindLatest(userID uint) (models.Subscription, error) {
+	var subscription models.Subscription
+	err := repo.dbConnection.
+		Where("user_id = ? AND status IN ?", userID, []models.SubscriptionStatus{models.SUBSCRIPTION_ACTIVE, models.SUBSCRIPTION_GRACE}).
+		Order("ends_at desc").Limit(1).Find(&subscription).Error
+	return subscription, err
+}
+
+// FindEnding returns the active subscriptions ending before the given time that were not reminded yet
+func (repo SubscriptionRepositoryImpl) FindEnding(before time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := repo.dbConnection.Preload("User").
+		Where("status = ? AND ends_at <= ? AND reminder_sent_at IS NULL", models.SUBSCRIPTION_ACTIVE, before).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// FindEnded returns the active subscriptions whose period is over
+func (repo SubscriptionRepositoryImpl) FindEnded(now time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := repo.dbConnection.Preload("User").
+		Where("status = ? AND ends_at <= ?", models.SUBSCRIPTION_ACTIVE, now).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// FindGraceEnded returns the subscriptions whose grace period is over
+func (repo SubscriptionRepositoryImpl) FindGraceEnded(now time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := repo.dbConnection.Preload("User").
+		Where("status = ? AND grace_ends_at <= ?", models.SUBSCRIPTION_GRACE, now).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// FindPremiumWithoutSubscription returns the Premium users who have no active or grace subscription, e.g. made
+// Premium before subscriptions existed
+func (repo SubscriptionRepositoryImpl) FindPremiumWithoutSubscription() ([]models.User, error) {
+	var users []models.User
+	covered := repo.dbConnection.Model(&models.Subscription{}).Select("user_id").
+		Where("status IN ?", []models.SubscriptionStatus{models.SUBSCRIPTION_ACTIVE, models.SUBSCRIPTION_GRACE})
+	err := repo.dbConnection.Where("type = ? AND id NOT IN (?)", models.PREMIUM, covered).Find(&users).Error
+	return users, err
+}
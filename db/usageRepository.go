@@ -0,0 +1,64 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UsageRepository interface {
+	Count(userID uint, action models.UsageAction, day time.Time) (uint, error)
+	Increment(userID uint, action models.UsageAction, day time.Time) (uint, error)
+	IncrementBelow(userID uint, action models.UsageAction, day time.Time, limit uint) (bool, error)
+}
+
+type UsageRepositoryImpl struct {
+	dbConnection *gorm.DB
+}
+
+func NewUsageRepository(dbConnection *gorm.DB) UsageRepository {
+	return UsageRepositoryImpl{dbConnection: dbConnection}
+}
+
+// Count returns how many times the user did action on day
+func (repo UsageRepositoryImpl) Count(userID uint, action models.UsageAction, day time.Time) (uint, error) {
+	var counter models.UsageCounter
+	result := repo.dbConnection.Where("user_id = ? AND day = ? AND action = ?", userID, day, action).Limit(1).Find(&counter)
+	return counter.Count, result.Error
+}
+
+// Increment counts one more action of the user on day and returns the new count
+func (repo UsageRepositoryImpl) Increment(userID uint, action models.UsageAction, day time.Time) (uint, error) {
+	counter := models.UsageCounter{UserID: userID, Day: day, Action: action, Count: 1}
+	err := repo.dbConnection.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "action"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr("usage_counters.count + 1"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&counter).Error
+	if err != nil {
+		return 0, err
+	}
+	return repo.Count(userID, action, day)
+}
+
+// IncrementBelow counts one more action of the user on day unless limit is reached, false when it is.
+// The check and the count are one statement, so actions sent at the same time cannot pass the limit together.
+func (repo UsageRepositoryImpl) IncrementBelow(userID uint, action models.UsageAction, day time.Time, limit uint) (bool, error) {
+	if limit == 0 {
+		return false, nil
+	}
+	counter := models.UsageCounter{UserID: userID, Day: day, Action: action, Count: 1}
+	result := repo.dbConnection.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "action"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr("usage_counters.count + 1"),
+			"updated_at": time.Now(),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{gorm.Expr("usage_counters.count < ?", limit)}},
+	}).Create(&counter)
+	return result.RowsAffected > 0, result.Error
+}
@@ -19,6 +19,7 @@ type UserRepository interface {
 	Delete(ID uint) error
 	UpdateUserType(ID uint, Type models.UserType) (models.User, error)
 	UpdateUserRole(ID uint, Role models.Role) (models.User, error)
+	ChangeUserRole(ID uint, from models.Role, to models.Role) (bool, error)
 	UpdateUser(ID uint, updatedData map[string]interface{}) (models.User, error)
 	GetLastFilterItem(userID uint) (*models.FilterItem, error)
 }
@@ -122,6 +123,12 @@ func (ur UserRepositoryImpl) UpdateUserRole(ID uint, Role models.Role) (models.U
 	return user, nil
 }
 
+// ChangeUserRole moves a user from one role to another, false when the user no longer has the from role
+func (ur UserRepositoryImpl) ChangeUserRole(ID uint, from models.Role, to models.Role) (bool, error) {
+	result := ur.dbConnection.Model(&models.User{}).Where("id = ? AND role = ?", ID, from).Update("role", to)
+	return result.RowsAffected > 0, result.Error
+}
+
 func (ur UserRepositoryImpl) UpdateUser(ID uint, updatedData map[string]interface{}) (models.User, error) {
 	var user models.User
 
@@ -11,6 +11,8 @@ type WatchListRepository interface {
 	FindAll() ([]models.WatchList, error)
 	Update(id uint, updatedData models.WatchList) (models.WatchList, error)
 	Delete(id uint) error
+	FindByUserID(userID uint) ([]models.WatchList, error)
+	CountByUserID(userID uint) (int64, error)
 }
 
 type WatchListRepositoryImpl struct {
@@ -60,3 +62,17 @@ func (repo *WatchListRepositoryImpl) Delete(id uint) error {
 	}
 	return nil
 }
+
+// FindByUserID retrieves the WatchLists of a user
+func (repo *WatchListRepositoryImpl) FindByUserID(userID uint) ([]models.WatchList, error) {
+	var watchLists []models.WatchList
+	err := repo.dbConnection.Where("user_id = ?", userID).Find(&watchLists).Error
+	return watchLists, err
+}
+
+// CountByUserID returns the number of WatchLists of a user
+func (repo *WatchListRepositoryImpl) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := repo.dbConnection.Model(&models.WatchList{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,35 @@
+// Package geo measures distances between points on the map
+package geo
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// Point is a location in degrees
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// NewPoint returns the point of optional coordinates, ok is false when one of them is missing
+func NewPoint(latitude *float64, longitude *float64) (Point, bool) {
+	if latitude == nil || longitude == nil {
+		return Point{}, false
+	}
+	return Point{Latitude: *latitude, Longitude: *longitude}, true
+}
+
+// Distance returns the great-circle distance between two points in kilometers, with the haversine formula
+func Distance(from Point, to Point) float64 {
+	fromLatitude, toLatitude := radians(from.Latitude), radians(to.Latitude)
+	latitudeDelta := toLatitude - fromLatitude
+	longitudeDelta := radians(to.Longitude - from.Longitude)
+
+	a := math.Sin(latitudeDelta/2)*math.Sin(latitudeDelta/2) +
+		math.Cos(fromLatitude)*math.Cos(toLatitude)*math.Sin(longitudeDelta/2)*math.Sin(longitudeDelta/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
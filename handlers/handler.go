@@ -1 +1,152 @@
 package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMetricsAddr  = ":8080"
+	defaultMaxCrawlAge  = 90 * time.Minute
+	databasePingTimeout = 2 * time.Second
+	statusOK            = "ok"
+	statusUnavailable   = "unavailable"
+)
+
+// Server serves the Prometheus metrics and the health endpoints
+type Server struct {
+	mux          *http.ServeMux
+	dbConnection *gorm.DB
+	lastCrawl    func() time.Time
+	maxCrawlAge  time.Duration
+	startedAt    time.Time
+	logger       *slog.Logger
+}
+
+// HealthResponse is the body of /healthz and /readyz
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewServer creates the server; lastCrawl reports the time of the last successful crawl cycle
+func NewServer(dbConnection *gorm.DB, lastCrawl func() time.Time) *Server {
+	maxCrawlAge := defaultMaxCrawlAge
+	if minutes, err := strconv.Atoi(utils.GetConfig("READY_MAX_CRAWL_AGE")); err == nil && minutes > 0 {
+		maxCrawlAge = time.Duration(minutes) * time.Minute
+	}
+
+	server := &Server{
+		mux:          http.NewServeMux(),
+		dbConnection: dbConnection,
+		lastCrawl:    lastCrawl,
+		maxCrawlAge:  maxCrawlAge,
+		startedAt:    time.Now(),
+		logger:       utils.NewLogger("HTTP_Server"),
+	}
+	server.mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	server.mux.HandleFunc("/healthz", server.healthz)
+	server.mux.HandleFunc("/readyz", server.readyz)
+	return server
+}
+
+// Handler returns the routes of the server
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start listens on METRICS_ADDR in the background
+func (s *Server) Start() {
+	addr := utils.GetConfig("METRICS_ADDR")
+	if addr == "" {
+		addr = defaultMetricsAddr
+	}
+	listen(s.logger, addr, s.Handler())
+}
+
+// listen serves handler on addr in the background
+func listen(logger *slog.Logger, addr string, handler http.Handler) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		logger.Info("http server listening", slog.String("addr", addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("http server stopped", slog.Any("error", err))
+		}
+	}()
+}
+
+// healthz reports whether the process is alive and can reach the database
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Status: statusOK, Checks: map[string]string{}}
+	s.checkDatabase(r.Context(), &response)
+	writeHealth(w, response)
+}
+
+// readyz additionally requires a recent successful crawl
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{Status: statusOK, Checks: map[string]string{}}
+	s.checkDatabase(r.Context(), &response)
+	s.checkCrawler(&response)
+	writeHealth(w, response)
+}
+
+func (s *Server) checkDatabase(ctx context.Context, response *HealthResponse) {
+	sqlDB, err := s.dbConnection.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, databasePingTimeout)
+		defer cancel()
+		err = sqlDB.PingContext(ctx)
+	}
+
+	if err != nil {
+		response.Status = statusUnavailable
+		response.Checks["database"] = err.Error()
+		return
+	}
+	response.Checks["database"] = statusOK
+}
+
+func (s *Server) checkCrawler(response *HealthResponse) {
+	lastCrawl := s.lastCrawl()
+	if lastCrawl.IsZero() {
+		// the first cycle may take a while, give it the same budget as a stale crawl
+		if time.Since(s.startedAt) <= s.maxCrawlAge {
+			response.Checks["crawler"] = "starting up"
+			return
+		}
+		response.Status = statusUnavailable
+		response.Checks["crawler"] = "no successful crawl yet"
+		return
+	}
+
+	age := time.Since(lastCrawl).Round(time.Second)
+	if age > s.maxCrawlAge {
+		response.Status = statusUnavailable
+		response.Checks["crawler"] = fmt.Sprintf("last successful crawl %s ago, more than %s", age, s.maxCrawlAge)
+		return
+	}
+	response.Checks["crawler"] = fmt.Sprintf("last successful crawl %s ago", age)
+}
+
+func writeHealth(w http.ResponseWriter, response HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if response.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/MagicalCrawler/RealEstateApp/jalali"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/payments"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const defaultPaymentAddr = ":8081"
+
+// PaymentProcessor activates the subscription of a payment the gateway redirected the user back for
+type PaymentProcessor interface {
+	HandleCallback(ctx context.Context, authority string, status string) (models.Subscription, error)
+}
+
+var paymentResultPage = template.Must(template.New("payment").Parse(`<!DOCTYPE html>
+<html dir="auto"><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body><h2>{{.Title}}</h2><p>{{.Text}}</p></body></html>`))
+
+// PaymentCallback handles the redirect of the payment gateway, e.g. /payments/callback?Authority=...&Status=OK
+func PaymentCallback(processor PaymentProcessor) http.Handler {
+	logger := utils.NewLogger("Payment_Callback")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authority := r.URL.Query().Get("Authority")
+		status := r.URL.Query().Get("Status")
+		if authority == "" {
+			writePaymentResult(w, http.StatusBadRequest, "Invalid payment", "The payment reference is missing.")
+			return
+		}
+
+		subscription, err := processor.HandleCallback(r.Context(), authority, status)
+		switch {
+		case err == nil:
+			writePaymentResult(w, http.StatusOK, "Payment successful",
+				fmt.Sprintf("Your Premium subscription is active until %s. You can go back to the bot.",
+					jalali.FromTime(subscription.EndsAt.In(utils.TehranLocation()))))
+		case errors.Is(err, payments.ErrUnknownPayment):
+			writePaymentResult(w, http.StatusNotFound, "Invalid payment", "This payment was not found.")
+		case errors.Is(err, payments.ErrPaymentFailed):
+			writePaymentResult(w, http.StatusOK, "Payment failed", "The payment was not completed. If money was taken from your account it will be returned by the bank.")
+		default:
+			logger.Error("payment callback failed", slog.String("authority", authority), slog.Any("error", err))
+			tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{URL: r.URL.String()})
+			writePaymentResult(w, http.StatusInternalServerError, "Payment is being checked", "We could not verify your payment right now, please reload this page in a few minutes.")
+		}
+	})
+}
+
+// NewPaymentHandler returns the routes of the payment server
+func NewPaymentHandler(processor PaymentProcessor) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/payments/callback", PaymentCallback(processor))
+	return mux
+}
+
+// StartPaymentServer serves the payment callback on PAYMENT_CALLBACK_ADDR in the background. It is kept apart from
+// the metrics server so the callback can be exposed to users while /metrics stays internal.
+func StartPaymentServer(processor PaymentProcessor) error {
+	addr := utils.GetConfig("PAYMENT_CALLBACK_ADDR")
+	if addr == "" {
+		addr = defaultPaymentAddr
+	}
+	metricsAddr := utils.GetConfig("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
+	if addr == metricsAddr {
+		return fmt.Errorf("payment callback address %q must differ from the metrics address", addr)
+	}
+	listen(utils.NewLogger("Payment_Server"), addr, NewPaymentHandler(processor))
+	return nil
+}
+
+func writePaymentResult(w http.ResponseWriter, statusCode int, title string, text string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	paymentResultPage.Execute(w, struct{ Title, Text string }{title, text})
+}
@@ -0,0 +1,202 @@
+// Package i18n holds the message catalogs of the bot, one per language, and writes numbers and dates the way
+// each language does
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/jalali"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+type Language string
+
+const (
+	English Language = "en"
+	Persian Language = "fa"
+)
+
+// Default is the language of users who never chose one
+const Default = English
+
+// Languages are the languages with a catalog, in the order they are offered
+var Languages = []Language{Persian, English}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogsProducer reads the catalogs once, a catalog that cannot be read is a build mistake
+var catalogsProducer func() map[Language]map[string]string = sync.OnceValue(func() map[Language]map[string]string {
+	catalogs := make(map[Language]map[string]string)
+	for _, language := range Languages {
+		content, err := locales.ReadFile(path.Join("locales", string(language)+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("read %s catalog: %v", language, err))
+		}
+		catalog := make(map[string]string)
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			panic(fmt.Sprintf("parse %s catalog: %v", language, err))
+		}
+		catalogs[language] = catalog
+	}
+	return catalogs
+})
+
+// Parse returns the language of a code like "fa" or "fa-IR", Default for codes without a catalog
+func Parse(code string) Language {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if base, _, found := strings.Cut(code, "-"); found {
+		code = base
+	}
+	for _, language := range Languages {
+		if code == string(language) {
+			return language
+		}
+	}
+	return Default
+}
+
+// Name returns the name of a language written in that language
+func Name(language Language) string {
+	return T(language, "language.name")
+}
+
+// T formats the message of a key in a language like fmt.Sprintf, numbers in the digits of the language.
+// Messages missing from a catalog fall back to the Default catalog, and to the key itself.
+func T(language Language, key string, args ...interface{}) string {
+	catalogs := catalogsProducer()
+	template, exists := catalogs[language][key]
+	if !exists {
+		language = Default
+		if template, exists = catalogs[Default][key]; !exists {
+			return key
+		}
+	}
+	return format(language, template, args)
+}
+
+// Has tells whether the catalog of a language has a message
+func Has(language Language, key string) bool {
+	_, exists := catalogsProducer()[language][key]
+	return exists
+}
+
+// Keys returns the keys of the catalog of a language, sorted
+func Keys(language Language) []string {
+	keys := make([]string, 0, len(catalogsProducer()[language]))
+	for key := range catalogsProducer()[language] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Labels returns the message of a key in every language that has it, to recognize button labels
+func Labels(key string) []string {
+	labels := make([]string, 0, len(Languages))
+	for _, language := range Languages {
+		if Has(language, key) {
+			labels = append(labels, T(language, key))
+		}
+	}
+	return labels
+}
+
+// Digits writes the digits of text in the digits of a language
+func Digits(language Language, text string) string {
+	if language == Persian {
+		return persian.Digits(text)
+	}
+	return text
+}
+
+// Number writes an integer with thousands separators, 1,250,000 in English and ۱٬۲۵۰٬۰۰۰ in Persian
+func Number(language Language, value int64) string {
+	digits := strconv.FormatInt(value, 10)
+	sign := ""
+	if value < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	separator := ","
+	if language == Persian {
+		separator = "٬"
+	}
+	var builder strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			builder.WriteString(separator)
+		}
+		builder.WriteRune(digit)
+	}
+	return Digits(language, sign+builder.String())
+}
+
+// Date writes the Jalali day of t in Tehran, like 1403/01/01 in English and ۱۴۰۳/۰۱/۰۱ in Persian
+func Date(language Language, t time.Time) string {
+	return Digits(language, jalali.FromTime(t.In(utils.TehranLocation())).String())
+}
+
+// DateTime writes the Jalali day and the minute of t in Tehran
+func DateTime(language Language, t time.Time) string {
+	return Date(language, t) + " " + Digits(language, t.In(utils.TehranLocation()).Format("15:04"))
+}
+
+// format is fmt.Sprintf writing the numbers of args in the digits of the language, the text of string
+// arguments like links and titles is kept as it is
+func format(language Language, template string, args []interface{}) string {
+	if language != Persian {
+		return fmt.Sprintf(template, args...)
+	}
+	var builder strings.Builder
+	next := 0
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' {
+			builder.WriteByte(template[i])
+			continue
+		}
+		end := i + 1
+		for end < len(template) && strings.IndexByte("+-# 0123456789.", template[end]) >= 0 {
+			end++
+		}
+		if end >= len(template) {
+			builder.WriteString(template[i:])
+			break
+		}
+		verb := template[i : end+1]
+		i = end
+		switch {
+		case verb == "%%":
+			builder.WriteByte('%')
+		case next >= len(args):
+			fmt.Fprintf(&builder, "%%!%c(MISSING)", verb[len(verb)-1])
+		default:
+			text := fmt.Sprintf(verb, args[next])
+			if isNumber(args[next]) {
+				text = persian.Digits(text)
+			}
+			builder.WriteString(text)
+			next++
+		}
+	}
+	return builder.String()
+}
+
+func isNumber(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
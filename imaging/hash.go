@@ -0,0 +1,33 @@
+package imaging
+
+import (
+	"image"
+	"math/bits"
+)
+
+// Hash returns the difference hash of an image: it is shrunk to 9x8 gray pixels and every bit tells whether
+// a pixel is brighter than its right neighbor. Resized, recompressed or slightly edited copies of a photo
+// get the same hash or one a few bits away.
+func Hash(img image.Image) uint64 {
+	small := resize(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luminance(small, x, y) > luminance(small, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Distance returns how many bits two hashes differ in, 0 to 64
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func luminance(img *image.RGBA, x int, y int) uint32 {
+	pixel := img.RGBAAt(x, y)
+	return 299*uint32(pixel.R) + 587*uint32(pixel.G) + 114*uint32(pixel.B)
+}
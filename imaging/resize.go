@@ -0,0 +1,98 @@
+// Package imaging decodes listing photos, fingerprints them with a perceptual hash and shrinks them to thumbnails
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	// ErrEmptyImage is returned for images without pixels
+	ErrEmptyImage = errors.New("empty image")
+	// ErrTooManyPixels is returned for images whose bitmap would be larger than allowed, a small compressed file
+	// can expand to gigabytes
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Decode reads a JPEG, PNG, GIF or WebP image. The size in its header is checked first, images of more than
+// maxPixels pixels are not decoded.
+func Decode(content []byte, maxPixels int) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrEmptyImage
+	}
+	if config.Width > maxPixels/config.Height {
+		return nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if img.Bounds().Empty() {
+		return nil, ErrEmptyImage
+	}
+	return img, nil
+}
+
+// EncodeJPEG writes an image as a JPEG of the given quality, 1 to 100
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Thumbnail shrinks an image to fit in a maxSize square, keeping its aspect ratio. Smaller images are not enlarged.
+func Thumbnail(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return resize(img, width, height)
+	}
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+	return resize(img, width, height)
+}
+
+// resize scales an image to width x height, every pixel is the average of the source pixels it covers
+func resize(img image.Image, width int, height int) *image.RGBA {
+	bounds := img.Bounds()
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			result.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / count >> 8), G: uint8(g / count >> 8), B: uint8(b / count >> 8), A: uint8(a / count >> 8),
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,177 @@
+// Package jalali converts dates between the Gregorian calendar and the Jalali (Solar Hijri) calendar used in Iran
+package jalali
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gregorianYears is the first year read as Gregorian, Jalali years stay below it for a few more centuries
+const gregorianYears = 1700
+
+// monthNames are the Persian names of the Jalali months
+var monthNames = [...]string{"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور", "مهر", "آبان", "آذر", "دی", "بهمن", "اسفند"}
+
+// Date is a day of the Jalali calendar, Month is 1 for Farvardin to 12 for Esfand
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// FromTime returns the Jalali day of the calendar day of t in its location
+func FromTime(t time.Time) Date {
+	return fromGregorian(t.Year(), int(t.Month()), t.Day())
+}
+
+// Today returns the current Jalali day in a location
+func Today(location *time.Location) Date {
+	return FromTime(time.Now().In(location))
+}
+
+// Time returns the start of the day in a location
+func (d Date) Time(location *time.Location) time.Time {
+	year, month, day := toGregorian(d.Year, d.Month, d.Day)
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+}
+
+// Valid tells whether the day exists, the last day of Esfand only in leap years
+func (d Date) Valid() bool {
+	if d.Month < 1 || d.Month > 12 || d.Day < 1 || d.Day > 31 {
+		return false
+	}
+	return FromTime(d.Time(time.UTC)) == d
+}
+
+// MonthName returns the Persian name of the month
+func (d Date) MonthName() string {
+	if d.Month < 1 || d.Month > 12 {
+		return ""
+	}
+	return monthNames[d.Month-1]
+}
+
+// String formats the day like 1403/01/01
+func (d Date) String() string {
+	return fmt.Sprintf("%04d/%02d/%02d", d.Year, d.Month, d.Day)
+}
+
+// Parse reads a day written like 1403/01/01 or 1403-1-1
+func Parse(value string) (Date, error) {
+	date, err := parseNumbers(value)
+	if err != nil {
+		return Date{}, err
+	}
+	if !date.Valid() {
+		return Date{}, fmt.Errorf("invalid jalali date %q", value)
+	}
+	return date, nil
+}
+
+// parseNumbers reads the year, month and day of a date separated by / or -
+func parseNumbers(value string) (Date, error) {
+	fields := strings.FieldsFunc(strings.TrimSpace(value), func(r rune) bool { return r == '/' || r == '-' })
+	numbers := make([]int, 0, 3)
+	for _, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return Date{}, fmt.Errorf("invalid date %q", value)
+		}
+		numbers = append(numbers, number)
+	}
+	if len(numbers) != 3 {
+		return Date{}, fmt.Errorf("invalid date %q", value)
+	}
+	return Date{Year: numbers[0], Month: numbers[1], Day: numbers[2]}, nil
+}
+
+// ParseDay reads a day written on either calendar, like 1403/01/01 or 2024-03-20, and returns its start in a
+// location. Years from 1700 are read as Gregorian, earlier ones as Jalali.
+func ParseDay(value string, location *time.Location) (time.Time, error) {
+	date, err := parseNumbers(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if date.Year < gregorianYears {
+		if !date.Valid() {
+			return time.Time{}, fmt.Errorf("invalid jalali date %q", value)
+		}
+		return date.Time(location), nil
+	}
+	day := time.Date(date.Year, time.Month(date.Month), date.Day, 0, 0, 0, 0, location)
+	if day.Year() != date.Year || int(day.Month()) != date.Month || day.Day() != date.Day {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return day, nil
+}
+
+// YearsSince returns the whole years from the start of a year to now, like the age of a building. Years from
+// 1700 are Gregorian, earlier ones Jalali, the year of now is taken in its location.
+func YearsSince(year int, now time.Time) int {
+	current := now.Year()
+	if year < gregorianYears {
+		current = FromTime(now).Year
+	}
+	if year <= 0 || year > current {
+		return 0
+	}
+	return current - year
+}
+
+// IsLeap tells whether a Jalali year has 366 days
+func IsLeap(year int) bool {
+	return Date{Year: year + 1, Month: 1, Day: 1}.Time(time.UTC).Sub(Date{Year: year, Month: 1, Day: 1}.Time(time.UTC)) == 366*24*time.Hour
+}
+
+// fromGregorian and toGregorian count days over the 33 year cycles of the arithmetic Jalali calendar, which
+// matches the astronomical one for the years in use
+func fromGregorian(gy int, gm int, gd int) Date {
+	daysBeforeMonth := [...]int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+	gy2 := gy
+	if gm > 2 {
+		gy2 = gy + 1
+	}
+	days := 355666 + 365*gy + (gy2+3)/4 - (gy2+99)/100 + (gy2+399)/400 + gd + daysBeforeMonth[gm-1]
+	jy := -1595 + 33*(days/12053)
+	days %= 12053
+	jy += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		jy += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+	if days < 186 {
+		return Date{Year: jy, Month: 1 + days/31, Day: 1 + days%31}
+	}
+	return Date{Year: jy, Month: 7 + (days-186)/30, Day: 1 + (days-186)%30}
+}
+
+func toGregorian(jy int, jm int, jd int) (int, int, int) {
+	jy += 1595
+	days := -355668 + 365*jy + (jy/33)*8 + (jy%33+3)/4 + jd
+	if jm < 7 {
+		days += (jm - 1) * 31
+	} else {
+		days += (jm-7)*30 + 186
+	}
+	gy := 400 * (days / 146097)
+	days %= 146097
+	if days > 36524 {
+		days--
+		gy += 100 * (days / 36524)
+		days %= 36524
+		if days >= 365 {
+			days++
+		}
+	}
+	gy += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		gy += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+	// time.Date carries the day of the year over the months
+	return gy, 1, days + 1
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "realestate"
+
+// Registry holds every metric exposed on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	CrawlerPages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "pages_total",
+		Help:      "Pages requested by the crawlers.",
+	}, []string{"source", "city"})
+
+	CrawlerBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "fetched_bytes_total",
+		Help:      "Bytes of page content fetched by the crawlers.",
+	}, []string{"source", "city"})
+
+	CrawlerPosts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "posts_total",
+		Help:      "Posts extracted by the crawlers.",
+	}, []string{"source", "city"})
+
+	CrawlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "errors_total",
+		Help:      "Failed crawl attempts by error class.",
+	}, []string{"source", "city", "class"})
+
+	CrawlerTaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "task_duration_seconds",
+		Help:      "Time spent crawling one city from one source.",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1200, 2400},
+	}, []string{"source", "city"})
+
+	CrawlerCycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "cycle_duration_seconds",
+		Help:      "Time spent on a whole crawl cycle.",
+		Buckets:   []float64{60, 300, 600, 1200, 1800, 3600, 7200},
+	})
+
+	CrawlerLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last crawl cycle that saved posts.",
+	})
+
+	BotUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "bot",
+		Name:      "updates_total",
+		Help:      "Telegram updates received by type.",
+	}, []string{"type"})
+
+	BotCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "bot",
+		Name:      "commands_total",
+		Help:      "Bot commands handled by outcome (ok, denied).",
+	}, []string{"command", "outcome"})
+
+	BotCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "bot",
+		Name:      "command_duration_seconds",
+		Help:      "Time spent executing a bot command.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	TelegramRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telegram",
+		Name:      "requests_total",
+		Help:      "Requests sent to the Telegram Bot API by method.",
+	}, []string{"method"})
+
+	TelegramErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telegram",
+		Name:      "errors_total",
+		Help:      "Failed Telegram Bot API requests by method.",
+	}, []string{"method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		CrawlerPages,
+		CrawlerBytes,
+		CrawlerPosts,
+		CrawlerErrors,
+		CrawlerTaskDuration,
+		CrawlerCycleDuration,
+		CrawlerLastSuccess,
+		BotUpdates,
+		BotCommands,
+		BotCommandDuration,
+		TelegramRequests,
+		TelegramErrors,
+	)
+}
+
+var registerDBStats sync.Once
+
+// RegisterDBStats exposes the connection pool stats of the database
+func RegisterDBStats(sqlDB *sql.DB, name string) {
+	registerDBStats.Do(func() {
+		Registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, name))
+	})
+}
+
+// ObserveSince records the seconds passed since start in the histogram
+func ObserveSince(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
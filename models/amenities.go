@@ -0,0 +1,19 @@
+package models
+
+import "github.com/MagicalCrawler/RealEstateApp/types"
+
+// Amenities are the amenities of a listing found in its description, storage, parking and elevator have
+// their own PostHistory columns
+type Amenities struct {
+	Balcony     bool
+	Rooftop     bool
+	Pool        bool
+	Sauna       bool
+	Jacuzzi     bool
+	Gym         bool
+	Lobby       bool
+	Guard       bool
+	Renovation  types.Renovation   `gorm:"type:varchar(31)"`
+	Document    types.DocumentType `gorm:"type:varchar(31)"`
+	Orientation types.Orientation  `gorm:"type:varchar(15)"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// SettingKey names a setting admins can change from the bot
+type SettingKey string
+
+const (
+	RENT_CONVERSION_RATE SettingKey = "rent_conversion_rate"
+)
+
+// AppSetting is a setting stored in the database, it overrides the config of the same meaning
+type AppSetting struct {
+	Key       SettingKey `gorm:"type:varchar(63);uniqueIndex"`
+	Value     string     `gorm:"type:text"`
+	UpdatedBy uint       // user who changed it last
+	gorm.Model
+}
@@ -3,9 +3,24 @@ package models
 import "gorm.io/gorm"
 
 type Bookmark struct {
-	User   User
-	Post   Post
-	UserID uint `gorm:"not null;foreignKey:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	PostID uint `gorm:"foreignKey:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	User     User
+	Post     Post
+	UserID   uint            `gorm:"not null;foreignKey:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	PostID   uint            `gorm:"foreignKey:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Note     string          `gorm:"type:text"` // personal note of the user
+	FolderID *uint           `gorm:"index"`     // nil when the bookmark is in no folder
+	Folder   *BookmarkFolder `gorm:"constraint:OnDelete:SET NULL;"`
+	// the prices the user was last told about, a crawl finding other prices notifies the user
+	Price   int64
+	Deposit int64
+	Rent    int64
+	gorm.Model
+}
+
+// BookmarkFolder is a named collection of bookmarks of a user, the user may share it with others
+type BookmarkFolder struct {
+	UserID uint   `gorm:"not null;uniqueIndex:idx_bookmark_folder_name"`
+	User   User   // the owner
+	Name   string `gorm:"type:varchar(63);not null;uniqueIndex:idx_bookmark_folder_name"`
 	gorm.Model
 }
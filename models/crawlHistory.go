@@ -7,8 +7,8 @@ import (
 
 type CrawlHistory struct {
 	PostNum     uint
-	CpuUsage    float32 `gorm:"type:decimal(7,2)"`
-	MemoryUsage float32 `gorm:"type:decimal(7,2)"`
+	CpuUsage    float32 `gorm:"type:decimal(7,2)"` // average CPU percent of the crawler process
+	MemoryUsage float32 `gorm:"type:decimal(7,2)"` // average resident memory of the crawler process in MB
 	RequestsNum uint
 	StartedAt   time.Time
 	FinishedAt  time.Time
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+// CrawlRun is the telemetry of crawling one city from one source inside a crawl cycle
+type CrawlRun struct {
+	CrawlHistoryID uint
+	CrawlHistory   CrawlHistory
+	Source         types.WebsiteSource `gorm:"type:string;index"`
+	City           string              `gorm:"type:varchar(63);index"`
+	StartedAt      time.Time           `gorm:"index"`
+	FinishedAt     time.Time
+	DurationMs     int64
+	RequestsNum    uint
+	SuccessNum     uint
+	FailureNum     uint
+	ErrorCounts    string `gorm:"type:text"` // JSON object of error class to count
+	BytesFetched   int64
+	PostNum        uint
+	NewPostNum     uint
+	UpdatedPostNum uint
+	ProcessRSS     uint64 // resident memory of the whole process in bytes when the run finished
+	// ProcessWideCPU is the CPU percent of the whole process while the run was going. The runs of a chunk go at
+	// the same time and each is charged the CPU of all of them, it is not the CPU of this run alone.
+	ProcessWideCPU float32 `gorm:"column:process_cpu;type:decimal(7,2)"`
+	ConcurrentRuns uint    // the runs of the chunk that were going at the same time, this one included
+	gorm.Model
+}
+
+// CrawlTrend aggregates the crawl runs of one source on one day
+type CrawlTrend struct {
+	Day            time.Time
+	Source         types.WebsiteSource
+	Runs           uint
+	RequestsNum    uint
+	FailureNum     uint
+	PostNum        uint
+	NewPostNum     uint
+	UpdatedPostNum uint
+	BytesFetched   int64
+	AvgDurationMs  int64
+	PeakRSS        uint64
+	ErrorCounts    map[string]uint
+}
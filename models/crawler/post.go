@@ -31,6 +31,10 @@ type Post struct {
 	Deposit             string
 	MonthlyRent         string
 	DepositOnRentDesc   string
+	SellerType          string  // the seller label of the page, e.g. "شخصی" or "مشاور املاک"
+	Category            string  // the category breadcrumbs of the page, e.g. "املاک فروش مسکونی آپارتمان"
+	Latitude            float64 // 0 when the page has no map
+	Longitude           float64
 	RentalMetadata      *RentalMetadata
 	Website             types.WebsiteSource
 }
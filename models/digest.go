@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DigestFrequency string
+
+const (
+	DIGEST_DAILY  DigestFrequency = "daily"
+	DIGEST_WEEKLY DigestFrequency = "weekly"
+)
+
+// DigestSubscription sends a summary of what changed for a saved filter on a schedule, in Tehran time
+type DigestSubscription struct {
+	UserID       uint `gorm:"index"`
+	User         User
+	FilterItemID uint `gorm:"not null;uniqueIndex"`
+	FilterItem   FilterItem
+	Frequency    DigestFrequency `gorm:"type:varchar(15)"`
+	Hour         int             // 0-23
+	Weekday      time.Weekday    // the day of weekly digests
+	gorm.Model
+}
+
+// DigestRun is one scheduled digest of a subscription and the period it covers. A run that was not sent is
+// retried, and the next run covers everything since the last sent one, so digests missed while the bot was
+// down are caught up.
+type DigestRun struct {
+	SubscriptionID uint `gorm:"not null;uniqueIndex:idx_digest_run_schedule"`
+	Subscription   DigestSubscription
+	ScheduledFor   time.Time `gorm:"not null;uniqueIndex:idx_digest_run_schedule"`
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	NewListings    int
+	PriceDrops     int
+	Removals       int
+	SentAt         *time.Time `gorm:"index"`
+	Error          string     `gorm:"type:text"`
+	gorm.Model
+}
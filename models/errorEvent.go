@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+type ErrorCategory string
+type ErrorStatus string
+
+const (
+	CRAWLER_ERROR ErrorCategory = "crawler"
+	SERVICE_ERROR ErrorCategory = "service"
+	BOT_ERROR     ErrorCategory = "bot"
+)
+
+const (
+	ERROR_OPEN         ErrorStatus = "open"
+	ERROR_ACKNOWLEDGED ErrorStatus = "acknowledged"
+	ERROR_RESOLVED     ErrorStatus = "resolved"
+)
+
+// ErrorEvent groups every occurrence of the same failure under one fingerprint
+type ErrorEvent struct {
+	Fingerprint string              `gorm:"type:varchar(40);uniqueIndex"`
+	Category    ErrorCategory       `gorm:"type:varchar(15);index"`
+	Status      ErrorStatus         `gorm:"type:varchar(15);index"`
+	Source      types.WebsiteSource `gorm:"type:varchar(15)"`
+	City        string              `gorm:"type:varchar(63)"`
+	URL         string              `gorm:"type:text"` // URL of the last occurrence
+	Message     string              `gorm:"type:text"`
+	Stack       string              `gorm:"type:text"`
+	Occurrences uint
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time `gorm:"index"`
+	gorm.Model
+}
+
+// ErrorEventFilter narrows the error events listed to admins, empty fields match everything
+type ErrorEventFilter struct {
+	Category ErrorCategory
+	Status   ErrorStatus
+}
@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// SORT_BY_MONTHLY_COST sorts search results by the rent without deposit, cheapest first
+const SORT_BY_MONTHLY_COST = "monthly_cost"
+
 type FilterItem struct {
 	ID               uint        `gorm:"primaryKey" json:"id"`
 	PriceMin         float64     `json:"price_min"`
@@ -24,6 +27,20 @@ type FilterItem struct {
 	HasElevator      bool        `json:"has_elevator"`
 	CreatedDateStart time.Time   `json:"created_date_start"`
 	CreatedDateEnd   time.Time   `json:"created_date_end"`
+	MonthlyCostMin   float64     `json:"monthly_cost_min"`
+	MonthlyCostMax   float64     `json:"monthly_cost_max"`
+	DepositMax       float64     `json:"deposit_max"`
+	RentMax          float64     `json:"rent_max"`
+	Affordable       bool        `json:"affordable"` // PriceMax, DepositMax and RentMax are the ranges of the affordability profile
+	SortBy           string      `json:"sort_by"` // empty or monthly_cost
+	HideRisky        bool        `json:"hide_risky"`
+	SellerType       string      `json:"seller_type"` // owner, agency or empty for both
+	IncludeKeywords  string      `json:"include_keywords"` // comma separated, a listing must contain all of them
+	ExcludeKeywords  string      `json:"exclude_keywords"` // comma separated, a listing must contain none of them
+	Amenities        string      `json:"amenities"` // comma separated types.Amenity values a listing must all have
+	Renovation       string      `json:"renovation"`
+	Document         string      `json:"document"`
+	Orientation      string      `json:"orientation"`
 	UserID           uint        `json:"user_id"` // Foreign Key
 	User             User        `gorm:"foreignKey:UserID"` // Define the relationship to the User model
 	WatchLists       []WatchList `gorm:"foreignKey:FilterItemID"` // Optional, for reverse lookup
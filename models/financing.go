@@ -0,0 +1,25 @@
+package models
+
+import "gorm.io/gorm"
+
+// LoanProduct is a home loan offered by a bank, admins configure the products users can choose from
+type LoanProduct struct {
+	Name       string  `gorm:"type:varchar(63);uniqueIndex"`
+	AnnualRate float64 // interest in percent per year
+	TermMonths int
+	MaxLTV     float64 // the largest share of the price the loan may cover, in percent
+	MaxAmount  int64   // 0 when the loan is only limited by MaxLTV
+	Active     bool    `gorm:"index"`
+	gorm.Model
+}
+
+// AffordabilityProfile is what a user can spend on a home, the search ranges they can afford are derived from it
+type AffordabilityProfile struct {
+	UserID        uint `gorm:"uniqueIndex"`
+	User          User
+	Cash          int64 // cash on hand for the down payment or the deposit
+	MonthlyBudget int64 // what the user can pay each month, as loan payments or rent
+	LoanProductID *uint // nil to buy without a loan
+	LoanProduct   *LoanProduct
+	gorm.Model
+}
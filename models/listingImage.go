@@ -0,0 +1,26 @@
+package models
+
+import "gorm.io/gorm"
+
+type ImageStatus string
+
+const (
+	IMAGE_ARCHIVED ImageStatus = "archived"
+	IMAGE_FAILED   ImageStatus = "failed" // the photo could not be downloaded or decoded, only its link is kept
+)
+
+// ListingImage is a photo of a post history copied to the blob store, so it outlives the link of the website.
+// Histories of a post showing the same photo share its blobs.
+type ListingImage struct {
+	PostHistoryID uint        `gorm:"index"`
+	PostID        uint        `gorm:"index"`
+	Position      int         // order of the photo in the listing, from 0
+	SourceURL     string      `gorm:"type:text;index"`
+	Status        ImageStatus `gorm:"type:varchar(15)"`
+	BlobKey       string      `gorm:"type:varchar(127);index"`
+	ThumbnailKey  string      `gorm:"type:varchar(127)"`
+	Hash          int64       `gorm:"index"` // perceptual hash, the bits of imaging.Hash
+	Width         int
+	Height        int
+	gorm.Model
+}
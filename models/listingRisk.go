@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RiskReason is a sign that a listing is fake, bait or spam
+type RiskReason string
+type RiskReview string
+
+const (
+	PRICE_TOO_LOW    RiskReason = "price_too_low"    // far below the neighborhood median
+	PRICE_TOO_HIGH   RiskReason = "price_too_high"   // far above the neighborhood median
+	DUPLICATE_IMAGES RiskReason = "duplicate_images" // photos also used by other posts
+	REPOSTED_TEXT    RiskReason = "reposted_text"    // the same description as another post
+	PHONE_NUMBER     RiskReason = "phone_number"     // a phone number in the description
+	AGENCY_TEXT      RiskReason = "agency_text"      // agency advertising in the description
+)
+
+const (
+	RISK_PENDING   RiskReview = "pending"
+	RISK_CONFIRMED RiskReview = "confirmed" // an admin agreed the listing is fake or spam
+	RISK_DISMISSED RiskReview = "dismissed" // an admin found the listing legit
+)
+
+// HIGH_RISK_SCORE is the score from which a listing is hidden from users who hide risky listings,
+// unless an admin dismissed its risk
+const HIGH_RISK_SCORE = 50
+
+// ListingRisk is the fraud and spam score, from 0 to 100, of a post history
+type ListingRisk struct {
+	PostHistoryID uint `gorm:"uniqueIndex"`
+	PostHistory   PostHistory
+	Score         int        `gorm:"index"`
+	Reasons       string     // comma separated RiskReason values
+	Review        RiskReview `gorm:"type:varchar(15);index"`
+	ReviewedBy    uint
+	ReviewedAt    *time.Time
+	gorm.Model
+}
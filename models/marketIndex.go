@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+// MarketIndex summarizes the listings of one week in a city. An empty Neighborhood or Building
+// means the row covers all neighborhoods or building types. Prices are in Rials per square meter.
+type MarketIndex struct {
+	Week                time.Time      `gorm:"uniqueIndex:idx_market_index"` // start of the week (Saturday, Tehran time)
+	City                string         `gorm:"type:varchar(63);uniqueIndex:idx_market_index"`
+	Neighborhood        string         `gorm:"type:varchar(63);uniqueIndex:idx_market_index"`
+	Building            types.Building `gorm:"type:varchar(31);uniqueIndex:idx_market_index"`
+	SaleListings        uint
+	PricePerMeterP25    float64
+	PricePerMeterMedian float64
+	PricePerMeterP75    float64
+	RentListings        uint
+	RentPerMeterP25     float64
+	RentPerMeterMedian  float64
+	RentPerMeterP75     float64
+	DepositToRentMedian float64 // months of rent the deposit is worth
+	gorm.Model
+}
+
+// MarketIndexQuery selects the index rows of one city, neighborhood and building type
+type MarketIndexQuery struct {
+	City         string
+	Neighborhood string
+	Building     types.Building
+	Since        time.Time
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Permission names something a role may do in the bot, roles are mapped to permissions in role_permissions
+type Permission string
+
+const (
+	PERM_SEARCH            Permission = "search"            // filters, searches, bookmarks, watchlists and digests
+	PERM_EXPORT            Permission = "export"            // CSV exports of search results
+	PERM_MANAGE_USERS      Permission = "manage_users"      // clients, Premium grants and the filters of users
+	PERM_VIEW_ERRORS       Permission = "view_errors"       // the error inbox and the crawl monitor
+	PERM_CONFIGURE_CRAWLER Permission = "configure_crawler" // crawler settings and the list of crawled advertisements
+	PERM_REVIEW_LISTINGS   Permission = "review_listings"   // the queue of risky listings
+	PERM_MANAGE_SETTINGS   Permission = "manage_settings"   // the rent conversion rate and loan products
+	PERM_MANAGE_ADMINS     Permission = "manage_admins"     // admins, demotions, role permissions and the audit log
+)
+
+// Permissions are all permissions, in the order they are listed
+var Permissions = []Permission{
+	PERM_SEARCH, PERM_EXPORT, PERM_MANAGE_USERS, PERM_VIEW_ERRORS, PERM_CONFIGURE_CRAWLER, PERM_REVIEW_LISTINGS,
+	PERM_MANAGE_SETTINGS, PERM_MANAGE_ADMINS,
+}
+
+// DefaultPermissions are the permissions of the roles when role_permissions is first created
+var DefaultPermissions = map[Role][]Permission{
+	USER:        {PERM_SEARCH, PERM_EXPORT},
+	ADMIN:       {PERM_SEARCH, PERM_EXPORT, PERM_MANAGE_USERS, PERM_VIEW_ERRORS, PERM_REVIEW_LISTINGS, PERM_MANAGE_SETTINGS},
+	SUPER_ADMIN: Permissions,
+}
+
+// Privileged tells whether using the permission is recorded in the audit log, everything beyond what users do
+func (p Permission) Privileged() bool {
+	return p != "" && p != PERM_SEARCH && p != PERM_EXPORT
+}
+
+// Known tells whether the permission is one of Permissions
+func (p Permission) Known() bool {
+	for _, permission := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles are all roles, highest first
+var Roles = []Role{SUPER_ADMIN, ADMIN, USER}
+
+var roleNames = map[Role]string{SUPER_ADMIN: "super_admin", ADMIN: "admin", USER: "user"}
+
+func (r Role) String() string {
+	if name, exists := roleNames[r]; exists {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseRole reads a role name like "admin"
+func ParseRole(name string) (Role, bool) {
+	for role, roleName := range roleNames {
+		if name == roleName {
+			return role, true
+		}
+	}
+	return USER, false
+}
+
+// RolePermission grants a permission to every user of a role
+type RolePermission struct {
+	gorm.Model
+	Role       Role       `gorm:"uniqueIndex:idx_role_permission"`
+	Permission Permission `gorm:"type:varchar(32);uniqueIndex:idx_role_permission"`
+}
+
+// AuditLog records a privileged action: who, with which role, what and when (CreatedAt)
+type AuditLog struct {
+	gorm.Model
+	UserID  uint `gorm:"index"`
+	User    User
+	Role    Role
+	Action  string `gorm:"type:varchar(64);index"` // the command or callback, like create_admin
+	Target  string // what it was done to, like "admin=12"
+	Allowed bool   // false for attempts without the permission
+}
@@ -1,13 +1,50 @@
 package models
 
 import (
+	"time"
+
 	"github.com/MagicalCrawler/RealEstateApp/types"
 	"gorm.io/gorm"
 )
 
+type PostStatus string
+
+const (
+	POST_ACTIVE  PostStatus = "active"
+	POST_REMOVED PostStatus = "removed" // deleted by its owner or the website
+	POST_EXPIRED PostStatus = "expired" // its publication period ended
+	POST_SOLD    PostStatus = "sold"    // sold or rented, as its page says
+)
+
 type Post struct {
-	UniqueCode string              `gorm:"not null;unique"`      // each ads has a unique code in divar
-	Website    types.WebsiteSource `gorm:"not null;type:string"` // for search between some sources
-	WatchedNum uint
+	UniqueCode  string              `gorm:"not null;unique"`      // each ads has a unique code in divar
+	Website     types.WebsiteSource `gorm:"not null;type:string"` // for search between some sources
+	WatchedNum  uint
+	Status      PostStatus `gorm:"type:varchar(15);default:active;index"`
+	FirstSeenAt time.Time  // first crawl that found the post
+	LastSeenAt  time.Time  `gorm:"index"` // last crawl or check that found it live
+	CheckedAt   *time.Time // last time the lifecycle tracker opened its page
+	RemovedAt   *time.Time // when it was found removed, expired or sold
 	gorm.Model
 }
+
+// Active tells whether the post is still listed on its website, as far as we know
+func (p Post) Active() bool {
+	return p.Status == "" || p.Status == POST_ACTIVE
+}
+
+// DaysOnMarket returns how many days the post has been listed, until it went away or until now
+func (p Post) DaysOnMarket(now time.Time) int {
+	firstSeen := p.FirstSeenAt
+	if firstSeen.IsZero() {
+		firstSeen = p.CreatedAt
+	}
+	end := now
+	if p.RemovedAt != nil {
+		end = *p.RemovedAt
+	}
+	if firstSeen.IsZero() || end.Before(firstSeen) {
+		return 0
+	}
+	return int(end.Sub(firstSeen).Hours() / 24)
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/MagicalCrawler/RealEstateApp/types"
 )
 
@@ -17,20 +19,42 @@ type PostHistory struct {
 	Neighborhood   string `gorm:"type:varchar(63)"`
 	Area           int
 	BedroomNum     int
-	BuyMode        types.BuyMode  `gorm:"type:string"`
-	Building       types.Building `gorm:"type:string"`
+	BuyMode        types.BuyMode    `gorm:"type:string"`
+	Building       types.Building   `gorm:"type:string"`
+	SellerType     types.SellerType `gorm:"type:varchar(15);index"` // empty when unknown
 	Age            uint8
 	FloorsNum      uint8
 	HasStorage     bool
 	HasParking     bool
 	HasElevator    bool
-	ImageURL       string `gorm:"type:text"`
-	Description    string `gorm:"type:text"`
+	Amenities      Amenities      `gorm:"embedded;embeddedPrefix:amenity_"`
+	ImageURL       string         `gorm:"type:text"`
+	Images         []ListingImage // archived copies of the photos of ImageURL
+	Description    string         `gorm:"type:text"`
+	SearchText     string         `gorm:"type:text"` // title and description normalized for keyword search
 	CrawlHistory   CrawlHistory
 	CrawlHistoryID uint
+	Latitude       *float64 // nil when the page had no map
+	Longitude      *float64
 	Capacity       string
 	NormalDays     string
 	Weekend        string
 	Holidays       string
 	CostPerPerson  string
+	Convertible    bool      // the deposit and rent can be traded against each other
+	FullDeposit    int64     // deposit of the same rental without rent
+	MonthlyCost    int64     `gorm:"index"` // rent of the same rental without deposit
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+// ComparableQuery selects the listings a post history can be compared with
+type ComparableQuery struct {
+	City          string
+	BuyMode       types.BuyMode
+	Building      types.Building
+	AreaMin       int
+	AreaMax       int
+	ExcludePostID uint
+	Since         time.Time
+	Limit         int
 }
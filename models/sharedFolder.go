@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FolderRole is what a user may do in a shared bookmark folder
+type FolderRole string
+
+const (
+	FOLDER_OWNER  FolderRole = "owner"  // the user who created the folder, never stored in FolderMember
+	FOLDER_EDITOR FolderRole = "editor" // may add listings to the folder
+	FOLDER_VIEWER FolderRole = "viewer" // may see, vote and comment on the listings of the folder
+)
+
+// FolderMember is a user a bookmark folder is shared with
+type FolderMember struct {
+	FolderID uint           `gorm:"not null;uniqueIndex:idx_folder_member"`
+	Folder   BookmarkFolder `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID   uint           `gorm:"not null;uniqueIndex:idx_folder_member;index"`
+	User     User
+	Role     FolderRole `gorm:"type:varchar(15)"`
+	gorm.Model
+}
+
+// FolderInvite is a link that makes whoever opens it a member of a folder
+type FolderInvite struct {
+	Token     string         `gorm:"type:varchar(32);uniqueIndex"`
+	FolderID  uint           `gorm:"not null;index"`
+	Folder    BookmarkFolder `gorm:"constraint:OnDelete:CASCADE;"`
+	Role      FolderRole     `gorm:"type:varchar(15)"`
+	ExpiresAt time.Time
+	gorm.Model
+}
+
+// BookmarkVote is the thumbs up, 1, or down, -1, of a member on a listing of a shared folder
+type BookmarkVote struct {
+	BookmarkID uint `gorm:"not null;uniqueIndex:idx_bookmark_vote"`
+	UserID     uint `gorm:"not null;uniqueIndex:idx_bookmark_vote"`
+	Value      int8
+	gorm.Model
+}
+
+// BookmarkComment is a comment of a member on a listing of a shared folder
+type BookmarkComment struct {
+	BookmarkID uint   `gorm:"not null;index"`
+	UserID     uint   `gorm:"not null"`
+	Text       string `gorm:"type:text"`
+	gorm.Model
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionStatus string
+type InvoiceStatus string
+
+const (
+	SUBSCRIPTION_ACTIVE  SubscriptionStatus = "active"
+	SUBSCRIPTION_GRACE   SubscriptionStatus = "grace"
+	SUBSCRIPTION_EXPIRED SubscriptionStatus = "expired"
+)
+
+const (
+	INVOICE_PENDING InvoiceStatus = "pending"
+	INVOICE_PAID    InvoiceStatus = "paid"
+	INVOICE_FAILED  InvoiceStatus = "failed"
+)
+
+// Subscription is a paid (or granted) Premium period of a user
+type Subscription struct {
+	UserID         uint `gorm:"index"`
+	User           User
+	Plan           string             `gorm:"type:varchar(31)"`
+	Status         SubscriptionStatus `gorm:"type:varchar(15);index"`
+	StartsAt       time.Time
+	EndsAt         time.Time `gorm:"index"`
+	GraceEndsAt    time.Time // the user keeps Premium until then when the subscription is not renewed
+	InvoiceID      *uint     `gorm:"uniqueIndex"` // nil when an admin granted the subscription
+	ReminderSentAt *time.Time
+	gorm.Model
+}
+
+// Invoice is a payment request sent to a payment gateway
+type Invoice struct {
+	UserID    uint `gorm:"index"`
+	User      User
+	Plan      string        `gorm:"type:varchar(31)"`
+	Amount    int64         // in Rials
+	Gateway   string        `gorm:"type:varchar(31)"`
+	Authority string        `gorm:"type:varchar(63);uniqueIndex"` // payment reference given by the gateway
+	Status    InvoiceStatus `gorm:"type:varchar(15);index"`
+	RefID     string        `gorm:"type:varchar(63)"` // transaction reference after verification
+	PaidAt    *time.Time
+	gorm.Model
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UsageAction string
+
+const (
+	SEARCH_USAGE UsageAction = "search"
+)
+
+// UsageCounter counts how many times a user did a metered action on one day
+type UsageCounter struct {
+	UserID uint        `gorm:"uniqueIndex:idx_usage_user_day_action"`
+	Day    time.Time   `gorm:"type:date;uniqueIndex:idx_usage_user_day_action"`
+	Action UsageAction `gorm:"type:varchar(15);uniqueIndex:idx_usage_user_day_action"`
+	Count  uint
+	gorm.Model
+}
@@ -25,4 +25,7 @@ type User struct {
 	Type             UserType
 	FilterItems      []FilterItem `gorm:"foreignKey:UserID"` // Reverse relationship: a user has many filter items
 	LastFilterItemID *uint        // Nullable field to store the last selected filter ID
+	Latitude         *float64     // the point the user compares listings to, like their workplace
+	Longitude        *float64
+	Language         string `gorm:"type:varchar(7)"` // the language of the bot texts, see i18n.Parse
 }
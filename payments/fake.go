@@ -0,0 +1,66 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type fakePayment struct {
+	amount   int64
+	paid     bool
+	verified bool
+}
+
+// FakeGateway is an in-memory gateway for local runs and tests. Its payment URL leads straight
+// to the callback; payments succeed once MarkPaid is called, or right away with autoPay.
+type FakeGateway struct {
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	sequence int
+	autoPay  bool
+}
+
+func NewFakeGateway(autoPay bool) *FakeGateway {
+	return &FakeGateway{payments: make(map[string]*fakePayment), autoPay: autoPay}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) CreatePayment(ctx context.Context, request PaymentRequest) (Payment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sequence++
+	authority := fmt.Sprintf("FAKE%010d", g.sequence)
+	g.payments[authority] = &fakePayment{amount: request.Amount, paid: g.autoPay}
+	return Payment{Authority: authority, URL: fmt.Sprintf("%s?Authority=%s&Status=OK", request.CallbackURL, authority)}, nil
+}
+
+// MarkPaid simulates the user paying the payment of authority
+func (g *FakeGateway) MarkPaid(authority string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if payment, exists := g.payments[authority]; exists {
+		payment.paid = true
+	}
+}
+
+func (g *FakeGateway) Verify(ctx context.Context, authority string, amount int64) (Verification, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, exists := g.payments[authority]
+	if !exists {
+		return Verification{}, ErrUnknownPayment
+	}
+	if !payment.paid || payment.amount != amount {
+		return Verification{}, ErrPaymentFailed
+	}
+
+	verification := Verification{RefID: "REF" + authority, AlreadyVerified: payment.verified}
+	payment.verified = true
+	return verification, nil
+}
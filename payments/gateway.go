@@ -0,0 +1,61 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+var (
+	// ErrPaymentFailed is returned when the gateway rejects a payment or its verification
+	ErrPaymentFailed = errors.New("payment failed")
+	// ErrUnknownPayment is returned when the gateway has no payment with the given authority
+	ErrUnknownPayment = errors.New("unknown payment")
+)
+
+// PaymentRequest asks a gateway to start a payment
+type PaymentRequest struct {
+	Amount      int64 // in Rials
+	Description string
+	CallbackURL string
+}
+
+// Payment is a started payment, the user pays it by opening URL
+type Payment struct {
+	Authority string
+	URL       string
+}
+
+// Verification is the result of a successful payment verification
+type Verification struct {
+	RefID string
+	// AlreadyVerified is set when the payment had been verified before, e.g. on a repeated callback
+	AlreadyVerified bool
+}
+
+// Gateway is a payment provider
+type Gateway interface {
+	Name() string
+	CreatePayment(ctx context.Context, request PaymentRequest) (Payment, error)
+	Verify(ctx context.Context, authority string, amount int64) (Verification, error)
+}
+
+// NewGatewayFromConfig returns the gateway selected by PAYMENT_GATEWAY. The fake gateway pays every invoice, so it
+// is only returned for local runs that also set PAYMENT_ALLOW_FAKE=true.
+func NewGatewayFromConfig() (Gateway, error) {
+	switch name := utils.GetConfig("PAYMENT_GATEWAY"); name {
+	case "zarinpal":
+		return NewZarinpalGateway(utils.GetConfig("ZARINPAL_MERCHANT_ID"), utils.GetConfig("ZARINPAL_BASE_URL"), utils.GetConfig("ZARINPAL_START_PAY_URL")), nil
+	case "fake":
+		if utils.GetConfig("PAYMENT_ALLOW_FAKE") != "true" {
+			return nil, errors.New("the fake payment gateway needs PAYMENT_ALLOW_FAKE=true")
+		}
+		return NewFakeGateway(true), nil
+	case "":
+		return nil, errors.New("PAYMENT_GATEWAY is not set")
+	default:
+		return nil, fmt.Errorf("unknown payment gateway %q", name)
+	}
+}
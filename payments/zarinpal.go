@@ -0,0 +1,143 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultZarinpalBaseURL     = "https://payment.zarinpal.com/pg/v4/payment"
+	defaultZarinpalStartPayURL = "https://payment.zarinpal.com/pg/StartPay/"
+	zarinpalSuccess            = 100
+	zarinpalAlreadyVerified    = 101
+)
+
+// ZarinpalGateway talks to the Zarinpal v4 REST API
+type ZarinpalGateway struct {
+	merchantID  string
+	baseURL     string
+	startPayURL string
+	client      *http.Client
+}
+
+// NewZarinpalGateway creates the gateway, empty URLs fall back to the production endpoints
+func NewZarinpalGateway(merchantID string, baseURL string, startPayURL string) *ZarinpalGateway {
+	if baseURL == "" {
+		baseURL = defaultZarinpalBaseURL
+	}
+	if startPayURL == "" {
+		startPayURL = defaultZarinpalStartPayURL
+	}
+	return &ZarinpalGateway{
+		merchantID:  merchantID,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		startPayURL: startPayURL,
+		client:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type zarinpalResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors json.RawMessage `json:"errors"`
+}
+
+type zarinpalRequestData struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Authority string `json:"authority"`
+}
+
+type zarinpalVerifyData struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	RefID   int64  `json:"ref_id"`
+}
+
+type zarinpalError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (g *ZarinpalGateway) Name() string {
+	return "zarinpal"
+}
+
+// CreatePayment requests a new authority and returns the StartPay URL for it
+func (g *ZarinpalGateway) CreatePayment(ctx context.Context, request PaymentRequest) (Payment, error) {
+	var data zarinpalRequestData
+	err := g.post(ctx, "/request.json", map[string]interface{}{
+		"merchant_id":  g.merchantID,
+		"amount":       request.Amount,
+		"description":  request.Description,
+		"callback_url": request.CallbackURL,
+	}, &data)
+	if err != nil {
+		return Payment{}, err
+	}
+	if data.Code != zarinpalSuccess || data.Authority == "" {
+		return Payment{}, fmt.Errorf("%w: zarinpal code %d %s", ErrPaymentFailed, data.Code, data.Message)
+	}
+	return Payment{Authority: data.Authority, URL: g.startPayURL + data.Authority}, nil
+}
+
+// Verify confirms the payment of authority for amount
+func (g *ZarinpalGateway) Verify(ctx context.Context, authority string, amount int64) (Verification, error) {
+	var data zarinpalVerifyData
+	err := g.post(ctx, "/verify.json", map[string]interface{}{
+		"merchant_id": g.merchantID,
+		"amount":      amount,
+		"authority":   authority,
+	}, &data)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	switch data.Code {
+	case zarinpalSuccess:
+		return Verification{RefID: strconv.FormatInt(data.RefID, 10)}, nil
+	case zarinpalAlreadyVerified:
+		return Verification{RefID: strconv.FormatInt(data.RefID, 10), AlreadyVerified: true}, nil
+	default:
+		return Verification{}, fmt.Errorf("%w: zarinpal code %d %s", ErrPaymentFailed, data.Code, data.Message)
+	}
+}
+
+func (g *ZarinpalGateway) post(ctx context.Context, path string, payload map[string]interface{}, data interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("zarinpal request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	var result zarinpalResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("could not decode zarinpal response: %w", err)
+	}
+
+	// zarinpal sends an empty array instead of an object for the part that is not set
+	var apiError zarinpalError
+	if json.Unmarshal(result.Errors, &apiError) == nil && apiError.Code != 0 {
+		return fmt.Errorf("%w: zarinpal code %d %s", ErrPaymentFailed, apiError.Code, apiError.Message)
+	}
+	if err := json.Unmarshal(result.Data, data); err != nil {
+		return fmt.Errorf("%w: unexpected zarinpal response with status %d", ErrPaymentFailed, response.StatusCode)
+	}
+	return nil
+}
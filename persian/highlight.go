@@ -0,0 +1,147 @@
+package persian
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const maxSnippets = 3
+
+type token struct {
+	start int // rune offsets in the original text
+	end   int
+	word  string
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+// Highlight returns the parts of text around the matches of the normalized terms, at most radius runes on
+// each side, with every match between « and ». It returns an empty string when no term matches.
+func Highlight(text string, terms []string, radius int) string {
+	runes := []rune(text)
+	tokens := tokenize(runes)
+
+	matches := make([]span, 0)
+	for _, term := range terms {
+		words := strings.Fields(term)
+		if len(words) == 0 {
+			continue
+		}
+		for i := 0; i+len(words) <= len(tokens); i++ {
+			if matchesAt(tokens[i:i+len(words)], words) {
+				matches = append(matches, span{tokens[i].start, tokens[i+len(words)-1].end})
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	matches = merge(matches)
+
+	windows := make([]span, len(matches))
+	for i, match := range matches {
+		windows[i] = wordWindow(tokens, match, radius)
+	}
+	windows = merge(windows)
+	if len(windows) > maxSnippets {
+		windows = windows[:maxSnippets]
+	}
+
+	snippets := make([]string, 0, len(windows))
+	for _, window := range windows {
+		var builder strings.Builder
+		if window.start > 0 {
+			builder.WriteString("…")
+		}
+		for i := window.start; i < window.end; i++ {
+			for _, match := range matches {
+				if match.start == i {
+					builder.WriteRune('«')
+				}
+			}
+			if unicode.IsSpace(runes[i]) {
+				builder.WriteRune(' ')
+			} else {
+				builder.WriteRune(runes[i])
+			}
+			for _, match := range matches {
+				if match.end == i+1 {
+					builder.WriteRune('»')
+				}
+			}
+		}
+		if window.end < len(runes) {
+			builder.WriteString("…")
+		}
+		snippets = append(snippets, strings.Join(strings.Fields(builder.String()), " "))
+	}
+	return strings.Join(snippets, " ")
+}
+
+// tokenize splits text into the words Normalize keeps, with their place in the text
+func tokenize(runes []rune) []token {
+	tokens := make([]token, 0)
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			if word := Normalize(string(runes[start:end])); word != "" {
+				tokens = append(tokens, token{start: start, end: end, word: word})
+			}
+			start = -1
+		}
+	}
+	for i, r := range runes {
+		folded := foldRune(r)
+		if folded == 0 || unicode.IsLetter(folded) || unicode.IsDigit(folded) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(runes))
+	return tokens
+}
+
+// wordWindow widens a match by up to radius runes on each side without cutting a word
+func wordWindow(tokens []token, match span, radius int) span {
+	window := match
+	for _, token := range tokens {
+		if token.start >= match.start-radius && token.start < window.start {
+			window.start = token.start
+		}
+		if token.end <= match.end+radius && token.end > window.end {
+			window.end = token.end
+		}
+	}
+	return window
+}
+
+func matchesAt(tokens []token, words []string) bool {
+	for i, word := range words {
+		if tokens[i].word != word {
+			return false
+		}
+	}
+	return true
+}
+
+// merge sorts the spans and joins the overlapping ones
+func merge(spans []span) []span {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := []span{spans[0]}
+	for _, next := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if next.start <= last.end {
+			last.end = max(last.end, next.end)
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
@@ -0,0 +1,85 @@
+// Package persian folds the spelling variants of Persian text so that searches match however a listing was typed
+package persian
+
+import (
+	"strings"
+	"unicode"
+)
+
+// folds maps Arabic letters and non Latin digits to the letters and digits searches use
+var folds = map[rune]rune{
+	'ي': 'ی', 'ى': 'ی', 'ئ': 'ی',
+	'ك': 'ک',
+	'ة': 'ه', 'ۀ': 'ه',
+	'أ': 'ا', 'إ': 'ا', 'آ': 'ا', 'ٱ': 'ا',
+	'ؤ': 'و',
+	'۰': '0', '۱': '1', '۲': '2', '۳': '3', '۴': '4', '۵': '5', '۶': '6', '۷': '7', '۸': '8', '۹': '9',
+	'٠': '0', '١': '1', '٢': '2', '٣': '3', '٤': '4', '٥': '5', '٦': '6', '٧': '7', '٨': '8', '٩': '9',
+}
+
+// Normalize folds letters and digits, drops diacritics and tatweel, lowercases, and turns zero width
+// non-joiners, punctuation and spacing into single spaces. "کمد‌ديواري، ۲عدد" becomes "کمد دیواری 2عدد".
+func Normalize(text string) string {
+	var builder strings.Builder
+	builder.Grow(len(text))
+	space := true
+	for _, r := range text {
+		r = foldRune(r)
+		switch {
+		case r == 0:
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(unicode.ToLower(r))
+			space = false
+		case !space:
+			builder.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSuffix(builder.String(), " ")
+}
+
+// Terms splits a comma separated list of keywords into normalized terms, a term can have several words
+func Terms(keywords string) []string {
+	terms := make([]string, 0)
+	for _, keyword := range strings.FieldsFunc(keywords, func(r rune) bool { return r == ',' || r == '،' || r == '\n' }) {
+		if term := Normalize(keyword); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// foldRune returns the searched form of r, 0 for a rune that is dropped
+func foldRune(r rune) rune {
+	if folded, exists := folds[r]; exists {
+		return folded
+	}
+	if r == 'ـ' || unicode.Is(unicode.Mn, r) { // tatweel and diacritics
+		return 0
+	}
+	return r
+}
+
+// persianDigits are the Persian forms of the digits 0 to 9
+var persianDigits = []rune("۰۱۲۳۴۵۶۷۸۹")
+
+// Digits writes the Latin digits of text in Persian
+func Digits(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return persianDigits[r-'0']
+		}
+		return r
+	}, text)
+}
+
+// LatinDigits writes the Persian and Arabic digits of text in Latin, leaving everything else as it is
+func LatinDigits(text string) string {
+	return strings.Map(func(r rune) rune {
+		if folded, exists := folds[r]; exists && folded >= '0' && folded <= '9' {
+			return folded
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,143 @@
+// Package report renders documents users download from the bot
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+// ComparisonRow is one compared figure with its value for every listing
+type ComparisonRow struct {
+	Name   string
+	Values []string
+}
+
+var comparisonTemplate = template.Must(template.New("comparison").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}"{{if .RTL}} dir="rtl"{{end}}>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Vazirmatn, Tahoma, Arial, sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 20px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 6px 10px; vertical-align: top; font-size: 13px; }
+th { background: #f2f2f2; text-align: start; white-space: nowrap; }
+tr:nth-child(even) td { background: #fafafa; }
+.note { color: #666; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+{{range .Rows}}<tr><th>{{.Name}}</th>{{range .Values}}<td dir="auto">{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{range .Notes}}<p class="note">{{.}}</p>
+{{end}}</body>
+</html>
+`))
+
+// comparisonRows are the catalog keys of the names of the compared figures, in their order
+var comparisonRows = []string{"listing", "price", "price_per_meter", "area", "bedrooms", "age", "floor", "amenities",
+	"monthly_cost", "distance"}
+
+// ComparisonRows returns the figures listings are compared on in a language, shared by the message and the document
+func ComparisonRows(language i18n.Language, comparison services.Comparison) []ComparisonRow {
+	rows := make([]ComparisonRow, 0, len(comparisonRows))
+	for _, row := range comparisonRows {
+		rows = append(rows, ComparisonRow{Name: i18n.T(language, "compare.row."+row)})
+	}
+	for _, compared := range comparison.Listings {
+		listing := compared.Listing
+		price := Amount(language, listing.Price)
+		if listing.BuyMode == types.Rent {
+			price = i18n.T(language, "compare.rent_price", Amount(language, listing.Deposit), Amount(language, listing.Rent))
+		}
+		amenities := make([]string, 0, len(compared.Amenities))
+		for _, amenity := range compared.Amenities {
+			amenities = append(amenities, string(amenity))
+		}
+		distance := i18n.T(language, "compare.unknown")
+		if compared.Distance >= 0 {
+			distance = i18n.T(language, "compare.distance", compared.Distance)
+		}
+
+		values := []string{
+			fmt.Sprintf("%s (#%d)", listing.Title, listing.ID),
+			price,
+			Amount(language, compared.PricePerMeter),
+			orUnknown(language, listing.Area, "compare.area"),
+			orUnknown(language, listing.BedroomNum, ""),
+			orUnknown(language, int(listing.Age), "compare.age"),
+			orUnknown(language, int(listing.FloorsNum), ""),
+			strings.Join(amenities, ", "),
+			Amount(language, compared.MonthlyCost),
+			distance,
+		}
+		for i := range rows {
+			rows[i].Values = append(rows[i].Values, values[i])
+		}
+	}
+	return rows
+}
+
+// ComparisonNotes explain how the figures were computed, in a language
+func ComparisonNotes(language i18n.Language, comparison services.Comparison) []string {
+	notes := []string{
+		i18n.T(language, "compare.note.monthly_cost", i18n.Digits(language, strconv.FormatFloat(comparison.Rate, 'f', -1, 64))),
+		i18n.T(language, "compare.note.price_per_meter"),
+	}
+	if comparison.Point == nil {
+		notes = append(notes, i18n.T(language, "compare.note.location"))
+	}
+	return notes
+}
+
+// ComparisonHTML renders a comparison as an HTML table in a language
+func ComparisonHTML(language i18n.Language, comparison services.Comparison, now time.Time) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := comparisonTemplate.Execute(buf, struct {
+		Language i18n.Language
+		RTL      bool
+		Title    string
+		Rows     []ComparisonRow
+		Notes    []string
+	}{
+		Language: language,
+		RTL:      language == i18n.Persian,
+		Title:    i18n.T(language, "compare.caption"),
+		Rows:     ComparisonRows(language, comparison),
+		Notes:    append(ComparisonNotes(language, comparison), i18n.T(language, "compare.created", i18n.DateTime(language, now))),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Amount writes an amount with thousands separators in a language, or "unknown" for 0
+func Amount(language i18n.Language, value int64) string {
+	if value == 0 {
+		return i18n.T(language, "compare.unknown")
+	}
+	return i18n.Number(language, value)
+}
+
+// orUnknown writes a figure with the catalog key of its unit, or "unknown" for 0
+func orUnknown(language i18n.Language, value int, unit string) string {
+	if value == 0 {
+		return i18n.T(language, "compare.unknown")
+	}
+	if unit == "" {
+		return i18n.Digits(language, strconv.Itoa(value))
+	}
+	return i18n.T(language, unit, value)
+}
@@ -0,0 +1,62 @@
+package report
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// printer is the headless browser documents are printed with. It is started with the first document and
+// kept for the next ones, a browser that crashed or was closed is started again.
+var printer struct {
+	mu      sync.Mutex
+	pw      *playwright.Playwright
+	browser playwright.Browser
+}
+
+// PDF prints an HTML document with the headless browser the crawlers use, one document at a time
+func PDF(html []byte) ([]byte, error) {
+	printer.mu.Lock()
+	defer printer.mu.Unlock()
+
+	browser, err := printerBrowser()
+	if err != nil {
+		return nil, err
+	}
+	page, err := browser.NewPage()
+	if err != nil {
+		return nil, fmt.Errorf("could not create page: %w", err)
+	}
+	defer page.Close()
+	if err := page.SetContent(string(html)); err != nil {
+		return nil, fmt.Errorf("could not load document: %w", err)
+	}
+	return page.PDF(playwright.PagePdfOptions{
+		Format:          playwright.String("A4"),
+		Landscape:       playwright.Bool(true),
+		PrintBackground: playwright.Bool(true),
+	})
+}
+
+// printerBrowser returns the running browser of the printer or starts it, the caller holds printer.mu
+func printerBrowser() (playwright.Browser, error) {
+	if printer.browser != nil && printer.browser.IsConnected() {
+		return printer.browser, nil
+	}
+	if printer.pw == nil {
+		pw, err := playwright.Run()
+		if err != nil {
+			return nil, fmt.Errorf("could not start playwright: %w", err)
+		}
+		printer.pw = pw
+	}
+	browser, err := printer.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not launch browser: %w", err)
+	}
+	printer.browser = browser
+	return browser, nil
+}
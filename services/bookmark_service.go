@@ -0,0 +1,356 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxBookmarkNote   = 500 // runes
+	maxFolderName     = 30  // runes, folder names are shown on buttons
+	defaultMaxFolders = 20
+	defaultInviteDays = 7
+)
+
+var (
+	// ErrBookmarkNotFound is returned for bookmarks and folders that do not exist or belong to another user
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+	// ErrInvalidFolderName is returned for empty or too long folder names
+	ErrInvalidFolderName = errors.New("invalid folder name")
+	// ErrFolderExists is returned when the user already has a folder of that name
+	ErrFolderExists = errors.New("folder already exists")
+	// ErrTooManyFolders is returned when the user reached BOOKMARK_MAX_FOLDERS
+	ErrTooManyFolders = errors.New("too many folders")
+	// ErrNoteTooLong is returned for notes longer than maxBookmarkNote
+	ErrNoteTooLong = errors.New("note too long")
+)
+
+// BookmarkedListing is a bookmark with the newest history of its post
+type BookmarkedListing struct {
+	Bookmark models.Bookmark
+	Latest   models.PostHistory
+	// votes and comments of the members of the folder, for the listings of a folder
+	Up       int
+	Down     int
+	Comments int
+}
+
+// BookmarkService manages the bookmarks of users, their notes and folders, and tells users when the price
+// of a bookmarked listing changes
+type BookmarkService struct {
+	bookmarkRepository db.BookmarkRepo
+	sharedRepository   db.SharedFolderRepo
+	postRepository     db.PostRepo
+	maxFolders         int
+	inviteTTL          time.Duration
+	notifierMutex      sync.RWMutex
+	notifier           Notifier
+	logger             *slog.Logger
+}
+
+func NewBookmarkService(bookmarkRepository db.BookmarkRepo, sharedRepository db.SharedFolderRepo, postRepository db.PostRepo) *BookmarkService {
+	return &BookmarkService{
+		bookmarkRepository: bookmarkRepository,
+		sharedRepository:   sharedRepository,
+		postRepository:     postRepository,
+		maxFolders:         intConfig("BOOKMARK_MAX_FOLDERS", defaultMaxFolders),
+		inviteTTL:          time.Duration(intConfig("BOOKMARK_INVITE_DAYS", defaultInviteDays)) * 24 * time.Hour,
+		logger:             utils.NewLogger("Bookmark_Service"),
+	}
+}
+
+// SetNotifier sets how users are told about price changes of their bookmarks and activity in shared folders
+func (s *BookmarkService) SetNotifier(notifier Notifier) {
+	s.notifierMutex.Lock()
+	defer s.notifierMutex.Unlock()
+	s.notifier = notifier
+}
+
+func (s *BookmarkService) notify(user models.User, text string) {
+	s.notifierMutex.RLock()
+	notifier := s.notifier
+	s.notifierMutex.RUnlock()
+	if notifier != nil && user.TelegramID != 0 {
+		notifier(user, text)
+	}
+}
+
+// Add bookmarks a post with its current prices, bookmarking a post twice returns the first bookmark
+func (s *BookmarkService) Add(user models.User, postID uint) (models.Bookmark, error) {
+	post, err := s.postRepository.FindByID(postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Bookmark{}, ErrBookmarkNotFound
+	}
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	if err := s.bookmarkRepository.Save(post, user); err != nil {
+		return models.Bookmark{}, err
+	}
+	bookmark, err := s.bookmarkRepository.Find(models.Bookmark{PostID: post.ID, UserID: user.ID})
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+
+	if bookmark.Price == 0 && bookmark.Deposit == 0 && bookmark.Rent == 0 {
+		latest, err := s.bookmarkRepository.FindLatestHistories([]uint{post.ID})
+		if err != nil {
+			return bookmark, err
+		}
+		if len(latest) > 0 {
+			bookmark.Price, bookmark.Deposit, bookmark.Rent = latest[0].Price, latest[0].Deposit, latest[0].Rent
+			err = s.bookmarkRepository.Update(bookmark)
+		}
+		return bookmark, err
+	}
+	return bookmark, nil
+}
+
+// List returns the bookmarks of a user with the newest history of their posts, or all the bookmarks of a folder
+// the user owns or is a member of when folderID is not 0
+func (s *BookmarkService) List(user models.User, folderID uint) ([]BookmarkedListing, error) {
+	var bookmarks []models.Bookmark
+	var err error
+	if folderID == 0 {
+		bookmarks, err = s.bookmarkRepository.FindAll(user.ID)
+	} else if _, _, err = s.FolderAccess(user, folderID); err == nil {
+		bookmarks, err = s.sharedRepository.FindFolderBookmarks(folderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	postIDs := make([]uint, 0, len(bookmarks))
+	bookmarkIDs := make([]uint, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		postIDs = append(postIDs, bookmark.PostID)
+		bookmarkIDs = append(bookmarkIDs, bookmark.ID)
+	}
+	latest, err := s.latestHistories(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	listings := make([]BookmarkedListing, 0, len(bookmarks))
+	index := make(map[uint]int)
+	for _, bookmark := range bookmarks {
+		index[bookmark.ID] = len(listings)
+		listings = append(listings, BookmarkedListing{Bookmark: bookmark, Latest: latest[bookmark.PostID]})
+	}
+	if folderID == 0 {
+		return listings, nil
+	}
+
+	votes, err := s.sharedRepository.FindVotes(bookmarkIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, vote := range votes {
+		if vote.Value > 0 {
+			listings[index[vote.BookmarkID]].Up++
+		} else if vote.Value < 0 {
+			listings[index[vote.BookmarkID]].Down++
+		}
+	}
+	comments, err := s.sharedRepository.FindComments(bookmarkIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		listings[index[comment.BookmarkID]].Comments++
+	}
+	return listings, nil
+}
+
+func (s *BookmarkService) latestHistories(postIDs []uint) (map[uint]models.PostHistory, error) {
+	histories, err := s.bookmarkRepository.FindLatestHistories(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[uint]models.PostHistory)
+	for _, history := range histories {
+		latest[history.PostID] = history
+	}
+	return latest, nil
+}
+
+// Get returns a bookmark of a user
+func (s *BookmarkService) Get(user models.User, bookmarkID uint) (models.Bookmark, error) {
+	bookmark, err := s.bookmarkRepository.FindByID(bookmarkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && bookmark.UserID != user.ID) {
+		return models.Bookmark{}, ErrBookmarkNotFound
+	}
+	return bookmark, err
+}
+
+// Remove deletes a bookmark of a user
+func (s *BookmarkService) Remove(user models.User, bookmarkID uint) error {
+	bookmark, err := s.Get(user, bookmarkID)
+	if err != nil {
+		return err
+	}
+	return s.bookmarkRepository.Delete(bookmark)
+}
+
+// SetNote sets the personal note of a bookmark, an empty note removes it
+func (s *BookmarkService) SetNote(user models.User, bookmarkID uint, note string) (models.Bookmark, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxBookmarkNote {
+		return models.Bookmark{}, ErrNoteTooLong
+	}
+	bookmark, err := s.Get(user, bookmarkID)
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	bookmark.Note = note
+	return bookmark, s.bookmarkRepository.Update(bookmark)
+}
+
+// Move puts a bookmark in a folder the user owns or edits, or in no folder when folderID is 0,
+// the other members of a shared folder are told about the new listing
+func (s *BookmarkService) Move(user models.User, bookmarkID uint, folderID uint) (models.Bookmark, error) {
+	bookmark, err := s.Get(user, bookmarkID)
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	previous := bookmark.FolderID
+	bookmark.FolderID = nil
+	bookmark.Folder = nil
+	if folderID != 0 {
+		folder, role, err := s.FolderAccess(user, folderID)
+		if err != nil {
+			return models.Bookmark{}, err
+		}
+		if role == models.FOLDER_VIEWER {
+			return models.Bookmark{}, ErrFolderReadOnly
+		}
+		bookmark.FolderID = &folder.ID
+		bookmark.Folder = &folder
+	}
+	if err := s.bookmarkRepository.Update(bookmark); err != nil {
+		return models.Bookmark{}, err
+	}
+
+	if bookmark.Folder != nil && (previous == nil || *previous != folderID) {
+		latest, _ := s.latestHistories([]uint{bookmark.PostID})
+		folder := *bookmark.Folder
+		s.notifyFolder(folder, user, func(member models.User) string {
+			return tr(member, "bookmark.folder_listing_added", folder.Name, listingTitle(member, latest[bookmark.PostID], bookmark.PostID))
+		})
+	}
+	return bookmark, nil
+}
+
+// Folders returns the folders a user owns by name
+func (s *BookmarkService) Folders(user models.User) ([]models.BookmarkFolder, error) {
+	return s.bookmarkRepository.FindFolders(user.ID)
+}
+
+// Folder returns a folder a user owns
+func (s *BookmarkService) Folder(user models.User, folderID uint) (models.BookmarkFolder, error) {
+	folder, err := s.bookmarkRepository.FindFolder(folderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && folder.UserID != user.ID) {
+		return models.BookmarkFolder{}, ErrBookmarkNotFound
+	}
+	return folder, err
+}
+
+// CreateFolder adds a named folder for a user
+func (s *BookmarkService) CreateFolder(user models.User, name string) (models.BookmarkFolder, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > maxFolderName {
+		return models.BookmarkFolder{}, ErrInvalidFolderName
+	}
+	folders, err := s.bookmarkRepository.FindFolders(user.ID)
+	if err != nil {
+		return models.BookmarkFolder{}, err
+	}
+	for _, folder := range folders {
+		if strings.EqualFold(folder.Name, name) {
+			return models.BookmarkFolder{}, ErrFolderExists
+		}
+	}
+	if s.maxFolders > 0 && len(folders) >= s.maxFolders {
+		return models.BookmarkFolder{}, ErrTooManyFolders
+	}
+	return s.bookmarkRepository.SaveFolder(models.BookmarkFolder{UserID: user.ID, Name: name})
+}
+
+// DeleteFolder deletes a folder of a user, its bookmarks are kept without a folder
+func (s *BookmarkService) DeleteFolder(user models.User, folderID uint) error {
+	folder, err := s.Folder(user, folderID)
+	if err != nil {
+		return err
+	}
+	return s.bookmarkRepository.DeleteFolder(folder)
+}
+
+// NotifyChanges tells users whose bookmarked posts a crawl found with other prices, it returns how many were told
+func (s *BookmarkService) NotifyChanges(crawlHistoryID uint) (int, error) {
+	bookmarks, err := s.bookmarkRepository.FindByCrawl(crawlHistoryID)
+	if err != nil || len(bookmarks) == 0 {
+		return 0, err
+	}
+	postIDs := make([]uint, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		postIDs = append(postIDs, bookmark.PostID)
+	}
+	latest, err := s.latestHistories(postIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for _, bookmark := range bookmarks {
+		history, exists := latest[bookmark.PostID]
+		if !exists || !pricesChanged(bookmark, history) {
+			continue
+		}
+		text := PriceChangeText(bookmark, history)
+		bookmark.Price, bookmark.Deposit, bookmark.Rent = history.Price, history.Deposit, history.Rent
+		if err := s.bookmarkRepository.Update(bookmark); err != nil {
+			return notified, err
+		}
+		s.notify(bookmark.User, text)
+		notified++
+	}
+	if notified > 0 {
+		s.logger.Info("bookmark price changes notified", slog.Int("bookmarks", notified))
+	}
+	return notified, nil
+}
+
+// pricesChanged ignores a history without prices, the crawler could not read them
+func pricesChanged(bookmark models.Bookmark, history models.PostHistory) bool {
+	if history.Price == 0 && history.Deposit == 0 && history.Rent == 0 {
+		return false
+	}
+	return bookmark.Price != history.Price || bookmark.Deposit != history.Deposit || bookmark.Rent != history.Rent
+}
+
+// PriceChangeText describes how the prices of a bookmarked listing changed since the user was last told,
+// in the language of the user who bookmarked it
+func PriceChangeText(bookmark models.Bookmark, history models.PostHistory) string {
+	user := bookmark.User
+	language := i18n.Parse(user.Language)
+	text := tr(user, "bookmark.price_changed", history.Title)
+	for _, change := range []struct {
+		name     string
+		old, new int64
+	}{{"price", bookmark.Price, history.Price}, {"deposit", bookmark.Deposit, history.Deposit}, {"rent", bookmark.Rent, history.Rent}} {
+		if change.old != change.new {
+			text += tr(user, "bookmark.change", tr(user, "bookmark.change."+change.name),
+				i18n.Number(language, change.old), i18n.Number(language, change.new))
+		}
+	}
+	return text
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"gorm.io/gorm"
+)
+
+const maxBookmarkComment = 500 // runes
+
+var (
+	// ErrFolderReadOnly is returned when a viewer of a shared folder tries to add listings to it
+	ErrFolderReadOnly = errors.New("folder is read only")
+	// ErrInviteInvalid is returned for invite links that do not exist or expired
+	ErrInviteInvalid = errors.New("invalid invite")
+	// ErrInvalidComment is returned for empty comments and comments longer than maxBookmarkComment
+	ErrInvalidComment = errors.New("invalid comment")
+)
+
+// SharedFolder is a folder shared with a user and the role of the user in it
+type SharedFolder struct {
+	Folder models.BookmarkFolder
+	Role   models.FolderRole
+}
+
+// FolderAccess returns a folder with the role of the user in it, the owner gets FOLDER_OWNER
+func (s *BookmarkService) FolderAccess(user models.User, folderID uint) (models.BookmarkFolder, models.FolderRole, error) {
+	folder, err := s.bookmarkRepository.FindFolder(folderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.BookmarkFolder{}, "", ErrBookmarkNotFound
+	}
+	if err != nil {
+		return models.BookmarkFolder{}, "", err
+	}
+	if folder.UserID == user.ID {
+		return folder, models.FOLDER_OWNER, nil
+	}
+	member, err := s.sharedRepository.FindMember(folderID, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.BookmarkFolder{}, "", ErrBookmarkNotFound
+	}
+	if err != nil {
+		return models.BookmarkFolder{}, "", err
+	}
+	return folder, member.Role, nil
+}
+
+// SharedFolders returns the folders other users shared with a user
+func (s *BookmarkService) SharedFolders(user models.User) ([]SharedFolder, error) {
+	members, err := s.sharedRepository.FindMemberships(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	folders := make([]SharedFolder, 0, len(members))
+	for _, member := range members {
+		if member.Folder.ID != 0 {
+			folders = append(folders, SharedFolder{Folder: member.Folder, Role: member.Role})
+		}
+	}
+	return folders, nil
+}
+
+// Members returns the members of a folder the user owns or is a member of
+func (s *BookmarkService) Members(user models.User, folderID uint) ([]models.FolderMember, error) {
+	if _, _, err := s.FolderAccess(user, folderID); err != nil {
+		return nil, err
+	}
+	return s.sharedRepository.FindMembers(folderID)
+}
+
+// Invite creates an invite link token that makes whoever opens it a viewer or editor of a folder the user owns
+func (s *BookmarkService) Invite(user models.User, folderID uint, role models.FolderRole, now time.Time) (models.FolderInvite, error) {
+	if role != models.FOLDER_VIEWER && role != models.FOLDER_EDITOR {
+		return models.FolderInvite{}, fmt.Errorf("invalid folder role %q", role)
+	}
+	folder, err := s.Folder(user, folderID)
+	if err != nil {
+		return models.FolderInvite{}, err
+	}
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return models.FolderInvite{}, err
+	}
+	return s.sharedRepository.SaveInvite(models.FolderInvite{
+		Token:     hex.EncodeToString(token),
+		FolderID:  folder.ID,
+		Role:      role,
+		ExpiresAt: now.Add(s.inviteTTL),
+	})
+}
+
+// Join makes a user a member of the folder of an invite, the owner of the folder is told
+func (s *BookmarkService) Join(user models.User, token string, now time.Time) (models.BookmarkFolder, models.FolderRole, error) {
+	invite, err := s.sharedRepository.FindInvite(token, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && invite.Folder.ID == 0) {
+		return models.BookmarkFolder{}, "", ErrInviteInvalid
+	}
+	if err != nil {
+		return models.BookmarkFolder{}, "", err
+	}
+	folder := invite.Folder
+	if folder.UserID == user.ID {
+		return folder, models.FOLDER_OWNER, nil
+	}
+	if err := s.sharedRepository.SaveMember(models.FolderMember{FolderID: folder.ID, UserID: user.ID, Role: invite.Role}); err != nil {
+		return models.BookmarkFolder{}, "", err
+	}
+
+	if owner, err := s.bookmarkRepository.FindFolder(folder.ID); err == nil {
+		s.notify(owner.User, tr(owner.User, "bookmark.member_joined", folder.Name, tr(owner.User, "bookmark.role."+string(invite.Role))))
+	}
+	s.logger.Info("user joined shared folder", slog.Any("folder", folder.ID), slog.Any("user", user.ID), slog.String("role", string(invite.Role)))
+	return folder, invite.Role, nil
+}
+
+// Leave removes a user from a folder shared with them
+func (s *BookmarkService) Leave(user models.User, folderID uint) error {
+	if _, err := s.sharedRepository.FindMember(folderID, user.ID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrBookmarkNotFound
+	} else if err != nil {
+		return err
+	}
+	return s.sharedRepository.DeleteMember(folderID, user.ID)
+}
+
+// StopSharing removes the members and invite links of a folder the user owns
+func (s *BookmarkService) StopSharing(user models.User, folderID uint) error {
+	folder, err := s.Folder(user, folderID)
+	if err != nil {
+		return err
+	}
+	return s.sharedRepository.DeleteMembers(folder.ID)
+}
+
+// Vote records the thumbs up, 1, or down, -1, of a user on a listing of a folder they can see
+func (s *BookmarkService) Vote(user models.User, bookmarkID uint, value int8) error {
+	if value != 1 && value != -1 {
+		return fmt.Errorf("invalid vote %d", value)
+	}
+	if _, _, err := s.folderBookmark(user, bookmarkID); err != nil {
+		return err
+	}
+	return s.sharedRepository.SaveVote(models.BookmarkVote{BookmarkID: bookmarkID, UserID: user.ID, Value: value})
+}
+
+// Comment adds a comment of a user on a listing of a folder they can see, the other members are told
+func (s *BookmarkService) Comment(user models.User, bookmarkID uint, text string) (models.BookmarkComment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxBookmarkComment {
+		return models.BookmarkComment{}, ErrInvalidComment
+	}
+	bookmark, folder, err := s.folderBookmark(user, bookmarkID)
+	if err != nil {
+		return models.BookmarkComment{}, err
+	}
+	comment, err := s.sharedRepository.SaveComment(models.BookmarkComment{BookmarkID: bookmark.ID, UserID: user.ID, Text: text})
+	if err != nil {
+		return models.BookmarkComment{}, err
+	}
+
+	latest, _ := s.latestHistories([]uint{bookmark.PostID})
+	s.notifyFolder(folder, user, func(member models.User) string {
+		return tr(member, "bookmark.folder_comment", listingTitle(member, latest[bookmark.PostID], bookmark.PostID), folder.Name, text)
+	})
+	return comment, nil
+}
+
+// Comments returns the comments on a listing of a folder the user can see, oldest first
+func (s *BookmarkService) Comments(user models.User, bookmarkID uint) ([]models.BookmarkComment, error) {
+	if _, _, err := s.folderBookmark(user, bookmarkID); err != nil {
+		return nil, err
+	}
+	return s.sharedRepository.FindComments([]uint{bookmarkID})
+}
+
+// folderBookmark returns a bookmark in a folder the user owns or is a member of
+func (s *BookmarkService) folderBookmark(user models.User, bookmarkID uint) (models.Bookmark, models.BookmarkFolder, error) {
+	bookmark, err := s.bookmarkRepository.FindByID(bookmarkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && bookmark.FolderID == nil) {
+		return models.Bookmark{}, models.BookmarkFolder{}, ErrBookmarkNotFound
+	}
+	if err != nil {
+		return models.Bookmark{}, models.BookmarkFolder{}, err
+	}
+	folder, _, err := s.FolderAccess(user, *bookmark.FolderID)
+	if err != nil {
+		return models.Bookmark{}, models.BookmarkFolder{}, err
+	}
+	return bookmark, folder, nil
+}
+
+// notifyFolder tells the owner and members of a folder, except the user who did something, about it. The text
+// is written for every user, in their language.
+func (s *BookmarkService) notifyFolder(folder models.BookmarkFolder, actor models.User, text func(models.User) string) {
+	members, err := s.sharedRepository.FindMembers(folder.ID)
+	if err != nil {
+		s.logger.Error("finding folder members failed", slog.Any("folder", folder.ID), slog.Any("error", err))
+		return
+	}
+	if len(members) == 0 {
+		return
+	}
+	if folder.UserID != actor.ID {
+		s.notify(folder.User, text(folder.User))
+	}
+	for _, member := range members {
+		if member.UserID != actor.ID {
+			s.notify(member.User, text(member.User))
+		}
+	}
+}
+
+// listingTitle returns the title of the newest history of a post, or its id for a post without one
+func listingTitle(user models.User, history models.PostHistory, postID uint) string {
+	if history.Title != "" {
+		return history.Title
+	}
+	return tr(user, "bookmark.untitled", postID)
+}
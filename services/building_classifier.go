@@ -0,0 +1,40 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+// buildingCues are the words of a category or title naming a building type, matched at the start of words so
+// "زیرزمین" is not land
+var buildingCues = []struct {
+	word     string
+	building types.Building
+}{
+	{"آپارتمان", types.Apartment},
+	{"برج", types.Apartment},
+	{"پنت‌هاوس", types.Apartment},
+	{"ویلا", types.Villa},
+	{"خانه", types.Villa},
+	{"زمین", types.Ground},
+	{"کلنگی", types.Ground},
+}
+
+// ClassifyBuilding returns the building type of a listing from the category its website files it under, or
+// guesses it from the title when the category does not name one. It returns an empty type when neither does.
+func ClassifyBuilding(category string, title string) types.Building {
+	for _, text := range []string{category, title} {
+		words := strings.Fields(persian.Normalize(text))
+		for _, cue := range buildingCues {
+			word := persian.Normalize(cue.word)
+			for _, candidate := range words {
+				if strings.HasPrefix(candidate, word) {
+					return cue.building
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
 	crawlerModels "github.com/MagicalCrawler/RealEstateApp/models/crawler"
 	"github.com/MagicalCrawler/RealEstateApp/utils"
 	"log/slog"
@@ -38,21 +40,35 @@ func (s *CityService) GetCities() ([]crawlerModels.City, error) {
 	}
 
 	divarAllCityAPIAddress := utils.GetConfig("API_CITIES_URL")
-	resp, err := http.Get(divarAllCityAPIAddress)
+	profile, err := crawlers.Identities().Next()
 	if err != nil {
-		s.logger.Error("failed to fetch cities ", err)
+		s.logger.Error("no proxy to fetch the cities through", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to fetch cities: %w", err)
+	}
+	request, err := profile.NewRequest(context.Background(), http.MethodGet, divarAllCityAPIAddress)
+	if err != nil {
+		s.logger.Error("failed to build cities request", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to build cities request: %w", err)
+	}
+	resp, err := profile.HTTPClient().Do(request)
+	if err != nil {
+		crawlers.Identities().ReportFailure(profile)
+		s.logger.Error("failed to fetch cities", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to fetch cities: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		s.logger.Error("unexpected status code ", resp.StatusCode)
+		crawlers.Identities().ReportFailure(profile)
+		s.logger.Error("unexpected status code", slog.Int("status", resp.StatusCode))
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
+	crawlers.Identities().ReportSuccess(profile)
+
 	var cityResponse crawlerModels.CityResponse
 	if err := json.NewDecoder(resp.Body).Decode(&cityResponse); err != nil {
-		s.logger.Error("failed to decode response ", err)
+		s.logger.Error("failed to decode response", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to decode city response: %w", err)
 	}
 
@@ -63,7 +79,7 @@ func (s *CityService) GetCities() ([]crawlerModels.City, error) {
 	// Filter cities based on app settings
 	provincialCenters, err := utils.LoadAppSettingsFile()
 	if err != nil {
-		s.logger.Error("failed to load app settings ", err)
+		s.logger.Error("failed to load app settings", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to load app settings: %w", err)
 	}
 
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/MagicalCrawler/RealEstateApp/amenities"
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/geo"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"gorm.io/gorm"
+)
+
+const (
+	MinCompared = 2
+	MaxCompared = 5
+)
+
+var (
+	// ErrCompareCount is returned when fewer than MinCompared or more than MaxCompared listings are compared
+	ErrCompareCount = fmt.Errorf("compare %d to %d listings", MinCompared, MaxCompared)
+	// ErrComparedNotFound is returned for a compared post history that does not exist
+	ErrComparedNotFound = errors.New("compared listing not found")
+)
+
+// ComparedListing is a listing with the figures it is compared on
+type ComparedListing struct {
+	Listing models.PostHistory
+	// price of a square meter, for rentals the full deposit of a square meter, 0 when the area is unknown
+	PricePerMeter int64
+	// for rentals the rent without deposit, for purchases the rent the price would be worth as a deposit
+	MonthlyCost int64
+	Distance    float64 // kilometers to the point of the user, -1 when one of them is unknown
+	Amenities   []types.Amenity
+}
+
+// Comparison is the listings a user compares, in the order they asked for
+type Comparison struct {
+	Listings []ComparedListing
+	Point    *geo.Point // the point of the user, nil when they never set one
+	Rate     float64    // the rent conversion rate the monthly costs were computed with, in percent per month
+}
+
+// ComparisonService puts listings side by side
+type ComparisonService struct {
+	postRepository db.PostRepo
+	rentService    *RentService
+}
+
+func NewComparisonService(postRepository db.PostRepo, rentService *RentService) *ComparisonService {
+	return &ComparisonService{postRepository: postRepository, rentService: rentService}
+}
+
+// Compare returns the comparison of post histories, the distances are measured from the point of the user
+func (s *ComparisonService) Compare(postHistoryIDs []uint, user models.User) (Comparison, error) {
+	ids := make([]uint, 0, len(postHistoryIDs))
+	seen := make(map[uint]bool)
+	for _, id := range postHistoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < MinCompared || len(ids) > MaxCompared {
+		return Comparison{}, ErrCompareCount
+	}
+
+	comparison := Comparison{Rate: s.rentService.Rate()}
+	if point, ok := geo.NewPoint(user.Latitude, user.Longitude); ok {
+		comparison.Point = &point
+	}
+	for _, id := range ids {
+		listing, err := s.postRepository.FindPostHistory(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Comparison{}, fmt.Errorf("%w: %d", ErrComparedNotFound, id)
+		}
+		if err != nil {
+			return Comparison{}, err
+		}
+		comparison.Listings = append(comparison.Listings, compareListing(listing, comparison.Point, comparison.Rate))
+	}
+	return comparison, nil
+}
+
+// compareListing computes the figures of a listing, rate is the rent conversion rate in percent per month
+func compareListing(listing models.PostHistory, point *geo.Point, rate float64) ComparedListing {
+	compared := ComparedListing{Listing: listing, Distance: -1, Amenities: amenities.Of(listing)}
+
+	price := listing.Price
+	if listing.BuyMode == types.Rent {
+		fullDeposit, monthlyCost := listing.FullDeposit, listing.MonthlyCost
+		if fullDeposit == 0 && monthlyCost == 0 {
+			fullDeposit, monthlyCost = ConvertRent(listing.Deposit, listing.Rent, rate)
+		}
+		price, compared.MonthlyCost = fullDeposit, monthlyCost
+	} else if rate > 0 {
+		compared.MonthlyCost = int64(math.Round(float64(listing.Price) * rate / 100))
+	}
+	if listing.Area > 0 {
+		compared.PricePerMeter = price / int64(listing.Area)
+	}
+
+	if location, ok := geo.NewPoint(listing.Latitude, listing.Longitude); ok && point != nil {
+		compared.Distance = geo.Distance(*point, location)
+	}
+	return compared
+}
@@ -2,47 +2,72 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/MagicalCrawler/RealEstateApp/amenities"
 	"github.com/MagicalCrawler/RealEstateApp/crawlers"
 	"github.com/MagicalCrawler/RealEstateApp/crawlers/divar"
 	"github.com/MagicalCrawler/RealEstateApp/crawlers/sheypoor"
 	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/jalali"
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
 	"github.com/MagicalCrawler/RealEstateApp/models"
 	crawlerModels "github.com/MagicalCrawler/RealEstateApp/models/crawler"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
 	"github.com/MagicalCrawler/RealEstateApp/utils"
 	"log"
 	"log/slog"
 	"math"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
 )
 
 // CrawlerService manages the crawling process
 type CrawlerService struct {
-	crawlers    []crawlers.Crawler
-	cityService *CityService
-	repository  *db.PostRepo
-	logger      *slog.Logger
+	crawlers           []crawlers.Crawler
+	cityService        *CityService
+	repository         *db.PostRepo
+	crawlRunRepository db.CrawlRunRepository
+	rentService        *RentService
+	fraudService       *FraudService
+	imageService       *ImageService
+	bookmarkService    *BookmarkService
+	lastSuccess        atomic.Int64
+	cancelArchive      context.CancelFunc // stops the photo archive of the previous cycle, nil before the first
+	logger             *slog.Logger
 }
 
 // NewCrawlerService creates a new instance of CrawlerService
-func NewCrawlerService(repository *db.PostRepo) *CrawlerService {
+func NewCrawlerService(repository *db.PostRepo, crawlRunRepository db.CrawlRunRepository, rentService *RentService,
+	fraudService *FraudService, imageService *ImageService, bookmarkService *BookmarkService) *CrawlerService {
 	return &CrawlerService{
 		crawlers: []crawlers.Crawler{
 			divar.NewDivarCrawler(),
 			sheypoor.NewSheypoorCrawler(),
 		},
-		cityService: NewCityService(),
-		repository:  repository,
-		logger:      utils.NewLogger("CrawlerService"),
+		cityService:        NewCityService(),
+		repository:         repository,
+		crawlRunRepository: crawlRunRepository,
+		rentService:        rentService,
+		fraudService:       fraudService,
+		imageService:       imageService,
+		bookmarkService:    bookmarkService,
+		logger:             utils.NewLogger("CrawlerService"),
 	}
 }
 
+// Crawlers returns the crawlers of the websites
+func (s *CrawlerService) Crawlers() []crawlers.Crawler {
+	return s.crawlers
+}
+
 // Start begins the crawling process
 func (s *CrawlerService) Start() {
 	go s.run()
@@ -68,12 +93,16 @@ func (s *CrawlerService) run() {
 func (s *CrawlerService) executeCrawlCycle() {
 	cities, err := s.cityService.GetCities()
 	if err != nil {
-		s.logger.Error("Failed to get cities", err)
+		s.logger.Error("Failed to get cities", slog.Any("error", err))
+		tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
 		return
 	}
 
 	chunkedCities := chunkCities(cities, 5)
-	var posts []crawlerModels.Post
+	var (
+		tasks      []*crawlTask
+		tasksMutex sync.Mutex
+	)
 
 	var (
 		totalCPU    float64
@@ -98,17 +127,12 @@ func (s *CrawlerService) executeCrawlCycle() {
 				wg.Add(1)
 				go func(crawler crawlers.Crawler, city crawlerModels.City) {
 					defer wg.Done()
-					result, err := crawler.Crawl(ctx, city)
-					if err != nil {
-						s.logger.Error("Failed to crawl city", city.Name, ", Error: ", err)
-						return
-					}
-
-					for i := range result {
-						result[i] = processPost(result[i])
-					}
+					task := s.runTask(ctx, crawler, city)
+					task.concurrentRuns = len(s.crawlers) * len(cityChunk)
 
-					posts = append(posts, result...)
+					tasksMutex.Lock()
+					tasks = append(tasks, task)
+					tasksMutex.Unlock()
 				}(crawler, city)
 			}
 		}
@@ -133,17 +157,103 @@ func (s *CrawlerService) executeCrawlCycle() {
 		ExecutionTime: executionTime,
 		TotalCPU:      totalCPU / float64(len(chunkedCities)),
 		TotalMemory:   totalMemory / float64(len(chunkedCities)),
-		Posts:         posts,
+		Tasks:         tasks,
 	}
 
-	err = mapAndSaveCrawlerSession(session, *s.repository)
+	metrics.CrawlerCycleDuration.Observe(executionTime.Seconds())
+
+	crawlHistory, err := mapAndSaveCrawlerSession(session, *s.repository, s.crawlRunRepository, s.rentService.Rate())
 	if err != nil {
-		s.logger.Error("Error saving crawler session:", err)
+		s.logger.Error("Error saving crawler session", slog.Any("error", err))
+	} else if session.PostCount() > 0 {
+		s.lastSuccess.Store(endTime.Unix())
+		metrics.CrawlerLastSuccess.Set(float64(endTime.Unix()))
+
+		// a photo archive still going from the last cycle gives way to this one
+		if s.cancelArchive != nil {
+			s.cancelArchive()
+		}
+		archiveCtx, cancel := context.WithCancel(context.Background())
+		s.cancelArchive = cancel
+		go s.archiveAndScore(archiveCtx, crawlHistory.ID)
+
+		if _, err := s.bookmarkService.NotifyChanges(crawlHistory.ID); err != nil {
+			s.logger.Error("Error notifying bookmark price changes", slog.Any("error", err))
+			tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+		}
+	}
+
+	if _, err := s.imageService.Prune(time.Now()); err != nil {
+		s.logger.Error("Error deleting expired listing photos", slog.Any("error", err))
 	}
 
 	s.logger.Info("All crawlers completed. Waiting for next cycle...")
 }
 
+// archiveAndScore archives the photos of a crawl in the background and scores its listings afterwards, so scoring
+// compares the photos by their perceptual hashes. Listings are scored by their photo links when ctx is canceled.
+func (s *CrawlerService) archiveAndScore(ctx context.Context, crawlHistoryID uint) {
+	if _, err := s.imageService.ArchiveCrawl(ctx, crawlHistoryID); err != nil && ctx.Err() == nil {
+		s.logger.Error("Error archiving listing photos", slog.Any("error", err))
+		tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+	}
+	if _, err := s.fraudService.ScoreCrawl(crawlHistoryID, time.Now()); err != nil {
+		s.logger.Error("Error scoring crawled listings", slog.Any("error", err))
+		tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+	}
+}
+
+// runTask crawls one city from one source and collects its telemetry
+func (s *CrawlerService) runTask(ctx context.Context, crawler crawlers.Crawler, city crawlerModels.City) *crawlTask {
+	task := &crawlTask{
+		source:    crawler.Source(),
+		city:      city,
+		stats:     crawlers.NewCrawlStats(),
+		startedAt: time.Now(),
+	}
+	cpuTimeAtStart := processCPUTime()
+
+	result, err := crawler.Crawl(crawlers.WithStats(ctx, task.stats), city)
+
+	task.finishedAt = time.Now()
+	task.processRSS, task.processCPU = processUsageSince(cpuTimeAtStart, task.finishedAt.Sub(task.startedAt))
+	defer observeTask(task)
+	if err != nil {
+		s.logger.Error("Failed to crawl city", slog.String("city", city.Name), slog.String("source", string(task.source)), slog.Any("error", err))
+		tracking.Capture(models.CRAWLER_ERROR, err, tracking.Origin{Source: task.source, City: city.Name})
+		return task
+	}
+
+	for i := range result {
+		result[i] = processPost(result[i])
+	}
+	task.posts = result
+	return task
+}
+
+// LastSuccessfulCrawl returns the end time of the last cycle that saved posts, or zero before the first one
+func (s *CrawlerService) LastSuccessfulCrawl() time.Time {
+	lastSuccess := s.lastSuccess.Load()
+	if lastSuccess == 0 {
+		return time.Time{}
+	}
+	return time.Unix(lastSuccess, 0)
+}
+
+// observeTask exports the telemetry of a finished task to Prometheus
+func observeTask(task *crawlTask) {
+	source, city := string(task.source), task.city.Name
+	stats := task.stats.Snapshot()
+
+	metrics.CrawlerPages.WithLabelValues(source, city).Add(float64(stats.Requests))
+	metrics.CrawlerBytes.WithLabelValues(source, city).Add(float64(stats.BytesFetched))
+	metrics.CrawlerPosts.WithLabelValues(source, city).Add(float64(len(task.posts)))
+	for class, count := range stats.ErrorCounts {
+		metrics.CrawlerErrors.WithLabelValues(source, city, string(class)).Add(float64(count))
+	}
+	metrics.CrawlerTaskDuration.WithLabelValues(source, city).Observe(task.finishedAt.Sub(task.startedAt).Seconds())
+}
+
 // Helper functions and types
 
 // Map for replacing Persian digits to English digits
@@ -193,7 +303,39 @@ type CrawlerSession struct {
 	ExecutionTime time.Duration
 	TotalCPU      float64
 	TotalMemory   float64
-	Posts         []crawlerModels.Post
+	Tasks         []*crawlTask
+}
+
+// crawlTask is the result and telemetry of crawling one city from one source
+type crawlTask struct {
+	source     types.WebsiteSource
+	city       crawlerModels.City
+	stats      *crawlers.CrawlStats
+	startedAt  time.Time
+	finishedAt time.Time
+	processRSS uint64
+	// processCPU is process-wide, the tasks of a chunk share it
+	processCPU     float64
+	concurrentRuns int
+	posts          []crawlerModels.Post
+}
+
+// PostCount returns the number of posts crawled in the whole session
+func (session CrawlerSession) PostCount() int {
+	count := 0
+	for _, task := range session.Tasks {
+		count += len(task.posts)
+	}
+	return count
+}
+
+// RequestCount returns the number of page requests sent in the whole session
+func (session CrawlerSession) RequestCount() uint {
+	var count uint
+	for _, task := range session.Tasks {
+		count += task.stats.Snapshot().Requests
+	}
+	return count
 }
 
 // chunkCities splits the cities into smaller chunks
@@ -209,7 +351,10 @@ func chunkCities(cities []crawlerModels.City, chunkSize int) [][]crawlerModels.C
 	return chunks
 }
 
-// monitorResources monitors CPU and memory usage
+// crawlerProcess is the running process, used to measure the resources the crawlers use
+var crawlerProcess, _ = process.NewProcess(int32(os.Getpid()))
+
+// monitorResources samples the CPU percent and resident memory (in MB) of the process
 func monitorResources(ctx context.Context, sampleInterval time.Duration) (float64, float64, error) {
 	var (
 		cpuSamples []float64
@@ -226,25 +371,55 @@ func monitorResources(ctx context.Context, sampleInterval time.Duration) (float6
 		case <-ctx.Done():
 			return calculateAverage(cpuSamples), calculateAverage(memSamples), nil
 		case <-ticker.C:
-			cpuPercent, err := cpu.Percent(0, false)
-			if err != nil {
-				logger.Error("Failed to get cpu percent", err)
+			if crawlerProcess == nil {
 				continue
 			}
-			if len(cpuPercent) > 0 {
-				cpuSamples = append(cpuSamples, cpuPercent[0])
+			cpuPercent, err := crawlerProcess.Percent(0)
+			if err != nil {
+				logger.Error("Failed to get cpu percent", slog.Any("error", err))
+				continue
 			}
+			cpuSamples = append(cpuSamples, cpuPercent)
 
-			memStat, err := mem.VirtualMemory()
+			memInfo, err := crawlerProcess.MemoryInfo()
 			if err != nil {
-				logger.Error("Failed to get mem usage", err)
+				logger.Error("Failed to get mem usage", slog.Any("error", err))
 				continue
 			}
-			memSamples = append(memSamples, memStat.UsedPercent)
+			memSamples = append(memSamples, float64(memInfo.RSS)/(1024*1024))
 		}
 	}
 }
 
+// processCPUTime returns the CPU seconds used by the process so far
+func processCPUTime() float64 {
+	if crawlerProcess == nil {
+		return 0
+	}
+	times, err := crawlerProcess.Times()
+	if err != nil {
+		return 0
+	}
+	return times.User + times.System
+}
+
+// processUsageSince returns the current resident memory in bytes and the CPU percent used since cpuTimeAtStart,
+// both of the whole process and so of every task running at the time
+func processUsageSince(cpuTimeAtStart float64, elapsed time.Duration) (uint64, float64) {
+	if crawlerProcess == nil {
+		return 0, 0
+	}
+	var rss uint64
+	if memInfo, err := crawlerProcess.MemoryInfo(); err == nil {
+		rss = memInfo.RSS
+	}
+	if elapsed <= 0 {
+		return rss, 0
+	}
+	cpuPercent := (processCPUTime() - cpuTimeAtStart) / elapsed.Seconds() * 100
+	return rss, math.Round(cpuPercent*100) / 100
+}
+
 // calculateAverage calculates the average of a slice of float64 numbers
 func calculateAverage(samples []float64) float64 {
 	var sum float64
@@ -257,12 +432,12 @@ func calculateAverage(samples []float64) float64 {
 	return sum / float64(len(samples))
 }
 
-func mapAndSaveCrawlerSession(session CrawlerSession, repository db.PostRepo) error {
+func mapAndSaveCrawlerSession(session CrawlerSession, repository db.PostRepo, crawlRunRepository db.CrawlRunRepository, rentRate float64) (models.CrawlHistory, error) {
 	crawlHistory := models.CrawlHistory{
-		PostNum:     uint(len(session.Posts)),
+		PostNum:     uint(session.PostCount()),
 		CpuUsage:    float32(math.Round(session.TotalCPU*100) / 100),
 		MemoryUsage: float32(math.Round(session.TotalMemory*100) / 100),
-		RequestsNum: uint(len(session.Posts)),
+		RequestsNum: session.RequestCount(),
 		StartedAt:   session.StartTime,
 		FinishedAt:  session.EndTime,
 	}
@@ -271,64 +446,129 @@ func mapAndSaveCrawlerSession(session CrawlerSession, repository db.PostRepo) er
 
 	insertedCrawlHistory, err := repository.CrawlHistorySaving(crawlHistory)
 	if err != nil {
-		logger.Error("failed to save CrawlHistory: ", err)
-		return fmt.Errorf("failed to save CrawlHistory: %w", err)
+		logger.Error("failed to save CrawlHistory", slog.Any("error", err))
+		tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+		return insertedCrawlHistory, fmt.Errorf("failed to save CrawlHistory: %w", err)
 	}
 
-	// 2. نگاشت Posts و PostHistory
-	for _, post := range session.Posts {
-		// ذخیره Post
-		dbPost := models.Post{
-			UniqueCode: post.ID,
-			Website:    post.Website,
-		}
+	for _, task := range session.Tasks {
+		var newPosts, updatedPosts uint
 
-		insertedPost, err := repository.PostSaving(dbPost.UniqueCode, post.Website)
-		if err != nil {
-			logger.Error("failed to save post: ", post.ID, "; error: ", err)
-			log.Printf("failed to save post %s: %v", post.ID, err)
-			continue
+		// 2. نگاشت Posts و PostHistory
+		for _, post := range task.posts {
+			isNew, err := savePost(post, insertedCrawlHistory, repository, rentRate)
+			if err != nil {
+				logger.Error("failed to save post", slog.String("post", post.ID), slog.Any("error", err))
+				log.Printf("failed to save post %s: %v", post.ID, err)
+				tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{Source: task.source, City: task.city.Name, URL: post.Link})
+				continue
+			}
+			if isNew {
+				newPosts++
+			} else {
+				updatedPosts++
+			}
 		}
 
-		postHistory := models.PostHistory{
-			PostID:         insertedPost.ID,
-			Title:          post.Title,
-			PostURL:        post.Link,
-			Price:          parsePrice(post.TotalPrice),
-			Deposit:        parsePrice(post.Deposit),
-			Rent:           parsePrice(post.MonthlyRent),
-			City:           post.City.Name,
-			Neighborhood:   post.Neighborhood,
-			Area:           parseArea(post.Area),
-			BedroomNum:     parseBedrooms(post.Rooms),
-			Age:            parseAge(post.YearBuilt),
-			FloorsNum:      parseFloors(post.Floor),
-			HasStorage:     containsFeature(post.Features, "انباری"),
-			HasParking:     containsFeature(post.Features, "پارکینگ"),
-			HasElevator:    containsFeature(post.Features, "آسانسور"),
-			ImageURL:       strings.Join(post.Images, ","),
-			Description:    post.Description,
-			CrawlHistoryID: insertedCrawlHistory.ID,
+		if _, err := crawlRunRepository.Save(mapCrawlRun(task, insertedCrawlHistory, newPosts, updatedPosts)); err != nil {
+			logger.Error("failed to save CrawlRun", slog.String("city", task.city.Name), slog.String("source", string(task.source)), slog.Any("error", err))
+			tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{Source: task.source, City: task.city.Name})
 		}
+	}
 
-		// بررسی وجود RentalMetadata
-		if post.RentalMetadata != nil {
-			postHistory.Capacity = post.RentalMetadata.Capacity
-			postHistory.NormalDays = post.RentalMetadata.NormalDayPrice
-			postHistory.Weekend = post.RentalMetadata.WeekendPrice
-			postHistory.Holidays = post.RentalMetadata.HolidayPrice
-			postHistory.CostPerPerson = post.RentalMetadata.ExtraPersonCost
-		}
+	return insertedCrawlHistory, nil
+}
 
-		_, err = repository.PostHistorySaving(postHistory, insertedPost, insertedCrawlHistory)
-		if err != nil {
-			logger.Error("failed to save PostHistory for post: ", dbPost.ID, "; error: ", err)
-			log.Printf("failed to save PostHistory for post %s: %v", post.ID, err)
-			continue
-		}
+// savePost stores a crawled post and its history, reporting whether the post was seen for the first time.
+// Rentals also get their equivalent full deposit and monthly cost at rentRate.
+func savePost(post crawlerModels.Post, crawlHistory models.CrawlHistory, repository db.PostRepo, rentRate float64) (bool, error) {
+	// ذخیره Post
+	dbPost := models.Post{
+		UniqueCode: post.ID,
+		Website:    post.Website,
+	}
+	isNew := !repository.PostIsExist(dbPost)
+
+	insertedPost, err := repository.PostSaving(dbPost.UniqueCode, post.Website)
+	if err != nil {
+		return false, err
+	}
+
+	postHistory := models.PostHistory{
+		PostID:         insertedPost.ID,
+		Title:          post.Title,
+		PostURL:        post.Link,
+		Price:          parsePrice(post.TotalPrice),
+		Deposit:        parsePrice(post.Deposit),
+		Rent:           parsePrice(post.MonthlyRent),
+		City:           post.City.Name,
+		Neighborhood:   post.Neighborhood,
+		Area:           parseArea(post.Area),
+		BedroomNum:     parseBedrooms(post.Rooms),
+		Age:            parseAge(post.YearBuilt),
+		FloorsNum:      parseFloors(post.Floor),
+		HasStorage:     containsFeature(post.Features, "انباری") || amenities.Has(post.Description, nil, types.Storage),
+		HasParking:     containsFeature(post.Features, "پارکینگ") || amenities.Has(post.Description, nil, types.Parking),
+		HasElevator:    containsFeature(post.Features, "آسانسور") || amenities.Has(post.Description, nil, types.Elevator),
+		Amenities:      amenities.Extract(post.Description, post.Features),
+		ImageURL:       strings.Join(post.Images, ","),
+		Description:    post.Description,
+		SellerType:     ClassifySeller(post.SellerType, post.Description),
+		Building:       ClassifyBuilding(post.Category, post.Title),
+		CrawlHistoryID: crawlHistory.ID,
+	}
+
+	if post.Latitude != 0 || post.Longitude != 0 {
+		postHistory.Latitude, postHistory.Longitude = &post.Latitude, &post.Longitude
+	}
+
+	// بررسی وجود RentalMetadata
+	if post.RentalMetadata != nil {
+		postHistory.Capacity = post.RentalMetadata.Capacity
+		postHistory.NormalDays = post.RentalMetadata.NormalDayPrice
+		postHistory.Weekend = post.RentalMetadata.WeekendPrice
+		postHistory.Holidays = post.RentalMetadata.HolidayPrice
+		postHistory.CostPerPerson = post.RentalMetadata.ExtraPersonCost
 	}
 
-	return nil
+	if postHistory.Deposit > 0 || postHistory.Rent > 0 {
+		postHistory.BuyMode = types.Rent
+		postHistory.Convertible = isConvertible(post.DepositOnRentDesc)
+		postHistory.FullDeposit, postHistory.MonthlyCost = ConvertRent(postHistory.Deposit, postHistory.Rent, rentRate)
+	} else if postHistory.Price > 0 {
+		postHistory.BuyMode = types.Shopping
+	}
+
+	if _, err = repository.PostHistorySaving(postHistory, insertedPost, crawlHistory); err != nil {
+		return false, fmt.Errorf("failed to save PostHistory: %w", err)
+	}
+	return isNew, nil
+}
+
+// mapCrawlRun converts the telemetry of a crawl task to its database model
+func mapCrawlRun(task *crawlTask, crawlHistory models.CrawlHistory, newPosts uint, updatedPosts uint) models.CrawlRun {
+	stats := task.stats.Snapshot()
+	errorCounts, _ := json.Marshal(stats.ErrorCounts)
+
+	return models.CrawlRun{
+		CrawlHistoryID: crawlHistory.ID,
+		Source:         task.source,
+		City:           task.city.Name,
+		StartedAt:      task.startedAt,
+		FinishedAt:     task.finishedAt,
+		DurationMs:     task.finishedAt.Sub(task.startedAt).Milliseconds(),
+		RequestsNum:    stats.Requests,
+		SuccessNum:     stats.Successes,
+		FailureNum:     stats.Failures,
+		ErrorCounts:    string(errorCounts),
+		BytesFetched:   stats.BytesFetched,
+		PostNum:        uint(len(task.posts)),
+		NewPostNum:     newPosts,
+		UpdatedPostNum: updatedPosts,
+		ProcessRSS:     task.processRSS,
+		ProcessWideCPU: float32(task.processCPU),
+		ConcurrentRuns: uint(task.concurrentRuns),
+	}
 }
 
 // Helper functions for parsing
@@ -341,6 +581,12 @@ func parsePrice(price string) int64 {
 	return parsed
 }
 
+// isConvertible reads the deposit and rent row of a listing, e.g. "قابل تبدیل" or "غیر قابل تبدیل"
+func isConvertible(description string) bool {
+	description = strings.ReplaceAll(description, "\u200c", " ")
+	return strings.Contains(description, "قابل تبدیل") && !strings.Contains(description, "غیر")
+}
+
 func parseArea(area string) int {
 	area = replaceDigits(area)
 	parsed, _ := strconv.Atoi(area)
@@ -353,14 +599,15 @@ func parseBedrooms(rooms string) int {
 	return parsed
 }
 
+// parseAge reads the build year of a listing, like "۱۳۹۸" or "قبل از ۱۳۷۰", the sites write it on the Jalali calendar
 func parseAge(yearBuilt string) uint8 {
-	currentYear := time.Now().Year()
-	yearBuilt = replaceDigits(yearBuilt)
-	builtYear, _ := strconv.Atoi(yearBuilt)
-	if builtYear > 0 {
-		return uint8(currentYear - builtYear)
-	}
-	return 0
+	builtYear, _ := strconv.Atoi(strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, replaceDigits(yearBuilt)))
+	return uint8(min(jalali.YearsSince(builtYear, time.Now().In(utils.TehranLocation())), math.MaxUint8))
 }
 
 func parseFloors(floor string) uint8 {
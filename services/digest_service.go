@@ -0,0 +1,400 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDigestInterval = 5 // minutes
+	defaultDigestTop      = 5
+	defaultDigestPageSize = 10
+)
+
+type DigestSection string
+
+const (
+	SectionNew        DigestSection = "new"
+	SectionPriceDrops DigestSection = "drops"
+	SectionRemoved    DigestSection = "removed"
+)
+
+var (
+	// ErrInvalidDigestSchedule is returned for a digest schedule that cannot be read
+	ErrInvalidDigestSchedule = errors.New("invalid digest schedule")
+	// ErrDigestFilterNotFound is returned when the filter does not exist or belongs to another user
+	ErrDigestFilterNotFound = errors.New("digest filter not found")
+	// ErrDigestNotFound is returned when the digest does not exist or was sent to another user
+	ErrDigestNotFound = errors.New("digest not found")
+)
+
+// DigestListing is a listing in a digest. Before is its history at the start of the period, for price drops.
+type DigestListing struct {
+	History models.PostHistory
+	Before  models.PostHistory
+	Drop    float64 // percent
+}
+
+// Digest is what changed for a saved filter in the period of a run
+type Digest struct {
+	Run        models.DigestRun
+	Filter     models.FilterItem
+	New        []DigestListing // cheapest first
+	PriceDrops []DigestListing // largest drop first
+	Removed    []DigestListing // latest first
+}
+
+// Empty tells whether nothing changed in the period
+func (d Digest) Empty() bool {
+	return len(d.New) == 0 && len(d.PriceDrops) == 0 && len(d.Removed) == 0
+}
+
+// Section returns the listings of a section
+func (d Digest) Section(section DigestSection) []DigestListing {
+	switch section {
+	case SectionNew:
+		return d.New
+	case SectionPriceDrops:
+		return d.PriceDrops
+	case SectionRemoved:
+		return d.Removed
+	}
+	return nil
+}
+
+// DigestNotifier sends a digest to its user, Top listings of each section at most
+type DigestNotifier func(user models.User, digest Digest, top int)
+
+// DigestService sends users scheduled summaries of the new listings, price drops and removals of their saved filters
+type DigestService struct {
+	repository       db.DigestRepository
+	filterRepository db.FilterItemRepository
+	top              int
+	pageSize         int
+	notifierMutex    sync.RWMutex
+	notifier         DigestNotifier
+	logger           *slog.Logger
+}
+
+func NewDigestService(repository db.DigestRepository, filterRepository db.FilterItemRepository) *DigestService {
+	return &DigestService{
+		repository:       repository,
+		filterRepository: filterRepository,
+		top:              intConfig("DIGEST_TOP_LISTINGS", defaultDigestTop),
+		pageSize:         intConfig("DIGEST_PAGE_SIZE", defaultDigestPageSize),
+		logger:           utils.NewLogger("Digest_Service"),
+	}
+}
+
+// SetNotifier sets how digests are sent to users
+func (s *DigestService) SetNotifier(notifier DigestNotifier) {
+	s.notifierMutex.Lock()
+	defer s.notifierMutex.Unlock()
+	s.notifier = notifier
+}
+
+func (s *DigestService) notify(user models.User, digest Digest) {
+	s.notifierMutex.RLock()
+	notifier := s.notifier
+	s.notifierMutex.RUnlock()
+	if notifier != nil && user.TelegramID != 0 {
+		notifier(user, digest, s.top)
+	}
+}
+
+// PageSize is the number of listings on a page of a digest section
+func (s *DigestService) PageSize() int {
+	return s.pageSize
+}
+
+// Start sends the due digests periodically in the background
+func (s *DigestService) Start() {
+	interval := time.Duration(intConfig("DIGEST_CHECK_INTERVAL", defaultDigestInterval)) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.RunDue(time.Now()); err != nil {
+				s.logger.Error("digest run failed", slog.Any("error", err))
+				tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ParseDigestSchedule reads "daily,<hour>" or "weekly,<weekday>,<hour>", hours in Tehran time
+func ParseDigestSchedule(value string) (models.DigestSubscription, error) {
+	fields := strings.Split(value, ",")
+	for i := range fields {
+		fields[i] = strings.ToLower(strings.TrimSpace(fields[i]))
+	}
+	subscription := models.DigestSubscription{Frequency: models.DigestFrequency(fields[0])}
+	switch {
+	case subscription.Frequency == models.DIGEST_DAILY && len(fields) == 2:
+	case subscription.Frequency == models.DIGEST_WEEKLY && len(fields) == 3:
+		weekday, ok := parseWeekday(fields[1])
+		if !ok {
+			return subscription, ErrInvalidDigestSchedule
+		}
+		subscription.Weekday = weekday
+	default:
+		return subscription, ErrInvalidDigestSchedule
+	}
+	hour, err := strconv.Atoi(replaceDigits(fields[len(fields)-1]))
+	if err != nil || hour < 0 || hour > 23 {
+		return subscription, ErrInvalidDigestSchedule
+	}
+	subscription.Hour = hour
+	return subscription, nil
+}
+
+// parseWeekday reads an English weekday name or its first three letters
+func parseWeekday(value string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if value == name || (len(value) == 3 && strings.HasPrefix(name, value)) {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// LastOccurrence returns the latest time at or before now a subscription is scheduled for
+func LastOccurrence(subscription models.DigestSubscription, now time.Time) time.Time {
+	occurrence := utils.TehranDayStart(now).Add(time.Duration(subscription.Hour) * time.Hour)
+	if occurrence.After(now) {
+		occurrence = occurrence.AddDate(0, 0, -1)
+	}
+	if subscription.Frequency == models.DIGEST_WEEKLY {
+		for occurrence.Weekday() != subscription.Weekday {
+			occurrence = occurrence.AddDate(0, 0, -1)
+		}
+	}
+	return occurrence.UTC()
+}
+
+// Subscriptions returns the digest subscriptions of a user
+func (s *DigestService) Subscriptions(user models.User) ([]models.DigestSubscription, error) {
+	return s.repository.FindUserSubscriptions(user.ID)
+}
+
+// Subscribe sends the digest of a saved filter of the user on a schedule, or changes the schedule
+func (s *DigestService) Subscribe(user models.User, filterID uint, schedule models.DigestSubscription) (models.DigestSubscription, error) {
+	if schedule.Hour < 0 || schedule.Hour > 23 ||
+		(schedule.Frequency != models.DIGEST_DAILY && schedule.Frequency != models.DIGEST_WEEKLY) {
+		return schedule, ErrInvalidDigestSchedule
+	}
+	if err := s.checkFilter(user, filterID); err != nil {
+		return schedule, err
+	}
+	schedule.UserID, schedule.FilterItemID = user.ID, filterID
+	subscription, err := s.repository.SaveSubscription(schedule)
+	if err == nil {
+		s.logger.Info("digest scheduled", slog.Uint64("user", uint64(user.ID)), slog.Uint64("filter", uint64(filterID)),
+			slog.String("frequency", string(schedule.Frequency)))
+	}
+	return subscription, err
+}
+
+// Unsubscribe stops the digest of a saved filter of the user
+func (s *DigestService) Unsubscribe(user models.User, filterID uint) error {
+	if err := s.checkFilter(user, filterID); err != nil {
+		return err
+	}
+	return s.repository.DeleteSubscription(filterID)
+}
+
+func (s *DigestService) checkFilter(user models.User, filterID uint) error {
+	filter, err := s.filterRepository.FindByID(filterID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && filter.UserID != user.ID) {
+		return ErrDigestFilterNotFound
+	}
+	return err
+}
+
+// RunDue sends the digests whose time has come, it returns how many were sent. A digest covers the period since
+// the last one that was sent, so a single digest catches up the ones missed while the bot was down. Digests with
+// nothing to tell are recorded but not sent.
+func (s *DigestService) RunDue(now time.Time) (int, error) {
+	subscriptions, err := s.repository.FindSubscriptions()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, subscription := range subscriptions {
+		ok, err := s.run(subscription, now)
+		if err != nil {
+			s.logger.Warn("could not send digest", slog.Uint64("subscription", uint64(subscription.ID)), slog.Any("error", err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	s.logger.Info("digests checked", slog.Int("subscriptions", len(subscriptions)), slog.Int("sent", sent))
+	return sent, nil
+}
+
+func (s *DigestService) run(subscription models.DigestSubscription, now time.Time) (bool, error) {
+	scheduledFor := LastOccurrence(subscription, now)
+	periodStart := subscription.CreatedAt
+	last, err := s.repository.FindLastSentRun(subscription.ID)
+	if err == nil {
+		periodStart = last.PeriodEnd
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if !scheduledFor.After(periodStart) {
+		return false, nil
+	}
+
+	run, err := s.repository.StartRun(subscription.ID, scheduledFor, periodStart.UTC())
+	if err != nil || run.SentAt != nil {
+		return false, err
+	}
+	digest, err := s.build(run, subscription.FilterItem)
+	if err != nil {
+		run.Error = err.Error()
+		if saveErr := s.repository.SaveRun(run); saveErr != nil {
+			s.logger.Error("could not record failed digest", slog.Uint64("run", uint64(run.ID)), slog.Any("error", saveErr))
+		}
+		return false, err
+	}
+
+	// the run is recorded as sent before the digest goes out, a run that could not be recorded is retried
+	// instead of sending the digest twice
+	run.NewListings, run.PriceDrops, run.Removals = len(digest.New), len(digest.PriceDrops), len(digest.Removed)
+	run.SentAt, run.Error = &now, ""
+	if err := s.repository.SaveRun(run); err != nil {
+		return false, err
+	}
+	digest.Run = run
+	if digest.Empty() {
+		return false, nil
+	}
+	s.notify(subscription.User, digest)
+	return true, nil
+}
+
+// Page returns a page of a section of a digest sent to the user and the number of listings in the section
+func (s *DigestService) Page(user models.User, runID uint, section DigestSection, page int) ([]DigestListing, int, error) {
+	run, err := s.repository.FindRun(runID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && run.Subscription.UserID != user.ID) {
+		return nil, 0, ErrDigestNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	digest, err := s.build(run, run.Subscription.FilterItem)
+	if err != nil {
+		return nil, 0, err
+	}
+	listings := digest.Section(section)
+	start := min(max(page, 0)*s.pageSize, len(listings))
+	return listings[start:min(start+s.pageSize, len(listings))], len(listings), nil
+}
+
+// build finds what changed for a filter in the period of a run
+func (s *DigestService) build(run models.DigestRun, filter models.FilterItem) (Digest, error) {
+	start, end := run.PeriodStart.UTC(), run.PeriodEnd.UTC()
+	digest := Digest{Run: run, Filter: filter}
+
+	histories, err := s.repository.FindNewListings(filter, start, end)
+	if err != nil {
+		return digest, err
+	}
+	for _, history := range histories {
+		digest.New = append(digest.New, DigestListing{History: history})
+	}
+	sort.SliceStable(digest.New, func(i, j int) bool {
+		return listingCost(digest.New[i].History) < listingCost(digest.New[j].History)
+	})
+
+	histories, err = s.repository.FindUpdatedListings(filter, start, end)
+	if err != nil {
+		return digest, err
+	}
+	postIDs := make([]uint, 0, len(histories))
+	for _, history := range histories {
+		postIDs = append(postIDs, history.PostID)
+	}
+	before, err := s.repository.FindHistoriesAt(postIDs, start)
+	if err != nil {
+		return digest, err
+	}
+	beforeByPost := make(map[uint]models.PostHistory)
+	for _, history := range before {
+		beforeByPost[history.PostID] = history
+	}
+	for _, history := range histories {
+		if drop := PriceDrop(beforeByPost[history.PostID], history); drop > 0 {
+			digest.PriceDrops = append(digest.PriceDrops, DigestListing{History: history, Before: beforeByPost[history.PostID], Drop: drop})
+		}
+	}
+	sort.SliceStable(digest.PriceDrops, func(i, j int) bool { return digest.PriceDrops[i].Drop > digest.PriceDrops[j].Drop })
+
+	histories, err = s.repository.FindRemovedListings(filter, start, end)
+	if err != nil {
+		return digest, err
+	}
+	for _, history := range histories {
+		digest.Removed = append(digest.Removed, DigestListing{History: history})
+	}
+	sort.SliceStable(digest.Removed, func(i, j int) bool {
+		return removedAt(digest.Removed[i].History).After(removedAt(digest.Removed[j].History))
+	})
+	return digest, nil
+}
+
+// PriceDrop returns by how many percent the price of a listing fell, 0 when it did not. Rentals compare their
+// monthly cost, the rent plus the deposit converted to rent.
+func PriceDrop(before models.PostHistory, after models.PostHistory) float64 {
+	if before.ID == 0 || before.BuyMode != after.BuyMode {
+		return 0
+	}
+	previous, current := listingCost(before), listingCost(after)
+	if previous <= 0 || current <= 0 || current >= previous {
+		return 0
+	}
+	return float64(previous-current) * 100 / float64(previous)
+}
+
+// listingCost is the price of a purchase, or the monthly cost of a rental
+func listingCost(history models.PostHistory) int64 {
+	if history.BuyMode == types.Rent {
+		if history.MonthlyCost > 0 {
+			return history.MonthlyCost
+		}
+		return history.Rent
+	}
+	return history.Price
+}
+
+func removedAt(history models.PostHistory) time.Time {
+	if history.Post.RemovedAt == nil {
+		return time.Time{}
+	}
+	return *history.Post.RemovedAt
+}
+
+// DescribeSchedule tells when a digest is sent, in the language of the user
+func DescribeSchedule(user models.User, subscription models.DigestSubscription) string {
+	if subscription.Frequency == models.DIGEST_WEEKLY {
+		return tr(user, "digest.weekly", tr(user, fmt.Sprintf("weekday.%d", subscription.Weekday)), subscription.Hour)
+	}
+	return tr(user, "digest.daily", subscription.Hour)
+}
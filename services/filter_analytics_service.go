@@ -0,0 +1,211 @@
+package services
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const anyValue = "any"
+
+// PriceBand is a range of the maximum price users ask for, Max is 0 for the last open band
+type PriceBand struct {
+	Min   float64
+	Max   float64
+	Count int
+}
+
+var priceBandEdges = []float64{500_000_000, 1_000_000_000, 2_000_000_000, 5_000_000_000, 10_000_000_000, 20_000_000_000}
+
+// ValueCount is how many filters ask for a value
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// FilterAnalytics aggregates all filters saved by users
+type FilterAnalytics struct {
+	Filters       int
+	Users         int
+	Cities        []ValueCount
+	Neighborhoods []ValueCount // "city/neighborhood"
+	Categories    []ValueCount
+	PriceBands    map[string][]PriceBand // per category
+	NoPrice       int                    // filters without any price limit
+	ZeroResults   int
+	Unchecked     int // filters whose results could not be counted
+	TopUsers      []UserFilterCount
+}
+
+// UserFilterCount is the number of filters one user saved
+type UserFilterCount struct {
+	UserID uint
+	Count  int
+}
+
+// FilterReport is a saved filter with the number of posts it currently matches
+type FilterReport struct {
+	Filter  models.FilterItem
+	Results int64
+	Err     error
+}
+
+// FilterAnalyticsService reports what users search for, to decide where crawling should focus
+type FilterAnalyticsService struct {
+	filterRepository db.FilterItemRepository
+	logger           *slog.Logger
+}
+
+func NewFilterAnalyticsService(filterRepository db.FilterItemRepository) *FilterAnalyticsService {
+	return &FilterAnalyticsService{
+		filterRepository: filterRepository,
+		logger:           utils.NewLogger("Filter_Analytics_Service"),
+	}
+}
+
+// Analyze aggregates all saved filters, limit caps the length of every top list
+func (s *FilterAnalyticsService) Analyze(limit int) (FilterAnalytics, error) {
+	filters, err := s.filterRepository.FindAll()
+	if err != nil {
+		return FilterAnalytics{}, err
+	}
+
+	analytics := FilterAnalytics{Filters: len(filters), PriceBands: make(map[string][]PriceBand)}
+	cities := make(map[string]int)
+	neighborhoods := make(map[string]int)
+	categories := make(map[string]int)
+	users := make(map[uint]int)
+
+	for _, filter := range filters {
+		users[filter.UserID]++
+		cities[valueOrAny(filter.City)]++
+		if filter.Neighborhood != "" {
+			neighborhoods[valueOrAny(filter.City)+"/"+strings.TrimSpace(filter.Neighborhood)]++
+		}
+		category := valueOrAny(filter.Category)
+		categories[category]++
+
+		if filter.PriceMin <= 0 && filter.PriceMax <= 0 {
+			analytics.NoPrice++
+		} else {
+			bands, exists := analytics.PriceBands[category]
+			if !exists {
+				bands = newPriceBands()
+			}
+			addToPriceBand(bands, filter)
+			analytics.PriceBands[category] = bands
+		}
+
+	}
+
+	counts, err := s.filterRepository.CountPostHistories(filters)
+	if err != nil {
+		s.logger.Warn("could not count filter results", slog.Any("error", err))
+		analytics.Unchecked = len(filters)
+	}
+	for _, results := range counts {
+		if results == 0 {
+			analytics.ZeroResults++
+		}
+	}
+
+	analytics.Users = len(users)
+	analytics.Cities = topValues(cities, limit)
+	analytics.Neighborhoods = topValues(neighborhoods, limit)
+	analytics.Categories = topValues(categories, 0)
+	for userID, count := range users {
+		analytics.TopUsers = append(analytics.TopUsers, UserFilterCount{UserID: userID, Count: count})
+	}
+	sort.Slice(analytics.TopUsers, func(i, j int) bool {
+		if analytics.TopUsers[i].Count != analytics.TopUsers[j].Count {
+			return analytics.TopUsers[i].Count > analytics.TopUsers[j].Count
+		}
+		return analytics.TopUsers[i].UserID < analytics.TopUsers[j].UserID
+	})
+	if limit > 0 && len(analytics.TopUsers) > limit {
+		analytics.TopUsers = analytics.TopUsers[:limit]
+	}
+	return analytics, nil
+}
+
+// UserFilters returns the filters of a user with the number of posts each matches
+func (s *FilterAnalyticsService) UserFilters(userID uint) ([]FilterReport, error) {
+	filters, err := s.filterRepository.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.filterRepository.CountPostHistories(filters)
+	reports := make([]FilterReport, 0, len(filters))
+	for i, filter := range filters {
+		report := FilterReport{Filter: filter, Err: err}
+		if err == nil {
+			report.Results = counts[i]
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// ZeroResultShare is the percent of checked filters that match no post
+func (analytics FilterAnalytics) ZeroResultShare() float64 {
+	checked := analytics.Filters - analytics.Unchecked
+	if checked == 0 {
+		return 0
+	}
+	return float64(analytics.ZeroResults) * 100 / float64(checked)
+}
+
+func newPriceBands() []PriceBand {
+	bands := make([]PriceBand, 0, len(priceBandEdges)+1)
+	var min float64
+	for _, edge := range priceBandEdges {
+		bands = append(bands, PriceBand{Min: min, Max: edge})
+		min = edge
+	}
+	return append(bands, PriceBand{Min: min})
+}
+
+// addToPriceBand counts the filter in the band of its maximum price, or its minimum when it has no maximum
+func addToPriceBand(bands []PriceBand, filter models.FilterItem) {
+	price := filter.PriceMax
+	if price <= 0 {
+		price = filter.PriceMin
+	}
+	for i := range bands {
+		if bands[i].Max == 0 || price < bands[i].Max {
+			bands[i].Count++
+			return
+		}
+	}
+}
+
+func valueOrAny(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return anyValue
+	}
+	return value
+}
+
+// topValues sorts the counts descending and keeps the first limit ones, all when limit is 0
+func topValues(counts map[string]int, limit int) []ValueCount {
+	values := make([]ValueCount, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
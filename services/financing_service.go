@@ -0,0 +1,302 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	maxLoanRate  = 100.0 // percent per year
+	maxLoanYears = 30
+)
+
+var (
+	// ErrInvalidLoanProduct is returned for loan products with a missing name or out of range terms
+	ErrInvalidLoanProduct = errors.New("invalid loan product")
+	// ErrInvalidProfile is returned for negative amounts, or a profile without cash or budget
+	ErrInvalidProfile = errors.New("invalid affordability profile")
+	// ErrNoProfile is returned when the user never told what they can afford
+	ErrNoProfile = errors.New("no affordability profile")
+)
+
+// AffordableRanges are the largest amounts a user can afford, as search filters
+type AffordableRanges struct {
+	PriceMax   int64 // purchase price, the cash plus Loan
+	Loan       int64 // the loan the purchase price assumes
+	DepositMax int64 // rental deposit, the cash
+	RentMax    int64 // monthly rent, the monthly budget
+}
+
+// Payment is the loan of a purchase and its monthly installment
+type Payment struct {
+	Loan    int64
+	Monthly int64
+	Product models.LoanProduct
+}
+
+// FinancingService manages loan products and the affordability profiles of users, and derives search ranges
+// and loan payments from them
+type FinancingService struct {
+	repository db.FinancingRepository
+	logger     *slog.Logger
+}
+
+func NewFinancingService(repository db.FinancingRepository) *FinancingService {
+	return &FinancingService{repository: repository, logger: utils.NewLogger("Financing_Service")}
+}
+
+// MonthlyPayment returns the installment of an amortized loan, annualRate in percent
+func MonthlyPayment(principal int64, annualRate float64, months int) int64 {
+	if principal <= 0 || months <= 0 {
+		return 0
+	}
+	rate := annualRate / 12 / 100
+	if rate == 0 {
+		return int64(math.Round(float64(principal) / float64(months)))
+	}
+	factor := math.Pow(1+rate, float64(months))
+	return int64(math.Round(float64(principal) * rate * factor / (factor - 1)))
+}
+
+// LoanFor returns the largest loan a monthly installment pays back, the inverse of MonthlyPayment
+func LoanFor(monthly int64, annualRate float64, months int) int64 {
+	if monthly <= 0 || months <= 0 {
+		return 0
+	}
+	rate := annualRate / 12 / 100
+	if rate == 0 {
+		return monthly * int64(months)
+	}
+	return int64(math.Floor(float64(monthly) * (1 - math.Pow(1+rate, -float64(months))) / rate))
+}
+
+// Ranges returns what a profile can afford. The loan is limited by the monthly budget, the largest amount of
+// the product, and its loan to value: the cash must cover the share of the price the loan may not.
+func Ranges(profile models.AffordabilityProfile) AffordableRanges {
+	ranges := AffordableRanges{PriceMax: profile.Cash, DepositMax: profile.Cash, RentMax: profile.MonthlyBudget}
+	product := profile.LoanProduct
+	if product == nil || !product.Active {
+		return ranges
+	}
+
+	loan := LoanFor(profile.MonthlyBudget, product.AnnualRate, product.TermMonths)
+	if product.MaxAmount > 0 && loan > product.MaxAmount {
+		loan = product.MaxAmount
+	}
+	if ltv := product.MaxLTV / 100; ltv < 1 {
+		loan = min(loan, int64(math.Floor(float64(profile.Cash)*ltv/(1-ltv))))
+	}
+	ranges.Loan = loan
+	ranges.PriceMax = profile.Cash + loan
+	return ranges
+}
+
+// ApplyRanges sets the affordable ranges as the largest price, deposit and rent of a filter. Purchases have
+// no deposit or rent and rentals no price, so the same filter finds both.
+func ApplyRanges(filter *models.FilterItem, ranges AffordableRanges) {
+	filter.PriceMax = float64(ranges.PriceMax)
+	filter.DepositMax = float64(ranges.DepositMax)
+	filter.RentMax = float64(ranges.RentMax)
+	filter.Affordable = true
+}
+
+// ReplacesLimits tells whether applying the ranges changes a largest price, deposit or rent the user set
+// themselves, ranges applied before are not the user's own
+func ReplacesLimits(filter models.FilterItem, ranges AffordableRanges) bool {
+	if filter.Affordable {
+		return false
+	}
+	replaces := func(limit float64, amount int64) bool {
+		return limit > 0 && limit != float64(amount)
+	}
+	return replaces(filter.PriceMax, ranges.PriceMax) || replaces(filter.DepositMax, ranges.DepositMax) ||
+		replaces(filter.RentMax, ranges.RentMax)
+}
+
+// ClearRanges removes the affordable ranges from a filter, the limits the user set themselves are kept
+func ClearRanges(filter *models.FilterItem) {
+	if !filter.Affordable {
+		return
+	}
+	filter.PriceMax, filter.DepositMax, filter.RentMax = 0, 0, 0
+	filter.Affordable = false
+}
+
+// ParseLoanProduct reads "<name>,<annual rate>,<years>,<max ltv>[,<max amount>]", like "Maskan,18,20,80"
+func ParseLoanProduct(value string) (models.LoanProduct, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 4 && len(fields) != 5 {
+		return models.LoanProduct{}, ErrInvalidLoanProduct
+	}
+	numbers := make([]float64, 0, 4)
+	for _, field := range fields[1:] {
+		number, err := strconv.ParseFloat(replaceDigits(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(field), "%"))), 64)
+		if err != nil {
+			return models.LoanProduct{}, ErrInvalidLoanProduct
+		}
+		numbers = append(numbers, number)
+	}
+	product := models.LoanProduct{
+		Name:       strings.Join(strings.Fields(fields[0]), " "),
+		AnnualRate: numbers[0],
+		TermMonths: int(math.Round(numbers[1] * 12)),
+		MaxLTV:     numbers[2],
+		Active:     true,
+	}
+	if len(numbers) == 4 {
+		product.MaxAmount = int64(numbers[3])
+	}
+	return product, validateLoanProduct(product)
+}
+
+func validateLoanProduct(product models.LoanProduct) error {
+	if product.Name == "" || len(product.Name) > 63 ||
+		product.AnnualRate < 0 || product.AnnualRate > maxLoanRate ||
+		product.TermMonths < 1 || product.TermMonths > maxLoanYears*12 ||
+		product.MaxLTV <= 0 || product.MaxLTV > 100 || product.MaxAmount < 0 {
+		return ErrInvalidLoanProduct
+	}
+	return nil
+}
+
+// Products returns the loan products users can choose, the cheapest first, or all of them for admins
+func (s *FinancingService) Products(activeOnly bool) ([]models.LoanProduct, error) {
+	return s.repository.FindLoanProducts(activeOnly)
+}
+
+// SaveProduct adds a loan product, or replaces the terms of the product with the same name
+func (s *FinancingService) SaveProduct(product models.LoanProduct, updatedBy uint) (models.LoanProduct, error) {
+	if err := validateLoanProduct(product); err != nil {
+		return models.LoanProduct{}, err
+	}
+	saved, err := s.repository.SaveLoanProduct(product)
+	if err == nil {
+		s.logger.Info("loan product saved", slog.String("name", saved.Name), slog.Bool("active", saved.Active),
+			slog.Uint64("by", uint64(updatedBy)))
+	}
+	return saved, err
+}
+
+// SetProductActive offers or withdraws a loan product, the profiles that chose a withdrawn product buy without a loan
+func (s *FinancingService) SetProductActive(productID uint, active bool, updatedBy uint) (models.LoanProduct, error) {
+	product, err := s.repository.FindLoanProduct(productID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.LoanProduct{}, ErrInvalidLoanProduct
+	}
+	if err != nil {
+		return models.LoanProduct{}, err
+	}
+	product.Active = active
+	return s.SaveProduct(product, updatedBy)
+}
+
+// Profile returns the affordability profile of a user
+func (s *FinancingService) Profile(user models.User) (models.AffordabilityProfile, error) {
+	profile, err := s.repository.FindProfile(user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.AffordabilityProfile{}, ErrNoProfile
+	}
+	return profile, err
+}
+
+// SaveProfile sets the cash and monthly budget of a user, keeping the loan product they chose
+func (s *FinancingService) SaveProfile(user models.User, cash int64, monthlyBudget int64) (models.AffordabilityProfile, error) {
+	if cash < 0 || monthlyBudget < 0 || (cash == 0 && monthlyBudget == 0) {
+		return models.AffordabilityProfile{}, ErrInvalidProfile
+	}
+	profile, err := s.Profile(user)
+	if err != nil && !errors.Is(err, ErrNoProfile) {
+		return models.AffordabilityProfile{}, err
+	}
+	if errors.Is(err, ErrNoProfile) {
+		// a new profile starts with the cheapest product, users can pick another one or none
+		if products, err := s.repository.FindLoanProducts(true); err == nil && len(products) > 0 {
+			profile.LoanProductID = &products[0].ID
+		}
+	}
+	profile.UserID, profile.Cash, profile.MonthlyBudget = user.ID, cash, monthlyBudget
+	return s.repository.SaveProfile(profile)
+}
+
+// RemoveProfile deletes the affordability profile of a user, the saved filters it set ranges on stop using them
+func (s *FinancingService) RemoveProfile(user models.User) error {
+	deleted, err := s.repository.DeleteProfile(user.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNoProfile
+	}
+	return nil
+}
+
+// ChooseProduct sets the loan product of the profile of a user, none when productID is 0
+func (s *FinancingService) ChooseProduct(user models.User, productID uint) (models.AffordabilityProfile, error) {
+	profile, err := s.Profile(user)
+	if err != nil {
+		return models.AffordabilityProfile{}, err
+	}
+	profile.LoanProductID = nil
+	if productID != 0 {
+		product, err := s.repository.FindLoanProduct(productID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !product.Active) {
+			return models.AffordabilityProfile{}, ErrInvalidLoanProduct
+		}
+		if err != nil {
+			return models.AffordabilityProfile{}, err
+		}
+		profile.LoanProductID = &product.ID
+	}
+	return s.repository.SaveProfile(profile)
+}
+
+// EstimatePayment returns the monthly loan payment of buying a listing, with the loan product of the user or
+// the cheapest one. The loan covers what the cash of the user does not, up to the limits of the product.
+// ok is false for rentals and when no product is offered.
+func (s *FinancingService) EstimatePayment(user models.User, listing models.PostHistory) (Payment, bool, error) {
+	if listing.BuyMode == types.Rent || listing.Price <= 0 {
+		return Payment{}, false, nil
+	}
+	profile, err := s.Profile(user)
+	if err != nil && !errors.Is(err, ErrNoProfile) {
+		return Payment{}, false, err
+	}
+	product := profile.LoanProduct
+	if product == nil || !product.Active {
+		if profile.ID != 0 && profile.LoanProductID == nil {
+			return Payment{}, false, nil // the user buys without a loan
+		}
+		products, err := s.repository.FindLoanProducts(true)
+		if err != nil || len(products) == 0 {
+			return Payment{}, false, err
+		}
+		product = &products[0]
+	}
+
+	loan := int64(math.Floor(float64(listing.Price) * product.MaxLTV / 100))
+	if product.MaxAmount > 0 {
+		loan = min(loan, product.MaxAmount)
+	}
+	if profile.ID != 0 {
+		loan = min(loan, max(listing.Price-profile.Cash, 0))
+	}
+	if loan <= 0 {
+		return Payment{}, false, nil
+	}
+	return Payment{Loan: loan, Monthly: MonthlyPayment(loan, product.AnnualRate, product.TermMonths), Product: *product}, true, nil
+}
+
+// ParseAmount reads an amount like "1,500,000,000" or "۱۵۰۰۰۰۰۰۰۰"
+func ParseAmount(value string) (int64, error) {
+	value = strings.NewReplacer(",", "", " ", "").Replace(replaceDigits(strings.TrimSpace(value)))
+	return strconv.ParseInt(value, 10, 64)
+}
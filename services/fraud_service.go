@@ -0,0 +1,347 @@
+package services
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/imaging"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	defaultFraudWindow = 30 // days
+	minPriceSamples    = 5
+	lowPriceRatio      = 0.4
+	highPriceRatio     = 3.0
+	minRepostLength    = 40 // runes, shorter descriptions are too common to mean a repost
+	// maxImageDistance is how many bits the perceptual hashes of two photos may differ in for them to be the same
+	// photo, resized, recompressed or slightly edited
+	maxImageDistance = 6
+)
+
+// riskWeights are the points every reason adds to a score, capped at 100
+var riskWeights = map[models.RiskReason]int{
+	models.PRICE_TOO_LOW:    40,
+	models.PRICE_TOO_HIGH:   25,
+	models.DUPLICATE_IMAGES: 35,
+	models.REPOSTED_TEXT:    25,
+	models.PHONE_NUMBER:     20,
+	models.AGENCY_TEXT:      15,
+}
+
+var (
+	// mobile numbers like 09121234567 or +98 912 123 4567, and Tehran style landlines like 021-12345678
+	phonePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?:^|\D)(?:\+98|0098|0)\s?9\d{2}[\s-]?\d{3}[\s-]?\d{4}(?:\D|$)`),
+		regexp.MustCompile(`(?:^|\D)0\d{2}[\s-]?\d{8}(?:\D|$)`),
+	}
+)
+
+// FraudService scores crawled listings for signs of fake, bait or spam posts
+type FraudService struct {
+	window         time.Duration
+	riskRepository db.ListingRiskRepository
+	logger         *slog.Logger
+}
+
+func NewFraudService(riskRepository db.ListingRiskRepository) *FraudService {
+	return &FraudService{
+		window:         time.Duration(intConfig("FRAUD_WINDOW_DAYS", defaultFraudWindow)) * 24 * time.Hour,
+		riskRepository: riskRepository,
+		logger:         utils.NewLogger("Fraud_Service"),
+	}
+}
+
+// ScoreCrawl scores the post histories saved by a crawl against the listings of the recent window,
+// it returns how many of them are high risk
+func (s *FraudService) ScoreCrawl(crawlHistoryID uint, now time.Time) (int, error) {
+	window, err := s.riskRepository.FindWindow(now.Add(-s.window))
+	if err != nil {
+		return 0, err
+	}
+	images, err := s.riskRepository.FindWindowImages(now.Add(-s.window))
+	if err != nil {
+		return 0, err
+	}
+	postImages := make(map[uint][]models.ListingImage)
+	for _, image := range images {
+		postImages[image.PostHistoryID] = append(postImages[image.PostHistoryID], image)
+	}
+	for i := range window {
+		window[i].Images = postImages[window[i].ID]
+	}
+	targets := make([]models.PostHistory, 0)
+	for _, post := range window {
+		if post.CrawlHistoryID == crawlHistoryID {
+			targets = append(targets, post)
+		}
+	}
+
+	risks := ScoreListings(targets, window)
+	postIDs := make([]uint, 0, len(targets))
+	for _, post := range targets {
+		postIDs = append(postIDs, post.PostID)
+	}
+	reviews, err := s.riskRepository.FindLatestReviews(postIDs)
+	if err != nil {
+		return 0, err
+	}
+	for i, post := range targets {
+		if review, exists := reviews[post.PostID]; exists {
+			risks[i].Review, risks[i].ReviewedBy, risks[i].ReviewedAt = review.Review, review.ReviewedBy, review.ReviewedAt
+		}
+	}
+	if err := s.riskRepository.Save(risks); err != nil {
+		return 0, err
+	}
+	high := 0
+	for _, risk := range risks {
+		if risk.Score >= models.HIGH_RISK_SCORE {
+			high++
+		}
+	}
+	s.logger.Info("listings scored", slog.Int("listings", len(risks)), slog.Int("high_risk", high))
+	return high, nil
+}
+
+// Risk returns the score of a post history, a zero risk when it was not scored
+func (s *FraudService) Risk(postHistoryID uint) (models.ListingRisk, error) {
+	return s.riskRepository.FindByPostHistory(postHistoryID)
+}
+
+// Pending returns the high risk listings waiting for an admin, and their total count
+func (s *FraudService) Pending(limit int) ([]models.ListingRisk, int64, error) {
+	return s.riskRepository.FindPending(limit)
+}
+
+// Review records an admin decision, a confirmed listing stays hidden and a dismissed one is shown again
+func (s *FraudService) Review(riskID uint, review models.RiskReview, reviewer models.User) (models.ListingRisk, error) {
+	risk, err := s.riskRepository.SetReview(riskID, review, reviewer.ID)
+	if err == nil {
+		s.logger.Info("listing risk reviewed", slog.Uint64("risk", uint64(riskID)), slog.String("review", string(review)),
+			slog.Uint64("by", uint64(reviewer.ID)))
+	}
+	return risk, err
+}
+
+// ScoreListings scores the targets, comparing them with all listings of the window. Rentals are priced by
+// their monthly cost, so a high deposit with a low rent is not a bait. Two histories of
+// the same post are never counted as duplicates of each other. Photos are compared by their perceptual
+// hash once archived, so a photo uploaded again under a new link, or slightly edited, is still a duplicate.
+func ScoreListings(targets []models.PostHistory, window []models.PostHistory) []models.ListingRisk {
+	medians := neighborhoodMedians(window)
+	imagePosts := make(map[string]map[uint]bool)
+	imageHashes := make([]postImageHash, 0)
+	textPosts := make(map[string]map[uint]bool)
+	for _, post := range window {
+		hashes, links := imageKeys(post)
+		for _, hash := range hashes {
+			imageHashes = append(imageHashes, postImageHash{hash: hash, post: postKey(post)})
+		}
+		for _, link := range links {
+			addPost(imagePosts, link, postKey(post))
+		}
+		if text := persian.Normalize(post.Description); len([]rune(text)) >= minRepostLength {
+			addPost(textPosts, text, postKey(post))
+		}
+	}
+
+	risks := make([]models.ListingRisk, 0, len(targets))
+	for _, post := range targets {
+		reasons := make([]models.RiskReason, 0)
+		if median, exists := medians[marketGroup(post)]; exists && post.Area > 0 && ListingValue(post) > 0 {
+			ratio := pricePerMeter(post) / median
+			if ratio < lowPriceRatio {
+				reasons = append(reasons, models.PRICE_TOO_LOW)
+			} else if ratio > highPriceRatio {
+				reasons = append(reasons, models.PRICE_TOO_HIGH)
+			}
+		}
+		if hasDuplicateImage(post, imagePosts, imageHashes) {
+			reasons = append(reasons, models.DUPLICATE_IMAGES)
+		}
+		if text := persian.Normalize(post.Description); len([]rune(text)) >= minRepostLength && sharedWithOthers(textPosts[text], postKey(post)) {
+			reasons = append(reasons, models.REPOSTED_TEXT)
+		}
+		if HasPhoneNumber(post.Description) {
+			reasons = append(reasons, models.PHONE_NUMBER)
+		}
+		if HasAgencyText(post.Description) {
+			reasons = append(reasons, models.AGENCY_TEXT)
+		}
+
+		risks = append(risks, models.ListingRisk{
+			PostHistoryID: post.ID,
+			Score:         riskScore(reasons),
+			Reasons:       joinReasons(reasons),
+			Review:        models.RISK_PENDING,
+		})
+	}
+	return risks
+}
+
+// HasPhoneNumber tells whether a text contains an Iranian mobile or landline number
+func HasPhoneNumber(text string) bool {
+	text = replaceDigits(text)
+	for _, pattern := range phonePatterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAgencyText tells whether a text advertises a real estate agency. It weighs the agency phrases against the
+// owner ones like ClassifySeller, so "بدون مشاور املاک" is not advertising.
+func HasAgencyText(text string) bool {
+	return ClassifySeller("", text) == types.Agency
+}
+
+// SplitReasons reads the reasons stored in a ListingRisk
+func SplitReasons(reasons string) []models.RiskReason {
+	result := make([]models.RiskReason, 0)
+	for _, reason := range strings.Split(reasons, ",") {
+		if reason != "" {
+			result = append(result, models.RiskReason(reason))
+		}
+	}
+	return result
+}
+
+type priceGroup struct {
+	city         string
+	neighborhood string
+	buyMode      string
+}
+
+func marketGroup(post models.PostHistory) priceGroup {
+	return priceGroup{
+		city:         strings.TrimSpace(post.City),
+		neighborhood: strings.TrimSpace(post.Neighborhood),
+		buyMode:      string(post.BuyMode),
+	}
+}
+
+// neighborhoodMedians returns the median price per m² of every neighborhood and buy mode with enough posts,
+// counting the latest history of every post once
+func neighborhoodMedians(window []models.PostHistory) map[priceGroup]float64 {
+	latest := make(map[uint]models.PostHistory)
+	for _, post := range window {
+		if previous, exists := latest[postKey(post)]; !exists || post.ID > previous.ID {
+			latest[postKey(post)] = post
+		}
+	}
+
+	samples := make(map[priceGroup][]float64)
+	for _, post := range latest {
+		if post.Area <= 0 || ListingValue(post) <= 0 || post.Neighborhood == "" {
+			continue
+		}
+		samples[marketGroup(post)] = append(samples[marketGroup(post)], pricePerMeter(post))
+	}
+
+	medians := make(map[priceGroup]float64)
+	for group, values := range samples {
+		if len(values) >= minPriceSamples {
+			medians[group] = Percentile(values, 50)
+		}
+	}
+	return medians
+}
+
+func postKey(post models.PostHistory) uint {
+	if post.PostID != 0 {
+		return post.PostID
+	}
+	return post.ID
+}
+
+func listingImages(post models.PostHistory) []string {
+	images := make([]string, 0)
+	for _, image := range strings.Split(post.ImageURL, ",") {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// postImageHash is the perceptual hash of a photo of a post
+type postImageHash struct {
+	hash uint64
+	post uint
+}
+
+// imageKeys identifies the photos of a listing by their perceptual hashes, or by their links before they are archived
+func imageKeys(post models.PostHistory) ([]uint64, []string) {
+	hashes := make([]uint64, 0, len(post.Images))
+	for _, image := range post.Images {
+		if image.Status == models.IMAGE_ARCHIVED {
+			hashes = append(hashes, uint64(image.Hash))
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, listingImages(post)
+	}
+	return hashes, nil
+}
+
+// hasDuplicateImage tells whether another post of the window has a photo of the post, by the same link or by a
+// perceptual hash at most maxImageDistance bits away
+func hasDuplicateImage(post models.PostHistory, imagePosts map[string]map[uint]bool, imageHashes []postImageHash) bool {
+	hashes, links := imageKeys(post)
+	for _, link := range links {
+		if sharedWithOthers(imagePosts[link], postKey(post)) {
+			return true
+		}
+	}
+	for _, hash := range hashes {
+		for _, other := range imageHashes {
+			if other.post != postKey(post) && imaging.Distance(hash, other.hash) <= maxImageDistance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func addPost(index map[string]map[uint]bool, value string, key uint) {
+	if index[value] == nil {
+		index[value] = make(map[uint]bool)
+	}
+	index[value][key] = true
+}
+
+func sharedWithOthers(posts map[uint]bool, key uint) bool {
+	for other := range posts {
+		if other != key {
+			return true
+		}
+	}
+	return false
+}
+
+func riskScore(reasons []models.RiskReason) int {
+	score := 0
+	for _, reason := range reasons {
+		score += riskWeights[reason]
+	}
+	if score > 100 {
+		return 100
+	}
+	return score
+}
+
+func joinReasons(reasons []models.RiskReason) string {
+	values := make([]string, len(reasons))
+	for i, reason := range reasons {
+		values[i] = string(reason)
+	}
+	return strings.Join(values, ",")
+}
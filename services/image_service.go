@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/imaging"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/storage"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	defaultImageWorkers   = 4
+	defaultImageMaxSize   = 10 // MB
+	defaultImageMaxPixels = 40 // megapixels
+	defaultThumbnailSize  = 320
+	defaultImageRetention = 90 // days
+	thumbnailQuality      = 80
+	imagePruneBatch       = 500
+)
+
+var (
+	// ErrNoImage is returned for listings without an archived photo
+	ErrNoImage = errors.New("listing has no archived image")
+	// ErrImageTooLarge is returned for photos larger than IMAGE_MAX_SIZE
+	ErrImageTooLarge = errors.New("image too large")
+)
+
+// ImageService copies the photos of listings to a blob store, with their thumbnails and perceptual hashes,
+// so listings keep their photos after the website deletes them. Photos of histories older than the retention
+// are deleted again, except the photos of bookmarked posts.
+type ImageService struct {
+	store           storage.BlobStore
+	imageRepository db.ListingImageRepository
+	limiters        *crawlers.LimiterRegistry
+	identities      *crawlers.IdentityProvider
+	workers         int
+	maxSize         int64
+	maxPixels       int
+	thumbnailSize   int
+	retention       time.Duration
+	logger          *slog.Logger
+}
+
+// NewImageService creates the service, photos are downloaded under the politeness limits and through the browser
+// identities and proxies the crawlers use
+func NewImageService(store storage.BlobStore, imageRepository db.ListingImageRepository, limiters *crawlers.LimiterRegistry,
+	identities *crawlers.IdentityProvider) *ImageService {
+	return &ImageService{
+		store:           store,
+		imageRepository: imageRepository,
+		limiters:        limiters,
+		identities:      identities,
+		workers:         max(1, intConfig("IMAGE_ARCHIVE_WORKERS", defaultImageWorkers)),
+		maxSize:         int64(intConfig("IMAGE_MAX_SIZE", defaultImageMaxSize)) << 20,
+		maxPixels:       intConfig("IMAGE_MAX_PIXELS", defaultImageMaxPixels) * 1_000_000,
+		thumbnailSize:   intConfig("IMAGE_THUMBNAIL_SIZE", defaultThumbnailSize),
+		retention:       time.Duration(intConfig("IMAGE_RETENTION_DAYS", defaultImageRetention)) * 24 * time.Hour,
+		logger:          utils.NewLogger("Image_Service"),
+	}
+}
+
+// ArchiveCrawl archives the photos of the post histories saved by a crawl, it returns how many photos were downloaded.
+// It stops early when ctx is canceled.
+func (s *ImageService) ArchiveCrawl(ctx context.Context, crawlHistoryID uint) (int, error) {
+	posts, err := s.imageRepository.FindCrawlPostHistories(crawlHistoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg         sync.WaitGroup
+		downloaded atomic.Int64
+		firstErr   error
+		errOnce    sync.Once
+		jobs       = make(chan models.PostHistory)
+	)
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for post := range jobs {
+				count, err := s.archive(ctx, post)
+				downloaded.Add(int64(count))
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+				}
+			}
+		}()
+	}
+feed:
+	for _, post := range posts {
+		select {
+		case jobs <- post:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	s.logger.Info("listing photos archived", slog.Int("listings", len(posts)), slog.Int64("downloaded", downloaded.Load()))
+	if err := ctx.Err(); err != nil {
+		return int(downloaded.Load()), err
+	}
+	return int(downloaded.Load()), firstErr
+}
+
+// ArchivePost archives the photos of the latest history of a post, unless they are archived already
+func (s *ImageService) ArchivePost(ctx context.Context, postID uint) error {
+	post, err := s.imageRepository.FindLatestPostHistory(postID)
+	if err != nil {
+		return err
+	}
+	images, err := s.imageRepository.FindByPostHistory(post.ID)
+	if err != nil || len(images) > 0 {
+		return err
+	}
+	_, err = s.archive(ctx, post)
+	return err
+}
+
+// Images returns the archived photos of a post history in the order of the listing
+func (s *ImageService) Images(postHistoryID uint) ([]models.ListingImage, error) {
+	images, err := s.imageRepository.FindByPostHistory(postHistoryID)
+	if err != nil {
+		return nil, err
+	}
+	archived := make([]models.ListingImage, 0, len(images))
+	for _, image := range images {
+		if image.Status == models.IMAGE_ARCHIVED {
+			archived = append(archived, image)
+		}
+	}
+	return archived, nil
+}
+
+// Cover returns the first archived photo of a post history, or its thumbnail
+func (s *ImageService) Cover(postHistoryID uint, thumbnail bool) ([]byte, error) {
+	images, err := s.Images(postHistoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, ErrNoImage
+	}
+	if thumbnail {
+		return s.store.Get(images[0].ThumbnailKey)
+	}
+	return s.store.Get(images[0].BlobKey)
+}
+
+// Prune deletes the photos of post histories older than the retention, keeping the photos of bookmarked posts
+// and blobs still used by newer histories. It returns how many images were deleted.
+func (s *ImageService) Prune(now time.Time) (int, error) {
+	deleted := 0
+	for {
+		images, err := s.imageRepository.FindExpired(now.Add(-s.retention), imagePruneBatch)
+		if err != nil {
+			return deleted, err
+		}
+		orphans, err := s.imageRepository.Delete(images)
+		if err != nil {
+			return deleted, err
+		}
+		for _, key := range orphans {
+			if err := s.store.Delete(key); err != nil {
+				s.logger.Warn("could not delete blob", slog.String("key", key), slog.Any("error", err))
+			}
+		}
+		deleted += len(images)
+		if len(images) < imagePruneBatch {
+			break
+		}
+	}
+	if deleted > 0 {
+		s.logger.Info("expired listing photos deleted", slog.Int("images", deleted))
+	}
+	return deleted, nil
+}
+
+// archive stores the photos of a post history, photos an earlier history archived are reused without downloading them
+func (s *ImageService) archive(ctx context.Context, post models.PostHistory) (int, error) {
+	sources := listingImages(post)
+	existing, err := s.imageRepository.FindBySources(sources)
+	if err != nil {
+		return 0, err
+	}
+	archived := make(map[string]models.ListingImage)
+	for _, image := range existing {
+		archived[image.SourceURL] = image
+	}
+
+	downloaded := 0
+	images := make([]models.ListingImage, 0, len(sources))
+	for position, source := range sources {
+		image, exists := archived[source]
+		if !exists {
+			image, err = s.download(ctx, source)
+			if err != nil && ctx.Err() != nil {
+				// nothing is saved for a canceled archive, the photos are archived again later
+				return downloaded, ctx.Err()
+			}
+			if err != nil {
+				s.logger.Warn("could not archive listing photo", slog.String("url", source), slog.Any("error", err))
+				image = models.ListingImage{SourceURL: source, Status: models.IMAGE_FAILED}
+			} else {
+				archived[source] = image
+				downloaded++
+			}
+		}
+		images = append(images, models.ListingImage{
+			PostHistoryID: post.ID,
+			PostID:        post.PostID,
+			Position:      position,
+			SourceURL:     source,
+			Status:        image.Status,
+			BlobKey:       image.BlobKey,
+			ThumbnailKey:  image.ThumbnailKey,
+			Hash:          image.Hash,
+			Width:         image.Width,
+			Height:        image.Height,
+		})
+	}
+	return downloaded, s.imageRepository.Save(images)
+}
+
+// download fetches a photo and stores it with its thumbnail under the SHA-256 of its content,
+// so the same photo posted under several links is stored once
+func (s *ImageService) download(ctx context.Context, source string) (models.ListingImage, error) {
+	limiter := s.limiters.For(source)
+	release, err := limiter.Acquire(ctx)
+	if err != nil {
+		return models.ListingImage{}, err
+	}
+	defer release()
+
+	profile, err := s.identities.Next()
+	if err != nil {
+		return models.ListingImage{}, err
+	}
+	request, err := profile.NewRequest(ctx, http.MethodGet, source)
+	if err != nil {
+		return models.ListingImage{}, err
+	}
+	response, err := profile.HTTPClient().Do(request)
+	if err != nil {
+		s.identities.ReportFailure(profile)
+		return models.ListingImage{}, err
+	}
+	defer response.Body.Close()
+	limiter.Report(response.StatusCode)
+	if crawlers.IsBlockedStatus(response.StatusCode) {
+		s.identities.ReportFailure(profile)
+	} else {
+		s.identities.ReportSuccess(profile)
+	}
+	if response.StatusCode != http.StatusOK {
+		return models.ListingImage{}, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, s.maxSize+1))
+	if err != nil {
+		return models.ListingImage{}, err
+	}
+	if int64(len(content)) > s.maxSize {
+		return models.ListingImage{}, ErrImageTooLarge
+	}
+
+	img, err := imaging.Decode(content, s.maxPixels)
+	if err != nil {
+		return models.ListingImage{}, err
+	}
+	thumbnail, err := imaging.EncodeJPEG(imaging.Thumbnail(img, s.thumbnailSize), thumbnailQuality)
+	if err != nil {
+		return models.ListingImage{}, err
+	}
+
+	sum := sha256.Sum256(content)
+	name := hex.EncodeToString(sum[:])
+	image := models.ListingImage{
+		SourceURL:    source,
+		Status:       models.IMAGE_ARCHIVED,
+		BlobKey:      "originals/" + name[:2] + "/" + name,
+		ThumbnailKey: "thumbnails/" + name[:2] + "/" + name + ".jpg",
+		Hash:         int64(imaging.Hash(img)),
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+	}
+	if err := s.store.Put(image.BlobKey, content); err != nil {
+		return models.ListingImage{}, err
+	}
+	if err := s.store.Put(image.ThumbnailKey, thumbnail); err != nil {
+		return models.ListingImage{}, err
+	}
+	return image, nil
+}
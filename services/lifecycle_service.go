@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	defaultLifecycleInterval = 60 // minutes
+	defaultLifecycleStale    = 48 // hours
+	defaultLifecycleBatch    = 20
+)
+
+// soldPhrases are written in the title or description of a post once it is sold or rented
+var soldPhrases = []string{"فروخته شد", "به فروش رفت", "اجاره داده شد", "اجاره رفت", "رهن داده شد", "واگذار شد"}
+
+// LifecycleService follows posts after they were crawled: posts no crawl found for a while are opened again
+// to learn whether they were removed, expired or sold, and users who bookmarked or watch them are told
+type LifecycleService struct {
+	repository    db.PostLifecycleRepository
+	crawlers      map[types.WebsiteSource]crawlers.Crawler
+	staleAfter    time.Duration
+	batch         int
+	notifierMutex sync.RWMutex
+	notifier      Notifier
+	logger        *slog.Logger
+}
+
+func NewLifecycleService(repository db.PostLifecycleRepository, sourceCrawlers []crawlers.Crawler) *LifecycleService {
+	bySource := make(map[types.WebsiteSource]crawlers.Crawler)
+	for _, crawler := range sourceCrawlers {
+		bySource[crawler.Source()] = crawler
+	}
+	return &LifecycleService{
+		repository: repository,
+		crawlers:   bySource,
+		staleAfter: time.Duration(intConfig("LIFECYCLE_STALE_HOURS", defaultLifecycleStale)) * time.Hour,
+		batch:      intConfig("LIFECYCLE_CHECK_BATCH", defaultLifecycleBatch),
+		logger:     utils.NewLogger("Lifecycle_Service"),
+	}
+}
+
+// SetNotifier sets how users are told about their bookmarked or watched posts going away
+func (s *LifecycleService) SetNotifier(notifier Notifier) {
+	s.notifierMutex.Lock()
+	defer s.notifierMutex.Unlock()
+	s.notifier = notifier
+}
+
+func (s *LifecycleService) notify(user models.User, text string) {
+	s.notifierMutex.RLock()
+	notifier := s.notifier
+	s.notifierMutex.RUnlock()
+	if notifier != nil && user.TelegramID != 0 {
+		notifier(user, text)
+	}
+}
+
+// Start checks stale posts periodically in the background
+func (s *LifecycleService) Start() {
+	interval := time.Duration(intConfig("LIFECYCLE_CHECK_INTERVAL", defaultLifecycleInterval)) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.CheckStale(context.Background(), time.Now()); err != nil {
+				s.logger.Error("lifecycle check failed", slog.Any("error", err))
+				tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// CheckStale opens the pages of a batch of posts no crawl found for a while, it returns how many went away
+func (s *LifecycleService) CheckStale(ctx context.Context, now time.Time) (int, error) {
+	posts, err := s.repository.FindStale(now.Add(-s.staleAfter), s.batch)
+	if err != nil {
+		return 0, err
+	}
+	gone := 0
+	for _, post := range posts {
+		status, err := s.Check(ctx, post, now)
+		if err != nil {
+			s.logger.Warn("could not check post", slog.Uint64("post", uint64(post.ID)), slog.Any("error", err))
+			continue
+		}
+		if status != models.POST_ACTIVE {
+			gone++
+		}
+	}
+	s.logger.Info("stale posts checked", slog.Int("posts", len(posts)), slog.Int("gone", gone))
+	return gone, nil
+}
+
+// Check opens the page of a post and records its status. Errors other than a removal notice leave the post active.
+func (s *LifecycleService) Check(ctx context.Context, post models.Post, now time.Time) (models.PostStatus, error) {
+	crawler, exists := s.crawlers[post.Website]
+	if !exists {
+		return models.POST_ACTIVE, fmt.Errorf("no crawler for %s", post.Website)
+	}
+	history, err := s.repository.FindLatestHistory(post.ID)
+	if err != nil {
+		return models.POST_ACTIVE, err
+	}
+	if history.PostURL == "" {
+		return models.POST_ACTIVE, s.repository.MarkChecked(post.ID, now, false)
+	}
+
+	details, err := crawler.CrawlPostDetails(ctx, history.PostURL)
+	status := models.POST_ACTIVE
+	switch {
+	case errors.Is(err, crawlers.ErrPostExpired):
+		status = models.POST_EXPIRED
+	case errors.Is(err, crawlers.ErrPostRemoved):
+		status = models.POST_REMOVED
+	case err != nil:
+		// the website may be down or blocking us, the post is checked again after staleAfter
+		if markErr := s.repository.MarkChecked(post.ID, now, false); markErr != nil {
+			return status, markErr
+		}
+		return status, err
+	case IsSoldText(details.Title + "\n" + details.Description):
+		status = models.POST_SOLD
+	}
+
+	if status == models.POST_ACTIVE {
+		return status, s.repository.MarkChecked(post.ID, now, true)
+	}
+	changed, err := s.repository.MarkGone(post.ID, status, now)
+	if err != nil || !changed {
+		return status, err
+	}
+	post.Status = status
+	post.RemovedAt = &now
+	s.notifyUsers(post, history)
+	return status, nil
+}
+
+// IsSoldText tells whether a title or description says the property was sold or rented
+func IsSoldText(text string) bool {
+	text = persian.Normalize(text)
+	for _, phrase := range soldPhrases {
+		if strings.Contains(text, persian.Normalize(phrase)) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyUsers tells the users who bookmarked a post or whose watchlist matches it that it went away, a user who
+// did both is told once
+func (s *LifecycleService) notifyUsers(post models.Post, history models.PostHistory) {
+	bookmarkUsers, err := s.repository.FindBookmarkUsers(post.ID)
+	if err != nil {
+		s.logger.Error("could not find bookmarks of post", slog.Uint64("post", uint64(post.ID)), slog.Any("error", err))
+	}
+	watchListUsers, err := s.repository.FindWatchListUsers(history)
+	if err != nil {
+		s.logger.Error("could not find watchlists of post", slog.Uint64("post", uint64(post.ID)), slog.Any("error", err))
+	}
+
+	days := post.DaysOnMarket(*post.RemovedAt)
+	told := make(map[uint]bool)
+	for _, user := range bookmarkUsers {
+		told[user.ID] = true
+		s.notify(user, tr(user, "lifecycle.bookmark_gone", goneText(user, post.Status), history.Title, days))
+	}
+	for _, user := range watchListUsers {
+		if told[user.ID] {
+			continue
+		}
+		told[user.ID] = true
+		s.notify(user, tr(user, "lifecycle.watchlist_gone", goneText(user, post.Status), history.Title, days))
+	}
+}
+
+func goneText(user models.User, status models.PostStatus) string {
+	switch status {
+	case models.POST_EXPIRED:
+		return tr(user, "lifecycle.went.expired")
+	case models.POST_SOLD:
+		return tr(user, "lifecycle.went.sold")
+	}
+	return tr(user, "lifecycle.went.removed")
+}
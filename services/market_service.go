@@ -0,0 +1,240 @@
+package services
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	defaultMarketWeeks    = 12
+	defaultMarketInterval = 6 * 60 // minutes
+	buildingBackfillBatch = 500
+)
+
+// MarketService computes the weekly price per square meter indices and keeps them in the market_indices table
+type MarketService struct {
+	weeks                 int
+	marketIndexRepository db.MarketIndexRepository
+	logger                *slog.Logger
+}
+
+func NewMarketService(marketIndexRepository db.MarketIndexRepository) *MarketService {
+	return &MarketService{
+		weeks:                 intConfig("MARKET_INDEX_WEEKS", defaultMarketWeeks),
+		marketIndexRepository: marketIndexRepository,
+		logger:                utils.NewLogger("Market_Service"),
+	}
+}
+
+// Weeks returns how many recent weeks the indices cover
+func (s *MarketService) Weeks() int {
+	return s.weeks
+}
+
+// Start classifies the building type of older listings once, then refreshes the indices periodically in the background
+func (s *MarketService) Start() {
+	interval := time.Duration(intConfig("MARKET_INDEX_INTERVAL", defaultMarketInterval)) * time.Minute
+	go func() {
+		if updated, err := s.BackfillBuildings(); err != nil {
+			s.logger.Error("building backfill failed", slog.Any("error", err))
+			tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+		} else if updated > 0 {
+			s.logger.Info("building types backfilled", slog.Int("rows", updated))
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Refresh(time.Now()); err != nil {
+				s.logger.Error("market index refresh failed", slog.Any("error", err))
+				tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// BackfillBuildings classifies the building type of the post histories saved without one by their title, so they
+// count in the per building type indices. It returns the number of post histories updated.
+func (s *MarketService) BackfillBuildings() (int, error) {
+	updated := 0
+	var afterID uint
+	for {
+		posts, err := s.marketIndexRepository.FindWithoutBuilding(afterID, buildingBackfillBatch)
+		if err != nil || len(posts) == 0 {
+			return updated, err
+		}
+		for _, post := range posts {
+			afterID = post.ID
+			building := ClassifyBuilding("", post.Title)
+			if building == "" {
+				continue
+			}
+			if err := s.marketIndexRepository.UpdateBuilding(post.ID, building); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
+
+// Refresh recomputes the indices of the recent weeks up to now
+func (s *MarketService) Refresh(now time.Time) error {
+	since := WeekStart(now).AddDate(0, 0, -7*(s.weeks-1))
+	samples, err := s.marketIndexRepository.FindSamples(since)
+	if err != nil {
+		return err
+	}
+	indices := ComputeMarketIndices(samples)
+	s.logger.Debug("market indices computed", slog.Int("samples", len(samples)), slog.Int("indices", len(indices)))
+	return s.marketIndexRepository.ReplaceSince(since, indices)
+}
+
+// Indices returns the weekly indices of a city, optionally narrowed to a neighborhood and building type
+func (s *MarketService) Indices(city string, neighborhood string, building types.Building, now time.Time) ([]models.MarketIndex, error) {
+	return s.marketIndexRepository.Find(models.MarketIndexQuery{
+		City:         strings.TrimSpace(city),
+		Neighborhood: strings.TrimSpace(neighborhood),
+		Building:     building,
+		Since:        WeekStart(now).AddDate(0, 0, -7*(s.weeks-1)),
+	})
+}
+
+// LatestCities returns the city wide indices of the latest week
+func (s *MarketService) LatestCities() ([]models.MarketIndex, error) {
+	return s.marketIndexRepository.FindLatestCities()
+}
+
+// WeekStart returns the Saturday midnight in Tehran that starts the week of t, the first day of the Iranian week
+func WeekStart(t time.Time) time.Time {
+	day := utils.TehranDayStart(t)
+	daysSinceSaturday := (int(day.Weekday()) + 1) % 7
+	return day.AddDate(0, 0, -daysSinceSaturday).UTC()
+}
+
+type marketKey struct {
+	week         time.Time
+	city         string
+	neighborhood string
+	building     types.Building
+}
+
+type marketSamples struct {
+	pricePerMeter []float64
+	rentPerMeter  []float64
+	depositToRent []float64
+}
+
+// ComputeMarketIndices groups the samples by week, city, neighborhood and building type. Every post counts
+// once per week with its latest history, and also counts in the rows of all neighborhoods and building types.
+func ComputeMarketIndices(samples []models.PostHistory) []models.MarketIndex {
+	type postWeek struct {
+		postID uint
+		week   time.Time
+	}
+	latest := make(map[postWeek]models.PostHistory)
+	for _, sample := range samples {
+		key := postWeek{postID: sample.PostID, week: WeekStart(sample.CreatedAt)}
+		if sample.PostID == 0 {
+			key.postID = sample.ID
+		}
+		if previous, exists := latest[key]; !exists || !sample.CreatedAt.Before(previous.CreatedAt) {
+			latest[key] = sample
+		}
+	}
+
+	groups := make(map[marketKey]*marketSamples)
+	for key, sample := range latest {
+		city := strings.TrimSpace(sample.City)
+		if city == "" || sample.Area <= 0 {
+			continue
+		}
+		keys := []marketKey{{week: key.week, city: city}}
+		if neighborhood := strings.TrimSpace(sample.Neighborhood); neighborhood != "" {
+			keys = append(keys, marketKey{week: key.week, city: city, neighborhood: neighborhood})
+		}
+		if sample.Building != "" {
+			for _, groupKey := range keys {
+				groupKey.building = sample.Building
+				keys = append(keys, groupKey)
+			}
+		}
+
+		area := float64(sample.Area)
+		for _, groupKey := range keys {
+			group, exists := groups[groupKey]
+			if !exists {
+				group = &marketSamples{}
+				groups[groupKey] = group
+			}
+			switch {
+			case sample.BuyMode == types.Shopping && sample.Price > 0:
+				group.pricePerMeter = append(group.pricePerMeter, float64(sample.Price)/area)
+			case sample.BuyMode == types.Rent && sample.Rent > 0:
+				group.rentPerMeter = append(group.rentPerMeter, float64(sample.Rent)/area)
+				group.depositToRent = append(group.depositToRent, float64(sample.Deposit)/float64(sample.Rent))
+			}
+		}
+	}
+
+	indices := make([]models.MarketIndex, 0, len(groups))
+	for key, group := range groups {
+		if len(group.pricePerMeter) == 0 && len(group.rentPerMeter) == 0 {
+			continue
+		}
+		indices = append(indices, models.MarketIndex{
+			Week:                key.week,
+			City:                key.city,
+			Neighborhood:        key.neighborhood,
+			Building:            key.building,
+			SaleListings:        uint(len(group.pricePerMeter)),
+			PricePerMeterP25:    Percentile(group.pricePerMeter, 25),
+			PricePerMeterMedian: Percentile(group.pricePerMeter, 50),
+			PricePerMeterP75:    Percentile(group.pricePerMeter, 75),
+			RentListings:        uint(len(group.rentPerMeter)),
+			RentPerMeterP25:     Percentile(group.rentPerMeter, 25),
+			RentPerMeterMedian:  Percentile(group.rentPerMeter, 50),
+			RentPerMeterP75:     Percentile(group.rentPerMeter, 75),
+			DepositToRentMedian: Percentile(group.depositToRent, 50),
+		})
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		a, b := indices[i], indices[j]
+		if !a.Week.Equal(b.Week) {
+			return a.Week.Before(b.Week)
+		}
+		if a.City != b.City {
+			return a.City < b.City
+		}
+		if a.Neighborhood != b.Neighborhood {
+			return a.Neighborhood < b.Neighborhood
+		}
+		return a.Building < b.Building
+	})
+	return indices
+}
+
+// Percentile returns the p-th percentile of values with linear interpolation, 0 for no values
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+var (
+	// ErrUnknownPermission is returned for a role or permission name that does not exist
+	ErrUnknownPermission = errors.New("unknown role or permission")
+	// ErrPermissionLockout is returned when super admins would lose manage_admins, nobody could grant it back
+	ErrPermissionLockout = errors.New("super admins keep manage_admins")
+	// ErrRoleUserNotFound is returned when the user whose role is changed does not exist
+	ErrRoleUserNotFound = errors.New("user not found")
+	// ErrOwnRole is returned when users change their own role
+	ErrOwnRole = errors.New("own role cannot be changed")
+	// ErrRoleNotAllowed is returned when the user is not below the role of the actor
+	ErrRoleNotAllowed = errors.New("role not allowed")
+	// ErrLowestRole is returned when demoting a regular user
+	ErrLowestRole = errors.New("user has the lowest role")
+)
+
+// PermissionService tells what the users of each role may do and records the privileged actions in the audit log.
+// The permissions of the roles are kept in memory and reloaded when admins change them.
+type PermissionService struct {
+	repository         db.PermissionRepository
+	auditLogRepository db.AuditLogRepository
+	userRepository     db.UserRepository
+	mutex              sync.RWMutex
+	permissions        map[models.Role]map[models.Permission]bool
+	logger             *slog.Logger
+}
+
+// NewPermissionService loads the permissions of the roles, the defaults when they cannot be read
+func NewPermissionService(repository db.PermissionRepository, auditLogRepository db.AuditLogRepository,
+	userRepository db.UserRepository) *PermissionService {
+	s := &PermissionService{
+		repository:         repository,
+		auditLogRepository: auditLogRepository,
+		userRepository:     userRepository,
+		logger:             utils.NewLogger("Permission_Service"),
+	}
+	if err := s.Reload(); err != nil {
+		s.logger.Error("could not load role permissions", slog.Any("error", err))
+		s.permissions = make(map[models.Role]map[models.Permission]bool)
+		for role, permissions := range models.DefaultPermissions {
+			s.permissions[role] = make(map[models.Permission]bool)
+			for _, permission := range permissions {
+				s.permissions[role][permission] = true
+			}
+		}
+	}
+	return s
+}
+
+// Reload reads the permissions of the roles again
+func (s *PermissionService) Reload() error {
+	rolePermissions, err := s.repository.FindAll()
+	if err != nil {
+		return err
+	}
+	permissions := make(map[models.Role]map[models.Permission]bool)
+	for _, rolePermission := range rolePermissions {
+		if permissions[rolePermission.Role] == nil {
+			permissions[rolePermission.Role] = make(map[models.Permission]bool)
+		}
+		permissions[rolePermission.Role][rolePermission.Permission] = true
+	}
+	s.mutex.Lock()
+	s.permissions = permissions
+	s.mutex.Unlock()
+	return nil
+}
+
+// Allowed tells whether a user may use a permission. The empty permission is open to everyone, users that are
+// not saved have no permission at all.
+func (s *PermissionService) Allowed(user models.User, permission models.Permission) bool {
+	if permission == "" {
+		return true
+	}
+	if user.ID == 0 {
+		return false
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.permissions[user.Role][permission]
+}
+
+// Permissions returns the permissions of a role in the order of models.Permissions
+func (s *PermissionService) Permissions(role models.Role) []models.Permission {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	permissions := make([]models.Permission, 0)
+	for _, permission := range models.Permissions {
+		if s.permissions[role][permission] {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+// SetPermission grants a permission to a role or revokes it
+func (s *PermissionService) SetPermission(actor models.User, role models.Role, permission models.Permission, granted bool) error {
+	if _, exists := models.DefaultPermissions[role]; !exists || !permission.Known() {
+		return ErrUnknownPermission
+	}
+	if role == models.SUPER_ADMIN && permission == models.PERM_MANAGE_ADMINS && !granted {
+		return ErrPermissionLockout
+	}
+
+	var err error
+	if granted {
+		err = s.repository.Grant(role, permission)
+	} else {
+		err = s.repository.Revoke(role, permission)
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.Info("role permission changed", slog.Any("actor", actor.ID), slog.String("role", role.String()),
+		slog.String("permission", string(permission)), slog.Bool("granted", granted))
+	return s.Reload()
+}
+
+// Demote moves a user one role down, an admin becomes a regular user
+func (s *PermissionService) Demote(actor models.User, userID uint) (models.User, error) {
+	return s.changeRole(actor, userID, func(role models.Role) (models.Role, error) {
+		if role == models.USER {
+			return role, ErrLowestRole
+		}
+		return role + 1, nil
+	})
+}
+
+// Revoke takes every role of a user back, they become a regular user
+func (s *PermissionService) Revoke(actor models.User, userID uint) (models.User, error) {
+	return s.changeRole(actor, userID, func(role models.Role) (models.Role, error) {
+		if role == models.USER {
+			return role, ErrLowestRole
+		}
+		return models.USER, nil
+	})
+}
+
+// changeRole changes the role of a user below the actor, roles are ranked SUPER_ADMIN first
+func (s *PermissionService) changeRole(actor models.User, userID uint, next func(models.Role) (models.Role, error)) (models.User, error) {
+	if actor.ID == userID {
+		return models.User{}, ErrOwnRole
+	}
+	user, err := s.userRepository.Find(userID)
+	if err != nil {
+		return user, err
+	}
+	if user.ID == 0 {
+		return user, ErrRoleUserNotFound
+	}
+	if user.Role <= actor.Role {
+		return user, ErrRoleNotAllowed
+	}
+	role, err := next(user.Role)
+	if err != nil {
+		return user, err
+	}
+
+	changed, err := s.userRepository.ChangeUserRole(user.ID, user.Role, role)
+	if err != nil {
+		return user, err
+	}
+	if !changed {
+		// another admin changed the role in between
+		return user, ErrRoleNotAllowed
+	}
+	s.logger.Info("user role changed", slog.Any("actor", actor.ID), slog.Any("user", user.ID),
+		slog.String("from", user.Role.String()), slog.String("to", role.String()))
+	user.Role = role
+	return user, nil
+}
+
+// Audit records a privileged action of a user, the action itself goes on when recording fails
+func (s *PermissionService) Audit(user models.User, action string, target string, allowed bool) {
+	_, err := s.auditLogRepository.Create(models.AuditLog{
+		UserID: user.ID, Role: user.Role, Action: action, Target: target, Allowed: allowed,
+	})
+	if err != nil {
+		s.logger.Error("could not record audit log", slog.Any("error", err), slog.String("action", action))
+		tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+	}
+}
+
+// AuditLogs returns the latest privileged actions, latest first
+func (s *PermissionService) AuditLogs(limit int) ([]models.AuditLog, error) {
+	return s.auditLogRepository.FindLatest(limit)
+}
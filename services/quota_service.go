@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+// ErrQuotaExceeded is wrapped by every QuotaError
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota holds the limits of one user tier, a zero limit means unlimited
+type Quota struct {
+	MaxFilters         int
+	MaxWatchLists      int
+	MinRefreshInterval int // minutes
+	MaxExportRows      int
+	MaxDailySearches   int
+}
+
+// QuotaError explains which limit of the user's plan was hit, its message is shown to the user in their language
+type QuotaError struct {
+	Message string
+}
+
+func (e *QuotaError) Error() string {
+	return e.Message
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// limitReached builds the QuotaError of a count limit, limit is the catalog key of its name like "quota.saved_filters"
+func (s *QuotaService) limitReached(user models.User, limit string, max int, premiumMax int) error {
+	msg := tr(user, "quota.limit_reached", max, tr(user, limit), PlanName(user, user.Type))
+	if user.Type == models.FREE {
+		if premiumMax > 0 {
+			msg += tr(user, "quota.premium_up_to", premiumMax)
+		} else {
+			msg += tr(user, "quota.premium_unlimited")
+		}
+	}
+	return &QuotaError{Message: msg}
+}
+
+// Usage is the current consumption of a user against their quota
+type Usage struct {
+	UserType      models.UserType
+	Quota         Quota
+	Filters       int64
+	WatchLists    int64
+	SearchesToday uint
+}
+
+// quotaConfigs are the prefixes of the configs overriding the limits of each tier, like QUOTA_FREE_FILTERS
+var quotaConfigs = map[models.UserType]string{models.FREE: "QUOTA_FREE_", models.PREMIUM: "QUOTA_PREMIUM_"}
+
+var defaultQuotas = map[models.UserType]Quota{
+	models.FREE: {
+		MaxFilters:         3,
+		MaxWatchLists:      1,
+		MinRefreshInterval: 60,
+		MaxExportRows:      50,
+		MaxDailySearches:   20,
+	},
+	models.PREMIUM: {
+		MaxFilters:         20,
+		MaxWatchLists:      10,
+		MinRefreshInterval: 10,
+		MaxExportRows:      1000,
+		MaxDailySearches:   500,
+	},
+}
+
+// QuotaService enforces the limits of the FREE and PREMIUM tiers
+type QuotaService struct {
+	quotas              map[models.UserType]Quota
+	filterRepository    db.FilterItemRepository
+	watchListRepository db.WatchListRepository
+	usageRepository     db.UsageRepository
+	logger              *slog.Logger
+}
+
+// NewQuotaService creates a QuotaService with the limits read from QUOTA_<TIER>_<LIMIT> configs
+func NewQuotaService(filterRepository db.FilterItemRepository, watchListRepository db.WatchListRepository, usageRepository db.UsageRepository) *QuotaService {
+	return &QuotaService{
+		quotas:              LoadQuotas(),
+		filterRepository:    filterRepository,
+		watchListRepository: watchListRepository,
+		usageRepository:     usageRepository,
+		logger:              utils.NewLogger("Quota_Service"),
+	}
+}
+
+// LoadQuotas returns the limits of each tier, falling back to the defaults for unset configs
+func LoadQuotas() map[models.UserType]Quota {
+	quotas := make(map[models.UserType]Quota, len(defaultQuotas))
+	for userType, quota := range defaultQuotas {
+		prefix := quotaConfigs[userType]
+		quotas[userType] = Quota{
+			MaxFilters:         intConfig(prefix+"FILTERS", quota.MaxFilters),
+			MaxWatchLists:      intConfig(prefix+"WATCHLISTS", quota.MaxWatchLists),
+			MinRefreshInterval: intConfig(prefix+"MIN_REFRESH_INTERVAL", quota.MinRefreshInterval),
+			MaxExportRows:      intConfig(prefix+"EXPORT_ROWS", quota.MaxExportRows),
+			MaxDailySearches:   intConfig(prefix+"DAILY_SEARCHES", quota.MaxDailySearches),
+		}
+	}
+	return quotas
+}
+
+func intConfig(key string, defaultValue int) int {
+	value, err := strconv.Atoi(utils.GetConfig(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// tr returns the message of a key in the language of a user, for the texts services send to users
+func tr(user models.User, key string, args ...interface{}) string {
+	return i18n.T(i18n.Parse(user.Language), key, args...)
+}
+
+// userDate writes a day in the Jalali calendar and the digits of the language of a user
+func userDate(user models.User, t time.Time) string {
+	return i18n.Date(i18n.Parse(user.Language), t)
+}
+
+// PlanName returns the display name of a tier in the language of a user
+func PlanName(user models.User, userType models.UserType) string {
+	if userType == models.PREMIUM {
+		return tr(user, "plan.premium")
+	}
+	return tr(user, "plan.free")
+}
+
+// QuotaFor returns the limits of the user; admins are not limited
+func (s *QuotaService) QuotaFor(user models.User) Quota {
+	if user.Role != models.USER {
+		return Quota{}
+	}
+	if quota, exists := s.quotas[user.Type]; exists {
+		return quota
+	}
+	return s.quotas[models.FREE]
+}
+
+// CheckSaveFilter returns a QuotaError when the user cannot save another filter
+func (s *QuotaService) CheckSaveFilter(user models.User) error {
+	quota := s.QuotaFor(user)
+	if quota.MaxFilters == 0 {
+		return nil
+	}
+	count, err := s.filterRepository.CountByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	if count >= int64(quota.MaxFilters) {
+		return s.limitReached(user, "quota.saved_filters", quota.MaxFilters, s.quotas[models.PREMIUM].MaxFilters)
+	}
+	return nil
+}
+
+// CheckWatchList returns a QuotaError when the user cannot add a watchlist refreshed every refreshInterval minutes
+func (s *QuotaService) CheckWatchList(user models.User, refreshInterval int) error {
+	quota := s.QuotaFor(user)
+	if quota.MinRefreshInterval > 0 && refreshInterval < quota.MinRefreshInterval {
+		msg := tr(user, "quota.refresh_limit", PlanName(user, user.Type), quota.MinRefreshInterval)
+		if user.Type == models.FREE {
+			msg += tr(user, "quota.premium_refresh", s.quotas[models.PREMIUM].MinRefreshInterval)
+		}
+		return &QuotaError{Message: msg}
+	}
+	if quota.MaxWatchLists == 0 {
+		return nil
+	}
+	count, err := s.watchListRepository.CountByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+	if count >= int64(quota.MaxWatchLists) {
+		return s.limitReached(user, "quota.watchlists", quota.MaxWatchLists, s.quotas[models.PREMIUM].MaxWatchLists)
+	}
+	return nil
+}
+
+// UseSearch counts a search of the user, or returns a QuotaError when the daily limit is reached
+func (s *QuotaService) UseSearch(user models.User) error {
+	quota := s.QuotaFor(user)
+	today := usageDay(time.Now())
+	if quota.MaxDailySearches == 0 {
+		if _, err := s.usageRepository.Increment(user.ID, models.SEARCH_USAGE, today); err != nil {
+			s.logger.Error("could not count search", slog.Uint64("user", uint64(user.ID)), slog.Any("error", err))
+		}
+		return nil
+	}
+	counted, err := s.usageRepository.IncrementBelow(user.ID, models.SEARCH_USAGE, today, uint(quota.MaxDailySearches))
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return s.limitReached(user, "quota.daily_searches", quota.MaxDailySearches, s.quotas[models.PREMIUM].MaxDailySearches)
+	}
+	return nil
+}
+
+// ExportLimit returns the maximum number of rows the user can export at once, zero means unlimited
+func (s *QuotaService) ExportLimit(user models.User) int {
+	return s.QuotaFor(user).MaxExportRows
+}
+
+// Usage returns the consumption of the user against their quota
+func (s *QuotaService) Usage(user models.User) (Usage, error) {
+	usage := Usage{UserType: user.Type, Quota: s.QuotaFor(user)}
+	var err error
+	if usage.Filters, err = s.filterRepository.CountByUserID(user.ID); err != nil {
+		return usage, err
+	}
+	if usage.WatchLists, err = s.watchListRepository.CountByUserID(user.ID); err != nil {
+		return usage, err
+	}
+	usage.SearchesToday, err = s.usageRepository.Count(user.ID, models.SEARCH_USAGE, usageDay(time.Now()))
+	return usage, err
+}
+
+// usageDay returns the Tehran calendar day of t, stored as a UTC date
+func usageDay(t time.Time) time.Time {
+	local := t.In(utils.TehranLocation())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
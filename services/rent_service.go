@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const (
+	defaultRentConversionRate = 3.0 // percent of the deposit paid as rent per month
+	maxRentConversionRate     = 20.0
+)
+
+// ErrInvalidRate is returned for a conversion rate that is not a positive percent up to maxRentConversionRate
+var ErrInvalidRate = errors.New("invalid conversion rate")
+
+// RentService converts between deposit and rent at a market rate. The rate is the monthly rent, in percent,
+// that one unit of deposit is worth: with 3, a deposit of 100M is worth 3M of rent per month.
+type RentService struct {
+	settingRepository db.AppSettingRepository
+	postRepository    db.PostRepo
+	mutex             sync.RWMutex
+	rate              float64
+	logger            *slog.Logger
+}
+
+// NewRentService loads the rate set by admins, or the RENT_CONVERSION_RATE config when they never set one
+func NewRentService(settingRepository db.AppSettingRepository, postRepository db.PostRepo) *RentService {
+	s := &RentService{
+		settingRepository: settingRepository,
+		postRepository:    postRepository,
+		rate:              defaultRentConversionRate,
+		logger:            utils.NewLogger("Rent_Service"),
+	}
+	if rate, err := ParseRate(utils.GetConfig("RENT_CONVERSION_RATE")); err == nil {
+		s.rate = rate
+	}
+
+	setting, err := settingRepository.Find(models.RENT_CONVERSION_RATE)
+	if err != nil {
+		s.logger.Error("could not load rent conversion rate", slog.Any("error", err))
+	} else if rate, err := ParseRate(setting.Value); err == nil {
+		s.rate = rate
+	}
+	return s
+}
+
+// Rate returns the conversion rate in percent per month
+func (s *RentService) Rate() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.rate
+}
+
+// SetRate stores a new rate and recomputes the equivalents of all rentals with it
+func (s *RentService) SetRate(rate float64, updatedBy uint) (int64, error) {
+	if rate <= 0 || rate > maxRentConversionRate {
+		return 0, ErrInvalidRate
+	}
+	if err := s.settingRepository.Set(models.RENT_CONVERSION_RATE, strconv.FormatFloat(rate, 'f', -1, 64), updatedBy); err != nil {
+		return 0, err
+	}
+
+	s.mutex.Lock()
+	s.rate = rate
+	s.mutex.Unlock()
+	s.logger.Info("rent conversion rate changed", slog.Float64("rate", rate), slog.Uint64("by", uint64(updatedBy)))
+	return s.Recompute()
+}
+
+// Recompute updates the full deposit and monthly cost of all rentals with the current rate. Post histories saved
+// before their buy mode was inferred get it first, so older rentals are recomputed too.
+func (s *RentService) Recompute() (int64, error) {
+	backfilled, err := s.postRepository.BackfillBuyModes()
+	if err != nil {
+		return 0, err
+	}
+	if backfilled > 0 {
+		s.logger.Info("buy modes backfilled", slog.Int64("rows", backfilled))
+	}
+	updated, err := s.postRepository.UpdateRentEquivalents(s.Rate() / 100)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Debug("rent equivalents recomputed", slog.Int64("rentals", updated))
+	return updated, nil
+}
+
+// ConvertRent returns the deposit without rent and the rent without deposit equivalent to a rental
+func ConvertRent(deposit int64, rent int64, rate float64) (int64, int64) {
+	if rate <= 0 || (deposit <= 0 && rent <= 0) {
+		return 0, 0
+	}
+	fraction := rate / 100
+	fullDeposit := math.Round(float64(deposit) + float64(rent)/fraction)
+	monthlyCost := math.Round(float64(rent) + float64(deposit)*fraction)
+	return int64(fullDeposit), int64(monthlyCost)
+}
+
+// ParseRate reads a percent like "3", "2.5" or "3%"
+func ParseRate(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	rate, err := strconv.ParseFloat(replaceDigits(value), 64)
+	if err != nil || rate <= 0 || rate > maxRentConversionRate {
+		return 0, ErrInvalidRate
+	}
+	return rate, nil
+}
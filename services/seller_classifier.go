@@ -0,0 +1,85 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+// sellerCue is a phrase of a description hinting at who posted a listing
+type sellerCue struct {
+	phrase string
+	weight int
+}
+
+var (
+	// sellerLabels maps the seller labels of the websites to seller types
+	sellerLabels = map[string]types.SellerType{
+		"شخصی":        types.Owner,
+		"مالک":        types.Owner,
+		"مشاور املاک": types.Agency,
+		"املاک":       types.Agency,
+		"آژانس املاک": types.Agency,
+		"مشاورین":     types.Agency,
+	}
+	// ownerCues are searched and removed first, so "بدون کمیسیون" does not count as a commission
+	ownerCues = []sellerCue{
+		{"بدون واسطه", 3},
+		{"بدون مشاور", 3},
+		{"بدون کمیسیون", 3},
+		{"مالک هستم", 3},
+		{"از طرف مالک", 2},
+		{"شخصی", 2},
+		{"مالک", 1},
+	}
+	// agencyCues are removed once found too, so "مشاور املاک" does not also count as "املاک" and "مشاور"
+	agencyCues = []sellerCue{
+		{"مشاور املاک", 3},
+		{"مشاورین املاک", 3},
+		{"آژانس", 3},
+		{"agency", 3},
+		{"realtor", 3},
+		{"املاک", 2},
+		{"کد فایل", 2},
+		{"فایل های مشابه", 2},
+		{"فایلهای مشابه", 2},
+		{"کمیسیون", 1},
+		{"مشاور", 1},
+	}
+)
+
+// ClassifySeller returns the seller type of a listing from the label its website shows, or guesses it from
+// the description when there is no label. It returns an empty type when the description is not conclusive.
+func ClassifySeller(label string, description string) types.SellerType {
+	label = persian.Normalize(label)
+	for sellerLabel, sellerType := range sellerLabels {
+		if label == persian.Normalize(sellerLabel) {
+			return sellerType
+		}
+	}
+
+	text := persian.Normalize(description)
+	owner := 0
+	for _, cue := range ownerCues {
+		if phrase := persian.Normalize(cue.phrase); strings.Contains(text, phrase) {
+			owner += cue.weight
+			text = strings.ReplaceAll(text, phrase, " ")
+		}
+	}
+	agency := 0
+	for _, cue := range agencyCues {
+		if phrase := persian.Normalize(cue.phrase); strings.Contains(text, phrase) {
+			agency += cue.weight
+			text = strings.ReplaceAll(text, phrase, " ")
+		}
+	}
+
+	switch {
+	case agency > owner:
+		return types.Agency
+	case owner > agency:
+		return types.Owner
+	}
+	return ""
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/payments"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultGraceDays     = 3
+	defaultReminderDays  = 3
+	defaultCheckInterval = time.Hour
+)
+
+// ErrUnknownPlan is returned for a plan ID that is not offered
+var ErrUnknownPlan = errors.New("unknown plan")
+
+// Plan is a Premium period users can buy
+type Plan struct {
+	ID    string
+	Title string
+	Days  int
+	Price int64 // in Rials
+}
+
+var defaultPlans = []Plan{
+	{ID: "monthly", Title: "1 month", Days: 30, Price: 1_000_000},
+	{ID: "quarterly", Title: "3 months", Days: 90, Price: 2_700_000},
+	{ID: "yearly", Title: "12 months", Days: 365, Price: 9_600_000},
+}
+
+// Notifier sends a message to a user through the bot
+type Notifier func(user models.User, text string)
+
+// SubscriptionService sells Premium subscriptions and downgrades users when they expire
+type SubscriptionService struct {
+	plans                  []Plan
+	graceDays              int
+	reminderDays           int
+	callbackURL            string
+	gateway                payments.Gateway
+	subscriptionRepository db.SubscriptionRepository
+	invoiceRepository      db.InvoiceRepository
+	userRepository         db.UserRepository
+	notifierMutex          sync.RWMutex
+	notifier               Notifier
+	logger                 *slog.Logger
+}
+
+// NewSubscriptionService creates the service with the plans and periods read from the config
+func NewSubscriptionService(gateway payments.Gateway, subscriptionRepository db.SubscriptionRepository, invoiceRepository db.InvoiceRepository, userRepository db.UserRepository) *SubscriptionService {
+	return &SubscriptionService{
+		plans:                  LoadPlans(),
+		graceDays:              intConfig("SUBSCRIPTION_GRACE_DAYS", defaultGraceDays),
+		reminderDays:           intConfig("SUBSCRIPTION_REMINDER_DAYS", defaultReminderDays),
+		callbackURL:            utils.GetConfig("PAYMENT_CALLBACK_URL"),
+		gateway:                gateway,
+		subscriptionRepository: subscriptionRepository,
+		invoiceRepository:      invoiceRepository,
+		userRepository:         userRepository,
+		logger:                 utils.NewLogger("Subscription_Service"),
+	}
+}
+
+// LoadPlans returns the plans with their prices read from PLAN_<ID>_PRICE configs
+func LoadPlans() []Plan {
+	plans := make([]Plan, len(defaultPlans))
+	for i, plan := range defaultPlans {
+		if price, err := strconv.ParseInt(utils.GetConfig("PLAN_"+strings.ToUpper(plan.ID)+"_PRICE"), 10, 64); err == nil && price > 0 {
+			plan.Price = price
+		}
+		plans[i] = plan
+	}
+	return plans
+}
+
+// SetNotifier sets how users are told about payments, reminders and expiry
+func (s *SubscriptionService) SetNotifier(notifier Notifier) {
+	s.notifierMutex.Lock()
+	defer s.notifierMutex.Unlock()
+	s.notifier = notifier
+}
+
+func (s *SubscriptionService) notify(user models.User, text string) {
+	s.notifierMutex.RLock()
+	notifier := s.notifier
+	s.notifierMutex.RUnlock()
+	if notifier != nil && user.TelegramID != 0 {
+		notifier(user, text)
+	}
+}
+
+// Plans returns the plans users can buy
+func (s *SubscriptionService) Plans() []Plan {
+	return s.plans
+}
+
+func (s *SubscriptionService) plan(planID string) (Plan, error) {
+	for _, plan := range s.plans {
+		if plan.ID == planID {
+			return plan, nil
+		}
+	}
+	return Plan{}, fmt.Errorf("%w: %s", ErrUnknownPlan, planID)
+}
+
+// Current returns the subscription that ends last among the user's active ones, or a zero subscription
+func (s *SubscriptionService) Current(user models.User) (models.Subscription, error) {
+	return s.subscriptionRepository.FindLatest(user.ID)
+}
+
+// CreateInvoice starts a payment of plan for the user and returns the invoice and the URL to pay it
+func (s *SubscriptionService) CreateInvoice(ctx context.Context, user models.User, planID string) (models.Invoice, string, error) {
+	plan, err := s.plan(planID)
+	if err != nil {
+		return models.Invoice{}, "", err
+	}
+
+	payment, err := s.gateway.CreatePayment(ctx, payments.PaymentRequest{
+		Amount:      plan.Price,
+		Description: fmt.Sprintf("Premium subscription, %s", plan.Title),
+		CallbackURL: s.callbackURL,
+	})
+	if err != nil {
+		return models.Invoice{}, "", err
+	}
+
+	invoice, err := s.invoiceRepository.Create(models.Invoice{
+		UserID:    user.ID,
+		Plan:      plan.ID,
+		Amount:    plan.Price,
+		Gateway:   s.gateway.Name(),
+		Authority: payment.Authority,
+		Status:    models.INVOICE_PENDING,
+	})
+	return invoice, payment.URL, err
+}
+
+// HandleCallback verifies the payment the gateway redirected the user back for and activates the
+// subscription. Repeated callbacks for the same payment return the subscription activated the first time.
+func (s *SubscriptionService) HandleCallback(ctx context.Context, authority string, status string) (models.Subscription, error) {
+	invoice, err := s.invoiceRepository.FindByAuthority(authority)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Subscription{}, payments.ErrUnknownPayment
+	}
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	switch invoice.Status {
+	case models.INVOICE_FAILED:
+		return models.Subscription{}, payments.ErrPaymentFailed
+	case models.INVOICE_PAID:
+		subscription, err := s.subscriptionRepository.FindByInvoiceID(invoice.ID)
+		if err != nil || subscription.ID != 0 {
+			return subscription, err
+		}
+		// the invoice was paid but the subscription was not created, finish the activation
+		return s.activate(invoice)
+	}
+
+	if status != "OK" {
+		if err := s.invoiceRepository.MarkFailed(invoice.ID); err != nil {
+			return models.Subscription{}, err
+		}
+		return models.Subscription{}, payments.ErrPaymentFailed
+	}
+
+	verification, err := s.gateway.Verify(ctx, authority, invoice.Amount)
+	if errors.Is(err, payments.ErrPaymentFailed) {
+		if markErr := s.invoiceRepository.MarkFailed(invoice.ID); markErr != nil {
+			return models.Subscription{}, markErr
+		}
+		return models.Subscription{}, err
+	}
+	if err != nil {
+		// a network failure does not mean the payment failed, the callback can be retried
+		return models.Subscription{}, err
+	}
+
+	marked, err := s.invoiceRepository.MarkPaid(invoice.ID, verification.RefID, time.Now())
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	if !marked {
+		// another callback for the same payment is activating it
+		return s.subscriptionRepository.FindByInvoiceID(invoice.ID)
+	}
+	return s.activate(invoice)
+}
+
+// activate creates the subscription of a paid invoice, starting when the user's current subscription ends
+func (s *SubscriptionService) activate(invoice models.Invoice) (models.Subscription, error) {
+	plan, err := s.plan(invoice.Plan)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	user, err := s.userRepository.Find(invoice.UserID)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	invoiceID := invoice.ID
+	subscription, err := s.startSubscription(user, plan, &invoiceID)
+	if err != nil {
+		if existing, findErr := s.subscriptionRepository.FindByInvoiceID(invoice.ID); findErr == nil && existing.ID != 0 {
+			return existing, nil
+		}
+		return subscription, err
+	}
+
+	s.notify(user, tr(user, "subscription.paid", userDate(user, subscription.EndsAt)))
+	return subscription, nil
+}
+
+// Grant gives the user a plan without payment, e.g. by an admin
+func (s *SubscriptionService) Grant(user models.User, planID string) (models.Subscription, error) {
+	plan, err := s.plan(planID)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	subscription, err := s.startSubscription(user, plan, nil)
+	if err != nil {
+		return subscription, err
+	}
+	s.notify(user, tr(user, "subscription.granted", userDate(user, subscription.EndsAt)))
+	return subscription, nil
+}
+
+func (s *SubscriptionService) startSubscription(user models.User, plan Plan, invoiceID *uint) (models.Subscription, error) {
+	startsAt := time.Now()
+	latest, err := s.subscriptionRepository.FindLatest(user.ID)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	// a renewal continues the current subscription instead of overlapping it
+	if latest.ID != 0 && latest.Status == models.SUBSCRIPTION_ACTIVE && latest.EndsAt.After(startsAt) {
+		startsAt = latest.EndsAt
+	}
+
+	endsAt := startsAt.AddDate(0, 0, plan.Days)
+	subscription, err := s.subscriptionRepository.Create(models.Subscription{
+		UserID:      user.ID,
+		Plan:        plan.ID,
+		Status:      models.SUBSCRIPTION_ACTIVE,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		GraceEndsAt: endsAt.AddDate(0, 0, s.graceDays),
+		InvoiceID:   invoiceID,
+	})
+	if err != nil {
+		return subscription, err
+	}
+
+	if _, err := s.userRepository.UpdateUserType(user.ID, models.PREMIUM); err != nil {
+		return subscription, err
+	}
+	return subscription, nil
+}
+
+// Start checks reminders and expiries periodically in the background
+func (s *SubscriptionService) Start() {
+	interval := defaultCheckInterval
+	if minutes, err := strconv.Atoi(utils.GetConfig("SUBSCRIPTION_CHECK_INTERVAL")); err == nil && minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.CheckExpiry(time.Now()); err != nil {
+				s.logger.Error("subscription check failed", slog.Any("error", err))
+				tracking.Capture(models.SERVICE_ERROR, err, tracking.Origin{})
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// CheckExpiry gives Premium users without a subscription one of the shortest plan, sends reminders for
+// subscriptions ending soon, starts the grace period of ended ones and downgrades users whose grace period is over
+func (s *SubscriptionService) CheckExpiry(now time.Time) error {
+	if err := s.subscribeLegacyPremium(now); err != nil {
+		return err
+	}
+
+	ending, err := s.subscriptionRepository.FindEnding(now.AddDate(0, 0, s.reminderDays))
+	if err != nil {
+		return err
+	}
+	for _, subscription := range ending {
+		if subscription.EndsAt.After(now) && !s.renewed(subscription) {
+			s.notify(subscription.User, tr(subscription.User, "subscription.ending",
+				userDate(subscription.User, subscription.EndsAt), tr(subscription.User, "command.subscribe")))
+		}
+		subscription.ReminderSentAt = &now
+		if err := s.subscriptionRepository.Update(subscription); err != nil {
+			return err
+		}
+	}
+
+	ended, err := s.subscriptionRepository.FindEnded(now)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range ended {
+		if s.renewed(subscription) {
+			subscription.Status = models.SUBSCRIPTION_EXPIRED
+		} else {
+			subscription.Status = models.SUBSCRIPTION_GRACE
+			s.notify(subscription.User, tr(subscription.User, "subscription.grace",
+				userDate(subscription.User, subscription.GraceEndsAt), tr(subscription.User, "command.subscribe")))
+		}
+		if err := s.subscriptionRepository.Update(subscription); err != nil {
+			return err
+		}
+	}
+
+	graceEnded, err := s.subscriptionRepository.FindGraceEnded(now)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range graceEnded {
+		subscription.Status = models.SUBSCRIPTION_EXPIRED
+		if err := s.subscriptionRepository.Update(subscription); err != nil {
+			return err
+		}
+		if s.renewed(subscription) {
+			continue
+		}
+		if _, err := s.userRepository.UpdateUserType(subscription.UserID, models.FREE); err != nil {
+			return err
+		}
+		s.notify(subscription.User, tr(subscription.User, "subscription.expired"))
+	}
+	return nil
+}
+
+// subscribeLegacyPremium starts a subscription for users made Premium without one, so their Premium lapses too
+func (s *SubscriptionService) subscribeLegacyPremium(now time.Time) error {
+	users, err := s.subscriptionRepository.FindPremiumWithoutSubscription()
+	if err != nil {
+		return err
+	}
+	plan := s.plans[0]
+	for _, user := range users {
+		endsAt := now.AddDate(0, 0, plan.Days)
+		subscription, err := s.subscriptionRepository.Create(models.Subscription{
+			UserID:      user.ID,
+			Plan:        plan.ID,
+			Status:      models.SUBSCRIPTION_ACTIVE,
+			StartsAt:    now,
+			EndsAt:      endsAt,
+			GraceEndsAt: endsAt.AddDate(0, 0, s.graceDays),
+		})
+		if err != nil {
+			return err
+		}
+		s.notify(user, tr(user, "subscription.legacy",
+			userDate(user, subscription.EndsAt), tr(user, "command.subscribe")))
+	}
+	return nil
+}
+
+// renewed reports whether the user has another subscription lasting past the given one
+func (s *SubscriptionService) renewed(subscription models.Subscription) bool {
+	latest, err := s.subscriptionRepository.FindLatest(subscription.UserID)
+	if err != nil {
+		s.logger.Error("could not find latest subscription", slog.Any("error", err))
+		return false
+	}
+	return latest.ID != 0 && latest.ID != subscription.ID && latest.EndsAt.After(subscription.EndsAt)
+}
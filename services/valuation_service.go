@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+)
+
+const (
+	defaultValuationNeighbors = 8
+	defaultValuationMaxAge    = 180 // days
+	minComparables            = 3
+	comparableAreaRange       = 0.4
+	comparableCandidates      = 2000
+)
+
+var (
+	// ErrNotValuable is returned for a listing without the price or area a valuation needs
+	ErrNotValuable = errors.New("listing has no price or area to value")
+	// ErrNotEnoughComparables is returned when too few similar listings are known
+	ErrNotEnoughComparables = errors.New("not enough comparable listings")
+)
+
+// Verdict compares the asked price with the estimated band
+type Verdict string
+
+const (
+	FAIR_PRICE  Verdict = "fair"
+	OVERPRICED  Verdict = "overpriced"
+	UNDERPRICED Verdict = "underpriced"
+)
+
+// Comparable is a listing used for a valuation with its distance to the valued one
+type Comparable struct {
+	Post     models.PostHistory
+	Distance float64
+}
+
+// Valuation is the expected value of a listing, its price for sales and its monthly rent for rents
+type Valuation struct {
+	Post        models.PostHistory
+	Asked       int64
+	Estimate    int64
+	Low         int64
+	High        int64
+	Verdict     Verdict
+	Comparables []Comparable
+}
+
+// ValuationService estimates what a listing should cost from the k nearest comparable listings
+type ValuationService struct {
+	neighbors      int
+	maxAge         time.Duration
+	postRepository db.PostRepo
+}
+
+func NewValuationService(postRepository db.PostRepo) *ValuationService {
+	return &ValuationService{
+		neighbors:      intConfig("VALUATION_NEIGHBORS", defaultValuationNeighbors),
+		maxAge:         time.Duration(intConfig("VALUATION_MAX_AGE_DAYS", defaultValuationMaxAge)) * 24 * time.Hour,
+		postRepository: postRepository,
+	}
+}
+
+// Value estimates the post history with the given id
+func (s *ValuationService) Value(postHistoryID uint, now time.Time) (Valuation, error) {
+	post, err := s.postRepository.FindPostHistory(postHistoryID)
+	if err != nil {
+		return Valuation{}, err
+	}
+	asked := ListingValue(post)
+	if asked <= 0 || post.Area <= 0 {
+		return Valuation{}, ErrNotValuable
+	}
+
+	candidates, err := s.postRepository.FindComparables(models.ComparableQuery{
+		City:          post.City,
+		BuyMode:       post.BuyMode,
+		Building:      post.Building,
+		AreaMin:       int(math.Floor(float64(post.Area) * (1 - comparableAreaRange))),
+		AreaMax:       int(math.Ceil(float64(post.Area) * (1 + comparableAreaRange))),
+		ExcludePostID: post.PostID,
+		Since:         now.Add(-s.maxAge),
+		Limit:         comparableCandidates,
+	})
+	if err != nil {
+		return Valuation{}, err
+	}
+	return Estimate(post, candidates, s.neighbors)
+}
+
+// Estimate values post from its k nearest candidates. The estimate is the distance weighted mean price
+// per square meter times the area, and the band is one weighted standard deviation around it.
+func Estimate(post models.PostHistory, candidates []models.PostHistory, k int) (Valuation, error) {
+	asked := ListingValue(post)
+	if asked <= 0 || post.Area <= 0 {
+		return Valuation{}, ErrNotValuable
+	}
+
+	comparables := make([]Comparable, 0, len(candidates))
+	for _, candidate := range candidates {
+		if samePost(post, candidate) || ListingValue(candidate) <= 0 || candidate.Area <= 0 {
+			continue
+		}
+		comparables = append(comparables, Comparable{Post: candidate, Distance: distance(post, candidate)})
+	}
+	if len(comparables) < minComparables {
+		return Valuation{}, ErrNotEnoughComparables
+	}
+	sort.SliceStable(comparables, func(i, j int) bool {
+		return comparables[i].Distance < comparables[j].Distance
+	})
+	if k > 0 && len(comparables) > k {
+		comparables = comparables[:k]
+	}
+
+	var weightSum, mean float64
+	for _, comparable := range comparables {
+		weight := 1 / (1 + comparable.Distance)
+		weightSum += weight
+		mean += weight * pricePerMeter(comparable.Post)
+	}
+	mean /= weightSum
+
+	var variance float64
+	for _, comparable := range comparables {
+		weight := 1 / (1 + comparable.Distance)
+		variance += weight * math.Pow(pricePerMeter(comparable.Post)-mean, 2)
+	}
+	deviation := math.Sqrt(variance / weightSum)
+
+	area := float64(post.Area)
+	valuation := Valuation{
+		Post:        post,
+		Asked:       asked,
+		Estimate:    int64(math.Round(mean * area)),
+		Low:         int64(math.Round(math.Max(mean-deviation, 0) * area)),
+		High:        int64(math.Round((mean + deviation) * area)),
+		Verdict:     FAIR_PRICE,
+		Comparables: comparables,
+	}
+	if asked > valuation.High {
+		valuation.Verdict = OVERPRICED
+	} else if asked < valuation.Low {
+		valuation.Verdict = UNDERPRICED
+	}
+	return valuation, nil
+}
+
+// distance weighs how different two listings are, 0 for the same neighborhood, area, age, floor and amenities
+func distance(a models.PostHistory, b models.PostHistory) float64 {
+	areaDifference := (float64(b.Area) - float64(a.Area)) / float64(a.Area) / 0.1
+	ageDifference := (float64(b.Age) - float64(a.Age)) / 5
+	floorDifference := (float64(b.FloorsNum) - float64(a.FloorsNum)) / 3
+	bedroomDifference := float64(b.BedroomNum - a.BedroomNum)
+
+	squared := areaDifference*areaDifference + ageDifference*ageDifference +
+		floorDifference*floorDifference + bedroomDifference*bedroomDifference
+	if !strings.EqualFold(strings.TrimSpace(a.Neighborhood), strings.TrimSpace(b.Neighborhood)) {
+		squared += 4
+	}
+	for _, same := range []bool{a.HasElevator == b.HasElevator, a.HasParking == b.HasParking, a.HasStorage == b.HasStorage} {
+		if !same {
+			squared += 0.5
+		}
+	}
+	return math.Sqrt(squared)
+}
+
+func samePost(a models.PostHistory, b models.PostHistory) bool {
+	return (a.ID != 0 && a.ID == b.ID) || (a.PostID != 0 && a.PostID == b.PostID)
+}
+
+// ListingValue is the price of a sale or the monthly cost of a rent, so rentals trading rent for deposit compare
+// fairly. It is 0 for a rental with a deposit whose monthly cost was not computed yet.
+func ListingValue(post models.PostHistory) int64 {
+	if post.BuyMode != types.Rent {
+		return post.Price
+	}
+	if post.MonthlyCost > 0 {
+		return post.MonthlyCost
+	}
+	if post.Deposit == 0 {
+		return post.Rent
+	}
+	return 0
+}
+
+func pricePerMeter(post models.PostHistory) float64 {
+	return float64(ListingValue(post)) / float64(post.Area)
+}
@@ -0,0 +1,55 @@
+// Package storage keeps binary objects, like the archived photos of listings, under string keys
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const defaultLocalPath = "./images"
+
+var (
+	// ErrBlobNotFound is returned when no blob is stored under a key
+	ErrBlobNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for keys that are empty or could leave the store, like "../x"
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore stores blobs under slash separated keys like "originals/ab/abcd.jpg"
+type BlobStore interface {
+	Name() string
+	Put(key string, content []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// NewBlobStoreFromConfig returns the store selected by IMAGE_STORE, the local filesystem store when it is unset
+func NewBlobStoreFromConfig() (BlobStore, error) {
+	switch name := utils.GetConfig("IMAGE_STORE"); name {
+	case "", "local":
+		path := utils.GetConfig("IMAGE_STORE_PATH")
+		if path == "" {
+			path = defaultLocalPath
+		}
+		return NewLocalStore(path)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown image store %q", name)
+	}
+}
+
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package storage
+
+import "sync"
+
+// MemoryStore keeps blobs in memory, for local runs and tests
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Name() string {
+	return "memory"
+}
+
+func (s *MemoryStore) Put(key string, content []byte) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = append([]byte(nil), content...)
+	return nil
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, exists := s.blobs[key]
+	if !exists {
+		return nil, ErrBlobNotFound
+	}
+	return append([]byte(nil), content...), nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+// Len returns how many blobs are stored
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.blobs)
+}
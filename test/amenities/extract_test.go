@@ -0,0 +1,67 @@
+package amenities
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/amenities"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixture is a listing description of the corpus with the amenities it mentions
+type fixture struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Features    []string `json:"features"`
+	Want        struct {
+		Balcony     bool               `json:"balcony"`
+		Rooftop     bool               `json:"rooftop"`
+		Pool        bool               `json:"pool"`
+		Sauna       bool               `json:"sauna"`
+		Jacuzzi     bool               `json:"jacuzzi"`
+		Gym         bool               `json:"gym"`
+		Lobby       bool               `json:"lobby"`
+		Guard       bool               `json:"guard"`
+		Storage     bool               `json:"storage"`
+		Parking     bool               `json:"parking"`
+		Elevator    bool               `json:"elevator"`
+		Renovation  types.Renovation   `json:"renovation"`
+		Document    types.DocumentType `json:"document"`
+		Orientation types.Orientation  `json:"orientation"`
+	} `json:"want"`
+}
+
+func TestExtractCorpus(t *testing.T) {
+	content, err := os.ReadFile("testdata/descriptions.json")
+	assert.NoError(t, err)
+	var fixtures []fixture
+	assert.NoError(t, json.Unmarshal(content, &fixtures))
+	assert.NotEmpty(t, fixtures)
+
+	for _, f := range fixtures {
+		t.Run(f.Name, func(t *testing.T) {
+			want := models.Amenities{
+				Balcony: f.Want.Balcony, Rooftop: f.Want.Rooftop, Pool: f.Want.Pool, Sauna: f.Want.Sauna,
+				Jacuzzi: f.Want.Jacuzzi, Gym: f.Want.Gym, Lobby: f.Want.Lobby, Guard: f.Want.Guard,
+				Renovation: f.Want.Renovation, Document: f.Want.Document, Orientation: f.Want.Orientation,
+			}
+			assert.Equal(t, want, amenities.Extract(f.Description, f.Features))
+			assert.Equal(t, f.Want.Storage, amenities.Has(f.Description, f.Features, types.Storage), "storage")
+			assert.Equal(t, f.Want.Parking, amenities.Has(f.Description, f.Features, types.Parking), "parking")
+			assert.Equal(t, f.Want.Elevator, amenities.Has(f.Description, f.Features, types.Elevator), "elevator")
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for value, want := range map[string]types.Amenity{"Pool": types.Pool, "استخر": types.Pool, " لابي ": types.Lobby, "روف گاردن": types.Rooftop} {
+		amenity, ok := amenities.Parse(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, want, amenity, value)
+	}
+	_, ok := amenities.Parse("helipad")
+	assert.False(t, ok)
+}
@@ -0,0 +1,37 @@
+package charts
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/charts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineChartPNG(t *testing.T) {
+	chart := charts.LineChart{Width: 200, Height: 100, Series: []charts.Series{
+		{Color: charts.Blue, Points: []float64{10, math.NaN(), 30, 20}},
+		{Color: charts.Orange, Points: []float64{5}},
+	}}
+
+	low, high, err := chart.Bounds()
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, low)
+	assert.Equal(t, 30.0, high)
+
+	content, err := chart.PNG()
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+	assert.Equal(t, 100, img.Bounds().Dy())
+}
+
+func TestLineChartWithoutData(t *testing.T) {
+	chart := charts.LineChart{Series: []charts.Series{{Points: []float64{math.NaN()}}}}
+	_, err := chart.PNG()
+	assert.True(t, errors.Is(err, charts.ErrNoData))
+}
@@ -0,0 +1,111 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() crawlers.PolitenessConfig {
+	return crawlers.PolitenessConfig{
+		RequestsPerSecond: 20,
+		Burst:             2,
+		MaxConcurrency:    1,
+		BackoffBase:       100 * time.Millisecond,
+		BackoffMax:        time.Second,
+		BreakerThreshold:  3,
+		BreakerCooldown:   time.Minute,
+	}
+}
+
+func TestLimiterTokenBucket(t *testing.T) {
+	limiter := crawlers.NewHostLimiter("divar.ir", testPolicy())
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := limiter.Acquire(ctx)
+		assert.NoError(t, err)
+		release()
+	}
+	// two requests come from the burst, the other two wait 50ms each for a new token
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Equal(t, uint64(4), limiter.Snapshot().Requests)
+}
+
+func TestLimiterConcurrencyCap(t *testing.T) {
+	limiter := crawlers.NewHostLimiter("divar.ir", testPolicy())
+
+	release, err := limiter.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, limiter.Snapshot().InFlight)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	release()
+	assert.Equal(t, 0, limiter.Snapshot().InFlight)
+}
+
+func TestLimiterCircuitBreaker(t *testing.T) {
+	limiter := crawlers.NewHostLimiter("sheypoor.com", testPolicy())
+
+	limiter.Report(429)
+	limiter.Report(503)
+	limiter.Report(200)
+	assert.False(t, limiter.Snapshot().Paused(), "a successful response resets the block counter")
+
+	limiter.Report(429)
+	limiter.Report(500)
+	limiter.Report(502)
+	state := limiter.Snapshot()
+	assert.True(t, state.Paused())
+	assert.Equal(t, uint64(5), state.Throttled)
+
+	_, err := limiter.Acquire(context.Background())
+	assert.True(t, errors.Is(err, crawlers.ErrCircuitOpen))
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	limiter := crawlers.NewHostLimiter("divar.ir", testPolicy())
+
+	first := limiter.Backoff(1)
+	assert.GreaterOrEqual(t, first, 100*time.Millisecond)
+	assert.LessOrEqual(t, first, 120*time.Millisecond)
+
+	third := limiter.Backoff(3)
+	assert.GreaterOrEqual(t, third, 400*time.Millisecond)
+
+	capped := limiter.Backoff(20)
+	assert.LessOrEqual(t, capped, 1200*time.Millisecond)
+}
+
+func TestLimiterWaitBackoffStopsOnCancel(t *testing.T) {
+	limiter := crawlers.NewHostLimiter("divar.ir", testPolicy())
+	assert.NoError(t, limiter.WaitBackoff(context.Background(), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	// the capped backoff is a second, canceling must not wait it out
+	assert.ErrorIs(t, limiter.WaitBackoff(ctx, 20), context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestLimiterRegistrySharesHosts(t *testing.T) {
+	registry := crawlers.NewLimiterRegistry(testPolicy())
+
+	a := registry.For("https://divar.ir/s/tehran/real-estate")
+	b := registry.For("https://divar.ir/v/some-post/wZ0kfXs_")
+	c := registry.For("https://www.sheypoor.com/s/tehran/real-estate")
+
+	assert.Same(t, a, b)
+	assert.NotSame(t, a, c)
+	assert.Len(t, registry.Snapshot(), 2)
+}
@@ -0,0 +1,37 @@
+package crawlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCoordinates(t *testing.T) {
+	pages := []struct {
+		name      string
+		html      string
+		ok        bool
+		latitude  float64
+		longitude float64
+	}{
+		{"meta tags", `<head><meta property="place:location:latitude" content="35.7219"><meta property="place:location:longitude" content="51.3347"></head>`, true, 35.7219, 51.3347},
+		{"map link", `<body><a href="https://www.google.com/maps/search/?api=1&query=35.70,51.41">نقشه</a></body>`, true, 35.70, 51.41},
+		{"map path", `<body><a href="https://www.google.com/maps/@35.7612,51.4102,16z">نقشه</a></body>`, true, 35.7612, 51.4102},
+		{"static map image", `<body><img src="https://map.example.ir/static?center=35.8,51.45&zoom=15"></body>`, true, 35.8, 51.45},
+		{"structured data", `<body><script type="application/ld+json">{"geo": {"latitude": "36.2972", "longitude": 59.6067}}</script></body>`, true, 36.2972, 59.6067},
+		{"not a map link", `<body><a href="https://example.com/?q=35.70,51.41">link</a></body>`, false, 0, 0},
+		{"hidden location", `<body><img src="https://map.example.ir/static?center=0.0,0.0"></body>`, false, 0, 0},
+		{"no map", `<body><h1>آپارتمان ۸۰ متری</h1></body>`, false, 0, 0},
+	}
+	for _, page := range pages {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page.html))
+		assert.NoError(t, err)
+		latitude, longitude, ok := crawlers.ParseCoordinates(doc)
+		assert.Equal(t, page.ok, ok, page.name)
+		assert.InDelta(t, page.latitude, latitude, 1e-9, page.name)
+		assert.InDelta(t, page.longitude, longitude, 1e-9, page.name)
+	}
+}
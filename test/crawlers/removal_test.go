@@ -0,0 +1,39 @@
+package crawlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRemoval(t *testing.T) {
+	assert.ErrorIs(t, crawlers.DetectRemoval(404, ""), crawlers.ErrPostRemoved)
+	assert.ErrorIs(t, crawlers.DetectRemoval(410, ""), crawlers.ErrPostRemoved)
+	assert.ErrorIs(t, crawlers.DetectRemoval(200, "این آگهی حذف شده است و دیگر نمایش داده نمی‌شود"), crawlers.ErrPostRemoved)
+	assert.ErrorIs(t, crawlers.DetectRemoval(200, "اين آگهي منقضي شده است"), crawlers.ErrPostExpired)
+	assert.NoError(t, crawlers.DetectRemoval(200, "آپارتمان ۸۰ متری، سند تک برگ"))
+}
+
+func TestPageTextSkipsScripts(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<html><body><h1>آپارتمان</h1><script>var notices = {"expired": "این آگهی منقضی شده است"}</script></body></html>`))
+	assert.NoError(t, err)
+	assert.NoError(t, crawlers.DetectRemoval(200, crawlers.PageText(doc)))
+	assert.Contains(t, crawlers.PageText(doc), "آپارتمان")
+}
+
+func TestDetectPageRemoval(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<html><body><h1>آپارتمان ۸۰ متری</h1><p>واحد طبقه دوم فروخته شد، آگهی قبلی منقضی شده است</p></body></html>`))
+	assert.NoError(t, err)
+	assert.NoError(t, crawlers.DetectPageRemoval(doc, "آپارتمان ۸۰ متری", "واحد طبقه دوم فروخته شد"),
+		"a live post quoting the phrases in its description is not removed")
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(
+		`<html><body><div class="notice">این آگهی حذف شده است</div></body></html>`))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, crawlers.DetectPageRemoval(doc, "", ""), crawlers.ErrPostRemoved)
+}
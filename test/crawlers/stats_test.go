@@ -0,0 +1,57 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/playwright-community/playwright-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawlStatsCounters(t *testing.T) {
+	stats := crawlers.NewCrawlStats()
+
+	stats.RecordRequest()
+	stats.RecordRequest()
+	stats.RecordRequest()
+	stats.RecordBytes(1024)
+	stats.RecordBytes(512)
+	stats.RecordSuccess()
+	stats.RecordFailure(crawlers.ErrorTimeout)
+	stats.RecordFailure(crawlers.ErrorTimeout)
+	stats.RecordFailure(crawlers.ErrorMissingFields)
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, uint(3), snapshot.Requests)
+	assert.Equal(t, uint(1), snapshot.Successes)
+	assert.Equal(t, uint(3), snapshot.Failures)
+	assert.Equal(t, int64(1536), snapshot.BytesFetched)
+	assert.Equal(t, uint(2), snapshot.ErrorCounts[crawlers.ErrorTimeout])
+	assert.Equal(t, uint(1), snapshot.ErrorCounts[crawlers.ErrorMissingFields])
+
+	snapshot.ErrorCounts[crawlers.ErrorTimeout] = 10
+	assert.Equal(t, uint(2), stats.Snapshot().ErrorCounts[crawlers.ErrorTimeout], "snapshots must not share the counters")
+}
+
+func TestClassifyError(t *testing.T) {
+	assert.Equal(t, crawlers.ErrorCircuitOpen, crawlers.ClassifyError(fmt.Errorf("divar.ir: %w", crawlers.ErrCircuitOpen)))
+	assert.Equal(t, crawlers.ErrorBlocked, crawlers.ClassifyError(fmt.Errorf("%w: status %d", crawlers.ErrBlocked, 429)))
+	assert.Equal(t, crawlers.ErrorTimeout, crawlers.ClassifyError(fmt.Errorf("goto: %w", playwright.ErrTimeout)))
+	assert.Equal(t, crawlers.ErrorCanceled, crawlers.ClassifyError(context.DeadlineExceeded))
+	assert.Equal(t, crawlers.ErrorNavigation, crawlers.ClassifyError(fmt.Errorf("net::ERR_CONNECTION_RESET")))
+}
+
+func TestStatsFromContext(t *testing.T) {
+	stats := crawlers.NewCrawlStats()
+	ctx := crawlers.WithStats(context.Background(), stats)
+
+	crawlers.StatsFromContext(ctx).RecordRequest()
+	assert.Equal(t, uint(1), stats.Snapshot().Requests)
+
+	orphan := crawlers.StatsFromContext(context.Background())
+	assert.NotNil(t, orphan)
+	orphan.RecordRequest()
+	assert.Equal(t, uint(1), stats.Snapshot().Requests)
+}
@@ -0,0 +1,27 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	assert.InDelta(t, 111.19, geo.Distance(geo.Point{}, geo.Point{Longitude: 1}), 0.01)
+	assert.Zero(t, geo.Distance(geo.Point{Latitude: 35.7, Longitude: 51.4}, geo.Point{Latitude: 35.7, Longitude: 51.4}))
+
+	// Azadi Tower to Milad Tower in Tehran
+	azadi, milad := geo.Point{Latitude: 35.6997, Longitude: 51.3381}, geo.Point{Latitude: 35.7448, Longitude: 51.3753}
+	assert.InDelta(t, 6.0, geo.Distance(azadi, milad), 0.1)
+	assert.Equal(t, geo.Distance(azadi, milad), geo.Distance(milad, azadi))
+}
+
+func TestNewPoint(t *testing.T) {
+	latitude, longitude := 35.7, 51.4
+	point, ok := geo.NewPoint(&latitude, &longitude)
+	assert.True(t, ok)
+	assert.Equal(t, geo.Point{Latitude: 35.7, Longitude: 51.4}, point)
+	_, ok = geo.NewPoint(&latitude, nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/handlers"
+	"github.com/MagicalCrawler/RealEstateApp/metrics"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/payments"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	dbConnection, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	return dbConnection
+}
+
+func getHealth(t *testing.T, handler http.Handler, path string) (int, handlers.HealthResponse) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var response handlers.HealthResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	return recorder.Code, response
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	t.Setenv("READY_MAX_CRAWL_AGE", "30")
+	lastCrawl := time.Now().Add(-10 * time.Minute)
+	server := handlers.NewServer(newTestDB(t), func() time.Time { return lastCrawl })
+
+	code, response := getHealth(t, server.Handler(), "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", response.Checks["database"])
+
+	code, response = getHealth(t, server.Handler(), "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, response.Checks["crawler"], "last successful crawl")
+
+	lastCrawl = time.Now().Add(-time.Hour)
+	code, response = getHealth(t, server.Handler(), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", response.Status)
+}
+
+func TestReadinessBeforeFirstCrawl(t *testing.T) {
+	server := handlers.NewServer(newTestDB(t), func() time.Time { return time.Time{} })
+
+	code, response := getHealth(t, server.Handler(), "/readyz")
+	assert.Equal(t, http.StatusOK, code, "a fresh process is ready while the first cycle runs")
+	assert.Equal(t, "starting up", response.Checks["crawler"])
+}
+
+func TestDatabaseDown(t *testing.T) {
+	dbConnection := newTestDB(t)
+	sqlDB, _ := dbConnection.DB()
+	sqlDB.Close()
+	server := handlers.NewServer(dbConnection, time.Now)
+
+	code, response := getHealth(t, server.Handler(), "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.NotEqual(t, "ok", response.Checks["database"])
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	metrics.BotUpdates.WithLabelValues("message").Inc()
+	server := handlers.NewServer(newTestDB(t), time.Now)
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	assert.True(t, strings.Contains(body, `realestate_bot_updates_total{type="message"}`))
+	assert.True(t, strings.Contains(body, "go_goroutines"))
+}
+
+type unpaidProcessor struct{}
+
+func (unpaidProcessor) HandleCallback(ctx context.Context, authority string, status string) (models.Subscription, error) {
+	return models.Subscription{}, payments.ErrPaymentFailed
+}
+
+func TestPaymentServerDoesNotServeMetrics(t *testing.T) {
+	handler := handlers.NewPaymentHandler(unpaidProcessor{})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/payments/callback?Authority=A1&Status=NOK", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Payment failed")
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestPaymentServerNeedsItsOwnAddress(t *testing.T) {
+	t.Setenv("METRICS_ADDR", ":18080")
+	t.Setenv("PAYMENT_CALLBACK_ADDR", ":18080")
+
+	assert.Error(t, handlers.StartPaymentServer(unpaidProcessor{}))
+}
@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+// without arguments every verb of a message is written as %!<verb>(MISSING)
+var verbPattern = regexp.MustCompile(`%!.\(MISSING\)`)
+
+func TestCatalogsMatch(t *testing.T) {
+	english := i18n.Keys(i18n.English)
+	assert.NotEmpty(t, english)
+	assert.Equal(t, english, i18n.Keys(i18n.Persian))
+
+	for _, key := range english {
+		assert.Equal(t, verbPattern.FindAllString(i18n.T(i18n.English, key), -1),
+			verbPattern.FindAllString(i18n.T(i18n.Persian, key), -1), key)
+	}
+}
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, i18n.Persian, i18n.Parse("fa"))
+	assert.Equal(t, i18n.Persian, i18n.Parse("fa-IR"))
+	assert.Equal(t, i18n.English, i18n.Parse("en-US"))
+	assert.Equal(t, i18n.Default, i18n.Parse("de"))
+	assert.Equal(t, i18n.Default, i18n.Parse(""))
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Selected filter ID: 12", i18n.T(i18n.English, "filter.selected", 12))
+	assert.Equal(t, "فیلتر انتخاب‌شده: ۱۲", i18n.T(i18n.Persian, "filter.selected", 12))
+	assert.Contains(t, i18n.T(i18n.English, "filter.monthly_cost_range.prompt", "3"), "rent plus 3% of the deposit")
+	assert.Equal(t, "no.such.key", i18n.T(i18n.Persian, "no.such.key"))
+	assert.Equal(t, []string{"زبان", "Language"}, i18n.Labels("command.language"))
+
+	// links and titles keep their digits, numbers are written in Persian
+	text := i18n.T(i18n.Persian, "search.post_button", "آپارتمان 120 متری", 3500000000, "تهران", "ونک", 120, 2,
+		"https://divar.ir/v/AZ123")
+	assert.Contains(t, text, "آپارتمان 120 متری")
+	assert.Contains(t, text, "۳۵۰۰۰۰۰۰۰۰")
+	assert.Contains(t, text, "(https://divar.ir/v/AZ123)")
+}
+
+func TestNumberAndDate(t *testing.T) {
+	assert.Equal(t, "1,250,000", i18n.Number(i18n.English, 1250000))
+	assert.Equal(t, "۱٬۲۵۰٬۰۰۰", i18n.Number(i18n.Persian, 1250000))
+	assert.Equal(t, "-950", i18n.Number(i18n.English, -950))
+
+	day := time.Date(2024, time.March, 20, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "1403/01/01", i18n.Date(i18n.English, day))
+	assert.Equal(t, "۱۴۰۳/۰۱/۰۱", i18n.Date(i18n.Persian, day))
+	assert.Equal(t, "۱۴۰۳/۰۱/۰۱ ۱۵:۳۰", i18n.DateTime(i18n.Persian, day))
+}
@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/imaging"
+	"github.com/stretchr/testify/assert"
+)
+
+// gradient draws a photo-like image of the given size, bright on the left and dark on the right with a square in it
+func gradient(width int, height int, inverted bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value := uint8(255 - 255*x/width)
+			if inverted {
+				value = 255 - value
+			}
+			if x > width/3 && x < width/2 && y > height/3 && y < 2*height/3 {
+				value = 200
+			}
+			img.Set(x, y, color.RGBA{R: value, G: value / 2, B: 255 - value, A: 255})
+		}
+	}
+	return img
+}
+
+func TestHashSurvivesResizeAndRecompression(t *testing.T) {
+	original := gradient(640, 480, false)
+	hash := imaging.Hash(original)
+
+	assert.LessOrEqual(t, imaging.Distance(hash, imaging.Hash(imaging.Thumbnail(original, 200))), 2)
+
+	content, err := imaging.EncodeJPEG(original, 60)
+	assert.NoError(t, err)
+	recompressed, err := imaging.Decode(content, 1_000_000)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, imaging.Distance(hash, imaging.Hash(recompressed)), 2)
+
+	assert.Greater(t, imaging.Distance(hash, imaging.Hash(gradient(640, 480, true))), 20)
+}
+
+func TestThumbnail(t *testing.T) {
+	assert.Equal(t, image.Rect(0, 0, 320, 240), imaging.Thumbnail(gradient(640, 480, false), 320).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 160, 320), imaging.Thumbnail(gradient(500, 1000, false), 320).Bounds())
+	// small images are not enlarged
+	assert.Equal(t, image.Rect(0, 0, 100, 50), imaging.Thumbnail(gradient(100, 50, false), 320).Bounds())
+}
+
+func TestDecodeRejectsOtherFiles(t *testing.T) {
+	_, err := imaging.Decode([]byte("<html>not found</html>"), 1_000_000)
+	assert.Error(t, err)
+}
+
+func TestDecodeRejectsTooManyPixels(t *testing.T) {
+	var content bytes.Buffer
+	assert.NoError(t, png.Encode(&content, gradient(400, 300, false)))
+
+	_, err := imaging.Decode(content.Bytes(), 100_000)
+	assert.ErrorIs(t, err, imaging.ErrTooManyPixels)
+	img, err := imaging.Decode(content.Bytes(), 120_000)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 400, 300), img.Bounds())
+}
+
+func TestDecodeWebP(t *testing.T) {
+	// a lossless 1x1 WebP, the format the Divar CDN serves
+	content, err := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	assert.NoError(t, err)
+	img, err := imaging.Decode(content, 1_000_000)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 1, 1), img.Bounds())
+}
@@ -0,0 +1,86 @@
+package jalali
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/jalali"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromTime(t *testing.T) {
+	cases := map[string]jalali.Date{
+		"2024-03-20": {Year: 1403, Month: 1, Day: 1},
+		"2026-10-19": {Year: 1405, Month: 7, Day: 27},
+		"2025-03-20": {Year: 1403, Month: 12, Day: 30},
+		"1979-02-11": {Year: 1357, Month: 11, Day: 22},
+	}
+	for gregorian, expected := range cases {
+		day, err := time.Parse("2006-01-02", gregorian)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, jalali.FromTime(day), gregorian)
+		assert.Equal(t, day, expected.Time(time.UTC), gregorian)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for day := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC); day.Year() < 2050; day = day.AddDate(0, 0, 1) {
+		date := jalali.FromTime(day)
+		assert.True(t, date.Valid())
+		if !assert.Equal(t, day, date.Time(time.UTC), date.String()) {
+			return
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	date, err := jalali.Parse("1403/01/01")
+	assert.NoError(t, err)
+	assert.Equal(t, jalali.Date{Year: 1403, Month: 1, Day: 1}, date)
+	assert.Equal(t, "1403/01/01", date.String())
+	assert.Equal(t, "فروردین", date.MonthName())
+
+	date, err = jalali.Parse("1402-12-29")
+	assert.NoError(t, err)
+	assert.Equal(t, jalali.Date{Year: 1402, Month: 12, Day: 29}, date)
+
+	for _, text := range []string{"", "1403", "1403/13/01", "1402/12/30", "1403/07/31", "a/b/c"} {
+		_, err := jalali.Parse(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestIsLeap(t *testing.T) {
+	assert.True(t, jalali.IsLeap(1403))
+	assert.False(t, jalali.IsLeap(1402))
+	assert.False(t, jalali.IsLeap(1404))
+	assert.True(t, jalali.IsLeap(1399))
+}
+
+func TestParseDay(t *testing.T) {
+	tehran := time.FixedZone("IRST", 3*60*60+30*60)
+	day, err := jalali.ParseDay("1403/01/01", tehran)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 20, 0, 0, 0, 0, tehran), day)
+
+	day, err = jalali.ParseDay(" 2024-03-20", tehran)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 20, 0, 0, 0, 0, tehran), day)
+
+	for _, text := range []string{"1403/13/01", "2024-02-30", "2024/03", "yesterday"} {
+		_, err := jalali.ParseDay(text, tehran)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestYearsSince(t *testing.T) {
+	// Nowruz 1405 is on March 21, 2026
+	beforeNowruz := time.Date(2026, time.March, 19, 12, 0, 0, 0, time.UTC)
+	afterNowruz := time.Date(2026, time.March, 22, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 6, jalali.YearsSince(1398, beforeNowruz))
+	assert.Equal(t, 7, jalali.YearsSince(1398, afterNowruz))
+	assert.Equal(t, 7, jalali.YearsSince(2019, afterNowruz))
+	assert.Equal(t, 0, jalali.YearsSince(1405, afterNowruz))
+	assert.Equal(t, 0, jalali.YearsSince(1410, afterNowruz))
+	assert.Equal(t, 0, jalali.YearsSince(0, afterNowruz))
+}
@@ -0,0 +1,32 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/payments"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayFromConfigFailsClosed(t *testing.T) {
+	t.Setenv("PAYMENT_GATEWAY", "")
+	_, err := payments.NewGatewayFromConfig()
+	assert.Error(t, err, "no gateway is chosen silently")
+
+	t.Setenv("PAYMENT_GATEWAY", "paypal")
+	_, err = payments.NewGatewayFromConfig()
+	assert.Error(t, err)
+
+	t.Setenv("PAYMENT_GATEWAY", "fake")
+	_, err = payments.NewGatewayFromConfig()
+	assert.Error(t, err, "the fake gateway pays every invoice")
+
+	t.Setenv("PAYMENT_ALLOW_FAKE", "true")
+	gateway, err := payments.NewGatewayFromConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "fake", gateway.Name())
+
+	t.Setenv("PAYMENT_GATEWAY", "zarinpal")
+	gateway, err = payments.NewGatewayFromConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "zarinpal", gateway.Name())
+}
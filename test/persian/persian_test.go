@@ -0,0 +1,42 @@
+package persian
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/persian"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"كمد ديواري":         "کمد دیواری",
+		"کمد‌دیواری، بالکن!": "کمد دیواری بالکن",
+		"  ۱۲۰ متر ٣ خواب ":  "120 متر 3 خواب",
+		"آپارتمانِ نـــوساز": "اپارتمان نوساز",
+		"Parking, LOBBY":     "parking lobby",
+		"":                   "",
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, persian.Normalize(text), text)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"بالکن", "کمد دیواری", "نوساز"}, persian.Terms("بالکن، كمد ديواري , ,نوساز"))
+	assert.Empty(t, persian.Terms(" , ،"))
+}
+
+func TestHighlight(t *testing.T) {
+	text := "آپارتمان ۶۳ متری با بالکن‌های بزرگ و کمد دیواری در همه اتاق‌ها"
+	assert.Equal(t, "…۶۳ متری با «بالکن»‌های بزرگ و «کمد دیواری» در همه اتاق…",
+		persian.Highlight(text, persian.Terms("بالكن, کمد ديواري"), 12))
+	assert.Equal(t, "«آپارتمان» ۶۳…", persian.Highlight(text, []string{"اپارتمان"}, 3))
+	assert.Empty(t, persian.Highlight(text, []string{"استخر"}, 10))
+	assert.Empty(t, persian.Highlight(text, []string{"دیواری کمد"}, 10))
+}
+
+func TestDigits(t *testing.T) {
+	assert.Equal(t, "۱۴۰۳/۰۱/۰۱", persian.Digits("1403/01/01"))
+	assert.Equal(t, "1403-120", persian.LatinDigits("۱۴۰۳-١٢٠"))
+	assert.Equal(t, "ونک 3", persian.LatinDigits(persian.Digits("ونک 3")))
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/report"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparisonHTML(t *testing.T) {
+	comparison := services.Comparison{Rate: 3, Listings: []services.ComparedListing{
+		{
+			Listing:       models.PostHistory{ID: 7, Title: "آپارتمان <نوساز>", BuyMode: types.Shopping, Price: 9_000_000_000, Area: 90},
+			PricePerMeter: 100_000_000, MonthlyCost: 270_000_000, Distance: 6.04, Amenities: []types.Amenity{types.Balcony},
+		},
+		{
+			Listing:  models.PostHistory{ID: 8, Title: "Rental", BuyMode: types.Rent, Deposit: 100_000_000, Rent: 10_000_000},
+			Distance: -1,
+		},
+	}}
+
+	rows := report.ComparisonRows(i18n.English, comparison)
+	assert.Equal(t, "Listing", rows[0].Name)
+	for _, row := range rows {
+		assert.Len(t, row.Values, 2, row.Name)
+	}
+	assert.Equal(t, []string{"9,000,000,000", "100,000,000 deposit, 10,000,000 rent"}, rows[1].Values)
+	assert.Equal(t, []string{"6.0 km", "unknown"}, rows[len(rows)-1].Values)
+
+	html, err := report.ComparisonHTML(i18n.English, comparison, time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), "آپارتمان &lt;نوساز&gt; (#7)")
+	assert.Contains(t, string(html), "<td dir=\"auto\">balcony</td>")
+	assert.Contains(t, string(html), "Send the bot a location")
+	assert.Contains(t, string(html), "Created 1403/09/30 15:30")
+
+	rows = report.ComparisonRows(i18n.Persian, comparison)
+	assert.Equal(t, "آگهی", rows[0].Name)
+	assert.Equal(t, []string{"۹٬۰۰۰٬۰۰۰٬۰۰۰", "ودیعه ۱۰۰٬۰۰۰٬۰۰۰، اجاره ۱۰٬۰۰۰٬۰۰۰"}, rows[1].Values)
+	assert.Equal(t, []string{"۶.۰ کیلومتر", "نامشخص"}, rows[len(rows)-1].Values)
+	html, err = report.ComparisonHTML(i18n.Persian, comparison, time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), `<html lang="fa" dir="rtl">`)
+	assert.Contains(t, string(html), "ساخته‌شده در ۱۴۰۳/۰۹/۳۰ ۱۵:۳۰")
+}
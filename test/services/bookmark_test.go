@@ -0,0 +1,226 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newBookmarkService(t *testing.T) (*gorm.DB, *services.BookmarkService) {
+	dbConnection := newTestDB(t, &models.User{}, &models.Post{}, &models.PostHistory{},
+		&models.BookmarkFolder{}, &models.Bookmark{}, &models.FolderMember{}, &models.FolderInvite{},
+		&models.BookmarkVote{}, &models.BookmarkComment{})
+	return dbConnection, services.NewBookmarkService(db.NewBookmarkRepository(dbConnection),
+		db.NewSharedFolderRepository(dbConnection), db.NewPostRepository(dbConnection))
+}
+
+func TestBookmarkFoldersAndNotes(t *testing.T) {
+	dbConnection, bookmarkService := newBookmarkService(t)
+	owner, other := models.User{TelegramID: 1, Role: models.USER}, models.User{TelegramID: 2, Role: models.USER}
+	assert.NoError(t, dbConnection.Create(&owner).Error)
+	assert.NoError(t, dbConnection.Create(&other).Error)
+	posts := []models.Post{{UniqueCode: "a", Website: types.Divar}, {UniqueCode: "b", Website: types.Divar}}
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: posts[0].ID, Title: "A", Price: 9_000_000_000}).Error)
+
+	first, err := bookmarkService.Add(owner, posts[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9_000_000_000), first.Price)
+	again, err := bookmarkService.Add(owner, posts[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	second, err := bookmarkService.Add(owner, posts[1].ID)
+	assert.NoError(t, err)
+	_, err = bookmarkService.Add(owner, 999)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+
+	folder, err := bookmarkService.CreateFolder(owner, "  North   side ")
+	assert.NoError(t, err)
+	assert.Equal(t, "North side", folder.Name)
+	_, err = bookmarkService.CreateFolder(owner, "north SIDE")
+	assert.ErrorIs(t, err, services.ErrFolderExists)
+	_, err = bookmarkService.CreateFolder(owner, " ")
+	assert.ErrorIs(t, err, services.ErrInvalidFolderName)
+	_, err = bookmarkService.CreateFolder(other, "North side")
+	assert.NoError(t, err)
+
+	_, err = bookmarkService.Move(other, first.ID, folder.ID)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+	moved, err := bookmarkService.Move(owner, first.ID, folder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, folder.ID, *moved.FolderID)
+
+	_, err = bookmarkService.SetNote(other, first.ID, "mine")
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+	_, err = bookmarkService.SetNote(owner, first.ID, " call the owner after 5 ")
+	assert.NoError(t, err)
+
+	listings, err := bookmarkService.List(owner, folder.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, listings, 1) {
+		assert.Equal(t, "call the owner after 5", listings[0].Bookmark.Note)
+		assert.Equal(t, "North side", listings[0].Bookmark.Folder.Name)
+		assert.Equal(t, "A", listings[0].Latest.Title)
+	}
+	listings, err = bookmarkService.List(owner, 0)
+	assert.NoError(t, err)
+	assert.Len(t, listings, 2)
+	listings, err = bookmarkService.List(other, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, listings)
+
+	// deleting a folder keeps its bookmarks
+	assert.ErrorIs(t, bookmarkService.DeleteFolder(other, folder.ID), services.ErrBookmarkNotFound)
+	assert.NoError(t, bookmarkService.DeleteFolder(owner, folder.ID))
+	kept, err := bookmarkService.Get(owner, first.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, kept.FolderID)
+	assert.Equal(t, "call the owner after 5", kept.Note)
+
+	assert.ErrorIs(t, bookmarkService.Remove(other, second.ID), services.ErrBookmarkNotFound)
+	assert.NoError(t, bookmarkService.Remove(owner, second.ID))
+	listings, err = bookmarkService.List(owner, 0)
+	assert.NoError(t, err)
+	assert.Len(t, listings, 1)
+}
+
+func TestNotifyBookmarkPriceChanges(t *testing.T) {
+	dbConnection, bookmarkService := newBookmarkService(t)
+	user := models.User{TelegramID: 1, Role: models.USER}
+	assert.NoError(t, dbConnection.Create(&user).Error)
+	posts := []models.Post{{UniqueCode: "changed", Website: types.Divar}, {UniqueCode: "same", Website: types.Divar}}
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+	for _, post := range posts {
+		history := models.PostHistory{PostID: post.ID, Title: post.UniqueCode, BuyMode: types.Rent, Deposit: 500_000_000, Rent: 20_000_000, CrawlHistoryID: 1}
+		assert.NoError(t, dbConnection.Create(&history).Error)
+		_, err := bookmarkService.Add(user, post.ID)
+		assert.NoError(t, err)
+	}
+
+	var texts []string
+	bookmarkService.SetNotifier(func(user models.User, text string) {
+		texts = append(texts, text)
+	})
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: posts[0].ID, Title: "changed", BuyMode: types.Rent,
+		Deposit: 500_000_000, Rent: 18_500_000, CrawlHistoryID: 2}).Error)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: posts[1].ID, Title: "same", BuyMode: types.Rent,
+		Deposit: 500_000_000, Rent: 20_000_000, CrawlHistoryID: 2}).Error)
+
+	notified, err := bookmarkService.NotifyChanges(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, notified)
+	if assert.Len(t, texts, 1) {
+		assert.Contains(t, texts[0], "changed")
+		assert.Contains(t, texts[0], "Rent: 20,000,000 → 18,500,000")
+		assert.NotContains(t, texts[0], "Deposit")
+	}
+	persian := services.PriceChangeText(models.Bookmark{User: models.User{Language: "fa"}, Rent: 20_000_000},
+		models.PostHistory{Title: "changed", Rent: 18_500_000})
+	assert.Contains(t, persian, "اجاره: ۲۰٬۰۰۰٬۰۰۰ ← ۱۸٬۵۰۰٬۰۰۰")
+
+	// the new price is remembered, the same crawl does not notify twice
+	notified, err = bookmarkService.NotifyChanges(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, notified)
+}
+
+func TestSharedBookmarkFolder(t *testing.T) {
+	dbConnection, bookmarkService := newBookmarkService(t)
+	owner := models.User{TelegramID: 1, Role: models.USER}
+	editor := models.User{TelegramID: 2, Role: models.USER}
+	viewer := models.User{TelegramID: 3, Role: models.USER}
+	for _, user := range []*models.User{&owner, &editor, &viewer} {
+		assert.NoError(t, dbConnection.Create(user).Error)
+	}
+	posts := []models.Post{{UniqueCode: "a", Website: types.Divar}, {UniqueCode: "b", Website: types.Divar}}
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: posts[0].ID, Title: "Garden flat", Price: 1}).Error)
+
+	notified := map[uint64][]string{}
+	bookmarkService.SetNotifier(func(user models.User, text string) {
+		notified[user.TelegramID] = append(notified[user.TelegramID], text)
+	})
+
+	now := time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC)
+	folder, err := bookmarkService.CreateFolder(owner, "Family")
+	assert.NoError(t, err)
+	_, err = bookmarkService.Invite(editor, folder.ID, models.FOLDER_EDITOR, now)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+	editorInvite, err := bookmarkService.Invite(owner, folder.ID, models.FOLDER_EDITOR, now)
+	assert.NoError(t, err)
+	viewerInvite, err := bookmarkService.Invite(owner, folder.ID, models.FOLDER_VIEWER, now)
+	assert.NoError(t, err)
+	assert.NotEqual(t, editorInvite.Token, viewerInvite.Token)
+
+	_, _, err = bookmarkService.Join(editor, "unknown", now)
+	assert.ErrorIs(t, err, services.ErrInviteInvalid)
+	_, _, err = bookmarkService.Join(editor, editorInvite.Token, now.AddDate(0, 1, 0))
+	assert.ErrorIs(t, err, services.ErrInviteInvalid)
+	joined, role, err := bookmarkService.Join(editor, editorInvite.Token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, folder.ID, joined.ID)
+	assert.Equal(t, models.FOLDER_EDITOR, role)
+	_, role, err = bookmarkService.Join(viewer, viewerInvite.Token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.FOLDER_VIEWER, role)
+	assert.Len(t, notified[1], 2)
+
+	shared, err := bookmarkService.SharedFolders(editor)
+	assert.NoError(t, err)
+	if assert.Len(t, shared, 1) {
+		assert.Equal(t, "Family", shared[0].Folder.Name)
+	}
+
+	// an editor adds a listing, the owner and the viewer are told
+	added, err := bookmarkService.Add(editor, posts[0].ID)
+	assert.NoError(t, err)
+	_, err = bookmarkService.Move(editor, added.ID, folder.ID)
+	assert.NoError(t, err)
+	assert.Contains(t, notified[1][2], "Garden flat")
+	assert.Len(t, notified[3], 1)
+	assert.Empty(t, notified[2])
+
+	viewed, err := bookmarkService.Add(viewer, posts[1].ID)
+	assert.NoError(t, err)
+	_, err = bookmarkService.Move(viewer, viewed.ID, folder.ID)
+	assert.ErrorIs(t, err, services.ErrFolderReadOnly)
+
+	assert.NoError(t, bookmarkService.Vote(viewer, added.ID, 1))
+	assert.NoError(t, bookmarkService.Vote(owner, added.ID, 1))
+	assert.NoError(t, bookmarkService.Vote(owner, added.ID, -1))
+	assert.ErrorIs(t, bookmarkService.Vote(viewer, viewed.ID, 1), services.ErrBookmarkNotFound)
+	_, err = bookmarkService.Comment(viewer, added.ID, " ")
+	assert.ErrorIs(t, err, services.ErrInvalidComment)
+	_, err = bookmarkService.Comment(viewer, added.ID, "Close to school")
+	assert.NoError(t, err)
+	assert.Len(t, notified[1], 4)
+	assert.Len(t, notified[2], 1)
+	assert.Len(t, notified[3], 1)
+
+	listings, err := bookmarkService.List(viewer, folder.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, listings, 1) {
+		assert.Equal(t, 1, listings[0].Up)
+		assert.Equal(t, 1, listings[0].Down)
+		assert.Equal(t, 1, listings[0].Comments)
+	}
+	comments, err := bookmarkService.Comments(owner, added.ID)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+
+	assert.NoError(t, bookmarkService.Leave(viewer, folder.ID))
+	_, err = bookmarkService.List(viewer, folder.ID)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+	assert.ErrorIs(t, bookmarkService.StopSharing(editor, folder.ID), services.ErrBookmarkNotFound)
+	assert.NoError(t, bookmarkService.StopSharing(owner, folder.ID))
+	_, err = bookmarkService.List(editor, folder.ID)
+	assert.ErrorIs(t, err, services.ErrBookmarkNotFound)
+	_, _, err = bookmarkService.Join(editor, editorInvite.Token, now)
+	assert.ErrorIs(t, err, services.ErrInviteInvalid)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareListings(t *testing.T) {
+	dbConnection := newTestDB(t, &models.AppSetting{}, &models.Post{}, &models.PostHistory{}, &models.FilterItem{})
+
+	latitude, longitude := 35.7448, 51.3753
+	listings := []models.PostHistory{
+		{Title: "Sale", BuyMode: types.Shopping, Price: 9_000_000_000, Area: 90, HasParking: true,
+			Amenities: models.Amenities{Balcony: true}, Latitude: &latitude, Longitude: &longitude},
+		{Title: "Rental", BuyMode: types.Rent, Deposit: 100_000_000, Rent: 10_000_000, Area: 80},
+	}
+	assert.NoError(t, dbConnection.Create(&listings).Error)
+
+	postRepository := db.NewPostRepository(dbConnection)
+	comparisonService := services.NewComparisonService(postRepository, services.NewRentService(db.NewAppSettingRepository(dbConnection), postRepository))
+
+	_, err := comparisonService.Compare([]uint{listings[0].ID, listings[0].ID}, models.User{})
+	assert.ErrorIs(t, err, services.ErrCompareCount)
+	_, err = comparisonService.Compare([]uint{1, 2, 3, 4, 5, 6}, models.User{})
+	assert.ErrorIs(t, err, services.ErrCompareCount)
+	_, err = comparisonService.Compare([]uint{listings[0].ID, 999}, models.User{})
+	assert.ErrorIs(t, err, services.ErrComparedNotFound)
+
+	comparison, err := comparisonService.Compare([]uint{listings[1].ID, listings[0].ID}, models.User{})
+	assert.NoError(t, err)
+	assert.Nil(t, comparison.Point)
+	assert.Equal(t, 3.0, comparison.Rate)
+	if assert.Len(t, comparison.Listings, 2) {
+		rental, sale := comparison.Listings[0], comparison.Listings[1]
+		assert.Equal(t, "Rental", rental.Listing.Title)
+		// 10M of rent is worth a 333M deposit at 3%
+		assert.Equal(t, int64(433_333_333/80), rental.PricePerMeter)
+		assert.Equal(t, int64(13_000_000), rental.MonthlyCost)
+		assert.Equal(t, -1.0, rental.Distance)
+
+		assert.Equal(t, int64(100_000_000), sale.PricePerMeter)
+		assert.Equal(t, int64(270_000_000), sale.MonthlyCost)
+		assert.Equal(t, []types.Amenity{types.Balcony, types.Parking}, sale.Amenities)
+		assert.Equal(t, -1.0, sale.Distance)
+	}
+
+	pointLatitude, pointLongitude := 35.6997, 51.3381
+	comparison, err = comparisonService.Compare([]uint{listings[0].ID, listings[1].ID},
+		models.User{Latitude: &pointLatitude, Longitude: &pointLongitude})
+	assert.NoError(t, err)
+	assert.NotNil(t, comparison.Point)
+	assert.InDelta(t, 6.0, comparison.Listings[0].Distance, 0.1)
+	assert.Equal(t, -1.0, comparison.Listings[1].Distance)
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDigestSchedule(t *testing.T) {
+	schedule, err := services.ParseDigestSchedule("daily, 9")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DIGEST_DAILY, schedule.Frequency)
+	assert.Equal(t, 9, schedule.Hour)
+
+	schedule, err = services.ParseDigestSchedule("Weekly,sat,۲۰")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DIGEST_WEEKLY, schedule.Frequency)
+	assert.Equal(t, time.Saturday, schedule.Weekday)
+	assert.Equal(t, 20, schedule.Hour)
+	assert.Equal(t, "weekly on Saturday at 20:00", services.DescribeSchedule(models.User{}, schedule))
+	assert.Equal(t, "هر هفته شنبه ساعت ۲۰:۰۰", services.DescribeSchedule(models.User{Language: "fa"}, schedule))
+
+	for _, value := range []string{"", "daily", "daily,24", "daily,-1", "weekly,9", "weekly,someday,9", "monthly,1,9"} {
+		_, err := services.ParseDigestSchedule(value)
+		assert.ErrorIs(t, err, services.ErrInvalidDigestSchedule, value)
+	}
+}
+
+func TestLastOccurrence(t *testing.T) {
+	// 08:30 on Monday in Tehran
+	now := time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC)
+
+	daily := models.DigestSubscription{Frequency: models.DIGEST_DAILY, Hour: 9}
+	assert.Equal(t, time.Date(2026, 10, 18, 5, 30, 0, 0, time.UTC), services.LastOccurrence(daily, now))
+	daily.Hour = 8
+	assert.Equal(t, time.Date(2026, 10, 19, 4, 30, 0, 0, time.UTC), services.LastOccurrence(daily, now))
+
+	weekly := models.DigestSubscription{Frequency: models.DIGEST_WEEKLY, Hour: 9, Weekday: time.Saturday}
+	assert.Equal(t, time.Date(2026, 10, 17, 5, 30, 0, 0, time.UTC), services.LastOccurrence(weekly, now))
+	weekly.Weekday, weekly.Hour = time.Monday, 8
+	assert.Equal(t, time.Date(2026, 10, 19, 4, 30, 0, 0, time.UTC), services.LastOccurrence(weekly, now))
+	weekly.Hour = 9
+	assert.Equal(t, time.Date(2026, 10, 12, 5, 30, 0, 0, time.UTC), services.LastOccurrence(weekly, now))
+}
+
+func TestPriceDrop(t *testing.T) {
+	before := models.PostHistory{ID: 1, BuyMode: types.Shopping, Price: 4_000_000_000}
+	assert.InDelta(t, 10, services.PriceDrop(before, models.PostHistory{BuyMode: types.Shopping, Price: 3_600_000_000}), 0.001)
+	assert.Zero(t, services.PriceDrop(before, models.PostHistory{BuyMode: types.Shopping, Price: 4_200_000_000}))
+	assert.Zero(t, services.PriceDrop(models.PostHistory{}, models.PostHistory{BuyMode: types.Shopping, Price: 1}))
+
+	rental := models.PostHistory{ID: 2, BuyMode: types.Rent, Rent: 20_000_000, MonthlyCost: 50_000_000}
+	assert.InDelta(t, 20, services.PriceDrop(rental, models.PostHistory{BuyMode: types.Rent, Rent: 20_000_000, MonthlyCost: 40_000_000}), 0.001)
+	assert.Zero(t, services.PriceDrop(rental, models.PostHistory{BuyMode: types.Shopping, Price: 1}))
+}
+
+func TestDigestRuns(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.FilterItem{}, &models.Post{}, &models.PostHistory{},
+		&models.DigestSubscription{}, &models.DigestRun{})
+
+	users := createTestUsers(t, dbConnection, models.USER, models.USER)
+	user, other := users[0], users[1]
+	filter := models.FilterItem{UserID: user.ID, City: "tehran", Category: string(types.Shopping)}
+	assert.NoError(t, dbConnection.Create(&filter).Error)
+
+	subscribed := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	old := subscribed.AddDate(0, 0, -10)
+	removedAt := subscribed.Add(4 * time.Hour)
+	posts := []models.Post{
+		{UniqueCode: "cheaper", Website: types.Divar, FirstSeenAt: old},
+		{UniqueCode: "new", Website: types.Divar, FirstSeenAt: subscribed.Add(2 * time.Hour)},
+		{UniqueCode: "elsewhere", Website: types.Divar, FirstSeenAt: subscribed.Add(2 * time.Hour)},
+		{UniqueCode: "sold", Website: types.Divar, FirstSeenAt: old, Status: models.POST_SOLD, RemovedAt: &removedAt},
+		{UniqueCode: "later", Website: types.Divar, FirstSeenAt: subscribed.Add(34 * time.Hour)},
+		{UniqueCode: "same", Website: types.Divar, FirstSeenAt: old},
+	}
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+	histories := []models.PostHistory{
+		{PostID: posts[0].ID, Title: "cheaper", City: "tehran", BuyMode: types.Shopping, Price: 3_000_000_000, CreatedAt: old},
+		{PostID: posts[0].ID, Title: "cheaper", City: "tehran", BuyMode: types.Shopping, Price: 2_700_000_000, CreatedAt: subscribed.Add(3 * time.Hour)},
+		{PostID: posts[1].ID, Title: "new", City: "tehran", BuyMode: types.Shopping, Price: 2_000_000_000, CreatedAt: subscribed.Add(2 * time.Hour)},
+		{PostID: posts[2].ID, Title: "elsewhere", City: "karaj", BuyMode: types.Shopping, Price: 1_000_000_000, CreatedAt: subscribed.Add(2 * time.Hour)},
+		{PostID: posts[3].ID, Title: "sold", City: "tehran", BuyMode: types.Shopping, Price: 5_000_000_000, CreatedAt: old},
+		{PostID: posts[4].ID, Title: "later", City: "tehran", BuyMode: types.Shopping, Price: 1_500_000_000, CreatedAt: subscribed.Add(34 * time.Hour)},
+		{PostID: posts[5].ID, Title: "same", City: "tehran", BuyMode: types.Shopping, Price: 1_000_000_000, CreatedAt: old},
+		{PostID: posts[5].ID, Title: "same", City: "tehran", BuyMode: types.Shopping, Price: 1_000_000_000, CreatedAt: subscribed.Add(3 * time.Hour)},
+	}
+	assert.NoError(t, dbConnection.Create(&histories).Error)
+
+	digestService := services.NewDigestService(db.NewDigestRepository(dbConnection), db.NewFilterItemRepository(dbConnection))
+	digests := make([]services.Digest, 0)
+	digestService.SetNotifier(func(user models.User, digest services.Digest, top int) {
+		digests = append(digests, digest)
+	})
+
+	_, err := digestService.Subscribe(other, filter.ID, models.DigestSubscription{Frequency: models.DIGEST_DAILY, Hour: 9})
+	assert.ErrorIs(t, err, services.ErrDigestFilterNotFound)
+	subscription, err := digestService.Subscribe(user, filter.ID, models.DigestSubscription{Frequency: models.DIGEST_DAILY, Hour: 9})
+	assert.NoError(t, err)
+	assert.NoError(t, dbConnection.Model(&subscription).Update("created_at", subscribed).Error)
+
+	// nothing is due before 09:00 in Tehran, 05:30 UTC
+	sent, err := digestService.RunDue(subscribed.Add(5 * time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+
+	sent, err = digestService.RunDue(subscribed.Add(6 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	if assert.Len(t, digests, 1) {
+		digest := digests[0]
+		assert.Equal(t, subscribed, digest.Run.PeriodStart.UTC())
+		assert.Equal(t, subscribed.Add(5*time.Hour+30*time.Minute), digest.Run.PeriodEnd.UTC())
+		if assert.Len(t, digest.New, 1) {
+			assert.Equal(t, "new", digest.New[0].History.Title)
+		}
+		if assert.Len(t, digest.PriceDrops, 1) {
+			assert.Equal(t, "cheaper", digest.PriceDrops[0].History.Title)
+			assert.Equal(t, int64(3_000_000_000), digest.PriceDrops[0].Before.Price)
+			assert.InDelta(t, 10, digest.PriceDrops[0].Drop, 0.001)
+		}
+		if assert.Len(t, digest.Removed, 1) {
+			assert.Equal(t, models.POST_SOLD, digest.Removed[0].History.Post.Status)
+		}
+	}
+
+	// a digest is sent once
+	sent, err = digestService.RunDue(subscribed.Add(7 * time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+
+	// after three days down a single digest covers everything since the last one
+	sent, err = digestService.RunDue(subscribed.AddDate(0, 0, 3).Add(6 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	if assert.Len(t, digests, 2) {
+		digest := digests[1]
+		assert.Equal(t, subscribed.Add(5*time.Hour+30*time.Minute), digest.Run.PeriodStart.UTC())
+		assert.Equal(t, subscribed.AddDate(0, 0, 3).Add(5*time.Hour+30*time.Minute), digest.Run.PeriodEnd.UTC())
+		if assert.Len(t, digest.New, 1) {
+			assert.Equal(t, "later", digest.New[0].History.Title)
+		}
+		assert.Empty(t, digest.PriceDrops)
+		assert.Empty(t, digest.Removed)
+	}
+
+	// quiet days are recorded without a message
+	sent, err = digestService.RunDue(subscribed.AddDate(0, 0, 4).Add(6 * time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Len(t, digests, 2)
+	var runs []models.DigestRun
+	assert.NoError(t, dbConnection.Order("scheduled_for").Find(&runs).Error)
+	if assert.Len(t, runs, 3) {
+		assert.Equal(t, []int{1, 1, 0}, []int{runs[0].NewListings, runs[1].NewListings, runs[2].NewListings})
+		assert.NotNil(t, runs[2].SentAt)
+	}
+
+	listings, total, err := digestService.Page(user, runs[0].ID, services.SectionPriceDrops, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, listings, 1)
+	_, _, err = digestService.Page(other, runs[0].ID, services.SectionPriceDrops, 0)
+	assert.ErrorIs(t, err, services.ErrDigestNotFound)
+
+	// a weekly schedule replaces the daily one, turning it off stops the digests
+	subscription, err = digestService.Subscribe(user, filter.ID, models.DigestSubscription{Frequency: models.DIGEST_WEEKLY, Weekday: time.Saturday, Hour: 9})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DIGEST_WEEKLY, subscription.Frequency)
+	assert.NoError(t, digestService.Unsubscribe(user, filter.ID))
+	subscriptions, err := digestService.Subscriptions(user)
+	assert.NoError(t, err)
+	assert.Empty(t, subscriptions)
+	_, err = digestService.Subscribe(user, filter.ID, models.DigestSubscription{Frequency: models.DIGEST_DAILY, Hour: 9})
+	assert.NoError(t, err)
+}
+
+// failingRunRepository fails to record digest runs while failing is set
+type failingRunRepository struct {
+	db.DigestRepository
+	failing bool
+}
+
+func (r *failingRunRepository) SaveRun(run models.DigestRun) error {
+	if r.failing {
+		return errors.New("database is down")
+	}
+	return r.DigestRepository.SaveRun(run)
+}
+
+func TestDigestNotSentTwiceWhenRunIsNotRecorded(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.FilterItem{}, &models.Post{}, &models.PostHistory{},
+		&models.DigestSubscription{}, &models.DigestRun{})
+	user := createTestUsers(t, dbConnection, models.USER)[0]
+	filter := models.FilterItem{UserID: user.ID, City: "tehran"}
+	assert.NoError(t, dbConnection.Create(&filter).Error)
+
+	subscribed := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	post := models.Post{UniqueCode: "new", Website: types.Divar, FirstSeenAt: subscribed.Add(time.Hour)}
+	assert.NoError(t, dbConnection.Create(&post).Error)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: post.ID, Title: "new", City: "tehran",
+		BuyMode: types.Shopping, Price: 1_000_000_000, CreatedAt: subscribed.Add(time.Hour)}).Error)
+
+	repository := &failingRunRepository{DigestRepository: db.NewDigestRepository(dbConnection), failing: true}
+	digestService := services.NewDigestService(repository, db.NewFilterItemRepository(dbConnection))
+	digests := 0
+	digestService.SetNotifier(func(user models.User, digest services.Digest, top int) {
+		assert.NotNil(t, digest.Run.SentAt)
+		digests++
+	})
+	subscription, err := digestService.Subscribe(user, filter.ID, models.DigestSubscription{Frequency: models.DIGEST_DAILY, Hour: 9})
+	assert.NoError(t, err)
+	assert.NoError(t, dbConnection.Model(&subscription).Update("created_at", subscribed).Error)
+
+	// the digest is not sent while its run can not be recorded as sent
+	sent, err := digestService.RunDue(subscribed.Add(6 * time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Zero(t, digests)
+
+	repository.failing = false
+	for range 2 {
+		_, err = digestService.RunDue(subscribed.Add(7 * time.Hour))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, digests)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newFilterAnalyticsService(t *testing.T) (*services.FilterAnalyticsService, *gorm.DB) {
+	dbConnection := newTestDB(t, &models.User{}, &models.FilterItem{}, &models.PostHistory{})
+	return services.NewFilterAnalyticsService(db.NewFilterItemRepository(dbConnection)), dbConnection
+}
+
+func TestFilterAnalytics(t *testing.T) {
+	analyticsService, dbConnection := newFilterAnalyticsService(t)
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{City: "tehran", Neighborhood: "vanak", Price: 8_000_000_000, BuyMode: types.Shopping, HasStorage: true},
+		{City: "karaj", Neighborhood: "gohardasht", Price: 3_000_000_000, BuyMode: types.Shopping},
+	}).Error)
+	assert.NoError(t, dbConnection.Create(&[]models.FilterItem{
+		{UserID: 1, City: "tehran", Neighborhood: "vanak", Category: "shopping", PriceMax: 9_000_000_000, HasStorage: true},
+		{UserID: 1, City: "tehran", Neighborhood: "vanak", Category: "shopping", PriceMax: 6_000_000_000},
+		{UserID: 1, City: "tehran", Category: "rent", PriceMin: 100_000_000},
+		{UserID: 2, City: "karaj", Category: "shopping", PriceMax: 1_000_000_000},
+		{UserID: 2, City: "shiraz"},
+	}).Error)
+
+	analytics, err := analyticsService.Analyze(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, analytics.Filters)
+	assert.Equal(t, 2, analytics.Users)
+	assert.Equal(t, []services.ValueCount{{Value: "tehran", Count: 3}, {Value: "karaj", Count: 1}}, analytics.Cities)
+	assert.Equal(t, []services.ValueCount{{Value: "tehran/vanak", Count: 2}}, analytics.Neighborhoods)
+	assert.Equal(t, []services.ValueCount{{Value: "shopping", Count: 3}, {Value: "any", Count: 1}, {Value: "rent", Count: 1}}, analytics.Categories)
+	assert.Equal(t, []services.UserFilterCount{{UserID: 1, Count: 3}, {UserID: 2, Count: 2}}, analytics.TopUsers)
+
+	// 1B goes to the 1B-2B band, 6B and 9B to the 5B-10B band
+	shoppingBands := analytics.PriceBands["shopping"]
+	assert.Equal(t, 1, shoppingBands[2].Count)
+	assert.Equal(t, 2, shoppingBands[4].Count)
+	assert.Equal(t, 1, analytics.PriceBands["rent"][0].Count)
+	assert.Equal(t, 1, analytics.NoPrice)
+
+	// only the 9B vanak filter matches a post
+	assert.Equal(t, 4, analytics.ZeroResults)
+	assert.Equal(t, 0, analytics.Unchecked)
+	assert.InDelta(t, 80, analytics.ZeroResultShare(), 0.01)
+}
+
+func TestUserFilterReports(t *testing.T) {
+	analyticsService, dbConnection := newFilterAnalyticsService(t)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{City: "tehran", Neighborhood: "vanak", Price: 8_000_000_000}).Error)
+	assert.NoError(t, dbConnection.Create(&[]models.FilterItem{
+		{UserID: 1, City: "tehran", Neighborhood: "vanak"},
+		{UserID: 1, City: "tehran", PriceMax: 1_000_000_000},
+		{UserID: 2, City: "tehran"},
+	}).Error)
+
+	reports, err := analyticsService.UserFilters(1)
+	assert.NoError(t, err)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, int64(1), reports[0].Results)
+	assert.Equal(t, int64(0), reports[1].Results)
+}
+
+func TestFilterResultsCountedInBatches(t *testing.T) {
+	analyticsService, dbConnection := newFilterAnalyticsService(t)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{City: "tehran", Price: 8_000_000_000}).Error)
+	filters := make([]models.FilterItem, 0, 120)
+	for i := 0; i < 120; i++ {
+		filter := models.FilterItem{UserID: uint(i%7 + 1), City: "tehran"}
+		if i%2 == 1 {
+			filter.PriceMax = 1_000_000_000
+		}
+		filters = append(filters, filter)
+	}
+	assert.NoError(t, dbConnection.Create(&filters).Error)
+
+	var countQueries int
+	assert.NoError(t, dbConnection.Callback().Row().After("gorm:row").Register("test:count_rows", func(*gorm.DB) {
+		countQueries++
+	}))
+
+	analytics, err := analyticsService.Analyze(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 60, analytics.ZeroResults)
+	assert.Equal(t, 0, analytics.Unchecked)
+	assert.Equal(t, 3, countQueries, "120 filters are counted in batches of 50")
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchByNeighborhoodAndStorage(t *testing.T) {
+	dbConnection := newTestDB(t, &models.PostHistory{}, &models.FilterItem{})
+	listings := []models.PostHistory{
+		{City: "tehran", Neighborhood: "niavaran", HasStorage: true},
+		{City: "tehran", Neighborhood: "niavaran"},
+		{City: "tehran", Neighborhood: "pasdaran", HasStorage: true},
+	}
+	assert.NoError(t, dbConnection.Create(&listings).Error)
+	filterRepository := db.NewFilterItemRepository(dbConnection)
+
+	posts, err := filterRepository.SearchPostHistory(models.FilterItem{Neighborhood: "niavaran"})
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+
+	posts, err = filterRepository.SearchPostHistory(models.FilterItem{Neighborhood: "niavaran", HasStorage: true})
+	assert.NoError(t, err)
+	if assert.Len(t, posts, 1) {
+		assert.Equal(t, listings[0].ID, posts[0].ID)
+	}
+}
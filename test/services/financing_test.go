@@ -0,0 +1,215 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoanPayments(t *testing.T) {
+	assert.Equal(t, int64(88_849), services.MonthlyPayment(1_000_000, 12, 12))
+	assert.Equal(t, int64(100_000), services.MonthlyPayment(1_200_000, 0, 12))
+	assert.Zero(t, services.MonthlyPayment(0, 12, 12))
+
+	loan := services.LoanFor(88_849, 12, 12)
+	assert.InDelta(t, 1_000_000, loan, 10)
+	assert.Equal(t, int64(1_200_000), services.LoanFor(100_000, 0, 12))
+}
+
+func TestAffordableRanges(t *testing.T) {
+	product := &models.LoanProduct{AnnualRate: 0, TermMonths: 100, MaxLTV: 80, Active: true}
+	profile := models.AffordabilityProfile{Cash: 1_000_000_000, MonthlyBudget: 30_000_000, LoanProduct: product}
+
+	// the budget pays a 3B loan, more than the 4B the cash allows at 80%
+	ranges := services.Ranges(profile)
+	assert.Equal(t, int64(3_000_000_000), ranges.Loan)
+	assert.Equal(t, int64(4_000_000_000), ranges.PriceMax)
+	assert.Equal(t, int64(1_000_000_000), ranges.DepositMax)
+	assert.Equal(t, int64(30_000_000), ranges.RentMax)
+
+	// the cash only covers 20% of 2.5B
+	profile.Cash = 500_000_000
+	ranges = services.Ranges(profile)
+	assert.Equal(t, int64(2_000_000_000), ranges.Loan)
+	assert.Equal(t, int64(2_500_000_000), ranges.PriceMax)
+
+	product.MaxAmount = 1_000_000_000
+	assert.Equal(t, int64(1_500_000_000), services.Ranges(profile).PriceMax)
+
+	product.Active = false
+	assert.Equal(t, int64(500_000_000), services.Ranges(profile).PriceMax)
+	profile.LoanProduct = nil
+	assert.Zero(t, services.Ranges(profile).Loan)
+}
+
+func TestParseLoanProduct(t *testing.T) {
+	product, err := services.ParseLoanProduct("Maskan  Bank,18%,20,80,4000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LoanProduct{Name: "Maskan Bank", AnnualRate: 18, TermMonths: 240, MaxLTV: 80, MaxAmount: 4_000_000_000, Active: true}, product)
+
+	product, err = services.ParseLoanProduct("Youth,۴,۱۲.۵,۹۰")
+	assert.NoError(t, err)
+	assert.Equal(t, 150, product.TermMonths)
+	assert.Zero(t, product.MaxAmount)
+
+	for _, value := range []string{"", "Maskan,18,20", ",18,20,80", "Maskan,18,20,0", "Maskan,18,20,120", "Maskan,18,0,80", "Maskan,x,20,80"} {
+		_, err := services.ParseLoanProduct(value)
+		assert.ErrorIs(t, err, services.ErrInvalidLoanProduct, value)
+	}
+}
+
+func TestAffordabilityFilterAndPayments(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.PostHistory{}, &models.FilterItem{},
+		&models.LoanProduct{}, &models.AffordabilityProfile{})
+	user := createTestUsers(t, dbConnection, models.USER)[0]
+
+	financingService := services.NewFinancingService(db.NewFinancingRepository(dbConnection))
+	sale := models.PostHistory{Title: "sale", BuyMode: types.Shopping, Price: 3_000_000_000}
+
+	// without products there is no payment to show
+	_, ok, err := financingService.EstimatePayment(user, sale)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	expensive, err := financingService.SaveProduct(models.LoanProduct{Name: "Bank", AnnualRate: 24, TermMonths: 120, MaxLTV: 70, Active: true}, 1)
+	assert.NoError(t, err)
+	cheap, err := financingService.SaveProduct(models.LoanProduct{Name: "Youth", AnnualRate: 0, TermMonths: 100, MaxLTV: 80, Active: true}, 1)
+	assert.NoError(t, err)
+	_, err = financingService.SaveProduct(models.LoanProduct{Name: "Youth", AnnualRate: 0, TermMonths: 100, MaxLTV: 80, MaxAmount: 1_500_000_000, Active: true}, 1)
+	assert.NoError(t, err)
+	products, err := financingService.Products(true)
+	assert.NoError(t, err)
+	if assert.Len(t, products, 2) {
+		assert.Equal(t, cheap.ID, products[0].ID)
+		assert.Equal(t, int64(1_500_000_000), products[0].MaxAmount)
+	}
+
+	// without a profile the cheapest product lends its largest share
+	payment, ok, err := financingService.EstimatePayment(user, sale)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1_500_000_000), payment.Loan)
+	assert.Equal(t, int64(15_000_000), payment.Monthly)
+
+	_, err = financingService.Profile(user)
+	assert.ErrorIs(t, err, services.ErrNoProfile)
+	_, err = financingService.SaveProfile(user, 0, 0)
+	assert.ErrorIs(t, err, services.ErrInvalidProfile)
+	profile, err := financingService.SaveProfile(user, 2_000_000_000, 10_000_000)
+	assert.NoError(t, err)
+	if assert.NotNil(t, profile.LoanProduct) {
+		assert.Equal(t, "Youth", profile.LoanProduct.Name)
+	}
+
+	// the cash pays 2B of the price, the loan the rest
+	payment, ok, err = financingService.EstimatePayment(user, sale)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1_000_000_000), payment.Loan)
+	assert.Equal(t, int64(10_000_000), payment.Monthly)
+	_, ok, err = financingService.EstimatePayment(user, models.PostHistory{BuyMode: types.Rent, Deposit: 1, Rent: 1})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	profile, err = financingService.ChooseProduct(user, expensive.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bank", profile.LoanProduct.Name)
+	_, err = financingService.SetProductActive(expensive.ID, false, 1)
+	assert.NoError(t, err)
+	_, err = financingService.ChooseProduct(user, expensive.ID)
+	assert.ErrorIs(t, err, services.ErrInvalidLoanProduct)
+	profile, err = financingService.ChooseProduct(user, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, profile.LoanProductID)
+	_, ok, err = financingService.EstimatePayment(user, sale)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the affordable ranges as a filter find purchases and rentals the user can pay
+	profile, err = financingService.ChooseProduct(user, cheap.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{Title: "affordable sale", BuyMode: types.Shopping, Price: 2_900_000_000},
+		{Title: "expensive sale", BuyMode: types.Shopping, Price: 3_100_000_000},
+		{Title: "affordable rental", BuyMode: types.Rent, Deposit: 1_500_000_000, Rent: 8_000_000},
+		{Title: "high rent", BuyMode: types.Rent, Deposit: 100_000_000, Rent: 12_000_000},
+		{Title: "high deposit", BuyMode: types.Rent, Deposit: 2_500_000_000},
+	}).Error)
+	filter := models.FilterItem{}
+	services.ApplyRanges(&filter, services.Ranges(profile))
+	assert.Equal(t, float64(3_000_000_000), filter.PriceMax)
+	results, err := db.NewFilterItemRepository(dbConnection).SearchPostHistory(filter)
+	assert.NoError(t, err)
+	titles := make([]string, 0, len(results))
+	for _, result := range results {
+		titles = append(titles, result.Title)
+	}
+	assert.ElementsMatch(t, []string{"affordable sale", "affordable rental"}, titles)
+}
+
+func TestApplyingRangesAsksBeforeReplacingLimits(t *testing.T) {
+	ranges := services.AffordableRanges{PriceMax: 3_000_000_000, DepositMax: 1_000_000_000, RentMax: 20_000_000}
+
+	assert.False(t, services.ReplacesLimits(models.FilterItem{City: "tehran"}, ranges))
+	assert.False(t, services.ReplacesLimits(models.FilterItem{PriceMax: 3_000_000_000}, ranges))
+	own := models.FilterItem{PriceMax: 2_000_000_000, RentMax: 20_000_000}
+	assert.True(t, services.ReplacesLimits(own, ranges))
+
+	// once applied, the ranges are the profile's and follow it without asking
+	services.ApplyRanges(&own, ranges)
+	assert.True(t, own.Affordable)
+	assert.False(t, services.ReplacesLimits(own, services.AffordableRanges{PriceMax: 1}))
+
+	services.ClearRanges(&own)
+	assert.Equal(t, models.FilterItem{}, own)
+	unchanged := models.FilterItem{PriceMax: 2_000_000_000}
+	services.ClearRanges(&unchanged)
+	assert.Equal(t, float64(2_000_000_000), unchanged.PriceMax)
+}
+
+func TestRemovingProfileClearsItsRanges(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.FilterItem{}, &models.LoanProduct{}, &models.AffordabilityProfile{})
+	users := createTestUsers(t, dbConnection, models.USER, models.USER)
+	user, other := users[0], users[1]
+	financingService := services.NewFinancingService(db.NewFinancingRepository(dbConnection))
+	profile, err := financingService.SaveProfile(user, 1_000_000_000, 20_000_000)
+	assert.NoError(t, err)
+	_, err = financingService.SaveProfile(other, 1_000_000_000, 20_000_000)
+	assert.NoError(t, err)
+
+	affordable := models.FilterItem{City: "tehran", UserID: user.ID}
+	services.ApplyRanges(&affordable, services.Ranges(profile))
+	own := models.FilterItem{City: "shiraz", PriceMax: 2_000_000_000, UserID: user.ID}
+	othersAffordable := models.FilterItem{City: "tehran", UserID: other.ID}
+	services.ApplyRanges(&othersAffordable, services.Ranges(profile))
+	assert.NoError(t, dbConnection.Create(&[]*models.FilterItem{&affordable, &own, &othersAffordable}).Error)
+
+	assert.NoError(t, financingService.RemoveProfile(user))
+	_, err = financingService.Profile(user)
+	assert.ErrorIs(t, err, services.ErrNoProfile)
+	assert.ErrorIs(t, financingService.RemoveProfile(user), services.ErrNoProfile)
+
+	var saved []models.FilterItem
+	assert.NoError(t, dbConnection.Order("id").Find(&saved).Error)
+	if assert.Len(t, saved, 3) {
+		assert.Zero(t, saved[0].PriceMax)
+		assert.Zero(t, saved[0].DepositMax)
+		assert.Zero(t, saved[0].RentMax)
+		assert.False(t, saved[0].Affordable)
+		assert.Equal(t, "tehran", saved[0].City)
+		assert.Equal(t, float64(2_000_000_000), saved[1].PriceMax)
+		assert.True(t, saved[2].Affordable)
+		assert.Equal(t, float64(1_000_000_000), saved[2].PriceMax)
+	}
+
+	// a new profile starts afresh
+	_, err = financingService.SaveProfile(user, 500_000_000, 10_000_000)
+	assert.NoError(t, err)
+	profile, err = financingService.Profile(user)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500_000_000), profile.Cash)
+}
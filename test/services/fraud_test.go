@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const repostedText = "آپارتمان نوساز با نور عالی و دسترسی به مترو، بازدید در تمام ساعات روز"
+
+// neighborhoodPosts returns five sales of pounak at 100M per m²
+func neighborhoodPosts() []models.PostHistory {
+	posts := make([]models.PostHistory, 0)
+	for i := 1; i <= 5; i++ {
+		posts = append(posts, models.PostHistory{
+			PostID: uint(i), City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping,
+			Area: 100, Price: int64(9_000_000_000 + i*500_000_000), ImageURL: fmt.Sprintf("https://img/%d.jpg", i),
+		})
+	}
+	return posts
+}
+
+func TestScoreListings(t *testing.T) {
+	window := neighborhoodPosts()
+	bait := models.PostHistory{PostID: 10, City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping, Area: 100,
+		Price: 2_000_000_000, ImageURL: "https://img/1.jpg,https://img/new.jpg", Description: "تماس: ۰۹۱۲۱۲۳۴۵۶۷ املاک پونک"}
+	copied := models.PostHistory{PostID: 11, City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping, Area: 100,
+		Price: 10_000_000_000, Description: repostedText}
+	original := models.PostHistory{PostID: 12, City: "karaj", Description: " " + repostedText + " "}
+	// an older history of the same post is not a duplicate of it
+	clean := models.PostHistory{PostID: 3, City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping, Area: 100,
+		Price: 10_500_000_000, ImageURL: "https://img/3.jpg"}
+	window = append(window, bait, copied, original, clean)
+
+	risks := services.ScoreListings([]models.PostHistory{bait, copied, clean}, window)
+	assert.Len(t, risks, 3)
+
+	assert.Equal(t, 100, risks[0].Score)
+	assert.Equal(t, []models.RiskReason{models.PRICE_TOO_LOW, models.DUPLICATE_IMAGES, models.PHONE_NUMBER, models.AGENCY_TEXT},
+		services.SplitReasons(risks[0].Reasons))
+	assert.Equal(t, 25, risks[1].Score)
+	assert.Equal(t, string(models.REPOSTED_TEXT), risks[1].Reasons)
+	assert.Zero(t, risks[2].Score)
+	assert.Empty(t, risks[2].Reasons)
+}
+
+func TestEditedPhotoIsDuplicate(t *testing.T) {
+	withPhoto := func(postID uint, hash uint64) models.PostHistory {
+		return models.PostHistory{PostID: postID, City: "tehran", ImageURL: fmt.Sprintf("https://img/%d.jpg", postID),
+			Images: []models.ListingImage{{Status: models.IMAGE_ARCHIVED, Hash: int64(hash)}}}
+	}
+	original := withPhoto(1, 0xF0F0_F0F0_F0F0_F0F0)
+	// recompressed or cropped a little, the hash is a few bits away
+	edited := withPhoto(2, 0xF0F0_F0F0_F0F0_F0F7)
+	other := withPhoto(3, 0x0F0F_0F0F_0F0F_0F0F)
+	window := []models.PostHistory{original, edited, other}
+
+	risks := services.ScoreListings([]models.PostHistory{edited, other}, window)
+	assert.Equal(t, string(models.DUPLICATE_IMAGES), risks[0].Reasons)
+	assert.Empty(t, risks[1].Reasons)
+}
+
+func TestHasPhoneNumber(t *testing.T) {
+	for _, text := range []string{"09121234567", "تماس ۰۹۱۲ ۱۲۳ ۴۵۶۷", "+98 912-123-4567", "تلفن 021-22334455"} {
+		assert.True(t, services.HasPhoneNumber(text), text)
+	}
+	for _, text := range []string{"قیمت 6900000000 تومان", "۶۳ متر، طبقه ۶", "کد 1234567"} {
+		assert.False(t, services.HasPhoneNumber(text), text)
+	}
+}
+
+func TestHasAgencyText(t *testing.T) {
+	for _, text := range []string{"املاک پونک", "آژانس مسکن نوین", "Call our agency"} {
+		assert.True(t, services.HasAgencyText(text), text)
+	}
+	for _, text := range []string{"بدون مشاور املاک، مالک هستم", "فروش مستقیم از مالک بدون واسطه املاک", "نور عالی"} {
+		assert.False(t, services.HasAgencyText(text), text)
+	}
+}
+
+func TestHighDepositRentalIsNotPricedTooLow(t *testing.T) {
+	rental := func(postID uint, deposit int64, rent int64) models.PostHistory {
+		post := models.PostHistory{PostID: postID, City: "tehran", Neighborhood: "pounak", BuyMode: types.Rent, Area: 100,
+			Deposit: deposit, Rent: rent}
+		post.FullDeposit, post.MonthlyCost = services.ConvertRent(deposit, rent, 3)
+		return post
+	}
+	window := make([]models.PostHistory, 0)
+	for i := 1; i <= 5; i++ {
+		window = append(window, rental(uint(i), 200_000_000, int64(28_000_000+i*1_000_000)))
+	}
+	highDeposit := rental(10, 1_000_000_000, 5_000_000)
+	window = append(window, highDeposit)
+
+	risks := services.ScoreListings([]models.PostHistory{highDeposit}, window)
+	assert.Zero(t, risks[0].Score, "the rent alone is a seventh of the others but the monthly cost is the same")
+}
+
+func TestReviewRiskyListings(t *testing.T) {
+	dbConnection := newTestDB(t, &models.PostHistory{}, &models.ListingRisk{}, &models.ListingImage{}, &models.FilterItem{})
+
+	posts := neighborhoodPosts()
+	posts = append(posts, models.PostHistory{PostID: 10, City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping,
+		Area: 100, Price: 2_000_000_000, ImageURL: "https://img/2.jpg", CrawlHistoryID: 7})
+	posts = append(posts, models.PostHistory{PostID: 11, City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping,
+		Area: 100, Price: 10_000_000_000, CrawlHistoryID: 7})
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+
+	fraudService := services.NewFraudService(db.NewListingRiskRepository(dbConnection))
+	high, err := fraudService.ScoreCrawl(7, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, high)
+
+	filterRepository := db.NewFilterItemRepository(dbConnection)
+	results, err := filterRepository.SearchPostHistory(models.FilterItem{City: "tehran", HideRisky: true})
+	assert.NoError(t, err)
+	assert.Len(t, results, 6)
+	results, err = filterRepository.SearchPostHistory(models.FilterItem{City: "tehran"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 7)
+
+	pending, total, err := fraudService.Pending(5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, uint(10), pending[0].PostHistory.PostID)
+
+		risk, err := fraudService.Review(pending[0].ID, models.RISK_DISMISSED, models.User{ID: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, models.RISK_DISMISSED, risk.Review)
+		assert.NotNil(t, risk.ReviewedAt)
+	}
+
+	// a dismissed listing is shown again and leaves the queue, also after a rescore
+	_, err = fraudService.ScoreCrawl(7, time.Now())
+	assert.NoError(t, err)
+	results, err = filterRepository.SearchPostHistory(models.FilterItem{City: "tehran", HideRisky: true})
+	assert.NoError(t, err)
+	assert.Len(t, results, 7)
+	_, total, err = fraudService.Pending(5)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+
+	// a recrawl of the dismissed post keeps the review instead of queueing it again
+	recrawl := models.PostHistory{PostID: 10, City: "tehran", Neighborhood: "pounak", BuyMode: types.Shopping,
+		Area: 100, Price: 2_000_000_000, ImageURL: "https://img/2.jpg", CrawlHistoryID: 8}
+	assert.NoError(t, dbConnection.Create(&recrawl).Error)
+	high, err = fraudService.ScoreCrawl(8, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, high)
+	risk, err := fraudService.Risk(recrawl.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RISK_DISMISSED, risk.Review)
+	assert.Equal(t, uint(1), risk.ReviewedBy)
+	results, err = filterRepository.SearchPostHistory(models.FilterItem{City: "tehran", HideRisky: true})
+	assert.NoError(t, err)
+	assert.Len(t, results, 8)
+	_, total, err = fraudService.Pending(5)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+}
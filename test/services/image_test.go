@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestLimiters returns limiters that let requests through without waiting
+func newTestLimiters() *crawlers.LimiterRegistry {
+	return crawlers.NewLimiterRegistry(crawlers.PolitenessConfig{RequestsPerSecond: 1000, Burst: 100, MaxConcurrency: 4})
+}
+
+// photo draws a PNG, bright to dark from left to right, or the other way around
+func photo(t *testing.T, inverted bool) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			value := uint8(255 - 255*x/400)
+			if inverted {
+				value = 255 - value
+			}
+			img.Set(x, y, color.RGBA{R: value, G: value, B: value, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestArchiveListingImages(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.Post{}, &models.PostHistory{}, &models.Bookmark{},
+		&models.ListingImage{}, &models.ListingRisk{})
+
+	// copy.png is the photo of a.png under another link, as when a scammer uploads it again
+	photos := map[string][]byte{"/a.png": photo(t, false), "/copy.png": photo(t, false), "/b.png": photo(t, true)}
+	var requests, browserRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !strings.HasPrefix(r.UserAgent(), "Go-http-client") {
+			browserRequests.Add(1)
+		}
+		content, exists := photos[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	posts := []models.PostHistory{
+		{PostID: 1, City: "tehran", ImageURL: server.URL + "/a.png," + server.URL + "/missing.png", CrawlHistoryID: 7},
+		{PostID: 2, City: "tehran", ImageURL: server.URL + "/copy.png," + server.URL + "/b.png", CrawlHistoryID: 7},
+	}
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+
+	store := storage.NewMemoryStore()
+	limiters := newTestLimiters()
+	imageService := services.NewImageService(store, db.NewListingImageRepository(dbConnection), limiters,
+		crawlers.NewIdentityProvider(nil, 3, time.Minute))
+	downloaded, err := imageService.ArchiveCrawl(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, downloaded)
+	// photos are fetched like pages, with a browser identity and under the limits of their host
+	assert.Equal(t, requests.Load(), browserRequests.Load())
+	if states := limiters.Snapshot(); assert.Len(t, states, 1) {
+		assert.Equal(t, uint64(requests.Load()), states[0].Requests)
+	}
+	// a.png and copy.png have the same content and share their original and thumbnail
+	assert.Equal(t, 4, store.Len())
+
+	images, err := imageService.Images(posts[0].ID)
+	assert.NoError(t, err)
+	if assert.Len(t, images, 1) {
+		assert.Equal(t, 400, images[0].Width)
+		assert.Equal(t, 300, images[0].Height)
+	}
+	original, err := imageService.Cover(posts[0].ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, photos["/a.png"], original)
+	thumbnail, err := imageService.Cover(posts[0].ID, true)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, thumbnail)
+
+	// the same photo under another link is a duplicate by its perceptual hash
+	_, err = services.NewFraudService(db.NewListingRiskRepository(dbConnection)).ScoreCrawl(7, time.Now())
+	assert.NoError(t, err)
+	var risk models.ListingRisk
+	assert.NoError(t, dbConnection.Where("post_history_id = ?", posts[1].ID).First(&risk).Error)
+	assert.Contains(t, services.SplitReasons(risk.Reasons), models.DUPLICATE_IMAGES)
+
+	// a new history of a post reuses the archived photos without downloading them again
+	before := requests.Load()
+	later := models.PostHistory{PostID: 1, City: "tehran", ImageURL: server.URL + "/a.png", CrawlHistoryID: 8}
+	assert.NoError(t, dbConnection.Create(&later).Error)
+	downloaded, err = imageService.ArchiveCrawl(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Zero(t, downloaded)
+	assert.Equal(t, before, requests.Load())
+
+	// once expired, only the photos of the bookmarked post are kept
+	assert.NoError(t, dbConnection.Create(&models.Bookmark{UserID: 1, PostID: 1}).Error)
+	deleted, err := imageService.Prune(time.Now().AddDate(0, 0, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, 2, store.Len())
+	_, err = imageService.Cover(posts[1].ID, false)
+	assert.ErrorIs(t, err, services.ErrNoImage)
+	original, err = imageService.Cover(later.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, photos["/a.png"], original)
+}
+
+func TestCanceledArchiveSavesNothing(t *testing.T) {
+	dbConnection := newTestDB(t, &models.Post{}, &models.PostHistory{}, &models.ListingImage{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(photo(t, false))
+	}))
+	defer server.Close()
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: 1, ImageURL: server.URL + "/a.png", CrawlHistoryID: 7}).Error)
+
+	imageService := services.NewImageService(storage.NewMemoryStore(), db.NewListingImageRepository(dbConnection),
+		newTestLimiters(), crawlers.NewIdentityProvider(nil, 3, time.Minute))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := imageService.ArchiveCrawl(ctx, 7)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// no failed photo is recorded, so the next archive tries the photo again
+	var images int64
+	assert.NoError(t, dbConnection.Model(&models.ListingImage{}).Count(&images).Error)
+	assert.Zero(t, images)
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywordFilters(t *testing.T) {
+	dbConnection := newTestDB(t, &models.Post{}, &models.PostHistory{}, &models.CrawlHistory{}, &models.FilterItem{})
+
+	postRepository := db.NewPostRepository(dbConnection)
+	for _, history := range []models.PostHistory{
+		{Title: "آپارتمان نوساز", Description: "بالکن بزرگ و كمد ديواري"},
+		{Title: "آپارتمان قدیمی", Description: "دارای بالکن، بدون آسانسور"},
+		{Title: "ویلا", Description: "کمد و دیواری"},
+	} {
+		history.City = "tehran"
+		_, err := postRepository.PostHistorySaving(history, models.Post{}, models.CrawlHistory{})
+		assert.NoError(t, err)
+	}
+
+	filterRepository := db.NewFilterItemRepository(dbConnection)
+	titles := func(filter models.FilterItem) []string {
+		posts, err := filterRepository.SearchPostHistory(filter)
+		assert.NoError(t, err)
+		result := make([]string, len(posts))
+		for i, post := range posts {
+			result[i] = post.Title
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"آپارتمان نوساز", "آپارتمان قدیمی"}, titles(models.FilterItem{IncludeKeywords: "بالکن"}))
+	assert.Equal(t, []string{"آپارتمان نوساز"}, titles(models.FilterItem{IncludeKeywords: "کمد دیواری"}))
+	assert.Equal(t, []string{"آپارتمان نوساز"}, titles(models.FilterItem{IncludeKeywords: "بالکن, نوساز"}))
+	assert.Equal(t, []string{"آپارتمان نوساز", "ویلا"}, titles(models.FilterItem{City: "tehran", ExcludeKeywords: "آسانسور"}))
+	assert.Empty(t, titles(models.FilterItem{IncludeKeywords: "بال"}))
+}
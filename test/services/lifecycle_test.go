@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/crawlers"
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	crawlerModels "github.com/MagicalCrawler/RealEstateApp/models/crawler"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// pageCrawler answers CrawlPostDetails with the post or error set for each URL
+type pageCrawler struct {
+	pages  map[string]crawlerModels.Post
+	errors map[string]error
+	opened []string
+}
+
+func (c *pageCrawler) Source() types.WebsiteSource {
+	return types.Divar
+}
+
+func (c *pageCrawler) Crawl(ctx context.Context, city crawlerModels.City) ([]crawlerModels.Post, error) {
+	return nil, nil
+}
+
+func (c *pageCrawler) CrawlPostDetails(ctx context.Context, postURL string) (crawlerModels.Post, error) {
+	c.opened = append(c.opened, postURL)
+	if err, exists := c.errors[postURL]; exists {
+		return crawlerModels.Post{}, err
+	}
+	return c.pages[postURL], nil
+}
+
+func TestCheckStalePosts(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.Post{}, &models.PostHistory{}, &models.Bookmark{},
+		&models.FilterItem{}, &models.WatchList{})
+
+	now := time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC)
+	firstSeen := now.AddDate(0, 0, -30)
+	stale := now.Add(-72 * time.Hour)
+	posts := []models.Post{
+		{UniqueCode: "removed", Website: types.Divar, Status: models.POST_ACTIVE, FirstSeenAt: firstSeen, LastSeenAt: stale},
+		{UniqueCode: "expired", Website: types.Divar, Status: models.POST_ACTIVE, FirstSeenAt: firstSeen, LastSeenAt: stale},
+		{UniqueCode: "sold", Website: types.Divar, Status: models.POST_ACTIVE, FirstSeenAt: firstSeen, LastSeenAt: stale},
+		{UniqueCode: "live", Website: types.Divar, Status: models.POST_ACTIVE, FirstSeenAt: firstSeen, LastSeenAt: stale},
+		{UniqueCode: "down", Website: types.Divar, Status: models.POST_ACTIVE, FirstSeenAt: firstSeen, LastSeenAt: stale},
+		{UniqueCode: "fresh", Website: types.Divar, Status: models.POST_ACTIVE, FirstSeenAt: firstSeen, LastSeenAt: now.Add(-time.Hour)},
+	}
+	assert.NoError(t, dbConnection.Create(&posts).Error)
+	for _, post := range posts {
+		assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: post.ID, Title: post.UniqueCode,
+			PostURL: "https://divar.ir/v/" + post.UniqueCode}).Error)
+	}
+	user := models.User{TelegramID: 42}
+	assert.NoError(t, dbConnection.Create(&user).Error)
+	assert.NoError(t, dbConnection.Create(&models.Bookmark{UserID: user.ID, PostID: posts[0].ID}).Error)
+
+	crawler := &pageCrawler{
+		pages: map[string]crawlerModels.Post{
+			"https://divar.ir/v/sold": {Title: "آپارتمان ۸۰ متری", Description: "این واحد فروخته شد"},
+			"https://divar.ir/v/live": {Title: "آپارتمان ۸۰ متری", Description: "سند تک برگ"},
+		},
+		errors: map[string]error{
+			"https://divar.ir/v/removed": crawlers.ErrPostRemoved,
+			"https://divar.ir/v/expired": crawlers.ErrPostExpired,
+			"https://divar.ir/v/down":    errors.New("timeout"),
+		},
+	}
+	lifecycleService := services.NewLifecycleService(db.NewPostLifecycleRepository(dbConnection), []crawlers.Crawler{crawler})
+	notifications := make([]string, 0)
+	lifecycleService.SetNotifier(func(user models.User, text string) {
+		notifications = append(notifications, text)
+	})
+
+	gone, err := lifecycleService.CheckStale(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, gone)
+	assert.Len(t, crawler.opened, 5)
+
+	statuses := make(map[string]models.Post)
+	var saved []models.Post
+	assert.NoError(t, dbConnection.Find(&saved).Error)
+	for _, post := range saved {
+		statuses[post.UniqueCode] = post
+	}
+	assert.Equal(t, models.POST_REMOVED, statuses["removed"].Status)
+	assert.Equal(t, models.POST_EXPIRED, statuses["expired"].Status)
+	assert.Equal(t, models.POST_SOLD, statuses["sold"].Status)
+	assert.Equal(t, models.POST_ACTIVE, statuses["live"].Status)
+	assert.Equal(t, models.POST_ACTIVE, statuses["down"].Status)
+	assert.Equal(t, 30, statuses["removed"].DaysOnMarket(now.AddDate(0, 0, 10)))
+	assert.Equal(t, 40, statuses["live"].DaysOnMarket(now.AddDate(0, 0, 10)))
+	assert.True(t, statuses["live"].LastSeenAt.Equal(now))
+
+	// only the bookmark owner of the removed post is told
+	if assert.Len(t, notifications, 1) {
+		assert.Contains(t, notifications[0], "was removed: removed")
+		assert.Contains(t, notifications[0], "30 days")
+	}
+
+	// checked posts are not opened again until they are stale again
+	crawler.opened = nil
+	_, err = lifecycleService.CheckStale(context.Background(), now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, crawler.opened)
+}
+
+func TestWatchListUsersToldWhenListingGoes(t *testing.T) {
+	dbConnection := newTestDB(t, &models.User{}, &models.Post{}, &models.PostHistory{}, &models.Bookmark{},
+		&models.FilterItem{}, &models.WatchList{})
+	now := time.Date(2024, 12, 20, 12, 0, 0, 0, time.UTC)
+	post := models.Post{UniqueCode: "removed", Website: types.Divar, Status: models.POST_ACTIVE,
+		FirstSeenAt: now.AddDate(0, 0, -12), LastSeenAt: now.Add(-72 * time.Hour)}
+	assert.NoError(t, dbConnection.Create(&post).Error)
+	assert.NoError(t, dbConnection.Create(&models.PostHistory{PostID: post.ID, Title: "removed", City: "tehran",
+		PostURL: "https://divar.ir/v/removed"}).Error)
+
+	// watcher's filter matches the post, stranger's does not, and both bookmarked and watched it
+	users := createTestUsers(t, dbConnection, models.USER, models.USER, models.USER)
+	watcher, stranger, both := users[0], users[1], users[2]
+	tehran := models.FilterItem{City: "tehran", UserID: watcher.ID}
+	shiraz := models.FilterItem{City: "shiraz", UserID: stranger.ID}
+	bothFilter := models.FilterItem{City: "tehran", UserID: both.ID}
+	assert.NoError(t, dbConnection.Create(&[]*models.FilterItem{&tehran, &shiraz, &bothFilter}).Error)
+	assert.NoError(t, dbConnection.Create(&[]models.WatchList{
+		{UserID: watcher.ID, FilterItemID: tehran.ID},
+		{UserID: watcher.ID, FilterItemID: tehran.ID},
+		{UserID: stranger.ID, FilterItemID: shiraz.ID},
+		{UserID: both.ID, FilterItemID: bothFilter.ID},
+	}).Error)
+	assert.NoError(t, dbConnection.Create(&models.Bookmark{UserID: both.ID, PostID: post.ID}).Error)
+
+	crawler := &pageCrawler{errors: map[string]error{"https://divar.ir/v/removed": crawlers.ErrPostRemoved}}
+	lifecycleService := services.NewLifecycleService(db.NewPostLifecycleRepository(dbConnection), []crawlers.Crawler{crawler})
+	notifications := make(map[uint64][]string)
+	lifecycleService.SetNotifier(func(user models.User, text string) {
+		notifications[user.TelegramID] = append(notifications[user.TelegramID], text)
+	})
+
+	status, err := lifecycleService.Check(context.Background(), post, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.POST_REMOVED, status)
+
+	assert.Len(t, notifications, 2)
+	if assert.Len(t, notifications[watcher.TelegramID], 1) {
+		assert.Contains(t, notifications[watcher.TelegramID][0], "matching your watchlist was removed: removed")
+		assert.Contains(t, notifications[watcher.TelegramID][0], "12 days")
+	}
+	if assert.Len(t, notifications[both.TelegramID], 1) {
+		assert.Contains(t, notifications[both.TelegramID][0], "you bookmarked was removed")
+	}
+}
+
+func TestIsSoldText(t *testing.T) {
+	assert.True(t, services.IsSoldText("آپارتمان ۸۰ متری (فروخته شد)"))
+	assert.True(t, services.IsSoldText("اجاره داده شد، ممنون از تماس شما"))
+	assert.False(t, services.IsSoldText("آپارتمان نوساز برای فروش"))
+}
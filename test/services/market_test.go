@@ -0,0 +1,138 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{40, 10, 30, 20}
+	assert.Equal(t, 10.0, services.Percentile(values, 0))
+	assert.Equal(t, 25.0, services.Percentile(values, 50))
+	assert.Equal(t, 17.5, services.Percentile(values, 25))
+	assert.Equal(t, 40.0, services.Percentile(values, 100))
+	assert.Equal(t, 0.0, services.Percentile(nil, 50))
+	assert.Equal(t, []float64{40, 10, 30, 20}, values, "the input is not sorted in place")
+}
+
+func TestWeekStartsOnSaturdayInTehran(t *testing.T) {
+	tehran := utils.TehranLocation()
+	saturday := time.Date(2024, 11, 2, 0, 0, 0, 0, tehran)
+
+	assert.True(t, saturday.UTC().Equal(services.WeekStart(time.Date(2024, 11, 2, 0, 0, 0, 0, tehran))))
+	assert.True(t, saturday.UTC().Equal(services.WeekStart(time.Date(2024, 11, 8, 23, 59, 0, 0, tehran))))
+	// 22:00 UTC on Friday is already Saturday in Tehran
+	assert.True(t, saturday.AddDate(0, 0, 7).UTC().Equal(services.WeekStart(time.Date(2024, 11, 8, 22, 0, 0, 0, time.UTC))))
+}
+
+func TestComputeMarketIndices(t *testing.T) {
+	monday := time.Date(2024, 11, 4, 12, 0, 0, 0, utils.TehranLocation())
+	samples := []models.PostHistory{
+		{PostID: 1, City: "tehran", Neighborhood: "punak", Building: types.Apartment, BuyMode: types.Shopping, Price: 5_000_000_000, Area: 100, CreatedAt: monday},
+		// a later snapshot of post 1 in the same week replaces the first one
+		{PostID: 1, City: "tehran", Neighborhood: "punak", Building: types.Apartment, BuyMode: types.Shopping, Price: 6_000_000_000, Area: 100, CreatedAt: monday.Add(time.Hour)},
+		{PostID: 2, City: "tehran", Neighborhood: "punak", Building: types.Villa, BuyMode: types.Shopping, Price: 16_000_000_000, Area: 200, CreatedAt: monday},
+		{PostID: 3, City: "tehran", Neighborhood: "vanak", Building: types.Apartment, BuyMode: types.Rent, Rent: 20_000_000, Deposit: 400_000_000, Area: 80, CreatedAt: monday},
+		{PostID: 4, City: "tehran", BuyMode: types.Shopping, Price: 1_000_000_000, Area: 0, CreatedAt: monday},
+	}
+
+	indices := services.ComputeMarketIndices(samples)
+	find := func(neighborhood string, building types.Building) models.MarketIndex {
+		for _, index := range indices {
+			if index.Neighborhood == neighborhood && index.Building == building {
+				return index
+			}
+		}
+		t.Fatalf("no index for %q %q", neighborhood, building)
+		return models.MarketIndex{}
+	}
+
+	city := find("", "")
+	assert.Equal(t, uint(2), city.SaleListings)
+	assert.Equal(t, 70_000_000.0, city.PricePerMeterMedian)
+	assert.Equal(t, uint(1), city.RentListings)
+	assert.Equal(t, 250_000.0, city.RentPerMeterMedian)
+	assert.Equal(t, 20.0, city.DepositToRentMedian)
+	assert.True(t, services.WeekStart(monday).Equal(city.Week))
+
+	punakApartments := find("punak", types.Apartment)
+	assert.Equal(t, uint(1), punakApartments.SaleListings)
+	assert.Equal(t, 60_000_000.0, punakApartments.PricePerMeterMedian)
+	assert.Equal(t, uint(1), find("", types.Villa).SaleListings)
+	assert.Equal(t, uint(0), find("vanak", "").SaleListings)
+	assert.Len(t, indices, 8)
+}
+
+func TestMarketRefreshReplacesWeeks(t *testing.T) {
+	dbConnection := newTestDB(t, &models.PostHistory{}, &models.MarketIndex{})
+	marketService := services.NewMarketService(db.NewMarketIndexRepository(dbConnection))
+
+	now := time.Now()
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{PostID: 1, City: "tehran", BuyMode: types.Shopping, Price: 5_000_000_000, Area: 100},
+		{PostID: 2, City: "karaj", BuyMode: types.Shopping, Price: 2_000_000_000, Area: 50},
+	}).Error)
+	assert.NoError(t, marketService.Refresh(now))
+	assert.NoError(t, marketService.Refresh(now), "refreshing twice does not duplicate rows")
+
+	indices, err := marketService.Indices("tehran", "", "", now)
+	assert.NoError(t, err)
+	assert.Len(t, indices, 1)
+	assert.Equal(t, 50_000_000.0, indices[0].PricePerMeterMedian)
+
+	cities, err := marketService.LatestCities()
+	assert.NoError(t, err)
+	assert.Len(t, cities, 2)
+}
+
+func TestClassifyBuilding(t *testing.T) {
+	tests := []struct {
+		category string
+		title    string
+		want     types.Building
+	}{
+		{"املاک فروش مسکونی آپارتمان", "۶۲ متر، ۶ ساله", types.Apartment},
+		{"املاک اجاره خانه و ویلا", "آپارتمان نقلی", types.Villa},
+		{"", "زمین ۲۰۰ متری مسکونی", types.Ground},
+		{"", "آپارتمان با زیرزمین", types.Apartment},
+		{"", "۸۰ متر، زیرزمین دار", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, services.ClassifyBuilding(test.category, test.title), test.title)
+	}
+}
+
+func TestOlderListingsCountInBuildingIndices(t *testing.T) {
+	dbConnection := newTestDB(t, &models.Post{}, &models.PostHistory{}, &models.MarketIndex{})
+	marketService := services.NewMarketService(db.NewMarketIndexRepository(dbConnection))
+
+	// saved before the buy mode and building type were set
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{PostID: 1, Title: "آپارتمان ۱۰۰ متری", City: "تهران", Neighborhood: "پونک", Price: 5_000_000_000, Area: 100},
+		{PostID: 2, Title: "آپارتمان ۵۰ متری", City: "تهران", Neighborhood: "پونک", Deposit: 500_000_000, Rent: 10_000_000, Area: 50},
+		{PostID: 3, Title: "ویلا ۲۰۰ متری", City: "تهران", Neighborhood: "پونک", Price: 9_000_000_000, Area: 200},
+	}).Error)
+
+	updated, err := db.NewPostRepository(dbConnection).BackfillBuyModes()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+	buildings, err := marketService.BackfillBuildings()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, buildings)
+
+	now := time.Now()
+	assert.NoError(t, marketService.Refresh(now))
+	indices, err := marketService.Indices("تهران", "پونک", types.Apartment, now)
+	assert.NoError(t, err)
+	assert.Len(t, indices, 1)
+	assert.Equal(t, uint(1), indices[0].SaleListings)
+	assert.Equal(t, uint(1), indices[0].RentListings)
+	assert.Equal(t, 50_000_000.0, indices[0].PricePerMeterMedian)
+}
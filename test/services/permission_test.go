@@ -0,0 +1,133 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newPermissionService(t *testing.T) (*services.PermissionService, *gorm.DB) {
+	dbConnection := newTestDB(t, &models.User{}, &models.RolePermission{}, &models.AuditLog{})
+	repository := db.NewPermissionRepository(dbConnection)
+	assert.NoError(t, repository.Seed(models.DefaultPermissions))
+	// seeding again keeps what is there
+	assert.NoError(t, repository.Seed(map[models.Role][]models.Permission{models.USER: {models.PERM_MANAGE_ADMINS}}))
+	return services.NewPermissionService(repository, db.NewAuditLogRepository(dbConnection),
+		db.CreateNewUserRepository(dbConnection)), dbConnection
+}
+
+func TestPermissionDefaults(t *testing.T) {
+	service, _ := newPermissionService(t)
+	superAdmin := models.User{ID: 1, Role: models.SUPER_ADMIN}
+	admin := models.User{ID: 2, Role: models.ADMIN}
+	user := models.User{ID: 3, Role: models.USER}
+
+	assert.True(t, service.Allowed(admin, models.PERM_SEARCH))
+	assert.True(t, service.Allowed(admin, models.PERM_MANAGE_USERS))
+	assert.False(t, service.Allowed(admin, models.PERM_MANAGE_ADMINS))
+	assert.False(t, service.Allowed(admin, models.PERM_CONFIGURE_CRAWLER))
+	assert.True(t, service.Allowed(user, models.PERM_EXPORT))
+	assert.False(t, service.Allowed(user, models.PERM_MANAGE_USERS))
+	assert.True(t, service.Allowed(user, ""))
+	for _, permission := range models.Permissions {
+		assert.True(t, service.Allowed(superAdmin, permission), permission)
+	}
+	// users that could not be saved have the zero role, which must not make them super admins
+	assert.False(t, service.Allowed(models.User{}, models.PERM_SEARCH))
+	assert.True(t, service.Allowed(models.User{}, ""))
+	assert.Equal(t, []models.Permission{models.PERM_SEARCH, models.PERM_EXPORT}, service.Permissions(models.USER))
+}
+
+func TestSetPermission(t *testing.T) {
+	service, _ := newPermissionService(t)
+	superAdmin := models.User{ID: 1, Role: models.SUPER_ADMIN}
+	admin := models.User{ID: 2, Role: models.ADMIN}
+
+	assert.NoError(t, service.SetPermission(superAdmin, models.ADMIN, models.PERM_MANAGE_USERS, false))
+	assert.False(t, service.Allowed(admin, models.PERM_MANAGE_USERS))
+	assert.NoError(t, service.SetPermission(superAdmin, models.ADMIN, models.PERM_MANAGE_USERS, true))
+	assert.NoError(t, service.SetPermission(superAdmin, models.ADMIN, models.PERM_MANAGE_USERS, true))
+	assert.True(t, service.Allowed(admin, models.PERM_MANAGE_USERS))
+	assert.NoError(t, service.SetPermission(superAdmin, models.ADMIN, models.PERM_CONFIGURE_CRAWLER, true))
+	assert.True(t, service.Allowed(admin, models.PERM_CONFIGURE_CRAWLER))
+
+	assert.ErrorIs(t, service.SetPermission(superAdmin, models.SUPER_ADMIN, models.PERM_MANAGE_ADMINS, false), services.ErrPermissionLockout)
+	assert.ErrorIs(t, service.SetPermission(superAdmin, models.ADMIN, "fly", true), services.ErrUnknownPermission)
+	assert.ErrorIs(t, service.SetPermission(superAdmin, models.Role(7), models.PERM_SEARCH, true), services.ErrUnknownPermission)
+	assert.True(t, service.Allowed(superAdmin, models.PERM_MANAGE_ADMINS))
+}
+
+func TestChangeRole(t *testing.T) {
+	service, dbConnection := newPermissionService(t)
+	superAdmin := models.User{TelegramID: 1, Role: models.SUPER_ADMIN}
+	other := models.User{TelegramID: 2, Role: models.SUPER_ADMIN}
+	admin := models.User{TelegramID: 3, Role: models.ADMIN}
+	user := models.User{TelegramID: 4, Role: models.USER}
+	for _, u := range []*models.User{&superAdmin, &other, &admin, &user} {
+		assert.NoError(t, dbConnection.Create(u).Error)
+	}
+
+	_, err := service.Demote(superAdmin, superAdmin.ID)
+	assert.ErrorIs(t, err, services.ErrOwnRole)
+	_, err = service.Demote(superAdmin, other.ID)
+	assert.ErrorIs(t, err, services.ErrRoleNotAllowed)
+	_, err = service.Revoke(admin, other.ID)
+	assert.ErrorIs(t, err, services.ErrRoleNotAllowed)
+	_, err = service.Demote(superAdmin, user.ID)
+	assert.ErrorIs(t, err, services.ErrLowestRole)
+	_, err = service.Demote(superAdmin, 99)
+	assert.ErrorIs(t, err, services.ErrRoleUserNotFound)
+
+	demoted, err := service.Demote(superAdmin, admin.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.USER, demoted.Role)
+	var saved models.User
+	assert.NoError(t, dbConnection.First(&saved, admin.ID).Error)
+	assert.Equal(t, models.USER, saved.Role)
+
+	assert.NoError(t, dbConnection.Model(&saved).Update("role", models.ADMIN).Error)
+	revoked, err := service.Revoke(superAdmin, admin.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.USER, revoked.Role)
+}
+
+func TestAuditLog(t *testing.T) {
+	service, dbConnection := newPermissionService(t)
+	admin := models.User{TelegramID: 1, Role: models.ADMIN}
+	assert.NoError(t, dbConnection.Create(&admin).Error)
+
+	service.Audit(admin, "errors", "", true)
+	service.Audit(admin, "admins", "", false)
+	entries, err := service.AuditLogs(1)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "admins", entries[0].Action)
+		assert.False(t, entries[0].Allowed)
+		assert.Equal(t, models.ADMIN, entries[0].Role)
+		assert.Equal(t, admin.TelegramID, entries[0].User.TelegramID)
+	}
+	entries, err = service.AuditLogs(10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestRoleNames(t *testing.T) {
+	for _, role := range models.Roles {
+		parsed, ok := models.ParseRole(role.String())
+		assert.True(t, ok)
+		assert.Equal(t, role, parsed)
+	}
+	_, ok := models.ParseRole("owner")
+	assert.False(t, ok)
+
+	assert.False(t, models.PERM_SEARCH.Privileged())
+	assert.False(t, models.PERM_EXPORT.Privileged())
+	assert.False(t, models.Permission("").Privileged())
+	assert.True(t, models.PERM_MANAGE_USERS.Privileged())
+	assert.True(t, models.PERM_MANAGE_ADMINS.Known())
+	assert.False(t, models.Permission("fly").Known())
+}
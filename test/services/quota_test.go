@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newQuotaService(t *testing.T) (*services.QuotaService, *gorm.DB) {
+	dbConnection := newTestDB(t, &models.User{}, &models.FilterItem{}, &models.WatchList{}, &models.UsageCounter{})
+
+	return services.NewQuotaService(
+		db.NewFilterItemRepository(dbConnection),
+		db.NewWatchListRepository(dbConnection),
+		db.NewUsageRepository(dbConnection),
+	), dbConnection
+}
+
+func TestQuotasFromConfig(t *testing.T) {
+	t.Setenv("QUOTA_FREE_FILTERS", "1")
+	t.Setenv("QUOTA_PREMIUM_DAILY_SEARCHES", "0")
+
+	quotas := services.LoadQuotas()
+	assert.Equal(t, 1, quotas[models.FREE].MaxFilters)
+	assert.Equal(t, 0, quotas[models.PREMIUM].MaxDailySearches, "zero means unlimited")
+	assert.Equal(t, 20, quotas[models.PREMIUM].MaxFilters)
+}
+
+func TestSaveFilterQuota(t *testing.T) {
+	t.Setenv("QUOTA_FREE_FILTERS", "2")
+	quotaService, dbConnection := newQuotaService(t)
+	free := models.User{ID: 1, TelegramID: 1, Role: models.USER, Type: models.FREE}
+	premium := models.User{ID: 2, TelegramID: 2, Role: models.USER, Type: models.PREMIUM}
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, quotaService.CheckSaveFilter(free))
+		assert.NoError(t, dbConnection.Create(&models.FilterItem{UserID: free.ID}).Error)
+	}
+
+	err := quotaService.CheckSaveFilter(free)
+	assert.True(t, errors.Is(err, services.ErrQuotaExceeded))
+	assert.Contains(t, err.Error(), "limit of 2 saved filters on the Free plan")
+	assert.Contains(t, err.Error(), "Premium users can have up to 20")
+
+	assert.NoError(t, quotaService.CheckSaveFilter(premium))
+}
+
+func TestWatchListQuota(t *testing.T) {
+	quotaService, dbConnection := newQuotaService(t)
+	free := models.User{ID: 1, Role: models.USER, Type: models.FREE}
+
+	err := quotaService.CheckWatchList(free, 15)
+	assert.True(t, errors.Is(err, services.ErrQuotaExceeded))
+	assert.Contains(t, err.Error(), "at most every 60 minutes")
+	free.Language = "fa"
+	err = quotaService.CheckWatchList(free, 15)
+	assert.Contains(t, err.Error(), "طرح رایگان دیده‌بان‌ها را حداکثر هر ۶۰ دقیقه")
+	free.Language = ""
+
+	assert.NoError(t, quotaService.CheckWatchList(free, 60))
+	assert.NoError(t, dbConnection.Create(&models.WatchList{UserID: free.ID, RefreshInterval: 60}).Error)
+	assert.True(t, errors.Is(quotaService.CheckWatchList(free, 60), services.ErrQuotaExceeded))
+
+	admin := models.User{ID: 3, Role: models.ADMIN}
+	assert.NoError(t, quotaService.CheckWatchList(admin, 1), "admins are not limited")
+}
+
+func TestDailySearchQuota(t *testing.T) {
+	t.Setenv("QUOTA_FREE_DAILY_SEARCHES", "3")
+	quotaService, _ := newQuotaService(t)
+	free := models.User{ID: 1, Role: models.USER, Type: models.FREE}
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, quotaService.UseSearch(free))
+	}
+	assert.True(t, errors.Is(quotaService.UseSearch(free), services.ErrQuotaExceeded))
+
+	usage, err := quotaService.Usage(free)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), usage.SearchesToday)
+	assert.Equal(t, 50, quotaService.ExportLimit(free))
+}
+
+func TestConcurrentSearchesKeepDailyQuota(t *testing.T) {
+	t.Setenv("QUOTA_FREE_DAILY_SEARCHES", "5")
+	quotaService, _ := newQuotaService(t)
+	free := models.User{ID: 1, Role: models.USER, Type: models.FREE}
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		allowed  int
+		exceeded int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := quotaService.UseSearch(free)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err == nil {
+				allowed++
+			} else if errors.Is(err, services.ErrQuotaExceeded) {
+				exceeded++
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5, allowed)
+	assert.Equal(t, 15, exceeded)
+
+	usage, err := quotaService.Usage(free)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), usage.SearchesToday)
+}
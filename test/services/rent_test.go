@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertRent(t *testing.T) {
+	fullDeposit, monthlyCost := services.ConvertRent(100_000_000, 10_000_000, 3)
+	assert.Equal(t, int64(433_333_333), fullDeposit)
+	assert.Equal(t, int64(13_000_000), monthlyCost)
+
+	fullDeposit, monthlyCost = services.ConvertRent(300_000_000, 0, 3)
+	assert.Equal(t, int64(300_000_000), fullDeposit)
+	assert.Equal(t, int64(9_000_000), monthlyCost)
+
+	fullDeposit, monthlyCost = services.ConvertRent(0, 0, 3)
+	assert.Zero(t, fullDeposit)
+	assert.Zero(t, monthlyCost)
+}
+
+func TestParseRate(t *testing.T) {
+	rate, err := services.ParseRate("2.5%")
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, rate)
+
+	rate, err = services.ParseRate("۳")
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, rate)
+
+	for _, value := range []string{"", "0", "-1", "50", "abc"} {
+		_, err := services.ParseRate(value)
+		assert.ErrorIs(t, err, services.ErrInvalidRate, value)
+	}
+}
+
+func TestSetRentRate(t *testing.T) {
+	dbConnection := newTestDB(t, &models.AppSetting{}, &models.PostHistory{}, &models.FilterItem{})
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{City: "tehran", BuyMode: types.Rent, Deposit: 600_000_000},
+		{City: "tehran", BuyMode: types.Rent, Deposit: 100_000_000, Rent: 10_000_000},
+		{City: "tehran", BuyMode: types.Shopping, Price: 8_000_000_000},
+	}).Error)
+
+	settingRepository := db.NewAppSettingRepository(dbConnection)
+	postRepository := db.NewPostRepository(dbConnection)
+	rentService := services.NewRentService(settingRepository, postRepository)
+	assert.Equal(t, 3.0, rentService.Rate())
+
+	_, err := rentService.SetRate(0, 1)
+	assert.ErrorIs(t, err, services.ErrInvalidRate)
+
+	updated, err := rentService.SetRate(4, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	assert.Equal(t, 4.0, rentService.Rate())
+
+	var posts []models.PostHistory
+	assert.NoError(t, dbConnection.Order("id").Find(&posts).Error)
+	assert.Equal(t, int64(24_000_000), posts[0].MonthlyCost)
+	assert.Equal(t, int64(600_000_000), posts[0].FullDeposit)
+	assert.Equal(t, int64(14_000_000), posts[1].MonthlyCost)
+	assert.Equal(t, int64(350_000_000), posts[1].FullDeposit)
+	assert.Zero(t, posts[2].MonthlyCost)
+
+	// the rate survives a restart
+	_, err = rentService.SetRate(2.5, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, services.NewRentService(settingRepository, postRepository).Rate())
+
+	// rentals can be narrowed and sorted by their monthly cost
+	filterRepository := db.NewFilterItemRepository(dbConnection)
+	results, err := filterRepository.SearchPostHistory(models.FilterItem{
+		Category:       string(types.Rent),
+		MonthlyCostMin: 10_000_000,
+		SortBy:         models.SORT_BY_MONTHLY_COST,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, int64(12_500_000), results[0].MonthlyCost)
+		assert.Equal(t, int64(15_000_000), results[1].MonthlyCost)
+	}
+}
+
+func TestRecomputeCoversRentalsSavedWithoutBuyMode(t *testing.T) {
+	dbConnection := newTestDB(t, &models.AppSetting{}, &models.PostHistory{})
+	// saved before the buy mode was inferred from the prices
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{City: "tehran", Deposit: 100_000_000, Rent: 10_000_000},
+		{City: "tehran", Rent: 20_000_000},
+		{City: "tehran", Price: 8_000_000_000},
+		{City: "tehran"},
+	}).Error)
+
+	rentService := services.NewRentService(db.NewAppSettingRepository(dbConnection), db.NewPostRepository(dbConnection))
+	updated, err := rentService.Recompute()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+
+	var posts []models.PostHistory
+	assert.NoError(t, dbConnection.Order("id").Find(&posts).Error)
+	assert.Equal(t, types.Rent, posts[0].BuyMode)
+	assert.Equal(t, int64(13_000_000), posts[0].MonthlyCost)
+	assert.Equal(t, int64(433_333_333), posts[0].FullDeposit)
+	assert.Equal(t, types.Rent, posts[1].BuyMode)
+	assert.Equal(t, int64(20_000_000), posts[1].MonthlyCost)
+	assert.Equal(t, types.Shopping, posts[2].BuyMode)
+	assert.Zero(t, posts[2].MonthlyCost)
+	assert.Empty(t, posts[3].BuyMode, "a post without any price stays unknown")
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifySeller(t *testing.T) {
+	tests := []struct {
+		label       string
+		description string
+		want        types.SellerType
+	}{
+		{"شخصی", "املاک بزرگ باراد", types.Owner},
+		{"مشاور‌املاک", "", types.Agency},
+		{" مشاور املاک ", "مالک هستم", types.Agency},
+		{"", "آپارتمان ۶۳ متری، مالک هستم و بدون واسطه", types.Owner},
+		{"", "فروش بدون کمیسیون، تماس با مالک", types.Owner},
+		{"", "مالک فروشنده قطعی\nفایلهای مشابه\nاملاک بزرگ باراد", types.Agency},
+		{"", "کد فایل ۱۲۳ مشاورین املاک پارس", types.Agency},
+		{"", "آژانس نمونه، بازدید با هماهنگی", types.Agency},
+		{"", "آپارتمان نوساز، نورگیر عالی", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, services.ClassifySeller(test.label, test.description), test.description)
+	}
+}
+
+func TestFilterBySellerType(t *testing.T) {
+	dbConnection := newTestDB(t, &models.PostHistory{}, &models.FilterItem{})
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		{City: "tehran", SellerType: types.Owner},
+		{City: "tehran", SellerType: types.Agency},
+		{City: "tehran"},
+	}).Error)
+
+	filterRepository := db.NewFilterItemRepository(dbConnection)
+	owners, err := filterRepository.SearchPostHistory(models.FilterItem{City: "tehran", SellerType: string(types.Owner)})
+	assert.NoError(t, err)
+	if assert.Len(t, owners, 1) {
+		assert.Equal(t, types.Owner, owners[0].SellerType)
+	}
+	all, err := filterRepository.CountPostHistory(models.FilterItem{City: "tehran"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), all)
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/i18n"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/payments"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type sentMessages struct {
+	mu       sync.Mutex
+	messages map[uint64][]string
+}
+
+func (s *sentMessages) notify(user models.User, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[user.TelegramID] = append(s.messages[user.TelegramID], text)
+}
+
+func (s *sentMessages) count(telegramID uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages[telegramID])
+}
+
+func newSubscriptionService(t *testing.T) (*services.SubscriptionService, *payments.FakeGateway, *gorm.DB, *sentMessages) {
+	t.Setenv("SUBSCRIPTION_GRACE_DAYS", "3")
+	t.Setenv("SUBSCRIPTION_REMINDER_DAYS", "3")
+	dbConnection := newTestDB(t, &models.User{}, &models.Subscription{}, &models.Invoice{})
+
+	gateway := payments.NewFakeGateway(false)
+	subscriptionService := services.NewSubscriptionService(
+		gateway,
+		db.NewSubscriptionRepository(dbConnection),
+		db.NewInvoiceRepository(dbConnection),
+		db.CreateNewUserRepository(dbConnection),
+	)
+	sent := &sentMessages{messages: make(map[uint64][]string)}
+	subscriptionService.SetNotifier(sent.notify)
+	return subscriptionService, gateway, dbConnection, sent
+}
+
+func createUser(t *testing.T, dbConnection *gorm.DB, telegramID uint64) models.User {
+	user := models.User{TelegramID: telegramID, Role: models.USER, Type: models.FREE}
+	assert.NoError(t, dbConnection.Create(&user).Error)
+	return user
+}
+
+func userType(t *testing.T, dbConnection *gorm.DB, user models.User) models.UserType {
+	var found models.User
+	assert.NoError(t, dbConnection.First(&found, user.ID).Error)
+	return found.Type
+}
+
+func TestPlanPricesFromConfig(t *testing.T) {
+	t.Setenv("PLAN_MONTHLY_PRICE", "1500000")
+
+	plans := services.LoadPlans()
+	assert.Equal(t, "monthly", plans[0].ID)
+	assert.Equal(t, int64(1500000), plans[0].Price)
+	assert.Equal(t, int64(2700000), plans[1].Price)
+}
+
+func TestPaymentCallbackIsIdempotent(t *testing.T) {
+	subscriptionService, gateway, dbConnection, sent := newSubscriptionService(t)
+	user := createUser(t, dbConnection, 100)
+	ctx := context.Background()
+
+	invoice, paymentURL, err := subscriptionService.CreateInvoice(ctx, user, "monthly")
+	assert.NoError(t, err)
+	assert.Contains(t, paymentURL, invoice.Authority)
+	assert.Equal(t, models.INVOICE_PENDING, invoice.Status)
+
+	_, err = subscriptionService.HandleCallback(ctx, invoice.Authority, "OK")
+	assert.True(t, errors.Is(err, payments.ErrPaymentFailed), "the payment was not done yet")
+
+	invoice, _, err = subscriptionService.CreateInvoice(ctx, user, "monthly")
+	assert.NoError(t, err)
+	gateway.MarkPaid(invoice.Authority)
+
+	first, err := subscriptionService.HandleCallback(ctx, invoice.Authority, "OK")
+	assert.NoError(t, err)
+	second, err := subscriptionService.HandleCallback(ctx, invoice.Authority, "OK")
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), first.EndsAt, time.Minute)
+
+	var subscriptions int64
+	dbConnection.Model(&models.Subscription{}).Count(&subscriptions)
+	assert.Equal(t, int64(1), subscriptions)
+	assert.Equal(t, models.PREMIUM, userType(t, dbConnection, user))
+	assert.Equal(t, 1, sent.count(user.TelegramID))
+
+	_, err = subscriptionService.HandleCallback(ctx, "UNKNOWN", "OK")
+	assert.True(t, errors.Is(err, payments.ErrUnknownPayment))
+}
+
+func TestCanceledPaymentFailsInvoice(t *testing.T) {
+	subscriptionService, gateway, dbConnection, _ := newSubscriptionService(t)
+	user := createUser(t, dbConnection, 100)
+	ctx := context.Background()
+
+	invoice, _, err := subscriptionService.CreateInvoice(ctx, user, "quarterly")
+	assert.NoError(t, err)
+	_, err = subscriptionService.HandleCallback(ctx, invoice.Authority, "NOK")
+	assert.True(t, errors.Is(err, payments.ErrPaymentFailed))
+
+	// paying afterwards does not revive a failed invoice
+	gateway.MarkPaid(invoice.Authority)
+	_, err = subscriptionService.HandleCallback(ctx, invoice.Authority, "OK")
+	assert.True(t, errors.Is(err, payments.ErrPaymentFailed))
+	assert.Equal(t, models.FREE, userType(t, dbConnection, user))
+
+	_, _, err = subscriptionService.CreateInvoice(ctx, user, "weekly")
+	assert.True(t, errors.Is(err, services.ErrUnknownPlan))
+}
+
+func TestRenewalStartsWhenCurrentSubscriptionEnds(t *testing.T) {
+	subscriptionService, _, dbConnection, _ := newSubscriptionService(t)
+	user := createUser(t, dbConnection, 100)
+
+	first, err := subscriptionService.Grant(user, "monthly")
+	assert.NoError(t, err)
+	renewal, err := subscriptionService.Grant(user, "quarterly")
+	assert.NoError(t, err)
+
+	assert.True(t, renewal.StartsAt.Equal(first.EndsAt))
+	assert.True(t, renewal.EndsAt.Equal(first.EndsAt.AddDate(0, 0, 90)))
+
+	current, err := subscriptionService.Current(user)
+	assert.NoError(t, err)
+	assert.Equal(t, renewal.ID, current.ID)
+}
+
+func TestExpiryRemindsThenDowngradesAfterGrace(t *testing.T) {
+	subscriptionService, _, dbConnection, sent := newSubscriptionService(t)
+	user := createUser(t, dbConnection, 100)
+	user.Language = "fa"
+	assert.NoError(t, dbConnection.Save(&user).Error)
+
+	subscription, err := subscriptionService.Grant(user, "monthly")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent.count(user.TelegramID))
+
+	// nothing happens while the subscription is far from its end
+	assert.NoError(t, subscriptionService.CheckExpiry(time.Now()))
+	assert.Equal(t, 1, sent.count(user.TelegramID))
+
+	reminderTime := subscription.EndsAt.AddDate(0, 0, -2)
+	assert.NoError(t, subscriptionService.CheckExpiry(reminderTime))
+	assert.NoError(t, subscriptionService.CheckExpiry(reminderTime.Add(time.Hour)))
+	assert.Equal(t, 2, sent.count(user.TelegramID), "the reminder is sent once")
+	assert.Contains(t, sent.messages[user.TelegramID][1], i18n.Date(i18n.Persian, subscription.EndsAt),
+		"the reminder has the Jalali date in Persian digits")
+
+	assert.NoError(t, subscriptionService.CheckExpiry(subscription.EndsAt.Add(time.Hour)))
+	assert.Equal(t, 3, sent.count(user.TelegramID))
+	assert.Equal(t, models.PREMIUM, userType(t, dbConnection, user), "premium is kept during the grace period")
+
+	assert.NoError(t, subscriptionService.CheckExpiry(subscription.GraceEndsAt.Add(time.Hour)))
+	assert.Equal(t, 4, sent.count(user.TelegramID))
+	assert.Equal(t, models.FREE, userType(t, dbConnection, user))
+
+	current, err := subscriptionService.Current(user)
+	assert.NoError(t, err)
+	assert.Zero(t, current.ID)
+}
+
+func TestRenewedSubscriptionIsNotDowngraded(t *testing.T) {
+	subscriptionService, _, dbConnection, sent := newSubscriptionService(t)
+	user := createUser(t, dbConnection, 100)
+
+	first, err := subscriptionService.Grant(user, "monthly")
+	assert.NoError(t, err)
+	_, err = subscriptionService.Grant(user, "monthly")
+	assert.NoError(t, err)
+
+	assert.NoError(t, subscriptionService.CheckExpiry(first.EndsAt.AddDate(0, 0, -1)))
+	assert.NoError(t, subscriptionService.CheckExpiry(first.GraceEndsAt.Add(time.Hour)))
+	assert.Equal(t, 2, sent.count(user.TelegramID), "no reminder or expiry message for a renewed subscription")
+	assert.Equal(t, models.PREMIUM, userType(t, dbConnection, user))
+}
+
+func TestLegacyPremiumGetsSubscription(t *testing.T) {
+	subscriptionService, _, dbConnection, sent := newSubscriptionService(t)
+	legacy := models.User{TelegramID: 100, Role: models.USER, Type: models.PREMIUM}
+	assert.NoError(t, dbConnection.Create(&legacy).Error)
+	subscribed := createUser(t, dbConnection, 200)
+	_, err := subscriptionService.Grant(subscribed, "yearly")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.NoError(t, subscriptionService.CheckExpiry(now))
+	assert.NoError(t, subscriptionService.CheckExpiry(now.Add(time.Hour)))
+	assert.Equal(t, 1, sent.count(legacy.TelegramID), "the legacy user is told once")
+	assert.Equal(t, 1, sent.count(subscribed.TelegramID))
+
+	current, err := subscriptionService.Current(legacy)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, now.AddDate(0, 0, 30), current.EndsAt, time.Minute)
+
+	assert.NoError(t, subscriptionService.CheckExpiry(current.EndsAt.Add(time.Hour)))
+	assert.NoError(t, subscriptionService.CheckExpiry(current.GraceEndsAt.Add(time.Hour)))
+	assert.Equal(t, models.FREE, userType(t, dbConnection, legacy))
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens an empty in-memory database with the tables of the given models
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	dbConnection, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	// every connection of the pool would open its own empty in-memory database
+	sqlDB, err := dbConnection.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, dbConnection.AutoMigrate(tables...))
+	return dbConnection
+}
+
+// createTestUsers saves a user of each role, their Telegram IDs count from 1
+func createTestUsers(t *testing.T, dbConnection *gorm.DB, roles ...models.Role) []models.User {
+	t.Helper()
+	users := make([]models.User, 0, len(roles))
+	for i, role := range roles {
+		user := models.User{TelegramID: uint64(i + 1), Role: role}
+		assert.NoError(t, dbConnection.Create(&user).Error)
+		users = append(users, user)
+	}
+	return users
+}
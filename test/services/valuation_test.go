@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/services"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func sale(postID uint, neighborhood string, area int, age uint8, price int64) models.PostHistory {
+	return models.PostHistory{
+		PostID: postID, City: "tehran", Neighborhood: neighborhood, BuyMode: types.Shopping, Building: types.Apartment,
+		Area: area, Age: age, FloorsNum: 3, BedroomNum: 2, Price: price,
+	}
+}
+
+func TestEstimatePrefersClosestComparables(t *testing.T) {
+	post := sale(1, "punak", 100, 5, 9_000_000_000)
+	candidates := []models.PostHistory{
+		sale(2, "punak", 100, 5, 6_000_000_000),
+		sale(3, "punak", 105, 6, 6_300_000_000),
+		sale(4, "punak", 95, 4, 5_700_000_000),
+		// far away listings are left out by k
+		sale(5, "vanak", 140, 30, 20_000_000_000),
+		sale(6, "vanak", 60, 0, 1_000_000_000),
+	}
+
+	valuation, err := services.Estimate(post, candidates, 3)
+	assert.NoError(t, err)
+	assert.Len(t, valuation.Comparables, 3)
+	assert.Equal(t, uint(2), valuation.Comparables[0].Post.PostID, "the identical listing is the closest")
+	assert.InDelta(t, 6_000_000_000, valuation.Estimate, 10_000_000)
+	assert.LessOrEqual(t, valuation.Low, valuation.Estimate)
+	assert.GreaterOrEqual(t, valuation.High, valuation.Estimate)
+	assert.Equal(t, services.OVERPRICED, valuation.Verdict)
+
+	post.Price = 6_000_000_000
+	valuation, err = services.Estimate(post, candidates, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, services.FAIR_PRICE, valuation.Verdict)
+}
+
+func TestEstimateComparesRentalsByMonthlyCost(t *testing.T) {
+	rental := func(postID uint, deposit int64, rent int64) models.PostHistory {
+		post := sale(postID, "punak", 100, 5, 0)
+		post.BuyMode = types.Rent
+		post.Deposit, post.Rent = deposit, rent
+		post.FullDeposit, post.MonthlyCost = services.ConvertRent(deposit, rent, 3)
+		return post
+	}
+	// a high deposit with a low rent costs as much as a low deposit with a high rent
+	post := rental(1, 1_000_000_000, 5_000_000)
+	candidates := []models.PostHistory{
+		rental(2, 100_000_000, 32_000_000),
+		rental(3, 200_000_000, 29_000_000),
+		rental(4, 0, 35_000_000),
+	}
+
+	valuation, err := services.Estimate(post, candidates, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(35_000_000), valuation.Asked)
+	assert.Equal(t, services.FAIR_PRICE, valuation.Verdict)
+
+	post.MonthlyCost = 0
+	_, err = services.Estimate(post, candidates, 3)
+	assert.True(t, errors.Is(err, services.ErrNotValuable), "the rent alone says nothing of a rental with a deposit")
+}
+
+func TestEstimateNeedsComparables(t *testing.T) {
+	post := sale(1, "punak", 100, 5, 6_000_000_000)
+	_, err := services.Estimate(post, []models.PostHistory{sale(2, "punak", 100, 5, 6_000_000_000)}, 8)
+	assert.True(t, errors.Is(err, services.ErrNotEnoughComparables))
+
+	post.Area = 0
+	_, err = services.Estimate(post, nil, 8)
+	assert.True(t, errors.Is(err, services.ErrNotValuable))
+}
+
+func TestValueSearchesComparableListings(t *testing.T) {
+	dbConnection := newTestDB(t, &models.PostHistory{})
+
+	post := sale(1, "punak", 100, 5, 5_000_000_000)
+	assert.NoError(t, dbConnection.Create(&post).Error)
+	assert.NoError(t, dbConnection.Create(&[]models.PostHistory{
+		// an older snapshot of the same post is not its own comparable
+		sale(1, "punak", 100, 5, 4_000_000_000),
+		sale(2, "punak", 100, 5, 6_000_000_000),
+		sale(2, "punak", 100, 5, 6_000_000_000),
+		sale(3, "punak", 110, 5, 6_600_000_000),
+		sale(4, "punak", 90, 5, 5_400_000_000),
+		// other cities, buy modes and too different areas are not compared
+		{PostID: 5, City: "karaj", Neighborhood: "punak", BuyMode: types.Shopping, Building: types.Apartment, Area: 100, Price: 1_000_000_000},
+		{PostID: 6, City: "tehran", Neighborhood: "punak", BuyMode: types.Rent, Building: types.Apartment, Area: 100, Rent: 10_000_000},
+		sale(7, "punak", 300, 5, 30_000_000_000),
+	}).Error)
+
+	valuationService := services.NewValuationService(db.NewPostRepository(dbConnection))
+	valuation, err := valuationService.Value(post.ID, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, valuation.Comparables, 3)
+	assert.Equal(t, int64(6_000_000_000), valuation.Estimate)
+	assert.Equal(t, services.UNDERPRICED, valuation.Verdict)
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/MagicalCrawler/RealEstateApp/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Put("originals/ab/abcd", []byte("photo")))
+	content, err := store.Get("originals/ab/abcd")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("photo"), content)
+
+	assert.NoError(t, store.Delete("originals/ab/abcd"))
+	assert.NoError(t, store.Delete("originals/ab/abcd"))
+	_, err = store.Get("originals/ab/abcd")
+	assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+
+	for _, key := range []string{"", "../secret", "/etc/passwd", "a//b", "a/./b"} {
+		assert.ErrorIs(t, store.Put(key, []byte("x")), storage.ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,130 @@
+package tracking
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/db"
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/tracking"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type memoryStore struct {
+	events []models.ErrorEvent
+}
+
+func (s *memoryStore) Record(event models.ErrorEvent) (models.ErrorEvent, error) {
+	s.events = append(s.events, event)
+	return event, nil
+}
+
+func newRepository(t *testing.T) db.ErrorEventRepository {
+	dbConnection, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, dbConnection.AutoMigrate(&models.ErrorEvent{}))
+	return db.NewErrorEventRepository(dbConnection)
+}
+
+func TestFingerprintMasksURLsAndNumbers(t *testing.T) {
+	first := tracking.Fingerprint(models.CRAWLER_ERROR, types.Divar, "timeout 30000ms navigating to https://divar.ir/v/a/wZ0kfXs_", "divar.Crawl")
+	second := tracking.Fingerprint(models.CRAWLER_ERROR, types.Divar, "timeout 15000ms navigating to https://divar.ir/v/b/gYa9Zm2p", "divar.Crawl")
+	assert.Equal(t, first, second)
+
+	assert.NotEqual(t, first, tracking.Fingerprint(models.CRAWLER_ERROR, types.Sheypoor, "timeout 30000ms navigating to https://divar.ir/v/a/wZ0kfXs_", "divar.Crawl"))
+	assert.NotEqual(t, first, tracking.Fingerprint(models.CRAWLER_ERROR, types.Divar, "timeout 30000ms navigating to https://divar.ir/v/a/wZ0kfXs_", "divar.CrawlPostDetails"))
+}
+
+func TestCaptureGroupsByCaller(t *testing.T) {
+	store := &memoryStore{}
+	tracking.SetStore(store)
+	defer tracking.SetStore(nil)
+
+	for i := 0; i < 2; i++ {
+		tracking.Capture(models.BOT_ERROR, errors.New("fetching posts: connection refused"), tracking.Origin{})
+	}
+	tracking.Capture(models.BOT_ERROR, nil, tracking.Origin{})
+
+	assert.Len(t, store.events, 2)
+	assert.Equal(t, store.events[0].Fingerprint, store.events[1].Fingerprint)
+	assert.Contains(t, store.events[0].Stack, "TestCaptureGroupsByCaller")
+}
+
+func panicking() {
+	var post *models.Post
+	_ = post.UniqueCode
+}
+
+func TestCapturePanic(t *testing.T) {
+	store := &memoryStore{}
+	tracking.SetStore(store)
+	defer tracking.SetStore(nil)
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				tracking.CapturePanic(models.BOT_ERROR, recovered, tracking.Origin{})
+			}
+		}()
+		panicking()
+	}()
+
+	assert.Len(t, store.events, 1)
+	assert.Contains(t, store.events[0].Message, "panic: runtime error")
+	assert.Contains(t, store.events[0].Stack, "tracking.panicking")
+	assert.Equal(t, tracking.Fingerprint(models.BOT_ERROR, "", store.events[0].Message,
+		"github.com/MagicalCrawler/RealEstateApp/test/tracking.panicking"), store.events[0].Fingerprint)
+}
+
+func TestRecordCountsOccurrencesAndReopens(t *testing.T) {
+	repository := newRepository(t)
+	event := tracking.NewEvent(models.CRAWLER_ERROR, "blocked by host: status 429", tracking.Origin{Source: types.Divar, City: "tehran"}, nil)
+
+	stored, err := repository.Record(event)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), stored.Occurrences)
+	assert.Equal(t, models.ERROR_OPEN, stored.Status)
+
+	_, err = repository.UpdateStatus(stored.ID, models.ERROR_ACKNOWLEDGED)
+	assert.NoError(t, err)
+	event.LastSeenAt = event.LastSeenAt.Add(time.Minute)
+	event.City = "karaj"
+	stored, err = repository.Record(event)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), stored.Occurrences)
+	assert.Equal(t, models.ERROR_ACKNOWLEDGED, stored.Status, "acknowledged errors stay acknowledged")
+	assert.Equal(t, "karaj", stored.City)
+
+	_, err = repository.UpdateStatus(stored.ID, models.ERROR_RESOLVED)
+	assert.NoError(t, err)
+	stored, err = repository.Record(event)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), stored.Occurrences)
+	assert.Equal(t, models.ERROR_OPEN, stored.Status, "a resolved error that happens again is reopened")
+}
+
+func TestFindPageFilters(t *testing.T) {
+	repository := newRepository(t)
+	for i, message := range []string{"bot failure", "crawler failure a", "crawler failure b"} {
+		category := models.CRAWLER_ERROR
+		if i == 0 {
+			category = models.BOT_ERROR
+		}
+		_, err := repository.Record(tracking.NewEvent(category, message, tracking.Origin{}, nil))
+		assert.NoError(t, err)
+	}
+
+	events, total, err := repository.FindPage(models.ErrorEventFilter{Category: models.CRAWLER_ERROR}, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, events, 1)
+
+	events, total, err = repository.FindPage(models.ErrorEventFilter{Status: models.ERROR_RESOLVED}, 0, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+	assert.Empty(t, events)
+}
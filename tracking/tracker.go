@@ -0,0 +1,146 @@
+package tracking
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MagicalCrawler/RealEstateApp/models"
+	"github.com/MagicalCrawler/RealEstateApp/types"
+	"github.com/MagicalCrawler/RealEstateApp/utils"
+)
+
+const maxStackFrames = 20
+
+// Store persists error events, db.ErrorEventRepository satisfies it
+type Store interface {
+	Record(event models.ErrorEvent) (models.ErrorEvent, error)
+}
+
+// Origin tells where an error happened, every field is optional
+type Origin struct {
+	Source types.WebsiteSource
+	City   string
+	URL    string
+}
+
+var (
+	storeMutex sync.RWMutex
+	store      Store
+)
+
+var loggerProducer func() *slog.Logger = sync.OnceValue(func() *slog.Logger {
+	return utils.NewLogger("Tracking")
+})
+
+var (
+	urlPattern    = regexp.MustCompile(`https?://\S+`)
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+// SetStore sets where captured errors are written; until it is called Capture does nothing
+func SetStore(s Store) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	store = s
+}
+
+// Capture records err under category. Events with the same category, source, normalized
+// message and calling function are grouped together.
+func Capture(category models.ErrorCategory, err error, origin Origin) {
+	if err == nil {
+		return
+	}
+	capture(category, err.Error(), origin, callers(3))
+}
+
+// CapturePanic records a recovered panic value as an error of category, it must be called from the deferred function
+func CapturePanic(category models.ErrorCategory, recovered any, origin Origin) {
+	frames := callers(3)
+	// group panics by the function that panicked rather than by the deferred handler that recovered
+	for i, frame := range frames {
+		if frame.Function == "runtime.gopanic" {
+			frames = frames[i+1:]
+			break
+		}
+	}
+	capture(category, fmt.Sprintf("panic: %v", recovered), origin, frames)
+}
+
+func capture(category models.ErrorCategory, message string, origin Origin, frames []runtime.Frame) {
+	storeMutex.RLock()
+	s := store
+	storeMutex.RUnlock()
+	if s == nil {
+		return
+	}
+
+	if _, err := s.Record(NewEvent(category, message, origin, frames)); err != nil {
+		loggerProducer().Error("could not record error event", slog.String("message", message), slog.Any("error", err))
+	}
+}
+
+// NewEvent builds an ErrorEvent with its fingerprint from a message and the stack frames of the caller
+func NewEvent(category models.ErrorCategory, message string, origin Origin, frames []runtime.Frame) models.ErrorEvent {
+	now := time.Now()
+	var function string
+	for _, frame := range frames {
+		// the panic machinery says nothing about where the failure is
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			function = frame.Function
+			break
+		}
+	}
+
+	return models.ErrorEvent{
+		Fingerprint: Fingerprint(category, origin.Source, message, function),
+		Category:    category,
+		Source:      origin.Source,
+		City:        origin.City,
+		URL:         origin.URL,
+		Message:     message,
+		Stack:       formatStack(frames),
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+}
+
+// Fingerprint hashes the parts of an error that stay the same between occurrences.
+// URLs and numbers are masked so the same failure on different pages is grouped.
+func Fingerprint(category models.ErrorCategory, source types.WebsiteSource, message string, function string) string {
+	normalized := urlPattern.ReplaceAllString(message, "<url>")
+	normalized = numberPattern.ReplaceAllString(normalized, "<n>")
+
+	hash := sha1.Sum([]byte(strings.Join([]string{string(category), string(source), normalized, function}, "|")))
+	return hex.EncodeToString(hash[:])
+}
+
+func callers(skip int) []runtime.Frame {
+	pcs := make([]uintptr, maxStackFrames)
+	n := runtime.Callers(skip, pcs)
+	iterator := runtime.CallersFrames(pcs[:n])
+
+	var frames []runtime.Frame
+	for {
+		frame, more := iterator.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+func formatStack(frames []runtime.Frame) string {
+	var builder strings.Builder
+	for _, frame := range frames {
+		fmt.Fprintf(&builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return builder.String()
+}
@@ -0,0 +1,45 @@
+package types
+
+// Amenity is a yes or no amenity of a listing
+type Amenity string
+
+const (
+	Balcony  Amenity = "balcony"
+	Rooftop  Amenity = "rooftop"
+	Pool     Amenity = "pool"
+	Sauna    Amenity = "sauna"
+	Jacuzzi  Amenity = "jacuzzi"
+	Gym      Amenity = "gym"
+	Lobby    Amenity = "lobby"
+	Guard    Amenity = "guard"
+	Storage  Amenity = "storage"
+	Parking  Amenity = "parking"
+	Elevator Amenity = "elevator"
+)
+
+type Renovation string
+
+const (
+	NewlyBuilt      Renovation = "new"
+	Renovated       Renovation = "renovated"
+	NeedsRenovation Renovation = "needs_renovation"
+)
+
+// DocumentType is the kind of ownership document of a property
+type DocumentType string
+
+const (
+	SingleDeed DocumentType = "single_deed" // سند تک برگ
+	MultiDeed  DocumentType = "multi_deed"  // سند منگوله دار
+	Endowment  DocumentType = "endowment"   // وقفی
+	Promissory DocumentType = "promissory"  // قولنامه ای
+)
+
+type Orientation string
+
+const (
+	North Orientation = "north"
+	South Orientation = "south"
+	East  Orientation = "east"
+	West  Orientation = "west"
+)
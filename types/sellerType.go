@@ -0,0 +1,8 @@
+package types
+
+type SellerType string
+
+const (
+	Owner  SellerType = "owner"
+	Agency SellerType = "agency"
+)
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/csv"
 	"strconv"
+	"time"
 
+	"github.com/MagicalCrawler/RealEstateApp/jalali"
 	"github.com/MagicalCrawler/RealEstateApp/models"
 )
 
@@ -16,6 +18,7 @@ func ExportCSV(input []models.PostHistory) ([]byte, error) {
 			"title", "url", "price", "deposit", "rent", "city", "neighbor", "area", "bedroom",
 			"mode", "type", "age", "floor", "storage", "parking", "elevator",
 			"img", "Description", "Capacity", "NormalDays", "weekend", "holidays", "CostPerPerson",
+			"date",
 		},
 	}
 	for _, post := range input {
@@ -25,6 +28,7 @@ func ExportCSV(input []models.PostHistory) ([]byte, error) {
 			string(post.BuyMode), string(post.Building), strconv.Itoa(int(post.Age)), strconv.Itoa(int(post.FloorsNum)),
 			strconv.FormatBool(post.HasStorage), strconv.FormatBool(post.HasParking), strconv.FormatBool(post.HasElevator),
 			post.ImageURL, post.Description, post.Capacity, post.NormalDays, post.Weekend, post.Holidays, post.CostPerPerson,
+			exportDate(post.CreatedAt),
 		})
 	}
 	w.WriteAll(csvResult)
@@ -32,4 +36,12 @@ func ExportCSV(input []models.PostHistory) ([]byte, error) {
 		return nil, err
 	}
 	return buf.Bytes(), nil
+}
+
+// exportDate writes the Jalali day a post history was seen in Tehran
+func exportDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return jalali.FromTime(date.In(TehranLocation())).String()
 }
\ No newline at end of file
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+var tehranLocationProducer func() *time.Location = sync.OnceValue(func() *time.Location {
+	location, err := time.LoadLocation("Asia/Tehran")
+	if err != nil {
+		// Iran has no daylight saving time since 2022, so a fixed offset is enough when tzdata is missing
+		return time.FixedZone("IRST", 3*60*60+30*60)
+	}
+	return location
+})
+
+// TehranLocation returns the time zone used for day boundaries in reports
+func TehranLocation() *time.Location {
+	return tehranLocationProducer()
+}
+
+// TehranDayStart returns the midnight in Tehran that starts the day of t
+func TehranDayStart(t time.Time) time.Time {
+	local := t.In(TehranLocation())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, TehranLocation())
+}